@@ -0,0 +1,155 @@
+// Package apperror defines the typed errors the API answers with. Each kind of error has a stable code,
+// which clients can rely on whatever the language of the message, and an HTTP status.
+package apperror
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Code identifies the kind of an error in responses
+type Code string
+
+const (
+	CodeInvalidRequest  Code = "invalid_request"
+	CodeValidation      Code = "validation_failed"
+	CodeUnauthorized    Code = "unauthorized"
+	CodeForbidden       Code = "forbidden"
+	CodeNotFound        Code = "not_found"
+	CodeConflict        Code = "conflict"
+	CodePayloadTooLarge Code = "payload_too_large"
+	CodeRateLimited     Code = "rate_limited"
+	CodeInternal        Code = "internal_error"
+	CodeTimeout         Code = "timeout"
+)
+
+// statuses maps every code to the HTTP status it is answered with
+var statuses = map[Code]int{
+	CodeInvalidRequest:  http.StatusBadRequest,
+	CodeValidation:      http.StatusBadRequest,
+	CodeUnauthorized:    http.StatusUnauthorized,
+	CodeForbidden:       http.StatusForbidden,
+	CodeNotFound:        http.StatusNotFound,
+	CodeConflict:        http.StatusConflict,
+	CodePayloadTooLarge: http.StatusRequestEntityTooLarge,
+	CodeRateLimited:     http.StatusTooManyRequests,
+	CodeInternal:        http.StatusInternalServerError,
+	CodeTimeout:         http.StatusGatewayTimeout,
+}
+
+// Error is an error meant to be answered to the client. The message is shown to the client (translated when
+// a translation exists), the cause is only logged.
+type Error struct {
+	Code    Code
+	Message string
+
+	// Fields holds the messages per invalid field of validation errors
+	Fields map[string][]string
+
+	// Details holds additional data for the client, e.g. the shifts a new shift overlaps
+	Details map[string]interface{}
+
+	// RetryAfter tells rate limited clients when to retry
+	RetryAfter time.Duration
+
+	cause error
+}
+
+// Error returns the message of the error
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the cause of the error, if any
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Status returns the HTTP status of the error
+func (e *Error) Status() int {
+	if status, ok := statuses[e.Code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// Is matches errors of the same code and message, so that a copy extended with WithDetails or
+// WithCause still matches the sentinel it was made from
+func (e *Error) Is(target error) bool {
+	var other *Error
+	return errors.As(target, &other) && other != nil && other.Code == e.Code && other.Message == e.Message
+}
+
+// WithDetails returns a copy of the error with additional data for the client
+func (e *Error) WithDetails(key string, value interface{}) *Error {
+	copied := *e
+	copied.Details = map[string]interface{}{}
+	for k, v := range e.Details {
+		copied.Details[k] = v
+	}
+	copied.Details[key] = value
+	return &copied
+}
+
+// WithCause returns a copy of the error recording the error that caused it
+func (e *Error) WithCause(cause error) *Error {
+	copied := *e
+	copied.cause = cause
+	return &copied
+}
+
+// BadRequest reports a request that cannot be understood, e.g. a malformed body or ID
+func BadRequest(message string) *Error {
+	return &Error{Code: CodeInvalidRequest, Message: message}
+}
+
+// Validation reports invalid input, with the messages per invalid field when known
+func Validation(message string, fields map[string][]string) *Error {
+	return &Error{Code: CodeValidation, Message: message, Fields: fields}
+}
+
+// InvalidField reports a single invalid field
+func InvalidField(field string, message string) *Error {
+	return Validation(message, map[string][]string{field: {message}})
+}
+
+// Unauthorized reports missing or wrong credentials
+func Unauthorized(message string) *Error {
+	return &Error{Code: CodeUnauthorized, Message: message}
+}
+
+// Forbidden reports an action that is not allowed
+func Forbidden(message string) *Error {
+	return &Error{Code: CodeForbidden, Message: message}
+}
+
+// NotFound reports a missing record
+func NotFound(message string) *Error {
+	return &Error{Code: CodeNotFound, Message: message}
+}
+
+// Conflict reports an action conflicting with the current state, e.g. a duplicate
+func Conflict(message string) *Error {
+	return &Error{Code: CodeConflict, Message: message}
+}
+
+// PayloadTooLarge reports a request body or an uploaded file above its size limit
+func PayloadTooLarge(message string) *Error {
+	return &Error{Code: CodePayloadTooLarge, Message: message}
+}
+
+// RateLimited reports a client sending too many requests
+func RateLimited(retryAfter time.Duration) *Error {
+	return &Error{Code: CodeRateLimited, Message: "Too many requests, please retry later", RetryAfter: retryAfter}
+}
+
+// Internal reports an unexpected failure. The cause is logged, never shown to the client.
+func Internal(cause error) *Error {
+	return &Error{Code: CodeInternal, Message: "An internal error occurred", cause: cause}
+}
+
+// Timeout reports a request cut off by its deadline
+func Timeout(cause error) *Error {
+	return &Error{Code: CodeTimeout, Message: "The request timed out", cause: cause}
+}
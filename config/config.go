@@ -0,0 +1,529 @@
+package config
+
+import (
+	"api-server/logger"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// Config holds the settings of the API server. Every setting is read from an environment variable;
+// variables missing from the environment are read from the optional .env file, then from the optional
+// file named by CONFIG_FILE (same KEY=VALUE format), then take their default.
+type Config struct {
+	Server   Server
+	Database Database
+	JWT      JWT
+	Uploads  Uploads
+	Storage  Storage
+	CORS     CORS
+	Security Security
+	LogLevel slog.Level // LOG_LEVEL: debug, info, warn or error (default info)
+}
+
+// Server holds the listening settings
+type Server struct {
+	Port        int    // PORT (default 3000)
+	TLSCertFile string // TLS_CERT_FILE, serving HTTPS when set together with TLS_KEY_FILE
+	TLSKeyFile  string // TLS_KEY_FILE
+
+	ReadHeaderTimeout time.Duration // READ_HEADER_TIMEOUT, the time to read the request headers (default 10s)
+	IdleTimeout       time.Duration // IDLE_TIMEOUT, how long keep-alive connections wait for a request (default 2m)
+
+	// ShutdownDelay is how long a stopping server keeps serving while failing /readyz, for the load balancer
+	// to notice (SHUTDOWN_DELAY, default 0s)
+	ShutdownDelay time.Duration
+	// ShutdownTimeout bounds the wait for in-flight requests when stopping (SHUTDOWN_TIMEOUT, default 30s)
+	ShutdownTimeout time.Duration
+}
+
+// Addr returns the address to listen on
+func (s Server) Addr() string {
+	return ":" + strconv.Itoa(s.Port)
+}
+
+// TLSEnabled tells whether HTTPS is served
+func (s Server) TLSEnabled() bool {
+	return s.TLSCertFile != "" && s.TLSKeyFile != ""
+}
+
+// Database holds the connection and pool settings of PostgreSQL
+type Database struct {
+	Host     string // DB_HOST
+	Port     int    // DB_PORT (default 5432)
+	User     string // DB_USER
+	Password string // DB_PASSWORD
+	Name     string // DB_NAME
+	SSLMode  string // DB_SSLMODE: disable, require (default), verify-ca or verify-full
+
+	MaxOpenConns    int           // DB_MAX_OPEN_CONNS (default 25, 0 is unlimited)
+	MaxIdleConns    int           // DB_MAX_IDLE_CONNS (default 10)
+	ConnMaxLifetime time.Duration // DB_CONN_MAX_LIFETIME (default 30m, 0 keeps connections forever)
+	ConnMaxIdleTime time.Duration // DB_CONN_MAX_IDLE_TIME (default 5m, 0 keeps idle connections forever)
+
+	// StatementTimeout bounds every SQL statement (the PostgreSQL statement_timeout) and every repository
+	// call but the bulk imports, read from DB_STATEMENT_TIMEOUT (default 5s, 0 disables it)
+	StatementTimeout time.Duration
+}
+
+// DSN returns the connection string of the database, whose statements are cut off after StatementTimeout
+func (d Database) DSN() string {
+	return d.dsn(d.Name, d.StatementTimeout)
+}
+
+// MigrationDSN returns the connection string of the database without statement timeout, for migrations
+// whose backfills can take longer than any request
+func (d Database) MigrationDSN() string {
+	return d.dsn(d.Name, 0)
+}
+
+// MaintenanceDSN returns the connection string of the "postgres" database, to create or drop the database
+func (d Database) MaintenanceDSN() string {
+	return d.dsn("postgres", 0)
+}
+
+// dsn pins the session time zone to UTC: the TIMESTAMP columns default to CURRENT_TIMESTAMP, which is the
+// local time of the session, and the queries read them as UTC whatever the time zone of the server. The rows
+// written before the sessions were pinned are converted by migration 0015.
+func (d Database) dsn(name string, statementTimeout time.Duration) string {
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s timezone=UTC statement_timeout=%d",
+		quoteDSN(d.Host), d.Port, quoteDSN(d.User), quoteDSN(d.Password), quoteDSN(name), d.SSLMode, statementTimeout.Milliseconds())
+}
+
+// quoteDSN quotes a connection string value, so passwords with spaces or quotes survive
+func quoteDSN(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
+
+// JWT holds the signing settings of authentication tokens
+type JWT struct {
+	Secret string // JWT_TOKEN, required: tokens signed with an empty key could be forged by anyone
+}
+
+// Uploads holds where uploaded files are stored and served from, and how large they may be
+type Uploads struct {
+	MaxRequestSize int64  // UPLOAD_MAX_REQUEST_MB, the largest request body (default 32 MB)
+	MaxImageSize   int64  // UPLOAD_MAX_IMAGE_MB, the largest image (default 5 MB)
+	MaxImagePixels int    // UPLOAD_MAX_IMAGE_MEGAPIXELS, the largest decoded image (default 40 megapixels)
+	ImagesDir      string // IMAGES_DIR, the profile images of the local storage (default public/images)
+	AssetsDir      string // ASSETS_DIR, the company logos of the local storage (default public/assets)
+	ImageURL       string // URL_IMAGE_PROFILE, prefixed to profile image names in responses
+	AssetURL       string // URL_IMAGE, prefixed to company logo names in responses
+
+	// ImageURLSecret signs the profile image URLs, read from IMAGE_URL_SECRET (defaults to JWT_TOKEN).
+	// Changing it invalidates the URLs handed out.
+	ImageURLSecret string
+	// ImageURLExpiry is how long a signed profile image URL stays valid at least, read from
+	// IMAGE_URL_EXPIRY (default 1h, at least 1m)
+	ImageURLExpiry time.Duration
+}
+
+// Storage selects where the uploaded files are stored
+type Storage struct {
+	// Backend is "local" (STORAGE_BACKEND, default), storing in IMAGES_DIR and ASSETS_DIR, or "s3", storing
+	// in a bucket shared by every instance of the API server
+	Backend string
+	S3      S3
+}
+
+// S3 locates the bucket of an S3-compatible service, such as AWS S3 or MinIO
+type S3 struct {
+	Endpoint  string // S3_ENDPOINT, the host and port of the service, e.g. localhost:9000 for MinIO
+	Region    string // S3_REGION, empty for MinIO
+	Bucket    string // S3_BUCKET, holding the profile images under images/ and the company logos under assets/
+	AccessKey string // S3_ACCESS_KEY
+	SecretKey string // S3_SECRET_KEY
+	UseSSL    bool   // S3_USE_SSL (default true)
+}
+
+// Storage backends
+const (
+	StorageLocal = "local"
+	StorageS3    = "s3"
+)
+
+// CORS holds the cross-origin policies: the admin web app policy applies to requests from its origins,
+// the kiosk policy to the others
+type CORS struct {
+	Admin  CORSPolicy    // CORS_ADMIN_ORIGINS, CORS_ADMIN_METHODS, CORS_ADMIN_HEADERS, CORS_ADMIN_CREDENTIALS
+	Kiosk  CORSPolicy    // CORS_KIOSK_ORIGINS, CORS_KIOSK_METHODS, CORS_KIOSK_HEADERS, CORS_KIOSK_CREDENTIALS
+	MaxAge time.Duration // CORS_MAX_AGE, how long browsers cache preflight responses (default 12h)
+}
+
+// CORSPolicy lists what browsers may send from some origins. Lists are comma separated.
+type CORSPolicy struct {
+	AllowedOrigins   []string // "*" allows any origin, only without credentials
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	AllowCredentials bool // Cookies and HTTP authentication, never with "*"
+}
+
+// Security holds the security headers of every response
+type Security struct {
+	// HSTSMaxAge is how long browsers only use HTTPS, sent on HTTPS requests (HSTS_MAX_AGE, default 8760h,
+	// 0 disables the header)
+	HSTSMaxAge time.Duration
+	// FrameOptions forbids framing the responses: DENY (FRAME_OPTIONS, default) or SAMEORIGIN
+	FrameOptions string
+}
+
+// Load reads and validates the configuration, reporting every invalid setting at once
+func Load() (*Config, error) {
+	if err := loadFiles(); err != nil {
+		return nil, err
+	}
+
+	r := &reader{}
+	cfg := &Config{
+		Server:   readServer(r),
+		Database: readDatabase(r),
+		JWT:      JWT{Secret: r.string("JWT_TOKEN", "")},
+		Uploads:  readUploads(r),
+		Storage:  readStorage(r),
+		CORS:     readCORS(r),
+		Security: readSecurity(r),
+	}
+
+	level, err := logger.ParseLevel(r.string("LOG_LEVEL", ""))
+	if err != nil {
+		r.fail("LOG_LEVEL: %v", err)
+	}
+	cfg.LogLevel = level
+
+	if cfg.JWT.Secret == "" {
+		r.fail("JWT_TOKEN is required")
+	}
+	if cfg.Uploads.ImageURLSecret == "" {
+		cfg.Uploads.ImageURLSecret = cfg.JWT.Secret
+	}
+
+	return cfg, r.err()
+}
+
+// LoadDatabase reads and validates the database settings only, for the tools that need nothing else
+func LoadDatabase() (*Database, error) {
+	if err := loadFiles(); err != nil {
+		return nil, err
+	}
+
+	r := &reader{}
+	database := readDatabase(r)
+	return &database, r.err()
+}
+
+// LoadUploads reads and validates the database, uploads and storage settings only, for the tools working on
+// the uploaded files
+func LoadUploads() (*Config, error) {
+	if err := loadFiles(); err != nil {
+		return nil, err
+	}
+
+	r := &reader{}
+	cfg := &Config{
+		Database: readDatabase(r),
+		Uploads:  readUploads(r),
+		Storage:  readStorage(r),
+	}
+	return cfg, r.err()
+}
+
+// loadFiles loads the variables of the optional .env file and of the file named by CONFIG_FILE, without
+// overriding the environment
+func loadFiles() error {
+	if err := godotenv.Load(".env"); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("error loading .env file: %w", err)
+	}
+
+	if file := os.Getenv("CONFIG_FILE"); file != "" {
+		if err := godotenv.Load(file); err != nil {
+			return fmt.Errorf("error loading CONFIG_FILE %s: %w", file, err)
+		}
+	}
+
+	return nil
+}
+
+func readServer(r *reader) Server {
+	server := Server{
+		Port:        r.int("PORT", 3000),
+		TLSCertFile: r.string("TLS_CERT_FILE", ""),
+		TLSKeyFile:  r.string("TLS_KEY_FILE", ""),
+
+		ReadHeaderTimeout: r.duration("READ_HEADER_TIMEOUT", 10*time.Second),
+		IdleTimeout:       r.duration("IDLE_TIMEOUT", 2*time.Minute),
+		ShutdownDelay:     r.duration("SHUTDOWN_DELAY", 0),
+		ShutdownTimeout:   r.duration("SHUTDOWN_TIMEOUT", 30*time.Second),
+	}
+
+	if server.Port < 1 || server.Port > 65535 {
+		r.fail("PORT must be between 1 and 65535")
+	}
+	if (server.TLSCertFile == "") != (server.TLSKeyFile == "") {
+		r.fail("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	for _, file := range []string{server.TLSCertFile, server.TLSKeyFile} {
+		if file == "" {
+			continue
+		}
+		if _, err := os.Stat(file); err != nil {
+			r.fail("TLS file %s cannot be read: %v", file, err)
+		}
+	}
+
+	return server
+}
+
+func readDatabase(r *reader) Database {
+	database := Database{
+		Host:             r.string("DB_HOST", ""),
+		Port:             r.int("DB_PORT", 5432),
+		User:             r.string("DB_USER", ""),
+		Password:         r.string("DB_PASSWORD", ""),
+		Name:             r.string("DB_NAME", ""),
+		SSLMode:          r.string("DB_SSLMODE", "require"),
+		MaxOpenConns:     r.int("DB_MAX_OPEN_CONNS", 25),
+		MaxIdleConns:     r.int("DB_MAX_IDLE_CONNS", 10),
+		ConnMaxLifetime:  r.duration("DB_CONN_MAX_LIFETIME", 30*time.Minute),
+		ConnMaxIdleTime:  r.duration("DB_CONN_MAX_IDLE_TIME", 5*time.Minute),
+		StatementTimeout: r.duration("DB_STATEMENT_TIMEOUT", 5*time.Second),
+	}
+
+	for _, required := range []struct{ key, value string }{
+		{"DB_HOST", database.Host}, {"DB_USER", database.User}, {"DB_NAME", database.Name},
+	} {
+		if required.value == "" {
+			r.fail("%s is required", required.key)
+		}
+	}
+	if database.Port < 1 || database.Port > 65535 {
+		r.fail("DB_PORT must be between 1 and 65535")
+	}
+	switch database.SSLMode {
+	case "disable", "require", "verify-ca", "verify-full":
+	default:
+		r.fail("DB_SSLMODE must be disable, require, verify-ca or verify-full")
+	}
+	if database.MaxOpenConns < 0 || database.MaxIdleConns < 0 {
+		r.fail("DB_MAX_OPEN_CONNS and DB_MAX_IDLE_CONNS cannot be negative")
+	}
+	if database.MaxOpenConns > 0 && database.MaxIdleConns > database.MaxOpenConns {
+		r.fail("DB_MAX_IDLE_CONNS cannot exceed DB_MAX_OPEN_CONNS")
+	}
+
+	return database
+}
+
+func readUploads(r *reader) Uploads {
+	uploads := Uploads{
+		MaxRequestSize: int64(r.int("UPLOAD_MAX_REQUEST_MB", 32)) << 20,
+		MaxImageSize:   int64(r.int("UPLOAD_MAX_IMAGE_MB", 5)) << 20,
+		MaxImagePixels: r.int("UPLOAD_MAX_IMAGE_MEGAPIXELS", 40) * 1000000,
+		ImagesDir:      r.string("IMAGES_DIR", "public/images"),
+		AssetsDir:      r.string("ASSETS_DIR", "public/assets"),
+		ImageURL:       r.string("URL_IMAGE_PROFILE", ""),
+		AssetURL:       r.string("URL_IMAGE", ""),
+		ImageURLSecret: r.string("IMAGE_URL_SECRET", ""),
+		ImageURLExpiry: r.duration("IMAGE_URL_EXPIRY", time.Hour),
+	}
+
+	if uploads.MaxRequestSize <= 0 || uploads.MaxImageSize <= 0 {
+		r.fail("UPLOAD_MAX_REQUEST_MB and UPLOAD_MAX_IMAGE_MB must be positive")
+	}
+	if uploads.MaxImagePixels <= 0 {
+		r.fail("UPLOAD_MAX_IMAGE_MEGAPIXELS must be positive")
+	}
+	if uploads.MaxImageSize > uploads.MaxRequestSize {
+		r.fail("UPLOAD_MAX_IMAGE_MB cannot exceed UPLOAD_MAX_REQUEST_MB")
+	}
+	if uploads.ImagesDir == "" || uploads.AssetsDir == "" {
+		r.fail("IMAGES_DIR and ASSETS_DIR cannot be empty")
+	}
+	if uploads.ImageURLExpiry < time.Minute {
+		r.fail("IMAGE_URL_EXPIRY must be at least 1m")
+	}
+
+	return uploads
+}
+
+func readStorage(r *reader) Storage {
+	storage := Storage{
+		Backend: strings.ToLower(r.string("STORAGE_BACKEND", StorageLocal)),
+		S3: S3{
+			Endpoint:  r.string("S3_ENDPOINT", ""),
+			Region:    r.string("S3_REGION", ""),
+			Bucket:    r.string("S3_BUCKET", ""),
+			AccessKey: r.string("S3_ACCESS_KEY", ""),
+			SecretKey: r.string("S3_SECRET_KEY", ""),
+			UseSSL:    r.bool("S3_USE_SSL", true),
+		},
+	}
+
+	switch storage.Backend {
+	case StorageLocal:
+	case StorageS3:
+		if storage.S3.Endpoint == "" || storage.S3.Bucket == "" {
+			r.fail("S3_ENDPOINT and S3_BUCKET are required when STORAGE_BACKEND is s3")
+		}
+		if storage.S3.AccessKey == "" || storage.S3.SecretKey == "" {
+			r.fail("S3_ACCESS_KEY and S3_SECRET_KEY are required when STORAGE_BACKEND is s3")
+		}
+	default:
+		r.fail("STORAGE_BACKEND must be local or s3")
+	}
+
+	return storage
+}
+
+// corsHeaders are the request headers browsers may send by default
+var corsHeaders = []string{"Origin", "Content-Type", "Accept", "Accept-Language", "Authorization", "X-Request-ID"}
+
+func readCORS(r *reader) CORS {
+	return CORS{
+		// The admin web app runs on the Vite dev server by default
+		Admin: readCORSPolicy(r, "CORS_ADMIN", CORSPolicy{
+			AllowedOrigins:   []string{"http://localhost:5173"},
+			AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+			AllowedHeaders:   corsHeaders,
+			AllowCredentials: true,
+		}),
+		// Kiosks sign in and vote from any origin, with bearer tokens rather than cookies
+		Kiosk: readCORSPolicy(r, "CORS_KIOSK", CORSPolicy{
+			AllowedOrigins: []string{"*"},
+			AllowedMethods: []string{"GET", "POST", "OPTIONS"},
+			AllowedHeaders: corsHeaders,
+		}),
+		MaxAge: r.duration("CORS_MAX_AGE", 12*time.Hour),
+	}
+}
+
+// readCORSPolicy reads the policy of the variables starting with a prefix, e.g. CORS_ADMIN_ORIGINS
+func readCORSPolicy(r *reader, prefix string, defaults CORSPolicy) CORSPolicy {
+	policy := CORSPolicy{
+		AllowedOrigins:   r.list(prefix+"_ORIGINS", defaults.AllowedOrigins),
+		AllowedMethods:   r.list(prefix+"_METHODS", defaults.AllowedMethods),
+		AllowedHeaders:   r.list(prefix+"_HEADERS", defaults.AllowedHeaders),
+		AllowCredentials: r.bool(prefix+"_CREDENTIALS", defaults.AllowCredentials),
+	}
+
+	if len(policy.AllowedOrigins) == 0 || len(policy.AllowedMethods) == 0 {
+		r.fail("%s_ORIGINS and %s_METHODS cannot be empty", prefix, prefix)
+	}
+	for _, origin := range policy.AllowedOrigins {
+		if !validOrigin(origin) {
+			r.fail("%s_ORIGINS: %q is not * or an origin like https://admin.example.com", prefix, origin)
+		}
+		if origin == "*" && policy.AllowCredentials {
+			r.fail("%s_ORIGINS cannot be * when %s_CREDENTIALS is true, list the origins instead", prefix, prefix)
+		}
+	}
+
+	return policy
+}
+
+func readSecurity(r *reader) Security {
+	security := Security{
+		HSTSMaxAge:   r.duration("HSTS_MAX_AGE", 365*24*time.Hour),
+		FrameOptions: strings.ToUpper(r.string("FRAME_OPTIONS", "DENY")),
+	}
+
+	if security.FrameOptions != "DENY" && security.FrameOptions != "SAMEORIGIN" {
+		r.fail("FRAME_OPTIONS must be DENY or SAMEORIGIN")
+	}
+
+	return security
+}
+
+// validOrigin tells whether a CORS origin is "*" or a scheme and host without path
+func validOrigin(origin string) bool {
+	if origin == "*" {
+		return true
+	}
+	parsed, err := url.Parse(origin)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != "" &&
+		parsed.Path == "" && parsed.RawQuery == ""
+}
+
+// reader reads typed environment variables, collecting the problems instead of stopping at the first
+type reader struct {
+	problems []string
+}
+
+func (r *reader) fail(format string, args ...interface{}) {
+	r.problems = append(r.problems, fmt.Sprintf(format, args...))
+}
+
+// err returns every problem found, nil when there is none
+func (r *reader) err() error {
+	if len(r.problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid configuration:\n  - %s", strings.Join(r.problems, "\n  - "))
+}
+
+func (r *reader) string(key string, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok && strings.TrimSpace(value) != "" {
+		return strings.TrimSpace(value)
+	}
+	return fallback
+}
+
+func (r *reader) int(key string, fallback int) int {
+	value := r.string(key, "")
+	if value == "" {
+		return fallback
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		r.fail("%s must be a whole number, got %q", key, value)
+		return fallback
+	}
+	return parsed
+}
+
+func (r *reader) duration(key string, fallback time.Duration) time.Duration {
+	value := r.string(key, "")
+	if value == "" {
+		return fallback
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed < 0 {
+		r.fail("%s must be a duration like 5s or 30m, got %q", key, value)
+		return fallback
+	}
+	return parsed
+}
+
+func (r *reader) bool(key string, fallback bool) bool {
+	value := r.string(key, "")
+	if value == "" {
+		return fallback
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		r.fail("%s must be true or false, got %q", key, value)
+		return fallback
+	}
+	return parsed
+}
+
+func (r *reader) list(key string, fallback []string) []string {
+	value := r.string(key, "")
+	if value == "" {
+		return fallback
+	}
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setEnv replaces the environment of a test with the given variables, every other setting read by Load
+// being blank so that it takes its default
+func setEnv(t *testing.T, variables map[string]string) {
+	t.Helper()
+	// No .env file
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getting the working directory: %v", err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("changing the working directory: %v", err)
+	}
+	t.Cleanup(func() { os.Chdir(dir) })
+
+	for _, key := range []string{
+		"CONFIG_FILE", "PORT", "TLS_CERT_FILE", "TLS_KEY_FILE", "LOG_LEVEL", "JWT_TOKEN",
+		"DB_HOST", "DB_PORT", "DB_USER", "DB_PASSWORD", "DB_NAME", "DB_SSLMODE", "DB_STATEMENT_TIMEOUT",
+		"DB_MAX_OPEN_CONNS", "DB_MAX_IDLE_CONNS", "UPLOAD_MAX_REQUEST_MB", "UPLOAD_MAX_IMAGE_MB",
+		"IMAGE_URL_SECRET", "IMAGE_URL_EXPIRY", "STORAGE_BACKEND", "S3_ENDPOINT", "S3_BUCKET",
+		"CORS_ADMIN_ORIGINS", "CORS_ADMIN_CREDENTIALS", "CORS_KIOSK_ORIGINS", "FRAME_OPTIONS",
+	} {
+		t.Setenv(key, "")
+	}
+	for key, value := range variables {
+		t.Setenv(key, value)
+	}
+}
+
+func TestLoadDefaults(t *testing.T) {
+	setEnv(t, map[string]string{"JWT_TOKEN": "jwt-secret", "DB_HOST": "db.internal", "DB_USER": "feedback", "DB_NAME": "feedback"})
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Server.Addr() != ":3000" || cfg.Server.TLSEnabled() {
+		t.Errorf("server = %+v, want plain HTTP on port 3000", cfg.Server)
+	}
+	if cfg.Database.SSLMode != "require" || cfg.Database.StatementTimeout != 5*time.Second {
+		t.Errorf("database = %+v, want sslmode require and a 5s statement timeout", cfg.Database)
+	}
+	if cfg.Uploads.ImageURLSecret != "jwt-secret" {
+		t.Errorf("IMAGE_URL_SECRET defaults to %q, want the JWT secret", cfg.Uploads.ImageURLSecret)
+	}
+	if cfg.Storage.Backend != StorageLocal || cfg.Security.FrameOptions != "DENY" {
+		t.Errorf("storage %q and frame options %q, want local and DENY", cfg.Storage.Backend, cfg.Security.FrameOptions)
+	}
+
+	dsn := cfg.Database.DSN()
+	for _, part := range []string{"host='db.internal'", "sslmode=require", "timezone=UTC", "statement_timeout=5000"} {
+		if !strings.Contains(dsn, part) {
+			t.Errorf("DSN() = %q, want it to contain %s", dsn, part)
+		}
+	}
+	if migration := cfg.Database.MigrationDSN(); !strings.HasSuffix(migration, "statement_timeout=0") {
+		t.Errorf("MigrationDSN() = %q, want the statement timeout disabled", migration)
+	}
+}
+
+func TestLoadReportsEveryProblem(t *testing.T) {
+	setEnv(t, map[string]string{
+		"PORT":                  "70000",
+		"TLS_CERT_FILE":         "/nonexistent/cert.pem",
+		"DB_HOST":               "db.internal",
+		"DB_SSLMODE":            "prefer",
+		"DB_STATEMENT_TIMEOUT":  "five seconds",
+		"UPLOAD_MAX_REQUEST_MB": "4",
+		"UPLOAD_MAX_IMAGE_MB":   "8",
+		"STORAGE_BACKEND":       "s3",
+		"CORS_KIOSK_ORIGINS":    "*",
+		"CORS_ADMIN_ORIGINS":    "https://admin.example.com/app",
+		"FRAME_OPTIONS":         "ALLOW",
+	})
+	t.Setenv("CORS_KIOSK_CREDENTIALS", "true")
+
+	_, err := Load()
+	if err == nil {
+		t.Fatal("Load() succeeded with an invalid configuration")
+	}
+	for _, problem := range []string{
+		"PORT must be between 1 and 65535",
+		"TLS_CERT_FILE and TLS_KEY_FILE must be set together",
+		"JWT_TOKEN is required",
+		"DB_USER is required",
+		"DB_NAME is required",
+		"DB_SSLMODE must be",
+		`DB_STATEMENT_TIMEOUT must be a duration like 5s or 30m, got "five seconds"`,
+		"UPLOAD_MAX_IMAGE_MB cannot exceed UPLOAD_MAX_REQUEST_MB",
+		"S3_ENDPOINT and S3_BUCKET are required",
+		`CORS_ADMIN_ORIGINS: "https://admin.example.com/app" is not * or an origin`,
+		"CORS_KIOSK_ORIGINS cannot be * when CORS_KIOSK_CREDENTIALS is true",
+		"FRAME_OPTIONS must be DENY or SAMEORIGIN",
+	} {
+		if !strings.Contains(err.Error(), problem) {
+			t.Errorf("Load() error does not report %q:\n%v", problem, err)
+		}
+	}
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	setEnv(t, map[string]string{"DB_HOST": "from-environment", "DB_USER": "feedback"})
+
+	// The file fills the blanks of the environment without overriding it
+	file := filepath.Join(t.TempDir(), "feedback.env")
+	content := "DB_HOST=from-file\nDB_NAME=feedback_file\nJWT_TOKEN=file-secret\nPORT=8443\n"
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatalf("writing the configuration file: %v", err)
+	}
+	t.Setenv("CONFIG_FILE", file)
+	for _, key := range []string{"DB_NAME", "JWT_TOKEN", "PORT"} {
+		os.Unsetenv(key) // godotenv only fills unset variables, t.Setenv restores them after the test
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Database.Host != "from-environment" || cfg.Database.Name != "feedback_file" || cfg.JWT.Secret != "file-secret" || cfg.Server.Port != 8443 {
+		t.Errorf("Load() = host %q, name %q, secret %q, port %d, want the environment first then the file",
+			cfg.Database.Host, cfg.Database.Name, cfg.JWT.Secret, cfg.Server.Port)
+	}
+
+	t.Setenv("CONFIG_FILE", filepath.Join(t.TempDir(), "missing.env"))
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "error loading CONFIG_FILE") {
+		t.Errorf("Load() with a missing CONFIG_FILE error = %v", err)
+	}
+}
@@ -4,45 +4,34 @@ package config
 import (
 	"database/sql"
 	"fmt"
-	"log"
-	"os"
+	"log/slog"
 
-	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
 
 var DB *sql.DB
 
-func InitDatabase() {
+// InitDatabase opens the connection pool of the database and checks it is reachable
+func InitDatabase(database Database) error {
 	var err error
 
-	// Load the .env file
-	err = godotenv.Load(".env") // Adjust the path according to your folder structure
-	if err != nil {
-		log.Fatalf("Error loading .env file: %v", err)
-	}
-
-	// Read environment variables
-	dbUser := os.Getenv("DB_USER")
-	dbPassword := os.Getenv("DB_PASSWORD")
-	dbName := os.Getenv("DB_NAME")
-	dbSslMode := os.Getenv("DB_SSLMODE")
-	dbHost := os.Getenv("DB_HOST")
-
-	// Define the connection string for PostgreSQL
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s sslmode=%s", dbHost, dbUser, dbPassword, dbName, dbSslMode)
-
 	// Open the database connection
-	DB, err = sql.Open("postgres", dsn)
+	DB, err = sql.Open("postgres", database.DSN())
 	if err != nil {
-		log.Fatalf("Error opening database: %v", err)
+		return fmt.Errorf("error opening database: %w", err)
 	}
 
+	// Size the connection pool
+	DB.SetMaxOpenConns(database.MaxOpenConns)
+	DB.SetMaxIdleConns(database.MaxIdleConns)
+	DB.SetConnMaxLifetime(database.ConnMaxLifetime)
+	DB.SetConnMaxIdleTime(database.ConnMaxIdleTime)
+
 	// Test the connection
-	err = DB.Ping()
-	if err != nil {
-		log.Fatalf("Cannot connect to database: %v", err)
+	if err := DB.Ping(); err != nil {
+		return fmt.Errorf("cannot connect to database: %w", err)
 	}
 
-	fmt.Println("Connected to the database")
+	slog.Info("Connected to the database", "host", database.Host, "name", database.Name)
+	return nil
 }
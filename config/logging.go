@@ -0,0 +1,13 @@
+package config
+
+import (
+	"api-server/logger"
+	"log/slog"
+	"os"
+)
+
+// InitLogger logs JSON records to the standard output at the configured level. The standard log package
+// is redirected to it too.
+func InitLogger(level slog.Level) {
+	slog.SetDefault(logger.New(os.Stdout, level))
+}
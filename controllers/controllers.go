@@ -1,26 +1,139 @@
 package controllers
 
 import (
+	"api-server/apperror"
+	"api-server/config"
 	"api-server/helpers"
+	"api-server/imaging"
+	"api-server/locale"
+	"api-server/metrics"
+	"api-server/middlewares"
 	"api-server/models"
-	"api-server/repository"
 	"api-server/repository/validation"
 	"api-server/services"
-	"io"
-	"log"
+	"api-server/storage"
+	"archive/zip"
+	"errors"
+	"fmt"
+	"log/slog"
 	"mime/multipart"
 	"net/http"
-	"os"
-	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// Handler serves the HTTP routes with the injected services
+type Handler struct {
+	services *services.Services
+	uploads  config.Uploads
+	stores   *storage.Stores
+}
+
+// NewHandler creates a Handler on the given services, accepting uploads as configured and keeping them
+// in the stores
+func NewHandler(services *services.Services, uploads config.Uploads, stores *storage.Stores) *Handler {
+	return &Handler{services: services, uploads: uploads, stores: stores}
+}
+
+// multipartMemory is the part of a multipart form kept in memory, the rest is buffered to temporary files
+const multipartMemory = 32 << 20
+
+// parseMultipartForm parses a multipart form body, reporting bodies above the upload limit
+func parseMultipartForm(c *gin.Context) bool {
+	if err := c.Request.ParseMultipartForm(multipartMemory); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.Error(middlewares.RequestTooLarge(tooLarge.Limit))
+			return false
+		}
+		c.Error(apperror.BadRequest("Failed to parse form"))
+		return false
+	}
+	return true
+}
+
+// checkImageSize reports an uploaded image above the image size limit
+func (h *Handler) checkImageSize(c *gin.Context, field string, fileHeader *multipart.FileHeader) bool {
+	if fileHeader.Size > h.uploads.MaxImageSize {
+		c.Error(apperror.InvalidField(field, fmt.Sprintf("the image cannot exceed %d MB", h.uploads.MaxImageSize>>20)))
+		return false
+	}
+	return true
+}
+
+// processImage reads an uploaded image and processes it to spec, reporting unacceptable images on the field
+func (h *Handler) processImage(c *gin.Context, field string, fileHeader *multipart.FileHeader, spec imaging.Spec) (*imaging.Image, bool) {
+	if !h.checkImageSize(c, field, fileHeader) {
+		return nil, false
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Error Image", "error", err)
+		c.Error(err) // Pass error to the middleware
+		return nil, false
+	}
+	defer file.Close()
+
+	processed, err := imaging.Process(file, imaging.Limits{MaxBytes: h.uploads.MaxImageSize, MaxPixels: h.uploads.MaxImagePixels}, spec)
+	if err != nil {
+		var invalid *imaging.Error
+		if errors.As(err, &invalid) {
+			c.Error(apperror.InvalidField(field, invalid.Error()))
+			return nil, false
+		}
+		slog.ErrorContext(c.Request.Context(), "Error Image", "error", err)
+		c.Error(err) // Pass error to the middleware
+		return nil, false
+	}
+	return processed, true
+}
+
+// thumbnailURL returns the URL of the thumbnail of a profile image, "" when it has none
+func (h *Handler) thumbnailURL(image string) string {
+	if thumbnail := imaging.ThumbnailName(image); thumbnail != "" {
+		return h.stores.Images.URL(thumbnail)
+	}
+	return ""
+}
+
+// requestLanguage returns the language negotiated by middlewares.Language, "" when none was requested
+func requestLanguage(c *gin.Context) string {
+	return c.GetString(locale.ContextKey)
+}
+
+// validationError returns the error answered for invalid input, with every message per field for
+// field errors (e.g. {"fields": {"email": ["email is required"]}})
+func validationError(err error) *apperror.Error {
+	var fieldErrors validation.FieldErrors
+	if errors.As(err, &fieldErrors) {
+		return apperror.Validation(fieldErrors.Error(), fieldErrors)
+	}
+	return apperror.Validation(err.Error(), nil)
+}
+
+// optionalInputString reads an optional text of a validated JSON input, nil when it was not given
+func optionalInputString(input map[string]interface{}, key string) *string {
+	if value, ok := input[key].(string); ok {
+		return &value
+	}
+	return nil
+}
+
+// optionalFormValue reads an optional form value, nil when it was not given
+func optionalFormValue(c *gin.Context, key string) *string {
+	if value := c.Request.FormValue(key); value != "" {
+		return &value
+	}
+	return nil
+}
+
 // BranchOffice Handlers
 
-func GetBranchOfficesHandler(c *gin.Context) {
+func (h *Handler) GetBranchOfficesHandler(c *gin.Context) {
 	pageStr := c.DefaultQuery("page", "1")
 	limitStr := c.DefaultQuery("limit", "5")
 
@@ -37,14 +150,19 @@ func GetBranchOfficesHandler(c *gin.Context) {
 	offset := (page - 1) * limit
 
 	// Use the service layer to get the branch offices
-	branchOffices, err := services.GetAllBranchOffices(limit, offset)
+	branchOffices, err := h.services.BranchOffices.GetAllBranchOffices(c.Request.Context(), limit, offset)
 	if err != nil {
 		c.Error(err) // Pass error to the middleware
 		return
 	}
 
+	lang := requestLanguage(c)
+	for i := range branchOffices {
+		branchOffices[i].Localize(lang)
+	}
+
 	// Fetch the total branch office count
-	totalCount, err := repository.GetBranchOfficesCount()
+	totalCount, err := h.services.BranchOffices.GetBranchOfficesCount(c.Request.Context())
 	if err != nil {
 		c.Error(err) // Pass error to the middleware
 		return
@@ -61,52 +179,99 @@ func GetBranchOfficesHandler(c *gin.Context) {
 	})
 }
 
-func GetBranchOfficesOptionHandler(c *gin.Context) {
+func (h *Handler) GetBranchOfficesOptionHandler(c *gin.Context) {
 
 	// Use the service layer to get the branch offices
-	branchOffices, err := services.GetAllBranchOfficesOptionList()
+	branchOffices, err := h.services.BranchOffices.GetAllBranchOfficesOptionList(c.Request.Context())
+	if err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+
+	lang := requestLanguage(c)
+	for i := range branchOffices {
+		branchOffices[i].Localize(lang)
+	}
+
+	c.JSON(http.StatusOK, branchOffices)
+}
+
+// GetNearbyBranchOfficesHandler lists the branch offices within ?radius_km (default 10) of the point
+// (?lat, ?lng), nearest first, with their satisfaction score
+func (h *Handler) GetNearbyBranchOfficesHandler(c *gin.Context) {
+	latitude, err := strconv.ParseFloat(c.Query("lat"), 64)
+	if err != nil || latitude < -90 || latitude > 90 {
+		c.Error(apperror.BadRequest("lat must be a number between -90 and 90"))
+		return
+	}
+
+	longitude, err := strconv.ParseFloat(c.Query("lng"), 64)
+	if err != nil || longitude < -180 || longitude > 180 {
+		c.Error(apperror.BadRequest("lng must be a number between -180 and 180"))
+		return
+	}
+
+	radiusKm, err := strconv.ParseFloat(c.DefaultQuery("radius_km", "10"), 64)
+	if err != nil || radiusKm <= 0 || radiusKm > 20000 {
+		c.Error(apperror.BadRequest("radius_km must be a number between 0 and 20000"))
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit <= 0 || limit > 100 {
+		c.Error(apperror.BadRequest("limit must be a number between 1 and 100"))
+		return
+	}
+
+	branchOffices, err := h.services.BranchOffices.GetNearbyBranchOffices(c.Request.Context(), latitude, longitude, radiusKm, limit)
 	if err != nil {
 		c.Error(err) // Pass error to the middleware
 		return
 	}
 
+	lang := requestLanguage(c)
+	for i := range branchOffices {
+		branchOffices[i].Localize(lang)
+	}
+
 	c.JSON(http.StatusOK, branchOffices)
 }
 
-func GetBranchOfficeHandler(c *gin.Context) {
+func (h *Handler) GetBranchOfficeHandler(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.Atoi(idParam)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid branch office ID"})
+		c.Error(apperror.BadRequest("Invalid branch office ID"))
 		return
 	}
 
-	branchOffice, err := services.GetBranchOfficeByID(uint(id))
+	branchOffice, err := h.services.BranchOffices.GetBranchOfficeByID(c.Request.Context(), uint(id))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Branch office not found"})
+		c.Error(err) // Pass error to the middleware
 		return
 	}
 
 	if branchOffice == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Branch office not found"})
+		c.Error(apperror.NotFound("Branch office not found"))
 		return
 	}
 
+	branchOffice.Localize(requestLanguage(c))
 	c.JSON(http.StatusOK, branchOffice)
 }
 
-func CreateBranchOfficeHandler(c *gin.Context) {
+func (h *Handler) CreateBranchOfficeHandler(c *gin.Context) {
 	var input map[string]interface{}
 
 	// Parse request body
 	if err := c.BindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		c.Error(apperror.BadRequest("Invalid request body"))
 		return
 	}
 
 	// Validate input using the validation function
 	if err := validation.ValidateBranchOffices(input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(validationError(err))
 		return
 	}
 
@@ -114,11 +279,28 @@ func CreateBranchOfficeHandler(c *gin.Context) {
 	branchOffice := models.BranchOfficeCreateRequest{
 		Name:         input["name"].(string),
 		Address:      input["address"].(string),
+		NameAr:       optionalInputString(input, "name_ar"),
+		NameEn:       optionalInputString(input, "name_en"),
+		AddressAr:    optionalInputString(input, "address_ar"),
+		AddressEn:    optionalInputString(input, "address_en"),
 		TotalCounter: uint(input["total_counter"].(float64)), // Assuming total_counter comes as float64 from JSON
+		Timezone:     models.DefaultBranchTimezone,
+	}
+	if timezone, ok := input["timezone"].(string); ok && timezone != "" {
+		branchOffice.Timezone = timezone
+	}
+	if cityID, ok := input["city_id"].(float64); ok {
+		city := uint(cityID)
+		branchOffice.CityID = &city
+	}
+	if latitude, ok := input["latitude"].(float64); ok {
+		longitude := input["longitude"].(float64) // Validated together with latitude
+		branchOffice.Latitude = &latitude
+		branchOffice.Longitude = &longitude
 	}
 
 	// Call service to create branch office
-	if err := services.CreateBranchOffice(&branchOffice); err != nil {
+	if err := h.services.BranchOffices.CreateBranchOffice(c.Request.Context(), &branchOffice); err != nil {
 		c.Error(err) // Pass error to the middleware
 		return
 	}
@@ -126,67 +308,324 @@ func CreateBranchOfficeHandler(c *gin.Context) {
 	c.JSON(http.StatusCreated, gin.H{"message": "Branch office created successfully"})
 }
 
-func UpdateBranchOfficeHandler(c *gin.Context) {
+func (h *Handler) UpdateBranchOfficeHandler(c *gin.Context) {
 	var input map[string]interface{}
 
 	idParam := c.Param("id")
 	id, err := strconv.Atoi(idParam)
 
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid branch office ID"})
+		c.Error(apperror.BadRequest("Invalid branch office ID"))
 		return
 	}
 
 	// Parse request body
 	if err := c.BindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		c.Error(apperror.BadRequest("Invalid request body"))
 		return
 	}
 
 	// Validate input using the validation function
 	if err := validation.ValidateBranchOffices(input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(validationError(err))
+		return
+	}
+
+	// The optional fields left out of the body keep their stored value, so clients sending only the name,
+	// address and total_counter do not reset them; null clears them
+	current, err := h.services.BranchOffices.GetBranchOfficeByID(c.Request.Context(), uint(id))
+	if err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+	if current == nil {
+		c.Error(apperror.NotFound("Branch office not found"))
 		return
 	}
 
 	branchOffice := models.BranchOfficeCreateRequest{
 		Name:         input["name"].(string),
 		Address:      input["address"].(string),
+		NameAr:       current.NameAr,
+		NameEn:       current.NameEn,
+		AddressAr:    current.AddressAr,
+		AddressEn:    current.AddressEn,
 		TotalCounter: uint(input["total_counter"].(float64)), // Assuming total_counter comes as float64 from JSON
+		Timezone:     current.Timezone,
+		CityID:       current.CityID,
+		Latitude:     current.Latitude,
+		Longitude:    current.Longitude,
+	}
+	for field, translation := range map[string]**string{
+		"name_ar":    &branchOffice.NameAr,
+		"name_en":    &branchOffice.NameEn,
+		"address_ar": &branchOffice.AddressAr,
+		"address_en": &branchOffice.AddressEn,
+	} {
+		if _, given := input[field]; given {
+			*translation = optionalInputString(input, field)
+		}
+	}
+	if timezone, ok := input["timezone"].(string); ok && timezone != "" {
+		branchOffice.Timezone = timezone
+	}
+	if value, given := input["city_id"]; given {
+		branchOffice.CityID = nil
+		if cityID, ok := value.(float64); ok {
+			city := uint(cityID)
+			branchOffice.CityID = &city
+		}
+	}
+	if value, given := input["latitude"]; given {
+		branchOffice.Latitude, branchOffice.Longitude = nil, nil
+		if latitude, ok := value.(float64); ok {
+			longitude := input["longitude"].(float64) // Validated together with latitude
+			branchOffice.Latitude = &latitude
+			branchOffice.Longitude = &longitude
+		}
+	}
+
+	// Policy for counters above a shrinking total_counter: "reject" (default) or "deactivate"
+	counterPolicy := c.DefaultQuery("counter_policy", models.CounterPolicyReject)
+	if counterPolicy != models.CounterPolicyReject && counterPolicy != models.CounterPolicyDeactivate {
+		c.Error(apperror.BadRequest("counter_policy must be either 'reject' or 'deactivate'"))
+		return
 	}
 
-	if err := services.UpdateBranchOffice(uint(id), &branchOffice); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update branch office"})
+	if err := h.services.BranchOffices.UpdateBranchOffice(c.Request.Context(), uint(id), &branchOffice, counterPolicy); err != nil {
+		c.Error(err) // Pass error to the middleware
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Branch office updated successfully"})
 }
 
-func DeleteBranchOfficeHandler(c *gin.Context) {
+func (h *Handler) DeleteBranchOfficeHandler(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.Atoi(idParam)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid branch office ID"})
+		c.Error(apperror.BadRequest("Invalid branch office ID"))
 		return
 	}
 
-	err = services.DeleteBranchOffice(uint(id))
+	// Archive the branch office, it can be restored or purged later
+	err = h.services.BranchOffices.DeleteBranchOffice(c.Request.Context(), uint(id))
 	if err != nil {
-		statusCode := http.StatusInternalServerError
-		if strings.Contains(err.Error(), "No branch office found with the given ID") {
-			statusCode = http.StatusNotFound
-		}
-		c.JSON(statusCode, gin.H{"error": err.Error()})
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Branch office archived successfully"})
+}
+
+// Region Handlers
+
+func (h *Handler) GetRegionsHandler(c *gin.Context) {
+	regions, err := h.services.Regions.GetAllRegions(c.Request.Context())
+	if err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+
+	c.JSON(http.StatusOK, regions)
+}
+
+func (h *Handler) GetRegionHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.BadRequest("Invalid region ID"))
+		return
+	}
+
+	region, err := h.services.Regions.GetRegionByID(c.Request.Context(), uint(id))
+	if err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+	if region == nil {
+		c.Error(apperror.NotFound("Region not found"))
+		return
+	}
+
+	regionId := region.ID
+	cities, err := h.services.Regions.GetAllCities(c.Request.Context(), &regionId)
+	if err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":           region.ID,
+		"name":         region.Name,
+		"total_cities": region.TotalCities,
+		"cities":       cities,
+	})
+}
+
+func (h *Handler) CreateRegionHandler(c *gin.Context) {
+	var input map[string]interface{}
+
+	// Parse request body
+	if err := c.BindJSON(&input); err != nil {
+		c.Error(apperror.BadRequest("Invalid request body"))
+		return
+	}
+
+	// Validate input using the validation function
+	if err := validation.ValidateRegion(input); err != nil {
+		c.Error(validationError(err))
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Branch office deleted successfully"})
+	region := models.RegionCreateRequest{Name: input["name"].(string)}
+	if err := h.services.Regions.CreateRegion(c.Request.Context(), &region); err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Region created successfully"})
+}
+
+func (h *Handler) UpdateRegionHandler(c *gin.Context) {
+	var input map[string]interface{}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.BadRequest("Invalid region ID"))
+		return
+	}
+
+	// Parse request body
+	if err := c.BindJSON(&input); err != nil {
+		c.Error(apperror.BadRequest("Invalid request body"))
+		return
+	}
+
+	// Validate input using the validation function
+	if err := validation.ValidateRegion(input); err != nil {
+		c.Error(validationError(err))
+		return
+	}
+
+	region := models.RegionCreateRequest{Name: input["name"].(string)}
+	if err := h.services.Regions.UpdateRegion(c.Request.Context(), uint(id), &region); err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Region updated successfully"})
+}
+
+func (h *Handler) DeleteRegionHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.BadRequest("Invalid region ID"))
+		return
+	}
+
+	if err := h.services.Regions.DeleteRegion(c.Request.Context(), uint(id)); err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Region deleted successfully"})
+}
+
+// City Handlers
+
+func (h *Handler) GetCitiesHandler(c *gin.Context) {
+	regionId, ok := parseRegionQuery(c)
+	if !ok {
+		return
+	}
+
+	cities, err := h.services.Regions.GetAllCities(c.Request.Context(), regionId)
+	if err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+
+	c.JSON(http.StatusOK, cities)
+}
+
+func (h *Handler) CreateCityHandler(c *gin.Context) {
+	var input map[string]interface{}
+
+	// Parse request body
+	if err := c.BindJSON(&input); err != nil {
+		c.Error(apperror.BadRequest("Invalid request body"))
+		return
+	}
+
+	// Validate input using the validation function
+	if err := validation.ValidateCity(input); err != nil {
+		c.Error(validationError(err))
+		return
+	}
+
+	city := models.CityCreateRequest{
+		Name:     input["name"].(string),
+		RegionID: uint(input["region_id"].(float64)),
+	}
+	if err := h.services.Regions.CreateCity(c.Request.Context(), &city); err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "City created successfully"})
+}
+
+func (h *Handler) UpdateCityHandler(c *gin.Context) {
+	var input map[string]interface{}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.BadRequest("Invalid city ID"))
+		return
+	}
+
+	// Parse request body
+	if err := c.BindJSON(&input); err != nil {
+		c.Error(apperror.BadRequest("Invalid request body"))
+		return
+	}
+
+	// Validate input using the validation function
+	if err := validation.ValidateCity(input); err != nil {
+		c.Error(validationError(err))
+		return
+	}
+
+	city := models.CityCreateRequest{
+		Name:     input["name"].(string),
+		RegionID: uint(input["region_id"].(float64)),
+	}
+	if err := h.services.Regions.UpdateCity(c.Request.Context(), uint(id), &city); err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "City updated successfully"})
+}
+
+func (h *Handler) DeleteCityHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.BadRequest("Invalid city ID"))
+		return
+	}
+
+	if err := h.services.Regions.DeleteCity(c.Request.Context(), uint(id)); err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "City deleted successfully"})
 }
 
 // User Handlers
 
-func GetUsersHandler(c *gin.Context) {
+func (h *Handler) GetUsersHandler(c *gin.Context) {
 	pageStr := c.DefaultQuery("page", "1")
 	role := c.DefaultQuery("role", "officer")
 	limitStr := c.DefaultQuery("limit", "10")
@@ -204,14 +643,14 @@ func GetUsersHandler(c *gin.Context) {
 	offset := (page - 1) * limit
 
 	// Use the service layer to get the users
-	users, err := services.GetAllUsers(limit, offset, role)
+	users, err := h.services.Users.GetAllUsers(c.Request.Context(), limit, offset, role)
 	if err != nil {
 		c.Error(err) // Pass error to the middleware
 		return
 	}
 
 	// Fetch the total user count
-	totalCount, err := services.GetUsersCount(role)
+	totalCount, err := h.services.Users.GetUsersCount(c.Request.Context(), role)
 	if err != nil {
 		c.Error(err) // Pass error to the middleware
 		return
@@ -219,8 +658,11 @@ func GetUsersHandler(c *gin.Context) {
 
 	totalPages := (totalCount + limit - 1) / limit
 
+	lang := requestLanguage(c)
 	for i := range users {
-		users[i].Image = os.Getenv("URL_IMAGE_PROFILE") + users[i].Image
+		users[i].Thumbnail = h.thumbnailURL(users[i].Image)
+		users[i].Image = h.stores.Images.URL(users[i].Image)
+		users[i].Localize(lang)
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -233,31 +675,36 @@ func GetUsersHandler(c *gin.Context) {
 	})
 }
 
-func GetUserHandler(c *gin.Context) {
+func (h *Handler) GetUserHandler(c *gin.Context) {
 	id := c.Param("id")
 	userID, err := strconv.Atoi(id)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		c.Error(apperror.BadRequest("Invalid user ID"))
 		return
 	}
 
-	user, err := services.GetUserByID(uint(userID))
+	user, err := h.services.Users.GetUserByID(c.Request.Context(), uint(userID))
 	if err != nil {
 		c.Error(err) // Pass error to the middleware
 		return
 	}
 
 	if user == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		c.Error(apperror.NotFound("User not found"))
 		return
 	}
 
-	c.JSON(http.StatusOK, user)
+	// The shape of the list of users: no password hash, the image at its signed URL. The name is not
+	// localized, the edit form shows every variant.
+	response := models.NewUserAllResponse(user)
+	response.Thumbnail = h.thumbnailURL(response.Image)
+	response.Image = h.stores.Images.URL(response.Image)
+
+	c.JSON(http.StatusOK, response)
 }
 
-func CreateUserHandler(c *gin.Context) {
-	if err := c.Request.ParseMultipartForm(32 << 20); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to parse form"})
+func (h *Handler) CreateUserHandler(c *gin.Context) {
+	if !parseMultipartForm(c) {
 		return
 	}
 
@@ -265,85 +712,60 @@ func CreateUserHandler(c *gin.Context) {
 	email := c.Request.FormValue("email")
 	password := c.Request.FormValue("password")
 	role := c.Request.FormValue("role")
-	branchIdStr := c.Request.FormValue("branch_id")
 
-	branchId, err := strconv.Atoi(branchIdStr)
-	if err != nil {
-		log.Println("Error converting branch_id to uint:", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid branch_id"})
+	branchId, regionId, ok := parseUserScope(c, role)
+	if !ok {
 		return
 	}
 
 	user := models.User{
-		FullName: fullName,
-		Email:    email,
-		Password: password,
-		Role:     role,
-		BranchId: uint(branchId),
-	}
-
-	// Handle file upload but don't save it yet
-	var fileHeader *multipart.FileHeader
-	fileHeader, err = c.FormFile("image")
+		FullName:   fullName,
+		FullNameAr: optionalFormValue(c, "full_name_ar"),
+		FullNameEn: optionalFormValue(c, "full_name_en"),
+		Email:      email,
+		Password:   password,
+		Role:       role,
+		BranchId:   branchId,
+		RegionId:   regionId,
+	}
+
+	// Process the uploaded image, stored by the service with the user
+	var image *imaging.Image
+	fileHeader, err := c.FormFile("image")
 	if err == nil {
-		// If there's a file, set the filename but don't save yet
-		fileName := helpers.GenerateFileName()
-		user.Image = fileName
+		var ok bool
+		if image, ok = h.processImage(c, "image", fileHeader, imaging.Avatar); !ok {
+			return
+		}
+		metrics.RecordUpload("profile_image", fileHeader.Size)
 	}
 
 	// Validate user
 	if err := validation.ValidateUser(&user); err != nil {
-		log.Println("Error Validation:", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		slog.ErrorContext(c.Request.Context(), "Error Validation", "error", err)
+		c.Error(validationError(err))
 		return
 	}
 
 	// Call service to create user
-	if err := services.CreateUser(&user); err != nil {
+	if err := h.services.Users.CreateUser(c.Request.Context(), &user, image); err != nil {
 		c.Error(err) // Pass error to the middleware
 		return
 	}
 
-	// Only save the image after the user is successfully created
-	if fileHeader != nil {
-		filePath := filepath.Join("public/images", user.Image)
-		file, err := fileHeader.Open()
-		if err != nil {
-			c.Error(err) // Pass error to the middleware
-			log.Println("Error Image:", err)
-			return
-		}
-		defer file.Close()
-
-		dst, err := os.Create(filePath)
-		if err != nil {
-			log.Println("Error Image:", err)
-			c.Error(err) // Pass error to the middleware
-			return
-		}
-		defer dst.Close()
-
-		if _, err := io.Copy(dst, file); err != nil {
-			log.Println("Error Image:", err)
-			c.Error(err) // Pass error to the middleware
-			return
-		}
-	}
-
 	c.JSON(http.StatusOK, gin.H{"message": "User created successfully"})
 }
 
-func UpdateUserHandler(c *gin.Context) {
+func (h *Handler) UpdateUserHandler(c *gin.Context) {
 	id := c.Param("id")
 	userID, err := strconv.Atoi(id)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		c.Error(apperror.BadRequest("Invalid user ID"))
 		return
 	}
 
 	// Parse the form for multipart data
-	if err := c.Request.ParseMultipartForm(32 << 20); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to parse form"})
+	if !parseMultipartForm(c) {
 		return
 	}
 
@@ -352,350 +774,1299 @@ func UpdateUserHandler(c *gin.Context) {
 	email := c.Request.FormValue("email")
 	password := c.Request.FormValue("password")
 	role := c.Request.FormValue("role")
-	branchIdStr := c.Request.FormValue("branch_id")
 
-	branchId, err := strconv.ParseUint(branchIdStr, 10, 32)
-	if err != nil {
-		log.Println("Error converting branch_id to uint:", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid branch_id"})
+	branchId, regionId, ok := parseUserScope(c, role)
+	if !ok {
 		return
 	}
 
 	// Find existing user
-	user, err := services.GetUserByID(uint(userID))
+	user, err := h.services.Users.GetUserByID(c.Request.Context(), uint(userID))
 	if err != nil || user == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		c.Error(apperror.NotFound("User not found"))
 		return
 	}
 
-	// Store the current image path
-	oldImagePath := user.Image
+	// Moving a user between branch offices must go through a transfer so their membership history is kept;
+	// joining or leaving a branch office opens or closes the membership with the update
+	if user.BranchId != 0 && branchId != 0 && branchId != user.BranchId {
+		c.Error(apperror.Conflict("Use POST /users/:id/transfer to move a user to another branch office"))
+		return
+	}
 
 	// Update fields
 	user.FullName = fullName
+	user.FullNameAr = optionalFormValue(c, "full_name_ar")
+	user.FullNameEn = optionalFormValue(c, "full_name_en")
 	user.Email = email
 	if password != "" {
 		user.Password = password // Only update if password is provided
 	}
 	user.Role = role
-	user.BranchId = uint(branchId)
+	user.BranchId = branchId
+	user.RegionId = regionId
 
-	// Handle file upload
+	// Process the uploaded image
+	var image *imaging.Image
 	fileHeader, err := c.FormFile("image")
 	if err == nil {
-		// If there's a file, handle the file upload
-		fileName := helpers.GenerateFileName() // Ensure this generates just the filename
-		filePath := filepath.Join("public/images", fileName)
-		file, err := fileHeader.Open()
-		if err != nil {
-			c.Error(err)
+		var ok bool
+		if image, ok = h.processImage(c, "image", fileHeader, imaging.Avatar); !ok {
 			return
 		}
-		defer file.Close()
+		metrics.RecordUpload("profile_image", fileHeader.Size)
+	}
 
-		dst, err := os.Create(filePath)
-		if err != nil {
-			c.Error(err)
+	// Validate user
+	if err := validation.ValidateUser(user); err != nil {
+		c.Error(validationError(err))
+		return
+	}
+
+	// Call service to update user, replacing the image when a new one was uploaded
+	if err := h.services.Users.UpdateUser(c.Request.Context(), uint(userID), user, password != "", image); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User updated successfully"})
+}
+
+// parseUserScope reads the branch_id and region_id form values. A regional manager is attached to a
+// region instead of a branch office, every other role requires a branch_id.
+func parseUserScope(c *gin.Context, role string) (uint, *uint, bool) {
+	var regionId *uint
+	if regionIdStr := c.Request.FormValue("region_id"); regionIdStr != "" {
+		parsed, err := strconv.ParseUint(regionIdStr, 10, 32)
+		if err != nil {
+			c.Error(apperror.BadRequest("Invalid region_id"))
+			return 0, nil, false
+		}
+		id := uint(parsed)
+		regionId = &id
+	}
+
+	branchIdStr := c.Request.FormValue("branch_id")
+	if role == "regional_manager" && branchIdStr == "" {
+		return 0, regionId, true
+	}
+
+	branchId, err := strconv.ParseUint(branchIdStr, 10, 32)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Error converting branch_id to uint", "error", err)
+		c.Error(apperror.BadRequest("Invalid branch_id"))
+		return 0, nil, false
+	}
+
+	return uint(branchId), regionId, true
+}
+
+func (h *Handler) DeleteUserHandler(c *gin.Context) {
+	id := c.Param("id")
+	userID, err := strconv.Atoi(id)
+	if err != nil {
+		c.Error(apperror.BadRequest("Invalid user ID"))
+		return
+	}
+
+	// Find existing user
+	user, err := h.services.Users.GetUserByID(c.Request.Context(), uint(userID))
+	if err != nil || user == nil {
+		c.Error(apperror.NotFound("User not found"))
+		return
+	}
+
+	// Archive the user, the image is kept until the user is purged
+	if err := h.services.Users.DeleteUser(c.Request.Context(), uint(user.ID)); err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User archived successfully"})
+}
+
+// TransferUserHandler moves a user to another branch office, closing their current branch membership
+func (h *Handler) TransferUserHandler(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.BadRequest("Invalid user ID"))
+		return
+	}
+
+	var input map[string]interface{}
+
+	// Parse request body into a map for validation
+	if err := c.BindJSON(&input); err != nil {
+		c.Error(apperror.BadRequest("Invalid request body"))
+		return
+	}
+
+	if err := validation.ValidateTransfer(input); err != nil {
+		c.Error(validationError(err))
+		return
+	}
+
+	transfer := models.TransferRequest{
+		UserID:   uint(userID),
+		BranchID: uint(input["branch_id"].(float64)),
+	}
+	if effectiveAt, ok := input["effective_at"].(string); ok {
+		transfer.EffectiveAt, _ = time.Parse(time.RFC3339, effectiveAt) // Validated above
+	}
+	if reason, ok := input["reason"].(string); ok {
+		transfer.Reason = reason
+	}
+
+	result, err := h.services.Users.TransferUser(c.Request.Context(), &transfer)
+	if err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User transferred successfully", "transfer": result})
+}
+
+func (h *Handler) GetUserMembershipsHandler(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.BadRequest("Invalid user ID"))
+		return
+	}
+
+	memberships, err := h.services.Users.GetBranchMembershipsByUserID(c.Request.Context(), uint(userID))
+	if err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+
+	c.JSON(http.StatusOK, memberships)
+}
+
+func (h *Handler) GetUsersByBranchOffice(c *gin.Context) {
+	id := c.Param("id")
+	branchId, err := strconv.Atoi(id)
+	if err != nil {
+		c.Error(apperror.BadRequest("Invalid user ID"))
+		return
+	}
+
+	// When a time window is given, list the officers that are free for a shift in that window
+	startsAtStr, endsAtStr := c.Query("starts_at"), c.Query("ends_at")
+	if startsAtStr != "" || endsAtStr != "" {
+		startsAt, err := time.Parse(time.RFC3339, startsAtStr)
+		if err != nil {
+			c.Error(apperror.BadRequest("starts_at must be an RFC 3339 timestamp"))
+			return
+		}
+		endsAt, err := time.Parse(time.RFC3339, endsAtStr)
+		if err != nil || !endsAt.After(startsAt) {
+			c.Error(apperror.BadRequest("ends_at must be an RFC 3339 timestamp after starts_at"))
+			return
+		}
+
+		users, err := h.services.Users.GetAvailableOfficersByBranchID(c.Request.Context(), uint(branchId), startsAt, endsAt)
+		if err != nil {
+			c.Error(err) // Pass error to the middleware
+			return
+		}
+
+		c.JSON(http.StatusOK, users)
+		return
+	}
+
+	// Use the service layer to get the users
+	users, err := h.services.Users.GetUsersByBranchID(c.Request.Context(), uint(branchId))
+	if err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+
+	c.JSON(http.StatusOK, users)
+}
+
+// BranchCounter Handlers
+
+func (h *Handler) GetBranchCounterHandlerByBranchId(c *gin.Context) {
+	idParam := c.Param("branch_id")
+
+	// Convert the string idParam to an integer
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		c.Error(apperror.BadRequest("Invalid branch office ID"))
+		return
+	}
+
+	// Check the branch office by ID
+	branchOffice, err := h.services.BranchOffices.GetBranchOfficeByID(c.Request.Context(), uint(id))
+	if err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+
+	// Call service to retrieve branch counters by branch ID
+	counters, err := h.services.BranchCounters.GetBranchCountersByBranchID(c.Request.Context(), uint(id))
+	if err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+
+	lang := requestLanguage(c)
+	branchOffice.Localize(lang)
+	for i := range counters {
+		counters[i].Thumbnail = h.thumbnailURL(counters[i].Image)
+		counters[i].Image = h.stores.Images.URL(counters[i].Image)
+		counters[i].Localize(lang)
+	}
+
+	// Return JSON response with counters and total counter
+	c.JSON(http.StatusOK, gin.H{
+		"list_counter":  counters,
+		"name_branch":   branchOffice.Name,
+		"total_counter": branchOffice.TotalCounter,
+	})
+}
+
+func (h *Handler) CreateBranchCounterHandler(c *gin.Context) {
+	var branchCounter models.BranchCounter
+	var input map[string]interface{}
+
+	// Parse request body into a map for validation
+	if err := c.BindJSON(&input); err != nil {
+		c.Error(apperror.BadRequest("Invalid request body"))
+		return
+	}
+
+	// Validate input using the validation function
+	if err := validation.ValidateBranchCounter(input); err != nil {
+		c.Error(validationError(err))
+		return
+	}
+
+	// Manually bind validated values to branchCounter struct
+	branchCounter.CounterLocation = input["counter_location"].(string)
+	branchCounter.UserID = uint(input["user_id"].(float64))
+	branchCounter.BranchID = uint(input["branch_id"].(float64))
+	if counterNumber, ok := input["counter_number"].(float64); ok {
+		branchCounter.CounterNumber = uint(counterNumber)
+	}
+
+	// Call service to create BranchCounter
+	if err := h.services.BranchCounters.CreateBranchCounter(c.Request.Context(), &branchCounter); err != nil {
+		c.Error(err) // Pass error to middleware
+		return
+	}
+
+	// Return success response
+	c.JSON(http.StatusCreated, gin.H{
+		"message":        "Branch counter created successfully",
+		"id":             branchCounter.ID,
+		"counter_number": branchCounter.CounterNumber,
+	})
+}
+
+func (h *Handler) GetCounterSlotsHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("branch_id"))
+	if err != nil {
+		c.Error(apperror.BadRequest("Invalid branch office ID"))
+		return
+	}
+
+	branchOffice, err := h.services.BranchOffices.GetBranchOfficeByID(c.Request.Context(), uint(id))
+	if err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+
+	slots, err := h.services.BranchCounters.GetCounterSlotsByBranchID(c.Request.Context(), branchOffice)
+	if err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+
+	// Vacant and inactive counters are neither occupied nor free
+	occupied, free := 0, 0
+	for _, slot := range slots {
+		switch slot.Status {
+		case "occupied":
+			occupied++
+		case "free":
+			free++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"branch_id":     branchOffice.ID,
+		"name_branch":   branchOffice.Name,
+		"total_counter": branchOffice.TotalCounter,
+		"occupied":      occupied,
+		"free":          free,
+		"slots":         slots,
+	})
+}
+
+func (h *Handler) GetBranchCounterStatsHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("branch_id"))
+	if err != nil {
+		c.Error(apperror.BadRequest("Invalid branch office ID"))
+		return
+	}
+
+	branchOffice, err := h.services.BranchOffices.GetBranchOfficeByID(c.Request.Context(), uint(id))
+	if err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+
+	from, to, err := services.ParseDateRange(c.Query("from"), c.Query("to"), branchOffice.Timezone)
+	if err != nil {
+		c.Error(apperror.BadRequest(err.Error()))
+		return
+	}
+
+	stats, err := h.services.BranchCounters.GetBranchCounterStatsByBranchID(c.Request.Context(), branchOffice.ID, from, to)
+	if err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"branch_id":   branchOffice.ID,
+		"name_branch": branchOffice.Name,
+		"from":        from.Format("2006-01-02"),
+		"to":          to.AddDate(0, 0, -1).Format("2006-01-02"),
+		"counters":    stats,
+	})
+}
+
+func (h *Handler) GetCounterStatsHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.BadRequest("Invalid counter ID"))
+		return
+	}
+
+	counter, err := h.services.BranchCounters.GetBranchCounterByID(c.Request.Context(), uint(id))
+	if err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+	if counter == nil {
+		c.Error(apperror.NotFound("Branch counter not found"))
+		return
+	}
+
+	branchOffice, err := h.services.BranchOffices.GetBranchOfficeByID(c.Request.Context(), counter.BranchID)
+	if err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+
+	from, to, err := services.ParseDateRange(c.Query("from"), c.Query("to"), branchOffice.Timezone)
+	if err != nil {
+		c.Error(apperror.BadRequest(err.Error()))
+		return
+	}
+
+	stats, err := h.services.BranchCounters.GetCounterStats(c.Request.Context(), counter, from, to)
+	if err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+func (h *Handler) DeleteBranchCounterHandler(c *gin.Context) {
+	id := c.Param("id")
+
+	// Call service to delete the branch counter
+	if err := h.services.BranchCounters.DeleteBranchCounter(c.Request.Context(), id); err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Branch counter deleted successfully", "id": id})
+}
+
+func (h *Handler) GetCurrentCounterOfficerHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.BadRequest("Invalid counter ID"))
+		return
+	}
+
+	// Optional moment to look up, defaults to now
+	at := time.Now()
+	if atStr := c.Query("at"); atStr != "" {
+		at, err = time.Parse(time.RFC3339, atStr)
+		if err != nil {
+			c.Error(apperror.BadRequest("at must be an RFC 3339 timestamp"))
 			return
 		}
-		defer dst.Close()
+	}
 
-		if _, err := io.Copy(dst, file); err != nil {
-			c.Error(err)
+	counter, err := h.services.BranchCounters.GetBranchCounterByID(c.Request.Context(), uint(id))
+	if err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+	if counter == nil {
+		c.Error(apperror.NotFound("Branch counter not found"))
+		return
+	}
+
+	current, err := h.services.CounterAssignments.GetCurrentCounterOfficer(c.Request.Context(), counter, at)
+	if err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+
+	current.Localize(requestLanguage(c))
+
+	c.JSON(http.StatusOK, current)
+}
+
+// CounterAssignment Handlers
+
+func (h *Handler) CreateCounterAssignmentHandler(c *gin.Context) {
+	var input map[string]interface{}
+
+	// Parse request body into a map for validation
+	if err := c.BindJSON(&input); err != nil {
+		c.Error(apperror.BadRequest("Invalid request body"))
+		return
+	}
+
+	// Validate input using the validation function
+	if err := validation.ValidateCounterAssignment(input); err != nil {
+		c.Error(validationError(err))
+		return
+	}
+
+	// Validated above, parsing cannot fail
+	startsAt, _ := time.Parse(time.RFC3339, input["starts_at"].(string))
+	endsAt, _ := time.Parse(time.RFC3339, input["ends_at"].(string))
+
+	counter, err := h.services.BranchCounters.GetBranchCounterByID(c.Request.Context(), uint(input["counter_id"].(float64)))
+	if err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+	if counter == nil || !counter.IsActive {
+		c.Error(apperror.BadRequest("Branch counter not found or inactive"))
+		return
+	}
+
+	// The officer must belong to the branch of the counter
+	user, err := h.services.Users.GetUserByID(c.Request.Context(), uint(input["user_id"].(float64)))
+	if err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+	if user == nil || user.Role != "officer" || user.BranchId != counter.BranchID {
+		c.Error(apperror.BadRequest("Officer not found in the counter's branch office"))
+		return
+	}
+
+	assignment := models.CounterAssignment{
+		CounterID: counter.ID,
+		UserID:    user.ID,
+		BranchID:  counter.BranchID,
+		StartsAt:  startsAt,
+		EndsAt:    endsAt,
+	}
+
+	conflicts, err := h.services.CounterAssignments.CreateCounterAssignment(c.Request.Context(), &assignment)
+	if err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+	if len(conflicts) > 0 {
+		c.Error(apperror.Conflict("The shift overlaps existing shifts of this officer or counter").WithDetails("conflicts", conflicts))
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Counter assignment created successfully", "id": assignment.ID})
+}
+
+func (h *Handler) GetBranchRosterHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("branch_id"))
+	if err != nil {
+		c.Error(apperror.BadRequest("Invalid branch office ID"))
+		return
+	}
+
+	branchOffice, err := h.services.BranchOffices.GetBranchOfficeByID(c.Request.Context(), uint(id))
+	if err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+
+	weekStart, weekEnd, err := services.WeekRange(c.Query("week"), branchOffice.Timezone)
+	if err != nil {
+		c.Error(apperror.BadRequest(err.Error()))
+		return
+	}
+
+	roster, err := h.services.CounterAssignments.GetBranchRoster(c.Request.Context(), branchOffice, weekStart, weekEnd)
+	if err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+
+	c.JSON(http.StatusOK, roster)
+}
+
+func (h *Handler) DeleteCounterAssignmentHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.BadRequest("Invalid counter assignment ID"))
+		return
+	}
+
+	if err := h.services.CounterAssignments.DeleteCounterAssignment(c.Request.Context(), uint(id)); err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Counter assignment deleted successfully", "id": id})
+}
+
+// Operating Hours Handlers
+
+// loadBranchOfficeParam loads the branch office referenced by the :id path parameter, writing the error response if it cannot
+func (h *Handler) loadBranchOfficeParam(c *gin.Context) (*models.BranchOfficeResponse, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.BadRequest("Invalid branch office ID"))
+		return nil, false
+	}
+
+	branchOffice, err := h.services.BranchOffices.GetBranchOfficeByID(c.Request.Context(), uint(id))
+	if err != nil {
+		c.Error(err) // Pass error to the middleware
+		return nil, false
+	}
+	if branchOffice == nil {
+		c.Error(apperror.NotFound("Branch office not found"))
+		return nil, false
+	}
+
+	return branchOffice, true
+}
+
+func (h *Handler) GetBranchScheduleHandler(c *gin.Context) {
+	branchOffice, ok := h.loadBranchOfficeParam(c)
+	if !ok {
+		return
+	}
+
+	schedule, err := h.services.OperatingHours.GetBranchSchedule(c.Request.Context(), branchOffice)
+	if err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+
+	c.JSON(http.StatusOK, schedule)
+}
+
+func (h *Handler) UpdateOpeningHoursHandler(c *gin.Context) {
+	branchOffice, ok := h.loadBranchOfficeParam(c)
+	if !ok {
+		return
+	}
+
+	var input map[string]interface{}
+
+	// Parse request body into a map for validation
+	if err := c.BindJSON(&input); err != nil {
+		c.Error(apperror.BadRequest("Invalid request body"))
+		return
+	}
+
+	if err := validation.ValidateOpeningHours(input); err != nil {
+		c.Error(validationError(err))
+		return
+	}
+
+	hours := []models.OpeningHours{}
+	for _, item := range input["hours"].([]interface{}) {
+		interval := item.(map[string]interface{})
+		hours = append(hours, models.OpeningHours{
+			DayOfWeek: uint(interval["day_of_week"].(float64)),
+			OpensAt:   interval["opens_at"].(string),
+			ClosesAt:  interval["closes_at"].(string),
+		})
+	}
+
+	if err := h.services.OperatingHours.ReplaceOpeningHours(c.Request.Context(), branchOffice.ID, hours, input["out_of_hours_vote_policy"].(string)); err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Opening hours updated successfully", "hours": hours})
+}
+
+func (h *Handler) CreateCalendarExceptionHandler(c *gin.Context) {
+	branchOffice, ok := h.loadBranchOfficeParam(c)
+	if !ok {
+		return
+	}
+
+	var input map[string]interface{}
+
+	// Parse request body into a map for validation
+	if err := c.BindJSON(&input); err != nil {
+		c.Error(apperror.BadRequest("Invalid request body"))
+		return
+	}
+
+	if err := validation.ValidateCalendarException(input); err != nil {
+		c.Error(validationError(err))
+		return
+	}
+
+	exception := models.CalendarException{
+		BranchID: branchOffice.ID,
+		Name:     input["name"].(string),
+		Kind:     input["kind"].(string),
+		StartsOn: input["starts_on"].(string),
+		EndsOn:   input["ends_on"].(string),
+	}
+	if exception.Kind == models.CalendarSpecialHours {
+		opensAt := input["opens_at"].(string)
+		closesAt := input["closes_at"].(string)
+		exception.OpensAt = &opensAt
+		exception.ClosesAt = &closesAt
+	}
+
+	if err := h.services.OperatingHours.CreateCalendarException(c.Request.Context(), &exception); err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Calendar exception created successfully", "exception": exception})
+}
+
+func (h *Handler) DeleteCalendarExceptionHandler(c *gin.Context) {
+	branchOffice, ok := h.loadBranchOfficeParam(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("exceptionId"))
+	if err != nil {
+		c.Error(apperror.BadRequest("Invalid calendar exception ID"))
+		return
+	}
+
+	if err := h.services.OperatingHours.DeleteCalendarException(c.Request.Context(), branchOffice.ID, uint(id)); err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Calendar exception deleted successfully", "id": id})
+}
+
+func (h *Handler) CreateTemporaryClosureHandler(c *gin.Context) {
+	branchOffice, ok := h.loadBranchOfficeParam(c)
+	if !ok {
+		return
+	}
+
+	var input map[string]interface{}
+
+	// Parse request body into a map for validation
+	if err := c.BindJSON(&input); err != nil {
+		c.Error(apperror.BadRequest("Invalid request body"))
+		return
+	}
+
+	if err := validation.ValidateTemporaryClosure(input); err != nil {
+		c.Error(validationError(err))
+		return
+	}
+
+	// Validated above, parsing cannot fail
+	startsAt, _ := time.Parse(time.RFC3339, input["starts_at"].(string))
+	endsAt, _ := time.Parse(time.RFC3339, input["ends_at"].(string))
+
+	closure := models.TemporaryClosure{
+		BranchID: branchOffice.ID,
+		Reason:   input["reason"].(string),
+		StartsAt: startsAt,
+		EndsAt:   endsAt,
+	}
+
+	if err := h.services.OperatingHours.CreateTemporaryClosure(c.Request.Context(), &closure); err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Temporary closure created successfully", "closure": closure})
+}
+
+func (h *Handler) DeleteTemporaryClosureHandler(c *gin.Context) {
+	branchOffice, ok := h.loadBranchOfficeParam(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("closureId"))
+	if err != nil {
+		c.Error(apperror.BadRequest("Invalid temporary closure ID"))
+		return
+	}
+
+	if err := h.services.OperatingHours.DeleteTemporaryClosure(c.Request.Context(), branchOffice.ID, uint(id)); err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Temporary closure deleted successfully", "id": id})
+}
+
+// GetBranchOpenStatusHandler tells whether a branch office is open now, or at the moment given by ?at (RFC 3339)
+func (h *Handler) GetBranchOpenStatusHandler(c *gin.Context) {
+	branchOffice, ok := h.loadBranchOfficeParam(c)
+	if !ok {
+		return
+	}
+
+	at := time.Now()
+	if atStr := c.Query("at"); atStr != "" {
+		parsed, err := time.Parse(time.RFC3339, atStr)
+		if err != nil {
+			c.Error(apperror.BadRequest("at must be an RFC 3339 timestamp"))
 			return
 		}
+		at = parsed
+	}
+
+	status, err := h.services.OperatingHours.GetBranchOpenStatus(c.Request.Context(), branchOffice, at)
+	if err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// Archive Handlers
+
+func (h *Handler) GetArchivedBranchOfficesHandler(c *gin.Context) {
+	branchOffices, err := h.services.Archive.GetArchivedBranchOffices(c.Request.Context())
+	if err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+
+	c.JSON(http.StatusOK, branchOffices)
+}
+
+func (h *Handler) GetArchivedUsersHandler(c *gin.Context) {
+	users, err := h.services.Archive.GetArchivedUsers(c.Request.Context())
+	if err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+
+	c.JSON(http.StatusOK, users)
+}
+
+func (h *Handler) RestoreBranchOfficeHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.BadRequest("Invalid branch office ID"))
+		return
+	}
+
+	if err := h.services.Archive.RestoreBranchOffice(c.Request.Context(), uint(id)); err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Branch office restored successfully", "id": id})
+}
+
+func (h *Handler) RestoreUserHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.BadRequest("Invalid user ID"))
+		return
+	}
+
+	if err := h.services.Archive.RestoreUser(c.Request.Context(), uint(id)); err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User restored successfully", "id": id})
+}
+
+// bindPurgeRequest reads the reason of a purge and who performs it. The actor is taken from the bearer
+// token when one is sent, otherwise from purged_by in the body.
+func bindPurgeRequest(c *gin.Context) (*models.PurgeRequest, bool) {
+	var request models.PurgeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.Error(apperror.BadRequest("Invalid request body"))
+		return nil, false
+	}
+
+	request.Reason = strings.TrimSpace(request.Reason)
+	if request.Reason == "" {
+		c.Error(apperror.BadRequest("reason is required to purge archived data"))
+		return nil, false
+	}
+
+	if tokenStr := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "); tokenStr != "" {
+		claims, err := helpers.ValidateJWT(tokenStr)
+		if err != nil || claims == nil {
+			c.Error(apperror.Unauthorized("Invalid token"))
+			return nil, false
+		}
+		request.PurgedBy = claims.Username
+	}
+
+	request.PurgedBy = strings.TrimSpace(request.PurgedBy)
+	if request.PurgedBy == "" {
+		c.Error(apperror.BadRequest("purged_by is required when no token is sent"))
+		return nil, false
+	}
+
+	return &request, true
+}
 
-		// Update the user's image field
-		user.Image = fileName // Store just the filename
+func (h *Handler) PurgeBranchOfficeHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.BadRequest("Invalid branch office ID"))
+		return
 	}
 
-	// Validate user
-	if err := validation.ValidateUser(user); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	request, ok := bindPurgeRequest(c)
+	if !ok {
 		return
 	}
 
-	// Call service to update user
-	if err := services.UpdateUser(uint(userID), user); err != nil {
-		c.Error(err)
+	images, err := h.services.Archive.PurgeBranchOffice(c.Request.Context(), uint(id), request)
+	if err != nil {
+		c.Error(err) // Pass error to the middleware
 		return
 	}
 
-	// Delete the old image if it exists and a new image was uploaded
-	if user.Image != oldImagePath && oldImagePath != "" {
-		oldImageFullPath := filepath.Join("public/images", oldImagePath) // Use the correct path
-		if err := os.Remove(oldImageFullPath); err != nil {
-			log.Println("Error deleting old image:", err)
-		}
-	}
+	// The rows are gone, remove the images of the purged users
+	h.services.Uploads.ReleaseImages(c.Request.Context(), images)
 
-	c.JSON(http.StatusOK, gin.H{"message": "User updated successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "Branch office purged successfully", "id": id})
 }
 
-func DeleteUserHandler(c *gin.Context) {
-	id := c.Param("id")
-	userID, err := strconv.Atoi(id)
+func (h *Handler) PurgeUserHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		c.Error(apperror.BadRequest("Invalid user ID"))
 		return
 	}
 
-	// Find existing user
-	user, err := services.GetUserByID(uint(userID))
-	if err != nil || user == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	request, ok := bindPurgeRequest(c)
+	if !ok {
 		return
 	}
 
-	// Store the image path for deletion
-	imagePath := user.Image
-
-	// Delete the user from the database
-	if err := services.DeleteUser(uint(user.ID)); err != nil {
+	image, err := h.services.Archive.PurgeUser(c.Request.Context(), uint(id), request)
+	if err != nil {
 		c.Error(err) // Pass error to the middleware
 		return
 	}
 
-	// Delete the user's image file from the filesystem
-	if imagePath != "" {
-		oldImageFullPath := filepath.Join("public/images", imagePath) // Adjust the path as needed
-		if err := os.Remove(oldImageFullPath); err != nil {
-			log.Println("Error deleting user image:", err)
-		}
+	if image != "" {
+		h.services.Uploads.ReleaseImages(c.Request.Context(), []string{image})
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "User purged successfully", "id": id})
 }
 
-func GetUsersByBranchOffice(c *gin.Context) {
-	id := c.Param("id")
-	branchId, err := strconv.Atoi(id)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
-		return
+func (h *Handler) GetPurgeAuditLogHandler(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
 	}
 
-	// Use the service layer to get the users
-	users, err := services.GetUsersByBranchID(uint(branchId))
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+
+	entries, err := h.services.Archive.GetPurgeAuditLog(c.Request.Context(), limit, (page-1)*limit)
 	if err != nil {
 		c.Error(err) // Pass error to the middleware
 		return
 	}
 
-	c.JSON(http.StatusOK, users)
+	c.JSON(http.StatusOK, gin.H{"page": page, "limit": limit, "entries": entries})
 }
 
-// BranchCounter Handlers
-
-func GetBranchCounterHandlerByBranchId(c *gin.Context) {
-	idParam := c.Param("branch_id")
+// Import Handlers
 
-	// Convert the string idParam to an integer
-	id, err := strconv.Atoi(idParam)
+// readImportFile reads the spreadsheet uploaded as "file" and the dry_run flag of an import request
+func readImportFile(c *gin.Context) ([]helpers.SpreadsheetRow, bool, bool) {
+	dryRun, err := strconv.ParseBool(c.DefaultPostForm("dry_run", "false"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid branch office ID"})
-		return
+		c.Error(apperror.BadRequest("dry_run must be true or false"))
+		return nil, false, false
 	}
 
-	// Check the branch office by ID
-	branchOffice, err := services.GetBranchOfficeByID(uint(id))
+	fileHeader, err := c.FormFile("file")
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Branch office not found"})
-		return
+		c.Error(apperror.BadRequest("A .csv or .xlsx file is required"))
+		return nil, false, false
 	}
 
-	// Call service to retrieve branch counters by branch ID
-	counters, err := services.GetBranchCountersByBranchID(uint(id))
+	file, err := fileHeader.Open()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+		c.Error(err) // Pass error to the middleware
+		return nil, false, false
 	}
+	defer file.Close()
 
-	for i := range counters {
-		counters[i].Image = os.Getenv("URL_IMAGE_PROFILE") + counters[i].Image
+	rows, err := helpers.ReadSpreadsheet(fileHeader.Filename, file, services.MaxImportRows)
+	if err != nil {
+		c.Error(apperror.BadRequest(err.Error()))
+		return nil, false, false
 	}
+	metrics.RecordUpload("import_sheet", fileHeader.Size)
 
-	// Return JSON response with counters and total counter
-	c.JSON(http.StatusOK, gin.H{
-		"list_counter":  counters,
-		"name_branch":   branchOffice.Name,
-		"total_counter": branchOffice.TotalCounter,
-	})
+	return rows, dryRun, true
 }
 
-func CreateBranchCounterHandler(c *gin.Context) {
-	var branchCounter models.BranchCounter
-	var input map[string]interface{}
+// respondImport answers 422 when rows were rejected, otherwise 200 for a dry run and 201 once created
+func respondImport(c *gin.Context, result *models.ImportResult) {
+	switch {
+	case len(result.Errors) > 0:
+		c.JSON(http.StatusUnprocessableEntity, result)
+	case result.DryRun:
+		c.JSON(http.StatusOK, result)
+	default:
+		c.JSON(http.StatusCreated, result)
+	}
+}
 
-	// Parse request body into a map for validation
-	if err := c.BindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+func (h *Handler) ImportBranchOfficesHandler(c *gin.Context) {
+	if !parseMultipartForm(c) {
 		return
 	}
 
-	// Validate input using the validation function
-	if err := validation.ValidateBranchCounter(input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	rows, dryRun, ok := readImportFile(c)
+	if !ok {
 		return
 	}
 
-	// Manually bind validated values to branchCounter struct
-	branchCounter.CounterLocation = input["counter_location"].(string)
-	branchCounter.UserID = uint(input["user_id"].(float64))
-	branchCounter.BranchID = uint(input["branch_id"].(float64))
-
-	// Call service to create BranchCounter
-	if err := services.CreateBranchCounter(&branchCounter); err != nil {
-		c.Error(err) // Pass error to middleware
+	result, err := h.services.Import.ImportBranchOffices(c.Request.Context(), rows, dryRun)
+	if err != nil {
+		c.Error(err) // Pass error to the middleware
 		return
 	}
 
-	// Return success response
-	c.JSON(http.StatusCreated, gin.H{"message": "Branch counter created successfully"})
+	respondImport(c, result)
 }
 
-func DeleteBranchCounterHandler(c *gin.Context) {
-	id := c.Param("id")
+func (h *Handler) ImportUsersHandler(c *gin.Context) {
+	if !parseMultipartForm(c) {
+		return
+	}
 
-	// Call service to delete the branch counter
-	if err := services.DeleteBranchCounter(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting branch counter"})
+	rows, dryRun, ok := readImportFile(c)
+	if !ok {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Branch counter deleted successfully", "id": id})
+	// Optional ZIP of profile images named after the users' emails
+	var images *zip.Reader
+	if imagesHeader, err := c.FormFile("images"); err == nil {
+		imagesFile, err := imagesHeader.Open()
+		if err != nil {
+			c.Error(err) // Pass error to the middleware
+			return
+		}
+		defer imagesFile.Close()
+
+		images, err = zip.NewReader(imagesFile, imagesHeader.Size)
+		if err != nil {
+			c.Error(apperror.BadRequest("images must be a valid ZIP file"))
+			return
+		}
+		metrics.RecordUpload("import_images", imagesHeader.Size)
+	}
+
+	result, err := h.services.Import.ImportUsers(c.Request.Context(), rows, images, dryRun)
+	if err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+
+	respondImport(c, result)
 }
 
 // CompanyProfile Handlers
 
-func GetCompanyProfileHandler(c *gin.Context) {
+func (h *Handler) GetCompanyProfileHandler(c *gin.Context) {
 	// Get the company profile
-	company, err := services.GetCompanyProfile()
+	company, err := h.services.CompanyProfile.GetCompanyProfile(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get company profile"})
+		c.Error(err) // Pass error to the middleware
 		return
 	}
 
 	if company == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Company profile not found"})
+		c.Error(apperror.NotFound("Company profile not found"))
 		return
 	}
 
 	// Prepend the URL to the image field
-	company.Logo = os.Getenv("URL_IMAGE") + company.Logo
+	company.Logo = h.stores.Assets.URL(company.Logo)
+	company.Localize(requestLanguage(c))
 
 	c.JSON(http.StatusOK, company)
 }
 
-func UpdateCompanyProfileHandler(c *gin.Context) {
+func (h *Handler) UpdateCompanyProfileHandler(c *gin.Context) {
 	// Get the current company profile
-	company, err := services.GetCompanyProfile() // Fetch company profile with ID 1
+	company, err := h.services.CompanyProfile.GetCompanyProfile(c.Request.Context()) // Fetch company profile with ID 1
 	if err != nil {
 		c.Error(err)
 		return
 	}
 
-	// Store the current image path (e.g., "application_logo.jpg")
-	oldImagePath := company.Logo
-
 	// Parse the form for multipart data
-	if err := c.Request.ParseMultipartForm(32 << 20); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to parse form"})
+	if !parseMultipartForm(c) {
 		return
 	}
 
 	// Retrieve form data
-	name := c.Request.FormValue("name")
-	fileHeader, err := c.FormFile("logo")
-	var logo string
+	profile := models.CompanyProfile{
+		Name:   c.Request.FormValue("name"),
+		NameAr: optionalFormValue(c, "name_ar"),
+		NameEn: optionalFormValue(c, "name_en"),
+	}
+	if err := validation.ValidateCompanyProfile(&profile); err != nil {
+		c.Error(validationError(err))
+		return
+	}
+
+	// Keep the current logo (e.g., "application_logo.jpg") unless a new one is uploaded
+	profile.Logo = company.Logo
 
-	// Handle the file if provided
+	var logo *imaging.Image
+	fileHeader, err := c.FormFile("logo")
 	if err == nil {
-		fileName := helpers.GenerateFileName()
-		logo = fileName // Store the filename for the logo
+		var ok bool
+		if logo, ok = h.processImage(c, "logo", fileHeader, imaging.Logo); !ok {
+			return
+		}
+		metrics.RecordUpload("company_logo", fileHeader.Size)
+	}
 
-		// Open the file
-		fileData, err := fileHeader.Open()
-		if err != nil {
-			c.Error(err)
+	// Call service to update company profile with ID 1
+	if err := h.services.CompanyProfile.UpdateCompanyProfile(c.Request.Context(), &profile, logo); err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Company profile updated successfully"})
+}
+
+// File Handlers
+
+// ServeImageHandler serves a profile image or thumbnail from the storage, to requests carrying a valid
+// signature when the images are served at signed URLs
+func (h *Handler) ServeImageHandler(c *gin.Context) {
+	h.serveFile(c, h.stores.Images)
+}
+
+// ServeAssetHandler serves a company logo from the storage
+func (h *Handler) ServeAssetHandler(c *gin.Context) {
+	h.serveFile(c, h.stores.Assets)
+}
+
+// serveFile answers the file named by the route, with range and conditional requests. Content-addressed
+// images never change, so they are cached for a year; files at signed URLs only by the browser, until the
+// URL expires.
+func (h *Handler) serveFile(c *gin.Context, store storage.Storage) {
+	name := c.Param("name")
+	verifier, signed := store.(storage.URLVerifier)
+	if signed {
+		if err := verifier.VerifyURL(name, c.Request.URL.Query()); err != nil {
+			c.Error(apperror.Forbidden("The file link is invalid or has expired")) // Pass error to the middleware
 			return
 		}
-		defer fileData.Close()
+	}
+
+	object, err := store.Get(c.Request.Context(), name)
+	if errors.Is(err, storage.ErrNotExist) {
+		c.Error(apperror.NotFound("File not found"))
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Error reading file", "name", name, "error", err)
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+	defer object.Close()
+
+	if object.ContentType != "" {
+		c.Header("Content-Type", object.ContentType)
+	}
+	if signed {
+		maxAge := int(time.Until(verifier.Expires(c.Request.URL.Query())).Seconds())
+		c.Header("Cache-Control", fmt.Sprintf("private, max-age=%d", max(maxAge, 0)))
+	} else if imaging.ContentAddressed(name) {
+		c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		c.Header("Cache-Control", "public, max-age=3600")
+	}
+	http.ServeContent(c.Writer, c.Request, name, object.ModTime, object)
+}
+
+// Voted Users Handlers
+func (h *Handler) VotedUserHandler(c *gin.Context) {
+	userId := c.Param("userId")
+	voteType := c.Query("vote") // Assume "like" or "dislike" is passed in the query string
+
+	id, err := strconv.Atoi(userId)
+	if err != nil {
+		c.Error(apperror.BadRequest("Invalid user ID"))
+		return
+	}
+
+	if voteType != "like" && voteType != "dislike" {
+		c.Error(apperror.BadRequest("Invalid vote type, the type only 'like' & 'dislike'!"))
+		return
+	}
 
-		// Save the new file
-		newFilePath := filepath.Join("public/assets", logo)
-		dst, err := os.Create(newFilePath)
+	user, err := h.services.Users.GetUserByID(c.Request.Context(), uint(id))
+	if err != nil || user == nil {
+		c.Error(apperror.BadRequest("User not found!"))
+		return
+	}
+
+	// Optional counter where the service happened; defaults to the officer's current counter
+	var counterID *uint
+	if counterIdStr := c.Query("counter_id"); counterIdStr != "" {
+		parsed, err := strconv.ParseUint(counterIdStr, 10, 32)
 		if err != nil {
-			c.Error(err)
+			c.Error(apperror.BadRequest("Invalid counter ID"))
 			return
 		}
-		defer dst.Close()
 
-		// Copy the contents to the new file
-		if _, err := io.Copy(dst, fileData); err != nil {
-			c.Error(err)
+		counter, err := h.services.BranchCounters.GetBranchCounterByID(c.Request.Context(), uint(parsed))
+		if err != nil {
+			c.Error(err) // Pass error to the middleware
 			return
 		}
-
-		// Delete the old image if it exists
-		if oldImagePath != "" {
-			oldImageFullPath := filepath.Join("public/assets", oldImagePath)
-			if err := os.Remove(oldImageFullPath); err != nil {
-				log.Println("Error deleting old image:", err)
-			} else {
-				log.Println("Old image deleted:", oldImageFullPath)
-			}
+		if counter == nil || !counter.IsActive || counter.BranchID != user.BranchId {
+			c.Error(apperror.BadRequest("Active counter not found in the officer's branch office"))
+			return
 		}
-	} else {
-		// No new image uploaded, use the old image path
-		logo = oldImagePath
+		counterID = &counter.ID
 	}
 
-	// Call service to update company profile with ID 1
-	if err := services.UpdateCompanyProfile(name, logo); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update company profile"})
+	// Record the vote for the officer
+	outOfHours, err := h.services.Votes.VotedUser(c.Request.Context(), voteType, user, counterID)
+	if err != nil {
+		c.Error(err) // Pass error to the middleware
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Company profile updated successfully"})
+	metrics.RecordVote(user.BranchId, voteType)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Add feedback for user successfully", "out_of_hours": outOfHours})
 }
 
-// Voted Users Handlers
-func VotedUserHandler(c *gin.Context) {
-	userId := c.Param("userId")
-	voteType := c.Query("vote") // Assume "like" or "dislike" is passed in the query string
+// VotedCounterHandler records a vote for whoever is serving at a counter right now
+func (h *Handler) VotedCounterHandler(c *gin.Context) {
+	voteType := c.Query("vote")
 
-	id, err := strconv.Atoi(userId)
+	id, err := strconv.Atoi(c.Param("counterId"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		c.Error(apperror.BadRequest("Invalid counter ID"))
 		return
 	}
 
 	if voteType != "like" && voteType != "dislike" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid vote type, the type only 'like' & 'dislike'!"})
+		c.Error(apperror.BadRequest("Invalid vote type, the type only 'like' & 'dislike'!"))
+		return
+	}
+
+	counter, err := h.services.BranchCounters.GetBranchCounterByID(c.Request.Context(), uint(id))
+	if err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+	if counter == nil {
+		c.Error(apperror.NotFound("Branch counter not found"))
+		return
+	}
+	if !counter.IsActive {
+		c.Error(apperror.Conflict("Branch counter is inactive"))
 		return
 	}
 
-	user, err := services.GetUserByID(uint(id))
+	current, err := h.services.CounterAssignments.GetCurrentCounterOfficer(c.Request.Context(), counter, time.Now())
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found!"})
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+	if current.UserID == nil {
+		c.Error(apperror.Conflict("No officer is serving at this counter"))
+		return
 	}
 
-	// Call service to update company profile with ID 1
-	if err := services.VotedUser(voteType, user); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	user, err := h.services.Users.GetUserByID(c.Request.Context(), *current.UserID)
+	if err != nil || user == nil {
+		c.Error(apperror.BadRequest("User not found!"))
+		return
+	}
+
+	outOfHours, err := h.services.Votes.VotedUser(c.Request.Context(), voteType, user, &counter.ID)
+	if err != nil {
+		c.Error(err) // Pass error to the middleware
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Add feedback for user successfully"})
+	metrics.RecordVote(user.BranchId, voteType)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Add feedback for user successfully", "user_id": user.ID, "out_of_hours": outOfHours})
 }
 
 // Dashboard Handlers
-func TotalDataDashboard(c *gin.Context) {
-	totalOfficer, totalLikes, totalDislikes, totalVoted, err := services.TotalDataDashboard()
+
+// parseRegionQuery reads the optional region_id query parameter used to roll dashboards up to a region
+func parseRegionQuery(c *gin.Context) (*uint, bool) {
+	regionIdStr := c.Query("region_id")
+	if regionIdStr == "" {
+		return nil, true
+	}
+
+	parsed, err := strconv.ParseUint(regionIdStr, 10, 32)
+	if err != nil {
+		c.Error(apperror.BadRequest("Invalid region ID"))
+		return nil, false
+	}
+
+	regionId := uint(parsed)
+	return &regionId, true
+}
+
+func (h *Handler) TotalDataDashboard(c *gin.Context) {
+	regionId, ok := parseRegionQuery(c)
+	if !ok {
+		return
+	}
+
+	totalOfficer, totalLikes, totalDislikes, totalVoted, err := h.services.Dashboard.TotalDataDashboard(c.Request.Context(), regionId)
 	if err != nil {
-		log.Println("Error getting total data dashboard:", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve dashboard data"})
+		slog.ErrorContext(c.Request.Context(), "Error getting total data dashboard", "error", err)
+		c.Error(err) // Pass error to the middleware
 		return
 	}
 
@@ -705,24 +2076,41 @@ func TotalDataDashboard(c *gin.Context) {
 		"total_likes":    totalLikes,
 		"total_dislikes": totalDislikes,
 		"total_voted":    totalVoted,
+		"region_id":      regionId,
 	}
 
 	// Return the JSON response
 	c.JSON(http.StatusOK, response)
 }
 
-func TotalLikeDislikeBranchOfficeHandler(c *gin.Context) {
-	result, err := repository.TotalDataBranchDashboard()
+func (h *Handler) TotalLikeDislikeBranchOfficeHandler(c *gin.Context) {
+	regionId, ok := parseRegionQuery(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.services.Dashboard.TotalDataBranchOfficeDashboard(c.Request.Context(), regionId)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Error getting total data dashboard", "error", err)
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *Handler) TotalLikeDislikeRegionHandler(c *gin.Context) {
+	result, err := h.services.Dashboard.TotalDataRegionDashboard(c.Request.Context())
 	if err != nil {
-		log.Println("Error getting total data dashboard:", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve dashboard data"})
+		slog.ErrorContext(c.Request.Context(), "Error getting region data dashboard", "error", err)
+		c.Error(err) // Pass error to the middleware
 		return
 	}
 
 	c.JSON(http.StatusOK, result)
 }
 
-func TotalDataOfficerHandler(c *gin.Context) {
+func (h *Handler) TotalDataOfficerHandler(c *gin.Context) {
 	pageStr := c.DefaultQuery("pages", "1")
 	limitStr := c.DefaultQuery("limit", "10")
 
@@ -738,15 +2126,20 @@ func TotalDataOfficerHandler(c *gin.Context) {
 
 	offset := (page - 1) * limit
 
+	regionId, ok := parseRegionQuery(c)
+	if !ok {
+		return
+	}
+
 	// Use the service layer to get the branch offices
-	officer, err := services.GetAllOfficers(uint(limit), uint(offset))
+	officer, err := h.services.Dashboard.GetAllOfficers(c.Request.Context(), uint(limit), uint(offset), regionId)
 	if err != nil {
 		c.Error(err) // Pass error to the middleware
 		return
 	}
 
 	// Fetch the total branch office count
-	totalCount, err := repository.GetUsersCount("officer")
+	totalCount, err := h.services.Dashboard.GetOfficersCount(c.Request.Context(), regionId)
 	if err != nil {
 		c.Error(err) // Pass error to the middleware
 		return
@@ -761,100 +2154,188 @@ func TotalDataOfficerHandler(c *gin.Context) {
 	})
 }
 
-func TotalLikeDislikeOfficerHandler(c *gin.Context) {
+func (h *Handler) TotalLikeDislikeOfficerHandler(c *gin.Context) {
 
 }
 
-func UpdateDataDashboardHandler(c *gin.Context) {
+func (h *Handler) UpdateDataDashboardHandler(c *gin.Context) {
 	voteType := c.DefaultQuery("option", "like")
 
 	id := c.Param("branchId")
 	branchId, err := strconv.Atoi(id)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid branch ID"})
+		c.Error(apperror.BadRequest("Invalid branch ID"))
 		return
 	}
 
-	err = services.UpdateDataDashboard(uint(branchId), voteType)
+	// Votes left out of the officer's likes by the out-of-hours policy are left out of the totals too
+	outOfHours, err := h.services.Dashboard.UpdateDataDashboard(c.Request.Context(), uint(branchId), voteType)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err) // Pass error to the middleware
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Dashboard updated successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "Dashboard updated successfully", "out_of_hours": outOfHours})
 }
 
 // Auth
-func LoginWebServerHandler(c *gin.Context) {
+func (h *Handler) LoginWebServerHandler(c *gin.Context) {
 	var input models.LoginRequest
 
 	// Parse request body into the LoginRequest model
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		c.Error(apperror.BadRequest("Invalid request body"))
 		return
 	}
 
 	// Validate input using the validation function (assuming you have this in place)
+	input.Email = validation.NormalizeEmail(input.Email)
 	if err := validation.CheckLoginUserInput(input.Email, input.Password); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(validationError(err))
 		return
 	}
 
 	// Call the service to authenticate the user
-	user, err := services.AuthenticationLoginUser(input.Email, input.Password)
+	user, err := h.services.Users.AuthenticationLoginUser(c.Request.Context(), input.Email, input.Password)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		metrics.RecordLogin("web", false)
+		c.Error(err) // Pass error to the middleware
 		return
 	}
 
 	// Generate JWT token for authenticated users
-	token, err := helpers.GenerateJWT(user.Email)
+	token, err := helpers.GenerateJWT(user.ID, user.Email)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		c.Error(apperror.Internal(err)) // Pass error to the middleware
 		return
 	}
 
+	metrics.RecordLogin("web", true)
+	c.Set(middlewares.UserIDKey, user.ID) // Attribute the login in the access log
+
 	// Send response with the generated token
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Login successful",
 		"token":   token,
-		"user":    user.FullName,
+		"user":    locale.Pick(requestLanguage(c), user.FullName, user.FullNameAr, user.FullNameEn),
 	})
 }
 
-func LoginMobileHandler(c *gin.Context) {
+func (h *Handler) LoginMobileHandler(c *gin.Context) {
 	var input models.LoginMobileRequest
 
 	// Parse request body into the LoginRequest model
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		c.Error(apperror.BadRequest("Invalid request body"))
 		return
 	}
 
 	// Validate input using the validation function (assuming you have this in place)
+	input.Email = validation.NormalizeEmail(input.Email)
 	if err := validation.CheckLoginUserInput(input.Email, input.Password); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(validationError(err))
 		return
 	}
 
 	// Call the service to authenticate the user
-	user, err := services.AuthenticationLoginUserMobile(input.Email, input.Password, *input.BranchId)
+	user, err := h.services.Users.AuthenticationLoginUserMobile(c.Request.Context(), input.Email, input.Password, *input.BranchId)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		metrics.RecordLogin("mobile", false)
+		c.Error(err) // Pass error to the middleware
 		return
 	}
 
 	// Generate JWT token for authenticated users
-	token, err := helpers.GenerateJWT(user.Email)
+	token, err := helpers.GenerateJWT(user.ID, user.Email)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		c.Error(apperror.Internal(err)) // Pass error to the middleware
 		return
 	}
 
+	metrics.RecordLogin("mobile", true)
+	c.Set(middlewares.UserIDKey, user.ID) // Attribute the login in the access log
+
 	// Send response with the generated token
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Login successful",
 		"token":   token,
-		"user":    user.FullName,
+		"user":    locale.Pick(requestLanguage(c), user.FullName, user.FullNameAr, user.FullNameEn),
 	})
 }
+
+// Analytics Handlers
+
+func (h *Handler) BranchVoteHeatmapHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("branchId"))
+	if err != nil {
+		c.Error(apperror.BadRequest("Invalid branch office ID"))
+		return
+	}
+
+	branchOffice, err := h.services.BranchOffices.GetBranchOfficeByID(c.Request.Context(), uint(id))
+	if err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+
+	// Optional officer filter
+	var userID *uint
+	if userIdStr := c.Query("user_id"); userIdStr != "" {
+		parsed, err := strconv.ParseUint(userIdStr, 10, 32)
+		if err != nil {
+			c.Error(apperror.BadRequest("Invalid user ID"))
+			return
+		}
+		uid := uint(parsed)
+		userID = &uid
+	}
+
+	from, to, err := services.ParseDateRange(c.Query("from"), c.Query("to"), branchOffice.Timezone)
+	if err != nil {
+		c.Error(apperror.BadRequest(err.Error()))
+		return
+	}
+
+	heatmap, err := h.services.Analytics.GetBranchVoteHeatmap(c.Request.Context(), branchOffice, userID, from, to)
+	if err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+
+	c.JSON(http.StatusOK, heatmap)
+}
+
+func (h *Handler) RegionVoteHeatmapHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("regionId"))
+	if err != nil {
+		c.Error(apperror.BadRequest("Invalid region ID"))
+		return
+	}
+
+	region, err := h.services.Regions.GetRegionByID(c.Request.Context(), uint(id))
+	if err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+	if region == nil {
+		c.Error(apperror.NotFound("Region not found"))
+		return
+	}
+
+	// A region spans several branch offices, so the timezone is chosen by the caller
+	timezone := c.DefaultQuery("timezone", models.DefaultBranchTimezone)
+
+	from, to, err := services.ParseDateRange(c.Query("from"), c.Query("to"), timezone)
+	if err != nil {
+		c.Error(apperror.BadRequest(err.Error()))
+		return
+	}
+
+	heatmap, err := h.services.Analytics.GetRegionVoteHeatmap(c.Request.Context(), region, timezone, from, to)
+	if err != nil {
+		c.Error(err) // Pass error to the middleware
+		return
+	}
+
+	c.JSON(http.StatusOK, heatmap)
+}
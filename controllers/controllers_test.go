@@ -0,0 +1,305 @@
+package controllers
+
+import (
+	"api-server/config"
+	"api-server/middlewares"
+	"api-server/models"
+	"api-server/repository/memory"
+	"api-server/services"
+	"api-server/storage"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newTestHandler creates a handler on an empty in-memory store, keeping the uploads in temporary directories
+// and serving the images at signed URLs
+func newTestHandler(t *testing.T) (*Handler, *memory.Store) {
+	t.Helper()
+	store := memory.NewStore()
+	stores := &storage.Stores{
+		Images: storage.NewSigned(storage.NewLocal(t.TempDir(), "/images/"), "secret", time.Hour),
+		Assets: storage.NewLocal(t.TempDir(), "/assets/"),
+	}
+	uploads := config.Uploads{}
+	return NewHandler(services.NewServices(store.Repositories(), uploads, stores), uploads, stores), store
+}
+
+// newTestRouter serves the routes registered by setup, with the error middleware
+func newTestRouter(setup func(r *gin.Engine)) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middlewares.ErrorHandler())
+	setup(r)
+	return r
+}
+
+// serveJSON sends a request with a JSON body and decodes the JSON response into response, when not nil
+func serveJSON(t *testing.T, r *gin.Engine, method string, path string, body string, response interface{}) int {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if response != nil {
+		if err := json.Unmarshal(w.Body.Bytes(), response); err != nil {
+			t.Fatalf("%s %s: decoding %q: %v", method, path, w.Body.String(), err)
+		}
+	}
+	return w.Code
+}
+
+func TestUpdateBranchOfficeKeepsOmittedFields(t *testing.T) {
+	h, store := newTestHandler(t)
+	r := newTestRouter(func(r *gin.Engine) {
+		r.GET("/branch_offices/:id", h.GetBranchOfficeHandler)
+		r.PUT("/branch_offices/:id", h.UpdateBranchOfficeHandler)
+	})
+
+	ctx := context.Background()
+	if err := store.CreateRegion(ctx, &models.RegionCreateRequest{Name: "Makkah"}); err != nil {
+		t.Fatalf("creating the region: %v", err)
+	}
+	if err := store.CreateCity(ctx, &models.CityCreateRequest{Name: "Jeddah", RegionID: 1}); err != nil {
+		t.Fatalf("creating the city: %v", err)
+	}
+	nameAr, nameEn, addressAr, addressEn := "فرع الكورنيش", "Corniche Branch", "طريق الكورنيش", "Corniche Road"
+	cityID, latitude, longitude := uint(1), 21.5433, 39.1728
+	if err := store.CreateBranchOffice(ctx, &models.BranchOfficeCreateRequest{
+		Name: "Corniche", Address: "Corniche Road", TotalCounter: 4, Timezone: "Asia/Dubai",
+		NameAr: &nameAr, NameEn: &nameEn, AddressAr: &addressAr, AddressEn: &addressEn,
+		CityID: &cityID, Latitude: &latitude, Longitude: &longitude,
+	}); err != nil {
+		t.Fatalf("creating the branch office: %v", err)
+	}
+
+	// An admin client knowing only the first fields of the request
+	code := serveJSON(t, r, http.MethodPut, "/branch_offices/1", `{"name": "Corniche North", "address": "Corniche Road 2", "total_counter": 5}`, nil)
+	if code != http.StatusOK {
+		t.Fatalf("PUT without the optional fields = %d, want %d", code, http.StatusOK)
+	}
+
+	var updated models.BranchOfficeResponse
+	if code := serveJSON(t, r, http.MethodGet, "/branch_offices/1", "", &updated); code != http.StatusOK {
+		t.Fatalf("GET = %d, want %d", code, http.StatusOK)
+	}
+	if updated.Name != "Corniche North" || updated.Address != "Corniche Road 2" || updated.TotalCounter != 5 {
+		t.Errorf("PUT did not update the given fields: %+v", updated)
+	}
+	if updated.Timezone != "Asia/Dubai" {
+		t.Errorf("timezone = %q, want it kept as %q", updated.Timezone, "Asia/Dubai")
+	}
+	if updated.CityID == nil || *updated.CityID != cityID {
+		t.Errorf("city_id = %v, want it kept as %d", updated.CityID, cityID)
+	}
+	if updated.Latitude == nil || *updated.Latitude != latitude || updated.Longitude == nil || *updated.Longitude != longitude {
+		t.Errorf("coordinates = %v, %v, want them kept as %v, %v", updated.Latitude, updated.Longitude, latitude, longitude)
+	}
+	for field, got := range map[string]*string{"name_ar": updated.NameAr, "name_en": updated.NameEn, "address_ar": updated.AddressAr, "address_en": updated.AddressEn} {
+		if got == nil {
+			t.Errorf("%s cleared by a PUT without it", field)
+		}
+	}
+
+	// Given as null or blank, they are cleared
+	body := `{"name": "Corniche North", "address": "Corniche Road 2", "total_counter": 5,
+		"city_id": null, "latitude": null, "longitude": null, "name_ar": null, "name_en": ""}`
+	if code := serveJSON(t, r, http.MethodPut, "/branch_offices/1", body, nil); code != http.StatusOK {
+		t.Fatalf("PUT clearing the optional fields = %d, want %d", code, http.StatusOK)
+	}
+	updated = models.BranchOfficeResponse{}
+	serveJSON(t, r, http.MethodGet, "/branch_offices/1", "", &updated)
+	if updated.CityID != nil || updated.Latitude != nil || updated.Longitude != nil || updated.NameAr != nil || updated.NameEn != nil {
+		t.Errorf("PUT with null fields did not clear them: %+v", updated)
+	}
+	if updated.Timezone != "Asia/Dubai" || updated.AddressAr == nil || updated.AddressEn == nil {
+		t.Errorf("PUT changed fields it did not give: %+v", updated)
+	}
+
+	if code := serveJSON(t, r, http.MethodPut, "/branch_offices/9", `{"name": "Nowhere", "address": "Nowhere", "total_counter": 1}`, nil); code != http.StatusNotFound {
+		t.Errorf("PUT of an unknown branch office = %d, want %d", code, http.StatusNotFound)
+	}
+}
+
+// imageQuery returns the query of a signed image URL, failing when the URL is not signed
+func imageQuery(t *testing.T, field string, rawURL string, name string) url.Values {
+	t.Helper()
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Path != "/images/"+name || parsed.Query().Get("signature") == "" {
+		t.Fatalf("%s = %q, want the signed URL of %s", field, rawURL, name)
+	}
+	return parsed.Query()
+}
+
+func TestGetUserSignsImageWithoutPassword(t *testing.T) {
+	h, store := newTestHandler(t)
+	r := newTestRouter(func(r *gin.Engine) {
+		r.GET("/users/:id", h.GetUserHandler)
+	})
+
+	ctx := context.Background()
+	if err := store.CreateBranchOffice(ctx, &models.BranchOfficeCreateRequest{Name: "Riyadh Main", Address: "King Fahd Road", TotalCounter: 2, Timezone: "Asia/Riyadh"}); err != nil {
+		t.Fatalf("creating the branch office: %v", err)
+	}
+	image := "0123456789abcdef0123456789abcdef.jpg"
+	user := &models.User{FullName: "Test Officer", Email: "officer@example.com", Password: "$2a$10$hash", Role: "officer", BranchId: 1, Image: image}
+	if err := store.CreateUser(ctx, user); err != nil {
+		t.Fatalf("creating the user: %v", err)
+	}
+
+	var response map[string]interface{}
+	if code := serveJSON(t, r, http.MethodGet, "/users/1", "", &response); code != http.StatusOK {
+		t.Fatalf("GET = %d, want %d", code, http.StatusOK)
+	}
+	for _, field := range []string{"password", "Password"} {
+		if _, ok := response[field]; ok {
+			t.Errorf("the response has a %s field", field)
+		}
+	}
+	if response["email"] != "officer@example.com" || response["branch_id"] != float64(1) {
+		t.Errorf("GET = %v, want the fields of the list of users", response)
+	}
+	imageURL, _ := response["image"].(string)
+	thumbnailURL, _ := response["thumbnail"].(string)
+	query := imageQuery(t, "image", imageURL, image)
+	if err := h.stores.Images.(storage.URLVerifier).VerifyURL(image, query); err != nil {
+		t.Errorf("the image URL is refused: %v", err)
+	}
+	imageQuery(t, "thumbnail", thumbnailURL, "0123456789abcdef0123456789abcdef_thumb.jpg")
+}
+
+func TestGetCurrentCounterOfficerSignsImage(t *testing.T) {
+	h, store := newTestHandler(t)
+	r := newTestRouter(func(r *gin.Engine) {
+		r.GET("/counters/:id/current", h.GetCurrentCounterOfficerHandler)
+	})
+
+	ctx := context.Background()
+	if err := store.CreateBranchOffice(ctx, &models.BranchOfficeCreateRequest{Name: "Riyadh Main", Address: "King Fahd Road", TotalCounter: 2, Timezone: "Asia/Riyadh"}); err != nil {
+		t.Fatalf("creating the branch office: %v", err)
+	}
+	image := "0123456789abcdef0123456789abcdef.jpg"
+	user := &models.User{FullName: "Test Officer", Email: "officer@example.com", Password: "$2a$10$hash", Role: "officer", BranchId: 1, Image: image}
+	if err := store.CreateUser(ctx, user); err != nil {
+		t.Fatalf("creating the user: %v", err)
+	}
+	if err := store.CreateBranchCounter(ctx, &models.BranchCounter{CounterLocation: "Entrance", BranchID: 1, UserID: user.ID}); err != nil {
+		t.Fatalf("creating the counter: %v", err)
+	}
+
+	var response models.CurrentCounterOfficerResponse
+	if code := serveJSON(t, r, http.MethodGet, "/counters/1/current", "", &response); code != http.StatusOK {
+		t.Fatalf("GET = %d, want %d", code, http.StatusOK)
+	}
+	if response.Source != "static" || response.UserID == nil || *response.UserID != user.ID {
+		t.Fatalf("GET = %+v, want the officer linked to the counter", response)
+	}
+	imageQuery(t, "image", response.Image, image)
+	imageQuery(t, "thumbnail", response.Thumbnail, "0123456789abcdef0123456789abcdef_thumb.jpg")
+}
+
+func TestGetCounterSlotsCountsFreeSlots(t *testing.T) {
+	h, store := newTestHandler(t)
+	r := newTestRouter(func(r *gin.Engine) {
+		r.GET("/branch_counters/:branch_id/slots", h.GetCounterSlotsHandler)
+	})
+
+	ctx := context.Background()
+	if err := store.CreateBranchOffice(ctx, &models.BranchOfficeCreateRequest{Name: "Madinah Quba", Address: "Quba Road", TotalCounter: 4, Timezone: "Asia/Riyadh"}); err != nil {
+		t.Fatalf("creating the branch office: %v", err)
+	}
+	officer := &models.User{FullName: "Quba Officer", Email: "quba@example.com", Password: "$2a$10$hash", Role: "officer", BranchId: 1}
+	if err := store.CreateUser(ctx, officer); err != nil {
+		t.Fatalf("creating the officer: %v", err)
+	}
+	// An occupied counter and a vacant one, leaving two free slots
+	for _, counter := range []*models.BranchCounter{
+		{CounterLocation: "Hall A", BranchID: 1, UserID: officer.ID},
+		{CounterLocation: "Hall B", BranchID: 1},
+	} {
+		if err := store.CreateBranchCounter(ctx, counter); err != nil {
+			t.Fatalf("creating counter %q: %v", counter.CounterLocation, err)
+		}
+	}
+
+	var response struct {
+		Occupied int                  `json:"occupied"`
+		Free     int                  `json:"free"`
+		Slots    []models.CounterSlot `json:"slots"`
+	}
+	if code := serveJSON(t, r, http.MethodGet, "/branch_counters/1/slots", "", &response); code != http.StatusOK {
+		t.Fatalf("GET = %d, want %d", code, http.StatusOK)
+	}
+	if response.Occupied != 1 || response.Free != 2 {
+		t.Errorf("occupied = %d, free = %d, want 1 and 2 with a vacant counter", response.Occupied, response.Free)
+	}
+	if len(response.Slots) != 4 || response.Slots[1].Status != "vacant" {
+		t.Errorf("slots = %+v, want 4 with the second one vacant", response.Slots)
+	}
+}
+
+func TestUpdateBranchOfficeShrinksCapacity(t *testing.T) {
+	h, store := newTestHandler(t)
+	r := newTestRouter(func(r *gin.Engine) {
+		r.PUT("/branch_offices/:id", h.UpdateBranchOfficeHandler)
+		r.GET("/branch_counters/:branch_id", h.GetBranchCounterHandlerByBranchId)
+		r.GET("/branch_counters/:branch_id/slots", h.GetCounterSlotsHandler)
+	})
+
+	ctx := context.Background()
+	if err := store.CreateBranchOffice(ctx, &models.BranchOfficeCreateRequest{Name: "Tabuk Central", Address: "Prince Fahd Road", TotalCounter: 4, Timezone: "Asia/Riyadh"}); err != nil {
+		t.Fatalf("creating the branch office: %v", err)
+	}
+	for _, location := range []string{"Gate 1", "Gate 2", "Gate 3", "Gate 4"} {
+		if err := store.CreateBranchCounter(ctx, &models.BranchCounter{CounterLocation: location, BranchID: 1}); err != nil {
+			t.Fatalf("creating counter %q: %v", location, err)
+		}
+	}
+	shrink := `{"name": "Tabuk Central", "address": "Prince Fahd Road", "total_counter": 2}`
+
+	if code := serveJSON(t, r, http.MethodPut, "/branch_offices/1", shrink, nil); code != http.StatusConflict {
+		t.Fatalf("shrinking below the active counters = %d, want %d", code, http.StatusConflict)
+	}
+	if code := serveJSON(t, r, http.MethodPut, "/branch_offices/1?counter_policy=drop", shrink, nil); code != http.StatusBadRequest {
+		t.Errorf("an unknown counter_policy = %d, want %d", code, http.StatusBadRequest)
+	}
+	if code := serveJSON(t, r, http.MethodPut, "/branch_offices/1?counter_policy=deactivate", shrink, nil); code != http.StatusOK {
+		t.Fatalf("shrinking with counter_policy=deactivate = %d, want %d", code, http.StatusOK)
+	}
+
+	var slots struct {
+		Slots []models.CounterSlot `json:"slots"`
+	}
+	serveJSON(t, r, http.MethodGet, "/branch_counters/1/slots", "", &slots)
+	statuses := []string{}
+	for _, slot := range slots.Slots {
+		statuses = append(statuses, slot.Status)
+	}
+	if strings.Join(statuses, ",") != "vacant,vacant,inactive,inactive" {
+		t.Errorf("slot statuses after the shrink = %v, want the counters above 2 inactive", statuses)
+	}
+	if err := store.CreateBranchCounter(ctx, &models.BranchCounter{CounterLocation: "Gate 5", CounterNumber: 3, BranchID: 1}); err == nil {
+		t.Errorf("creating counter 3 above the new capacity succeeded")
+	}
+
+	// Growing again brings the deactivated counters back
+	grow := `{"name": "Tabuk Central", "address": "Prince Fahd Road", "total_counter": 3}`
+	if code := serveJSON(t, r, http.MethodPut, "/branch_offices/1", grow, nil); code != http.StatusOK {
+		t.Fatalf("growing = %d, want %d", code, http.StatusOK)
+	}
+	var counters struct {
+		List []models.BranchCounterWithNames `json:"list_counter"`
+	}
+	serveJSON(t, r, http.MethodGet, "/branch_counters/1", "", &counters)
+	if len(counters.List) != 3 || counters.List[2].CounterNumber != 3 {
+		t.Errorf("active counters after growing to 3 = %+v, want counters 1 to 3", counters.List)
+	}
+}
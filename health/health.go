@@ -0,0 +1,112 @@
+// Package health answers the probes of the load balancer: liveness tells the process is up, readiness
+// tells it can serve requests.
+package health
+
+import (
+	"api-server/migration"
+	"api-server/storage"
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// checkTimeout bounds every readiness check, so a hung database fails the probe instead of stalling it
+const checkTimeout = 2 * time.Second
+
+// Checker runs the readiness checks of the API server
+type Checker struct {
+	db              *sql.DB
+	expectedVersion int
+	stores          []storage.Storage
+	draining        atomic.Bool
+}
+
+// NewChecker creates a Checker for a database, which must be at the version of the embedded migrations,
+// and for the storages of the uploads
+func NewChecker(db *sql.DB, stores ...storage.Storage) (*Checker, error) {
+	migrations, err := migration.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Checker{db: db, expectedVersion: migration.LatestVersion(migrations), stores: stores}, nil
+}
+
+// Drain makes the readiness probe fail from now on, so the load balancer stops sending requests while
+// the in-flight ones complete
+func (h *Checker) Drain() {
+	h.draining.Store(true)
+}
+
+// Live answers 200 as long as the process serves requests
+func (h *Checker) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Ready answers 200 when the database is reachable and migrated and the uploads can be stored,
+// otherwise 503 with the failed checks, whose errors are logged
+func (h *Checker) Ready(c *gin.Context) {
+	if h.draining.Load() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "draining"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), checkTimeout)
+	defer cancel()
+
+	checks := gin.H{}
+	ready := true
+	for name, check := range map[string]func(context.Context) error{
+		"database":   h.checkDatabase,
+		"migrations": h.checkMigrations,
+		"uploads":    h.checkUploads,
+	} {
+		if err := check(ctx); err != nil {
+			// The probe is public and the errors name hosts and buckets, they are only logged
+			slog.WarnContext(ctx, "Readiness check failed", "check", name, "error", err)
+			checks[name] = "failed"
+			ready = false
+			continue
+		}
+		checks[name] = "ok"
+	}
+
+	if !ready {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unavailable", "checks": checks})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ready", "checks": checks})
+}
+
+// checkDatabase pings the database
+func (h *Checker) checkDatabase(ctx context.Context) error {
+	return h.db.PingContext(ctx)
+}
+
+// checkMigrations checks the schema is at the version of the embedded migrations
+func (h *Checker) checkMigrations(ctx context.Context) error {
+	version, err := migration.CurrentVersion(ctx, h.db)
+	if err != nil {
+		return err
+	}
+	if version != h.expectedVersion {
+		return fmt.Errorf("schema at version %d, expected %d", version, h.expectedVersion)
+	}
+	return nil
+}
+
+// checkUploads checks every storage of the uploads
+func (h *Checker) checkUploads(ctx context.Context) error {
+	for _, store := range h.stores {
+		if err := store.Check(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package health
+
+import (
+	"api-server/migration"
+	"api-server/storage"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeDatabase answers the queries of the readiness checks
+type fakeDatabase struct {
+	down    bool // Every call fails, as with an unreachable server
+	hung    bool // Every call waits for the context, as with a server that stopped answering
+	version int  // Highest applied migration, 0 without a schema_migrations table
+}
+
+func (d fakeDatabase) Connect(context.Context) (driver.Conn, error) { return fakeConn{d}, nil }
+func (d fakeDatabase) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct{ database fakeDatabase }
+
+func (c fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c fakeConn) Close() error                        { return nil }
+func (c fakeConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+// wait fails the call when the database is down or hung
+func (c fakeConn) wait(ctx context.Context) error {
+	switch {
+	case c.database.hung:
+		<-ctx.Done()
+		return ctx.Err()
+	case c.database.down:
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func (c fakeConn) Ping(ctx context.Context) error { return c.wait(ctx) }
+
+func (c fakeConn) QueryContext(ctx context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	if err := c.wait(ctx); err != nil {
+		return nil, err
+	}
+	switch query {
+	case "SELECT to_regclass('schema_migrations') IS NOT NULL":
+		return &fakeRows{value: c.database.version > 0}, nil
+	case "SELECT COALESCE(MAX(version), 0) FROM schema_migrations":
+		return &fakeRows{value: int64(c.database.version)}, nil
+	}
+	return nil, errors.New("unexpected query: " + query)
+}
+
+// fakeRows is a result of one row and one column
+type fakeRows struct {
+	value driver.Value
+	read  bool
+}
+
+func (r *fakeRows) Columns() []string { return []string{"value"} }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.read {
+		return io.EOF
+	}
+	dest[0] = r.value
+	r.read = true
+	return nil
+}
+
+// probe sends a request to a health route and decodes the response
+func probe(t *testing.T, ctx context.Context, handler gin.HandlerFunc) (int, map[string]interface{}) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/probe", handler)
+
+	req := httptest.NewRequest(http.MethodGet, "/probe", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decoding %s: %v", w.Body.String(), err)
+	}
+	return w.Code, body
+}
+
+func TestReady(t *testing.T) {
+	migrations, err := migration.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	latest := migration.LatestVersion(migrations)
+	writable := storage.NewLocal(t.TempDir(), "/images/")
+	missing := storage.NewLocal(filepath.Join(t.TempDir(), "missing"), "/assets/")
+
+	tests := []struct {
+		name     string
+		database fakeDatabase
+		stores   []storage.Storage
+		status   int
+		checks   map[string]interface{}
+	}{
+		{"ready", fakeDatabase{version: latest}, []storage.Storage{writable}, http.StatusOK,
+			map[string]interface{}{"database": "ok", "migrations": "ok", "uploads": "ok"}},
+		{"database down", fakeDatabase{down: true, version: latest}, []storage.Storage{writable}, http.StatusServiceUnavailable,
+			map[string]interface{}{"database": "failed", "migrations": "failed", "uploads": "ok"}},
+		{"schema behind the server", fakeDatabase{version: latest - 1}, []storage.Storage{writable}, http.StatusServiceUnavailable,
+			map[string]interface{}{"database": "ok", "migrations": "failed", "uploads": "ok"}},
+		{"schema never migrated", fakeDatabase{}, []storage.Storage{writable}, http.StatusServiceUnavailable,
+			map[string]interface{}{"database": "ok", "migrations": "failed", "uploads": "ok"}},
+		{"one storage not writable", fakeDatabase{version: latest}, []storage.Storage{writable, missing}, http.StatusServiceUnavailable,
+			map[string]interface{}{"database": "ok", "migrations": "ok", "uploads": "failed"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := sql.OpenDB(tt.database)
+			defer db.Close()
+			checker, err := NewChecker(db, tt.stores...)
+			if err != nil {
+				t.Fatalf("NewChecker() error = %v", err)
+			}
+
+			status, body := probe(t, context.Background(), checker.Ready)
+			if status != tt.status || !reflect.DeepEqual(body["checks"], tt.checks) {
+				t.Errorf("Ready() = %d %v, want %d with the checks %v", status, body, tt.status, tt.checks)
+			}
+			// The errors name hosts and directories, they are not returned
+			if body["error"] != nil || len(body) != 2 {
+				t.Errorf("Ready() body = %v, want only the status and the checks", body)
+			}
+
+			// Liveness does not depend on the checks
+			if status, body := probe(t, context.Background(), checker.Live); status != http.StatusOK || body["status"] != "ok" {
+				t.Errorf("Live() = %d %v, want 200", status, body)
+			}
+		})
+	}
+}
+
+func TestReadyHungDatabase(t *testing.T) {
+	db := sql.OpenDB(fakeDatabase{hung: true})
+	defer db.Close()
+	checker, err := NewChecker(db)
+	if err != nil {
+		t.Fatalf("NewChecker() error = %v", err)
+	}
+
+	// The probe fails when its deadline passes instead of waiting for the database
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	status, body := probe(t, ctx, checker.Ready)
+	if status != http.StatusServiceUnavailable || body["status"] != "unavailable" {
+		t.Errorf("Ready() = %d %v, want 503", status, body)
+	}
+	if elapsed := time.Since(start); elapsed > checkTimeout {
+		t.Errorf("Ready() took %v with a hung database, want at most %v", elapsed, checkTimeout)
+	}
+}
+
+func TestReadyDraining(t *testing.T) {
+	migrations, err := migration.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	db := sql.OpenDB(fakeDatabase{version: migration.LatestVersion(migrations)})
+	defer db.Close()
+	checker, err := NewChecker(db, storage.NewLocal(t.TempDir(), "/images/"))
+	if err != nil {
+		t.Fatalf("NewChecker() error = %v", err)
+	}
+
+	if status, _ := probe(t, context.Background(), checker.Ready); status != http.StatusOK {
+		t.Fatalf("Ready() before Drain() = %d, want 200", status)
+	}
+
+	// Once draining, the probe fails though every check would pass, and the process is still live
+	checker.Drain()
+	if status, body := probe(t, context.Background(), checker.Ready); status != http.StatusServiceUnavailable || body["status"] != "draining" {
+		t.Errorf("Ready() after Drain() = %d %v, want 503 draining", status, body)
+	}
+	if status, _ := probe(t, context.Background(), checker.Live); status != http.StatusOK {
+		t.Errorf("Live() after Drain() = %d, want 200", status)
+	}
+}
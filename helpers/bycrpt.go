@@ -1,7 +1,9 @@
 package helpers
 
 import (
-	"fmt"
+	"context"
+	"errors"
+	"log/slog"
 
 	"golang.org/x/crypto/bcrypt"
 )
@@ -15,11 +17,16 @@ func HashingPasswordFunc(password string) (string, error) {
 	return string(hashedPassword), nil
 }
 
-// CheckPasswordHashFunc compares a hashed password with a plain text one
-func CheckPasswordHashFunc(password string, hash string) bool {
+// CheckPasswordHashFunc compares a hashed password with a plain text one. A wrong password is expected
+// and only logged at debug level, a malformed hash is logged as an error.
+func CheckPasswordHashFunc(ctx context.Context, password string, hash string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		slog.DebugContext(ctx, "Password does not match the hash")
+		return false
+	}
 	if err != nil {
-		fmt.Println(err)
+		slog.ErrorContext(ctx, "Error comparing password hash", "error", err)
 		return false
 	}
 	return true
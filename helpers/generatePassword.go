@@ -0,0 +1,58 @@
+package helpers
+
+import (
+	"crypto/rand"
+	"math/big"
+)
+
+// Character classes of generated passwords, without look-alike characters (0/O, 1/l/I)
+const (
+	passwordUpper   = "ABCDEFGHJKLMNPQRSTUVWXYZ"
+	passwordLower   = "abcdefghijkmnopqrstuvwxyz"
+	passwordDigits  = "23456789"
+	passwordSpecial = "!@#$%&*?"
+)
+
+// GeneratePassword creates a random password of the given length (at least 4) that contains an uppercase
+// letter, a lowercase letter, a digit and a special character, as required by validation.ValidatePassword
+func GeneratePassword(length int) (string, error) {
+	if length < 4 {
+		length = 4
+	}
+
+	classes := []string{passwordUpper, passwordLower, passwordDigits, passwordSpecial}
+	all := passwordUpper + passwordLower + passwordDigits + passwordSpecial
+
+	password := make([]byte, length)
+	for i := range password {
+		charset := all
+		if i < len(classes) {
+			charset = classes[i] // One character of every class first
+		}
+		char, err := randomChar(charset)
+		if err != nil {
+			return "", err
+		}
+		password[i] = char
+	}
+
+	// Shuffle so the required classes are not always in front
+	for i := len(password) - 1; i > 0; i-- {
+		j, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return "", err
+		}
+		password[i], password[j.Int64()] = password[j.Int64()], password[i]
+	}
+
+	return string(password), nil
+}
+
+// randomChar picks a random character of the charset using a cryptographically secure source
+func randomChar(charset string) (byte, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(len(charset))))
+	if err != nil {
+		return 0, err
+	}
+	return charset[n.Int64()], nil
+}
@@ -1,26 +1,40 @@
 package helpers
 
 import (
-	"os"
+	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-// Load JWT secret from environment variable
-var jwtKey = []byte(os.Getenv("JWT_TOKEN"))
+// jwtKey signs and verifies the tokens, set from the configuration at startup
+var jwtKey []byte
+
+// errNoJWTKey refuses to sign or accept tokens before a secret is set, as an empty key can be forged
+var errNoJWTKey = errors.New("the JWT secret is not configured")
+
+// SetJWTKey sets the secret tokens are signed with
+func SetJWTKey(secret string) {
+	jwtKey = []byte(secret)
+}
 
 // Custom Claims structure
 type Claims struct {
+	UserID   uint   `json:"user_id"`
 	Username string `json:"username"`
 	jwt.RegisteredClaims
 }
 
 // GenerateJWT creates a new token for a valid user
-func GenerateJWT(username string) (string, error) {
+func GenerateJWT(userID uint, username string) (string, error) {
+	if len(jwtKey) == 0 {
+		return "", errNoJWTKey
+	}
+
 	// Set token expiration time, e.g., 24 hours
 	expirationTime := time.Now().Add(24 * time.Hour)
 	claims := &Claims{
+		UserID:   userID,
 		Username: username,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
@@ -42,6 +56,10 @@ func GenerateJWT(username string) (string, error) {
 
 // ValidateJWT parses and validates the JWT token string
 func ValidateJWT(tokenStr string) (*Claims, error) {
+	if len(jwtKey) == 0 {
+		return nil, errNoJWTKey
+	}
+
 	claims := &Claims{}
 
 	// Parse the token and validate the signature
@@ -0,0 +1,97 @@
+package helpers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// SpreadsheetRow is a data row of an imported sheet, keyed by the lower-cased header names
+type SpreadsheetRow struct {
+	Line   int // Line number in the file, the header being line 1
+	Values map[string]string
+}
+
+// ReadSpreadsheet reads a CSV or XLSX file (chosen by its extension) whose first row holds the column names.
+// Cells are trimmed and empty rows are skipped. Only the first sheet of an XLSX workbook is read.
+func ReadSpreadsheet(fileName string, file io.Reader, maxRows int) ([]SpreadsheetRow, error) {
+	var records [][]string
+
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".csv":
+		reader := csv.NewReader(file)
+		reader.FieldsPerRecord = -1 // Rows may omit trailing empty cells
+		reader.TrimLeadingSpace = true
+
+		for {
+			record, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("invalid CSV file: %w", err)
+			}
+			records = append(records, record)
+			if len(records) > maxRows+1 {
+				return nil, fmt.Errorf("the file cannot contain more than %d rows", maxRows)
+			}
+		}
+	case ".xlsx":
+		workbook, err := excelize.OpenReader(file)
+		if err != nil {
+			return nil, fmt.Errorf("invalid XLSX file: %w", err)
+		}
+		defer workbook.Close()
+
+		sheets := workbook.GetSheetList()
+		if len(sheets) == 0 {
+			return nil, errors.New("the XLSX file has no sheet")
+		}
+
+		records, err = workbook.GetRows(sheets[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid XLSX file: %w", err)
+		}
+		if len(records) > maxRows+1 {
+			return nil, fmt.Errorf("the file cannot contain more than %d rows", maxRows)
+		}
+	default:
+		return nil, errors.New("the file must be a .csv or .xlsx file")
+	}
+
+	if len(records) == 0 {
+		return nil, errors.New("the file is empty")
+	}
+
+	header := make([]string, len(records[0]))
+	for i, name := range records[0] {
+		// Spreadsheet tools often prepend a byte order mark to the first cell
+		header[i] = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+	}
+
+	rows := []SpreadsheetRow{}
+	for i, record := range records[1:] {
+		row := SpreadsheetRow{Line: i + 2, Values: map[string]string{}}
+		empty := true
+		for j, cell := range record {
+			if j >= len(header) || header[j] == "" {
+				continue
+			}
+			cell = strings.TrimSpace(cell)
+			if cell != "" {
+				empty = false
+			}
+			row.Values[header[j]] = cell
+		}
+		if !empty {
+			rows = append(rows, row)
+		}
+	}
+
+	return rows, nil
+}
@@ -0,0 +1,229 @@
+// Package imaging turns uploaded images into the files stored by the API server: only JPEG, PNG and WebP
+// content is accepted, decompression bombs are refused, the metadata (EXIF, GPS position, ...) is dropped
+// by re-encoding, and the image is resized to a standard size with a thumbnail and named after its content.
+package imaging
+
+import (
+	"api-server/storage"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/webp"
+)
+
+// Spec is the geometry of a kind of image
+type Spec struct {
+	Size      int  // the longest side of the stored image, smaller images are not enlarged
+	Thumbnail int  // the longest side of the thumbnail
+	Square    bool // keep the center square, for avatars
+}
+
+// Standard sizes of the stored images
+var (
+	Avatar = Spec{Size: 512, Thumbnail: 128, Square: true}
+	Logo   = Spec{Size: 1024, Thumbnail: 256}
+)
+
+// Limits bound the uploads accepted
+type Limits struct {
+	MaxBytes  int64 // the largest file
+	MaxPixels int   // the largest decoded image, width × height
+}
+
+// Image is an upload processed to a Spec, ready to be stored
+type Image struct {
+	Name        string // the content hash with the extension of the format, e.g. 9f86d081884c7d659a2feaa0c55ad015.jpg
+	ContentType string
+	Data        []byte
+	Thumbnail   []byte // stored as ThumbnailName(Name)
+}
+
+// Error rejects an upload that is not an acceptable image, its message can be shown to the client
+type Error struct {
+	message string
+}
+
+func (e *Error) Error() string {
+	return e.message
+}
+
+const (
+	jpegQuality = 85
+	hashLength  = 32 // hex digits of the SHA-256 kept in names
+)
+
+// format decodes one of the accepted types
+type format struct {
+	decode       func(io.Reader) (image.Image, error)
+	decodeConfig func(io.Reader) (image.Config, error)
+}
+
+// formats are the accepted types, by sniffed content type
+var formats = map[string]format{
+	"image/jpeg": {jpeg.Decode, jpeg.DecodeConfig},
+	"image/png":  {png.Decode, png.DecodeConfig},
+	"image/webp": {webp.Decode, webp.DecodeConfig},
+}
+
+// Names given by Process; images stored before have no thumbnail
+var (
+	processedName        = regexp.MustCompile(`^[0-9a-f]{32}\.(jpg|png)$`)
+	contentAddressedName = regexp.MustCompile(`^[0-9a-f]{32}(_thumb)?\.(jpg|png)$`)
+)
+
+// Process reads an uploaded image and re-encodes it to spec. Errors of type *Error reject the upload,
+// other errors are internal.
+func Process(r io.Reader, limits Limits, spec Spec) (*Image, error) {
+	data, err := io.ReadAll(io.LimitReader(r, limits.MaxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limits.MaxBytes {
+		return nil, &Error{fmt.Sprintf("the image cannot exceed %d MB", limits.MaxBytes>>20)}
+	}
+
+	// Trust the content, not the file name or the type declared by the client
+	contentType := http.DetectContentType(data)
+	decoder, ok := formats[contentType]
+	if !ok {
+		return nil, &Error{"the image must be a JPEG, PNG or WebP file"}
+	}
+
+	// The header gives the dimensions before any memory is allocated for the pixels
+	config, err := decoder.decodeConfig(bytes.NewReader(data))
+	if err != nil || config.Width <= 0 || config.Height <= 0 {
+		return nil, &Error{"the image is corrupted"}
+	}
+	if config.Width > limits.MaxPixels/config.Height {
+		return nil, &Error{fmt.Sprintf("the image cannot exceed %d megapixels", limits.MaxPixels/1000000)}
+	}
+
+	decoded, err := decoder.decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, &Error{"the image is corrupted"}
+	}
+
+	// Fitting in a square box commutes with the rotations, so the smaller image is oriented
+	resized := fit(decoded, spec.Size, spec.Square)
+	if contentType == "image/jpeg" {
+		resized = orient(resized, exifOrientation(data))
+	}
+	thumbnail := fit(resized, spec.Thumbnail, false)
+
+	// JPEG stays JPEG and PNG stays PNG; WebP, which has no encoder here, becomes PNG when transparent
+	encode, extension, outputType := encodeJPEG, ".jpg", "image/jpeg"
+	if contentType == "image/png" || (contentType == "image/webp" && !resized.Opaque()) {
+		encode, extension, outputType = encodePNG, ".png", "image/png"
+	}
+
+	processed := &Image{ContentType: outputType}
+	if processed.Data, err = encode(resized); err != nil {
+		return nil, err
+	}
+	if processed.Thumbnail, err = encode(thumbnail); err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(processed.Data)
+	processed.Name = hex.EncodeToString(hash[:])[:hashLength] + extension
+
+	return processed, nil
+}
+
+// ThumbnailName returns the name of the thumbnail of an image, "" for images stored before thumbnails
+func ThumbnailName(name string) string {
+	if !processedName.MatchString(name) {
+		return ""
+	}
+	extension := filepath.Ext(name)
+	return strings.TrimSuffix(name, extension) + "_thumb" + extension
+}
+
+// Store keeps images: a storage.Storage, or a storage.Transaction staging them for a database change
+type Store interface {
+	Put(ctx context.Context, name string, data io.Reader, size int64, contentType string) error
+	Delete(ctx context.Context, name string) error
+}
+
+// Compile-time checks that both kinds of store can keep images
+var (
+	_ Store = storage.Storage(nil)
+	_ Store = (*storage.Transaction)(nil)
+)
+
+// Save stores an image and its thumbnail
+func Save(ctx context.Context, store Store, processed *Image) error {
+	if err := store.Put(ctx, processed.Name, bytes.NewReader(processed.Data), int64(len(processed.Data)), processed.ContentType); err != nil {
+		return err
+	}
+	if err := store.Put(ctx, ThumbnailName(processed.Name), bytes.NewReader(processed.Thumbnail), int64(len(processed.Thumbnail)), processed.ContentType); err != nil {
+		store.Delete(ctx, processed.Name)
+		return err
+	}
+	return nil
+}
+
+// Remove deletes an image and its thumbnail
+func Remove(ctx context.Context, store Store, name string) error {
+	if thumbnail := ThumbnailName(name); thumbnail != "" {
+		if err := store.Delete(ctx, thumbnail); err != nil {
+			return err
+		}
+	}
+	return store.Delete(ctx, name)
+}
+
+// ContentAddressed tells whether a file is an image or thumbnail named after its content by Process,
+// which never changes and can be cached forever
+func ContentAddressed(name string) bool {
+	return contentAddressedName.MatchString(name)
+}
+
+// fit scales an image down so its longest side is at most size, after keeping its center square when
+// square is set. The result always starts at the origin.
+func fit(src image.Image, size int, square bool) *image.NRGBA {
+	bounds := src.Bounds()
+	if square {
+		side := min(bounds.Dx(), bounds.Dy())
+		x := bounds.Min.X + (bounds.Dx()-side)/2
+		y := bounds.Min.Y + (bounds.Dy()-side)/2
+		bounds = image.Rect(x, y, x+side, y+side)
+	}
+
+	width, height := bounds.Dx(), bounds.Dy()
+	if longest := max(width, height); longest > size {
+		width = max(1, width*size/longest)
+		height = max(1, height*size/longest)
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	if width == bounds.Dx() && height == bounds.Dy() {
+		draw.Draw(dst, dst.Bounds(), src, bounds.Min, draw.Src)
+	} else {
+		draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Src, nil)
+	}
+	return dst
+}
+
+func encodeJPEG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+	return buf.Bytes(), err
+}
+
+func encodePNG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	err := (&png.Encoder{CompressionLevel: png.BestCompression}).Encode(&buf, img)
+	return buf.Bytes(), err
+}
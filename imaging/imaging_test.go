@@ -0,0 +1,197 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"strings"
+	"testing"
+)
+
+const cellSize = 16
+
+// palette are the colors of the cells of the fixture, far enough apart to survive JPEG compression
+var palette = []color.NRGBA{
+	{0xFF, 0x00, 0x00, 0xFF}, // Red
+	{0x00, 0xFF, 0x00, 0xFF}, // Green
+	{0x00, 0x00, 0xFF, 0xFF}, // Blue
+	{0xFF, 0xFF, 0x00, 0xFF}, // Yellow
+	{0xFF, 0x00, 0xFF, 0xFF}, // Magenta
+	{0x00, 0xFF, 0xFF, 0xFF}, // Cyan
+}
+
+var testLimits = Limits{MaxBytes: 1 << 20, MaxPixels: 1000000}
+
+// cells returns a 3 × 2 grid of cells colored by their number, as in displayed
+func cells() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 3*cellSize, 2*cellSize))
+	for y := 0; y < img.Bounds().Dy(); y++ {
+		for x := 0; x < img.Bounds().Dx(); x++ {
+			img.SetNRGBA(x, y, palette[y/cellSize*3+x/cellSize])
+		}
+	}
+	return img
+}
+
+// cellAt returns the number of the palette color closest to the center of a cell
+func cellAt(img image.Image, x, y int) int {
+	r, g, b, _ := img.At(x*cellSize+cellSize/2, y*cellSize+cellSize/2).RGBA()
+	closest, distance := 0, -1
+	for i, c := range palette {
+		dr, dg, db := int(r>>8)-int(c.R), int(g>>8)-int(c.G), int(b>>8)-int(c.B)
+		if d := dr*dr + dg*dg + db*db; distance < 0 || d < distance {
+			closest, distance = i, d
+		}
+	}
+	return closest
+}
+
+func encodeFixture(t *testing.T, encode func(image.Image) ([]byte, error), img image.Image) []byte {
+	t.Helper()
+	data, err := encode(img)
+	if err != nil {
+		t.Fatalf("encoding the fixture: %v", err)
+	}
+	return data
+}
+
+func TestProcessOrientation(t *testing.T) {
+	stored := encodeFixture(t, encodeJPEG, cells())
+
+	for orientation := 1; orientation <= 8; orientation++ {
+		want := displayed[orientation]
+		data := withOrientation(stored, binary.LittleEndian, orientation)
+
+		processed, err := Process(bytes.NewReader(data), testLimits, Logo)
+		if err != nil {
+			t.Fatalf("Process(orientation %d) error = %v", orientation, err)
+		}
+		if exifOrientation(processed.Data) != 1 {
+			t.Errorf("Process(orientation %d) kept the EXIF orientation", orientation)
+		}
+
+		for _, output := range []struct {
+			name string
+			data []byte
+		}{{"image", processed.Data}, {"thumbnail", processed.Thumbnail}} {
+			img, err := jpeg.Decode(bytes.NewReader(output.data))
+			if err != nil {
+				t.Fatalf("decoding the %s of orientation %d: %v", output.name, orientation, err)
+			}
+			if size := img.Bounds().Size(); size != image.Pt(len(want[0])*cellSize, len(want)*cellSize) {
+				t.Errorf("%s of orientation %d is %v, want %d × %d cells", output.name, orientation, size, len(want[0]), len(want))
+				continue
+			}
+			for y, row := range want {
+				for x, cell := range row {
+					if got := cellAt(img, x, y); got != cell {
+						t.Errorf("%s of orientation %d has cell %d at (%d, %d), want %d", output.name, orientation, got, x, y, cell)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestProcessLimits(t *testing.T) {
+	jpegData := encodeFixture(t, encodeJPEG, cells())
+	gifData := encodeFixture(t, func(img image.Image) ([]byte, error) {
+		var buf bytes.Buffer
+		err := gif.Encode(&buf, img, nil)
+		return buf.Bytes(), err
+	}, cells())
+
+	tests := []struct {
+		name    string
+		data    []byte
+		limits  Limits
+		message string
+	}{
+		{
+			name:    "oversize",
+			data:    jpegData,
+			limits:  Limits{MaxBytes: int64(len(jpegData)) - 1, MaxPixels: testLimits.MaxPixels},
+			message: "the image cannot exceed",
+		},
+		{
+			name:    "too many pixels",
+			data:    jpegData,
+			limits:  Limits{MaxBytes: testLimits.MaxBytes, MaxPixels: 3*cellSize*2*cellSize - 1},
+			message: "megapixels",
+		},
+		{
+			// A script uploaded as photo.jpg: the content is sniffed, the name is never looked at
+			name:    "spoofed extension",
+			data:    []byte("<html><script>alert(document.cookie)</script></html>"),
+			limits:  testLimits,
+			message: "must be a JPEG, PNG or WebP file",
+		},
+		{
+			name:    "unaccepted format",
+			data:    gifData,
+			limits:  testLimits,
+			message: "must be a JPEG, PNG or WebP file",
+		},
+		{
+			name:    "corrupted",
+			data:    append([]byte{0xFF, 0xD8, 0xFF, 0xE0}, bytes.Repeat([]byte{0xAB}, 64)...),
+			limits:  testLimits,
+			message: "the image is corrupted",
+		},
+		{
+			name:    "truncated",
+			data:    jpegData[:len(jpegData)/2],
+			limits:  testLimits,
+			message: "the image is corrupted",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processed, err := Process(bytes.NewReader(tt.data), tt.limits, Logo)
+			var rejected *Error
+			if !errors.As(err, &rejected) {
+				t.Fatalf("Process() = %v, %v, want an *Error", processed, err)
+			}
+			if !strings.Contains(rejected.Error(), tt.message) {
+				t.Errorf("Process() error = %q, want it to contain %q", rejected.Error(), tt.message)
+			}
+		})
+	}
+
+	// The limits themselves are accepted
+	exact := Limits{MaxBytes: int64(len(jpegData)), MaxPixels: 3 * cellSize * 2 * cellSize}
+	if _, err := Process(bytes.NewReader(jpegData), exact, Logo); err != nil {
+		t.Errorf("Process() at the limits error = %v", err)
+	}
+}
+
+func TestProcessResize(t *testing.T) {
+	data := encodeFixture(t, encodeJPEG, cells())
+	spec := Spec{Size: 2 * cellSize, Thumbnail: cellSize, Square: true}
+
+	processed, err := Process(bytes.NewReader(data), testLimits, spec)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	for _, output := range []struct {
+		name string
+		data []byte
+		want image.Point
+	}{{"image", processed.Data, image.Pt(2*cellSize, 2*cellSize)}, {"thumbnail", processed.Thumbnail, image.Pt(cellSize, cellSize)}} {
+		config, err := jpeg.DecodeConfig(bytes.NewReader(output.data))
+		if err != nil {
+			t.Fatalf("decoding the %s: %v", output.name, err)
+		}
+		if size := image.Pt(config.Width, config.Height); size != output.want {
+			t.Errorf("%s size = %v, want %v", output.name, size, output.want)
+		}
+	}
+	if !ContentAddressed(processed.Name) || !ContentAddressed(ThumbnailName(processed.Name)) {
+		t.Errorf("Process() name %q is not content addressed", processed.Name)
+	}
+}
@@ -0,0 +1,112 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+)
+
+// exifOrientation returns the EXIF orientation of a JPEG, from 1 to 8, and 1 when there is none. Cameras
+// store the pixels as shot and record the rotation to display them with, which re-encoding drops.
+func exifOrientation(data []byte) int {
+	// After the start of image, every segment is a marker FF xx followed by its big-endian length
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		if marker == 0xFF { // Fill byte
+			i++
+			continue
+		}
+		if marker == 0xDA || marker == 0xD9 { // Start of scan or end of image, the metadata is over
+			return 1
+		}
+
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			return 1
+		}
+		segment := data[i+4 : i+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return tiffOrientation(segment[6:])
+		}
+		i += 2 + length
+	}
+	return 1
+}
+
+// tiffOrientation reads the orientation tag of the first IFD of an EXIF TIFF structure
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	offset := int(order.Uint32(tiff[4:]))
+	if offset < 8 || offset+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[offset:]))
+	for i := 0; i < entries; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) != 0x0112 {
+			continue
+		}
+		if orientation := int(order.Uint16(tiff[entry+8:])); orientation >= 1 && orientation <= 8 {
+			return orientation
+		}
+		return 1
+	}
+	return 1
+}
+
+// orient applies an EXIF orientation to an image starting at the origin
+func orient(src *image.NRGBA, orientation int) *image.NRGBA {
+	if orientation <= 1 || orientation > 8 {
+		return src
+	}
+
+	width, height := src.Bounds().Dx(), src.Bounds().Dy()
+	dstWidth, dstHeight := width, height
+	if orientation >= 5 { // The orientations from 5 to 8 swap the sides
+		dstWidth, dstHeight = height, width
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // Mirrored
+				dx, dy = width-1-x, y
+			case 3: // Upside down
+				dx, dy = width-1-x, height-1-y
+			case 4: // Mirrored upside down
+				dx, dy = x, height-1-y
+			case 5: // Mirrored, rotated counterclockwise
+				dx, dy = y, x
+			case 6: // Rotated counterclockwise, turned clockwise
+				dx, dy = height-1-y, x
+			case 7: // Mirrored, rotated clockwise
+				dx, dy = height-1-y, width-1-x
+			case 8: // Rotated clockwise, turned counterclockwise
+				dx, dy = y, width-1-x
+			}
+			copy(dst.Pix[dst.PixOffset(dx, dy):dst.PixOffset(dx, dy)+4], src.Pix[src.PixOffset(x, y):src.PixOffset(x, y)+4])
+		}
+	}
+	return dst
+}
@@ -0,0 +1,156 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"testing"
+)
+
+// displayed is, for every EXIF orientation, the grid displayed from a stored grid of 3 × 2 cells numbered
+//
+//	0 1 2
+//	3 4 5
+//
+// following the meaning of the 0th row and column in the EXIF specification
+var displayed = map[int][][]int{
+	1: {{0, 1, 2}, {3, 4, 5}},   // Row 0 at the top, column 0 on the left
+	2: {{2, 1, 0}, {5, 4, 3}},   // Row 0 at the top, column 0 on the right
+	3: {{5, 4, 3}, {2, 1, 0}},   // Row 0 at the bottom, column 0 on the right
+	4: {{3, 4, 5}, {0, 1, 2}},   // Row 0 at the bottom, column 0 on the left
+	5: {{0, 3}, {1, 4}, {2, 5}}, // Row 0 on the left, column 0 at the top
+	6: {{3, 0}, {4, 1}, {5, 2}}, // Row 0 on the right, column 0 at the top
+	7: {{5, 2}, {4, 1}, {3, 0}}, // Row 0 on the right, column 0 at the bottom
+	8: {{2, 5}, {1, 4}, {0, 3}}, // Row 0 on the left, column 0 at the bottom
+	0: {{0, 1, 2}, {3, 4, 5}},   // Invalid, left as stored
+	9: {{0, 1, 2}, {3, 4, 5}},   // Invalid, left as stored
+}
+
+// tiffFixture returns an EXIF TIFF structure whose first IFD holds a resolution unit entry, then the
+// orientation entry when orientation is not 0
+func tiffFixture(order binary.ByteOrder, orientation int) []byte {
+	entries := [][3]uint16{{0x0128, 3, 2}} // Tag, type SHORT, value
+	if orientation != 0 {
+		entries = append(entries, [3]uint16{0x0112, 3, uint16(orientation)})
+	}
+
+	var buf bytes.Buffer
+	if order == binary.LittleEndian {
+		buf.WriteString("II")
+	} else {
+		buf.WriteString("MM")
+	}
+	binary.Write(&buf, order, uint16(42))
+	binary.Write(&buf, order, uint32(8)) // The first IFD follows the header
+	binary.Write(&buf, order, uint16(len(entries)))
+	for _, entry := range entries {
+		binary.Write(&buf, order, entry[0])
+		binary.Write(&buf, order, entry[1])
+		binary.Write(&buf, order, uint32(1)) // Count
+		binary.Write(&buf, order, entry[2])
+		binary.Write(&buf, order, uint16(0)) // Padding of the value to 4 bytes
+	}
+	binary.Write(&buf, order, uint32(0)) // No next IFD
+	return buf.Bytes()
+}
+
+// withSegment inserts a segment right after the start of image of a JPEG
+func withSegment(jpegData []byte, marker byte, payload []byte) []byte {
+	segment := []byte{0xFF, marker, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	segment = append(segment, payload...)
+
+	data := append([]byte{}, jpegData[:2]...)
+	data = append(data, segment...)
+	return append(data, jpegData[2:]...)
+}
+
+// withOrientation inserts an EXIF segment with an orientation into a JPEG
+func withOrientation(jpegData []byte, order binary.ByteOrder, orientation int) []byte {
+	return withSegment(jpegData, 0xE1, append([]byte("Exif\x00\x00"), tiffFixture(order, orientation)...))
+}
+
+func TestExifOrientation(t *testing.T) {
+	plain, err := encodeJPEG(image.NewGray(image.Rect(0, 0, 8, 8)))
+	if err != nil {
+		t.Fatalf("encoding the fixture: %v", err)
+	}
+
+	for orientation := 1; orientation <= 8; orientation++ {
+		for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+			data := withOrientation(plain, order, orientation)
+			if got := exifOrientation(data); got != orientation {
+				t.Errorf("exifOrientation(%v, %d) = %d", order, orientation, got)
+			}
+		}
+	}
+
+	xmp := withSegment(plain, 0xE1, []byte("http://ns.adobe.com/xap/1.0/\x00<x:xmpmeta/>"))
+	truncated := withOrientation(plain, binary.BigEndian, 6)
+	truncated = truncated[:2+4+len("Exif\x00\x00")+10]
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"no EXIF", plain, 1},
+		{"no orientation", withOrientation(plain, binary.LittleEndian, 0), 1},
+		{"invalid orientation", withOrientation(plain, binary.LittleEndian, 9), 1},
+		{"EXIF after another APP1", withSegment(withOrientation(plain, binary.BigEndian, 6), 0xE1, []byte("other")), 6},
+		{"XMP only", xmp, 1},
+		{"truncated", truncated, 1},
+		{"not a JPEG", []byte("\x89PNG\r\n\x1a\n"), 1},
+		{"empty", nil, 1},
+	}
+	for _, tt := range tests {
+		if got := exifOrientation(tt.data); got != tt.want {
+			t.Errorf("exifOrientation(%s) = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTiffOrientation(t *testing.T) {
+	tests := []struct {
+		name string
+		tiff []byte
+		want int
+	}{
+		{"little endian", tiffFixture(binary.LittleEndian, 3), 3},
+		{"big endian", tiffFixture(binary.BigEndian, 8), 8},
+		{"no orientation", tiffFixture(binary.BigEndian, 0), 1},
+		{"unknown byte order", append([]byte("XX"), tiffFixture(binary.BigEndian, 6)[2:]...), 1},
+		{"IFD out of bounds", tiffFixture(binary.BigEndian, 6)[:12], 1},
+		{"too short", []byte("MM\x00*"), 1},
+	}
+	for _, tt := range tests {
+		if got := tiffOrientation(tt.tiff); got != tt.want {
+			t.Errorf("tiffOrientation(%s) = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestOrient(t *testing.T) {
+	// A 3 × 2 image whose pixels are numbered by their red value
+	src := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 3; x++ {
+			src.SetNRGBA(x, y, color.NRGBA{R: uint8(y*3 + x), A: 0xFF})
+		}
+	}
+
+	for orientation, want := range displayed {
+		got := orient(src, orientation)
+		if got.Bounds() != image.Rect(0, 0, len(want[0]), len(want)) {
+			t.Errorf("orient(%d) bounds = %v, want %d × %d", orientation, got.Bounds(), len(want[0]), len(want))
+			continue
+		}
+		for y, row := range want {
+			for x, cell := range row {
+				if r := got.NRGBAAt(x, y).R; int(r) != cell {
+					t.Errorf("orient(%d) at (%d, %d) = pixel %d, want %d", orientation, x, y, r, cell)
+				}
+			}
+		}
+	}
+}
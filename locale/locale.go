@@ -0,0 +1,58 @@
+package locale
+
+import (
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// Supported response languages
+const (
+	Arabic  = "ar"
+	English = "en"
+)
+
+// ContextKey is the gin context key holding the language negotiated from the Accept-Language header
+const ContextKey = "language"
+
+// matcher picks the best supported language; English comes first as the language of the base fields
+var matcher = language.NewMatcher([]language.Tag{language.English, language.Arabic})
+
+// Parse negotiates the response language from an Accept-Language header (e.g. "ar-SA,ar;q=0.9,en;q=0.8").
+// It returns "" when the header is missing or names no supported language, for the caller to pick a default.
+func Parse(header string) string {
+	if strings.TrimSpace(header) == "" {
+		return ""
+	}
+
+	tags, _, err := language.ParseAcceptLanguage(header)
+	if err != nil || len(tags) == 0 {
+		return ""
+	}
+
+	_, index, confidence := matcher.Match(tags...)
+	if confidence == language.No {
+		return ""
+	}
+	if index == 1 {
+		return Arabic
+	}
+	return English
+}
+
+// Pick returns the variant of a text in the requested language, falling back to the base text when
+// that variant is not set
+func Pick(lang string, base string, arabic *string, english *string) string {
+	var variant *string
+	switch lang {
+	case Arabic:
+		variant = arabic
+	case English:
+		variant = english
+	}
+
+	if variant != nil && *variant != "" {
+		return *variant
+	}
+	return base
+}
@@ -0,0 +1,208 @@
+package locale
+
+import "strings"
+
+// arabicMessages translates the English API messages. Messages missing from the catalog are sent in English.
+var arabicMessages = map[string]string{
+	// Requests and identifiers
+	"Invalid request body":                                  "محتوى الطلب غير صالح",
+	"Failed to parse form":                                  "تعذرت قراءة النموذج",
+	"Invalid branch office ID":                              "معرف الفرع غير صالح",
+	"Invalid branch ID":                                     "معرف الفرع غير صالح",
+	"Invalid branch_id":                                     "قيمة branch_id غير صالحة",
+	"Invalid user ID":                                       "معرف المستخدم غير صالح",
+	"Invalid region ID":                                     "معرف المنطقة غير صالح",
+	"Invalid region_id":                                     "قيمة region_id غير صالحة",
+	"Invalid city ID":                                       "معرف المدينة غير صالح",
+	"Invalid counter ID":                                    "معرف الكاونتر غير صالح",
+	"Invalid counter assignment ID":                         "معرف المناوبة غير صالح",
+	"Invalid calendar exception ID":                         "معرف الاستثناء غير صالح",
+	"Invalid temporary closure ID":                          "معرف الإغلاق المؤقت غير صالح",
+	"Invalid token":                                         "رمز الدخول غير صالح",
+	"Failed to generate token":                              "تعذر إنشاء رمز الدخول",
+	"An internal error occurred":                            "حدث خطأ داخلي",
+	"The request timed out":                                 "انتهت مهلة الطلب",
+	"Too many requests, please retry later":                 "طلبات كثيرة جداً، يرجى المحاولة لاحقاً",
+	"Route not found":                                       "المسار غير موجود",
+	"File not found":                                        "الملف غير موجود",
+	"The file link is invalid or has expired":               "رابط الملف غير صالح أو منتهي الصلاحية",
+	"Missing required field":                                "حقل مطلوب مفقود",
+	"Data failed validation check":                          "البيانات لم تجتز التحقق",
+	"dry_run must be true or false":                         "يجب أن تكون قيمة dry_run إما true أو false",
+	"A .csv or .xlsx file is required":                      "يلزم إرفاق ملف ‎.csv أو ‎.xlsx",
+	"images must be a valid ZIP file":                       "يجب أن تكون الصور ملف ZIP صالحاً",
+	"at must be an RFC 3339 timestamp":                      "يجب أن تكون قيمة at طابعاً زمنياً بصيغة RFC 3339",
+	"starts_at must be an RFC 3339 timestamp":               "يجب أن تكون قيمة starts_at طابعاً زمنياً بصيغة RFC 3339",
+	"ends_at must be an RFC 3339 timestamp after starts_at": "يجب أن تكون قيمة ends_at طابعاً زمنياً بصيغة RFC 3339 بعد starts_at",
+	"lat must be a number between -90 and 90":               "يجب أن تكون قيمة lat رقماً بين ‎-90 و90",
+	"lng must be a number between -180 and 180":             "يجب أن تكون قيمة lng رقماً بين ‎-180 و180",
+	"radius_km must be a number between 0 and 20000":        "يجب أن تكون قيمة radius_km رقماً بين 0 و20000",
+	"limit must be a number between 1 and 100":              "يجب أن تكون قيمة limit رقماً بين 1 و100",
+
+	// Not found
+	"Branch office not found":                                 "الفرع غير موجود",
+	"branch office not found":                                 "الفرع غير موجود",
+	"User not found":                                          "المستخدم غير موجود",
+	"User not found!":                                         "المستخدم غير موجود",
+	"Region not found":                                        "المنطقة غير موجودة",
+	"Branch counter not found":                                "الكاونتر غير موجود",
+	"Branch counter not found or inactive":                    "الكاونتر غير موجود أو غير مفعل",
+	"Branch counter is inactive":                              "الكاونتر غير مفعل",
+	"Company profile not found":                               "ملف الشركة غير موجود",
+	"email not found":                                         "البريد الإلكتروني غير موجود",
+	"No officer is serving at this counter":                   "لا يوجد موظف يخدم على هذا الكاونتر",
+	"Officer not found in the counter's branch office":        "الموظف غير موجود في فرع هذا الكاونتر",
+	"Active counter not found in the officer's branch office": "لا يوجد كاونتر مفعل في فرع هذا الموظف",
+
+	// Failures
+	"Failed to retrieve dashboard data": "تعذر جلب بيانات لوحة المعلومات",
+	"Failed to update company profile":  "تعذر تحديث ملف الشركة",
+	"Failed to get company profile":     "تعذر جلب ملف الشركة",
+	"Failed to update branch office":    "تعذر تحديث الفرع",
+	"Error deleting branch counter":     "تعذر حذف الكاونتر",
+
+	// Database constraints
+	"Duplicate key: the email already exists":                              "البريد الإلكتروني مستخدم مسبقاً",
+	"Duplicate key: the name already exists":                               "الاسم مستخدم مسبقاً",
+	"Duplicate key: the record already exists":                             "السجل موجود مسبقاً",
+	"Foreign key violation, a referenced record does not exist":            "أحد السجلات المرتبطة غير موجود",
+	"Foreign key violation, Branch ID not found!":                          "الفرع المحدد غير موجود",
+	"Foreign key violation, City ID not found!":                            "المدينة المحددة غير موجودة",
+	"Foreign key violation, please check user_id or branch_id ":            "تحقق من قيمتي user_id وbranch_id",
+	"Foreign key violation, the region does not exist or still has cities": "المنطقة غير موجودة أو ما زالت تحتوي على مدن",
+
+	// Authentication
+	"invalid email":         "البريد الإلكتروني غير صحيح",
+	"invalid password":      "كلمة المرور غير صحيحة",
+	"invalid branch office": "الفرع غير صحيح",
+	"authorization failed: user is not authorized": "فشل التفويض: المستخدم غير مخول",
+
+	// Votes
+	"Invalid vote type, the type only 'like' & 'dislike'!": "نوع التصويت غير صالح، القيم المسموحة 'like' و'dislike' فقط",
+	"invalid vote type": "نوع التصويت غير صالح",
+	"the branch office is closed, votes outside operating hours are rejected": "الفرع مغلق، ولا تقبل التصويتات خارج ساعات العمل",
+
+	// Branch offices, counters and shifts
+	"counter_policy must be either 'reject' or 'deactivate'":                                                                              "يجب أن تكون قيمة counter_policy إما 'reject' أو 'deactivate'",
+	"the branch office has active counters numbered above the new total_counter, retry with counter_policy=deactivate to deactivate them": "يوجد في الفرع كاونترات مفعلة بأرقام أعلى من العدد الجديد، أعد المحاولة مع counter_policy=deactivate لإيقافها",
+	"all counters of this branch office are already in use":                                                                               "جميع كاونترات هذا الفرع مستخدمة",
+	"counter_number is already used in this branch office":                                                                                "رقم الكاونتر مستخدم في هذا الفرع",
+	"counter_number must be between 1 and the total_counter of the branch office":                                                         "يجب أن يكون رقم الكاونتر بين 1 وعدد كاونترات الفرع",
+	"branch office has an invalid timezone":                                                                                               "المنطقة الزمنية للفرع غير صالحة",
+	"a shift cannot be longer than 24 hours":                                                                                              "لا يمكن أن تتجاوز المناوبة 24 ساعة",
+	"date range cannot exceed one year":                                                                                                   "لا يمكن أن تتجاوز الفترة سنة واحدة",
+	"'from' date must not be after 'to' date":                                                                                             "يجب ألا يكون تاريخ البداية بعد تاريخ النهاية",
+	"invalid 'from' date, expected format YYYY-MM-DD":                                                                                     "تاريخ البداية غير صالح، الصيغة المطلوبة YYYY-MM-DD",
+	"invalid 'to' date, expected format YYYY-MM-DD":                                                                                       "تاريخ النهاية غير صالح، الصيغة المطلوبة YYYY-MM-DD",
+	"invalid 'week' date, expected format YYYY-MM-DD":                                                                                     "تاريخ الأسبوع غير صالح، الصيغة المطلوبة YYYY-MM-DD",
+
+	"The shift overlaps existing shifts of this officer or counter": "تتداخل المناوبة مع مناوبات أخرى لهذا الموظف أو الكاونتر",
+
+	// Archive and transfers
+	"reason is required to purge archived data":                             "السبب مطلوب لحذف البيانات المؤرشفة نهائياً",
+	"purged_by is required when no token is sent":                           "قيمة purged_by مطلوبة عند عدم إرسال رمز الدخول",
+	"only archived records can be purged, archive it first":                 "لا يمكن الحذف النهائي إلا للسجلات المؤرشفة، قم بأرشفته أولاً",
+	"the user's branch office is archived, restore the branch office first": "فرع المستخدم مؤرشف، قم باستعادة الفرع أولاً",
+	"Use POST /users/:id/transfer to move a user to another branch office":  "استخدم POST /users/:id/transfer لنقل المستخدم إلى فرع آخر",
+	"the user already belongs to this branch office":                        "المستخدم ينتمي إلى هذا الفرع بالفعل",
+	"only users working in a branch office can be transferred":              "لا يمكن نقل إلا المستخدمين العاملين في فرع",
+	"effective_at cannot be in the future":                                  "لا يمكن أن يكون تاريخ النفاذ في المستقبل",
+	"effective_at must be after the start of the current branch membership": "يجب أن يكون تاريخ النفاذ بعد بداية الانتماء الحالي للفرع",
+
+	// Validation
+	"address cannot be empty":                                             "العنوان مطلوب",
+	"branch office name cannot be empty":                                  "اسم الفرع مطلوب",
+	"region name cannot be empty":                                         "اسم المنطقة مطلوب",
+	"city name cannot be empty":                                           "اسم المدينة مطلوب",
+	"full name cannot be empty":                                           "الاسم الكامل مطلوب",
+	"full name must be at least 3 characters":                             "يجب ألا يقل الاسم الكامل عن 3 أحرف",
+	"full name cannot exceed 255 characters":                              "يجب ألا يتجاوز الاسم الكامل 255 حرفاً",
+	"full name can only contain letters, spaces and the characters ' . -": "يجب أن يحتوي الاسم الكامل على حروف ومسافات والرموز ' . - فقط",
+	"email cannot be empty":                                               "البريد الإلكتروني مطلوب",
+	"invalid email format":                                                "صيغة البريد الإلكتروني غير صحيحة",
+	"password cannot be empty":                                            "كلمة المرور مطلوبة",
+	"password must be at least 6 characters":                              "يجب ألا تقل كلمة المرور عن 6 أحرف",
+	"password must contain at least one digit":                            "يجب أن تحتوي كلمة المرور على رقم واحد على الأقل",
+	"password must contain at least one lowercase letter":                 "يجب أن تحتوي كلمة المرور على حرف صغير واحد على الأقل",
+	"password must contain at least one uppercase letter":                 "يجب أن تحتوي كلمة المرور على حرف كبير واحد على الأقل",
+	"password must contain at least one special character":                "يجب أن تحتوي كلمة المرور على رمز خاص واحد على الأقل",
+	"role must be either 'administrator' 'admin', 'supervisor', 'regional_manager', or 'officer'": "يجب أن يكون الدور 'administrator' أو 'admin' أو 'supervisor' أو 'regional_manager' أو 'officer'",
+	"region_id is required for a regional manager":                                                "قيمة region_id مطلوبة لمدير المنطقة",
+	"region_id must be a valid number":                                                            "يجب أن تكون قيمة region_id رقماً صالحاً",
+	"branch_id must be a valid number":                                                            "يجب أن تكون قيمة branch_id رقماً صالحاً",
+	"city_id must be a valid number":                                                              "يجب أن تكون قيمة city_id رقماً صالحاً",
+	"user_id must be a valid number":                                                              "يجب أن تكون قيمة user_id رقماً صالحاً",
+	"counter_id must be a valid number":                                                           "يجب أن تكون قيمة counter_id رقماً صالحاً",
+	"counter_location must be a non-empty string":                                                 "موقع الكاونتر مطلوب",
+	"counter_number must be a positive whole number":                                              "يجب أن يكون رقم الكاونتر عدداً صحيحاً موجباً",
+	"total counter must be greater than 0":                                                        "يجب أن يكون عدد الكاونترات أكبر من 0",
+	"total counter cannot exceed 100":                                                             "يجب ألا يتجاوز عدد الكاونترات 100",
+	"total counter must be a whole number":                                                        "يجب أن يكون عدد الكاونترات عدداً صحيحاً",
+	"timezone must be a string":                                                                   "يجب أن تكون المنطقة الزمنية نصاً",
+	"timezone must be a valid IANA timezone name":                                                 "يجب أن تكون المنطقة الزمنية اسماً صالحاً من قاعدة IANA",
+	"latitude and longitude must be given together":                                               "يجب إدخال خط العرض وخط الطول معاً",
+	"latitude must be a number between -90 and 90":                                                "يجب أن يكون خط العرض رقماً بين ‎-90 و90",
+	"longitude must be a number between -180 and 180":                                             "يجب أن يكون خط الطول رقماً بين ‎-180 و180",
+	"name is required":                                                                            "الاسم مطلوب",
+	"reason is required":                                                                          "السبب مطلوب",
+	"reason must be a string":                                                                     "يجب أن يكون السبب نصاً",
+	"reason cannot exceed 500 characters":                                                         "يجب ألا يتجاوز السبب 500 حرف",
+	"kind must be 'holiday' or 'special_hours'":                                                   "يجب أن يكون النوع 'holiday' أو 'special_hours'",
+	"a holiday cannot have opening hours":                                                         "لا يمكن أن تكون للعطلة ساعات عمل",
+	"ends_at must be after starts_at":                                                             "يجب أن يكون وقت النهاية بعد وقت البداية",
+	"ends_on cannot be before starts_on":                                                          "لا يمكن أن يكون تاريخ النهاية قبل تاريخ البداية",
+	"hours must be a list of opening intervals":                                                   "يجب أن تكون ساعات العمل قائمة من الفترات",
+	"out_of_hours_vote_policy must be 'accept', 'flag' or 'reject'":                               "يجب أن تكون سياسة التصويت خارج ساعات العمل 'accept' أو 'flag' أو 'reject'",
+	"effective_at must be an RFC 3339 timestamp":                                                  "يجب أن يكون تاريخ النفاذ طابعاً زمنياً بصيغة RFC 3339",
+
+	// Images
+	"the image must be a JPEG, PNG or WebP file": "يجب أن تكون الصورة بصيغة JPEG أو PNG أو WebP",
+	"the image is corrupted":                     "الصورة تالفة",
+
+	// Imports
+	"the file is empty":                     "الملف فارغ",
+	"the file must be a .csv or .xlsx file": "يجب أن يكون الملف بصيغة ‎.csv أو ‎.xlsx",
+	"the XLSX file has no sheet":            "ملف XLSX لا يحتوي على أي ورقة",
+}
+
+// arabicPrefixes translates messages that end with a variable part, such as an ID
+var arabicPrefixes = map[string]string{
+	"the image cannot exceed ":                            "لا يمكن أن يتجاوز حجم الصورة ",
+	"the request body cannot exceed ":                     "لا يمكن أن يتجاوز حجم الطلب ",
+	"no branch office found with the given ID: ":          "لا يوجد فرع بالمعرف: ",
+	"no archived branch office found with the given ID: ": "لا يوجد فرع مؤرشف بالمعرف: ",
+	"no user found with the given ID: ":                   "لا يوجد مستخدم بالمعرف: ",
+	"no archived user found with the given ID: ":          "لا يوجد مستخدم مؤرشف بالمعرف: ",
+	"no region found with the given ID: ":                 "لا توجد منطقة بالمعرف: ",
+	"no city found with the given ID: ":                   "لا توجد مدينة بالمعرف: ",
+	"no counter assignment found with the given ID: ":     "لا توجد مناوبة بالمعرف: ",
+	"no calendar exception found with the given ID: ":     "لا يوجد استثناء بالمعرف: ",
+	"no temporary closure found with the given ID: ":      "لا يوجد إغلاق مؤقت بالمعرف: ",
+}
+
+// Translate returns a message in the requested language. English and unknown languages keep the message.
+func Translate(lang string, message string) string {
+	if lang != Arabic {
+		return message
+	}
+
+	if translated, ok := arabicMessages[message]; ok {
+		return translated
+	}
+	for prefix, translated := range arabicPrefixes {
+		if strings.HasPrefix(message, prefix) {
+			return translated + strings.TrimPrefix(message, prefix)
+		}
+	}
+
+	return message
+}
+
+// TranslateAll translates each message of a list
+func TranslateAll(lang string, messages []string) []string {
+	translated := make([]string, len(messages))
+	for i, message := range messages {
+		translated[i] = Translate(lang, message)
+	}
+	return translated
+}
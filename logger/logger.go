@@ -0,0 +1,72 @@
+// Package logger sets up the structured logger of the API. Records logged with the context of a request
+// carry the ID of that request, so every line logged while serving it can be found from the access log
+// or from an error response.
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// requestIDKey is the context key of the request ID
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of the context carrying a request ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID carried by the context, "" when there is none
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// ParseLevel reads a log level: "debug", "info" (the default when empty), "warn" or "error"
+func ParseLevel(value string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return slog.LevelInfo, fmt.Errorf("unknown log level %q, expected debug, info, warn or error", value)
+}
+
+// New returns a logger writing JSON records at or above the given level
+func New(w io.Writer, level slog.Leveler) *slog.Logger {
+	return slog.New(contextHandler{slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level})})
+}
+
+// contextHandler adds the request ID carried by the context of each record
+type contextHandler struct {
+	slog.Handler
+}
+
+// Handle adds the request ID, if any, and writes the record
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+// WithAttrs keeps adding request IDs to the records of loggers made with With
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup keeps adding request IDs to the records of loggers made with WithGroup
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
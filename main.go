@@ -2,41 +2,159 @@ package main
 
 import (
 	"api-server/config"
+	"api-server/controllers"
+	"api-server/health"
+	"api-server/helpers"
+	"api-server/metrics"
 	"api-server/middlewares"
+	"api-server/migration"
+	"api-server/repository"
 	"api-server/routes"
+	"api-server/services"
+	"api-server/storage"
+	"context"
+	"errors"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
+	_ "time/tzdata" // Embed the timezone database so branch timezones resolve in minimal containers
 
-	"github.com/gin-contrib/cors" // Import the cors package
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
+	// Load and validate the configuration, refusing to start with an invalid one
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Log structured records at the configured level
+	config.InitLogger(cfg.LogLevel)
+
+	// Sign authentication tokens with the configured secret
+	helpers.SetJWTKey(cfg.JWT.Secret)
+
 	// Initialize the database
-	config.InitDatabase()
-
-	// Set up the router
-	r := gin.Default()
-
-	// Apply CORS middleware
-	r.Use(cors.New(cors.Config{
-		// AllowOrigins:     []string{"http://localhost:5173"},                   // Specify allowed origin
-		AllowOrigins:     []string{"*"},                                       // Specify allowed origin
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}, // Allow these HTTP methods
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept"},        // Allow specific headers
-		ExposeHeaders:    []string{"Content-Length"},                          // Expose headers if needed
-		AllowCredentials: true,                                                // Allow cookies/authentication headers
-		MaxAge:           12 * time.Hour,                                      // Cache preflight requests for 12 hours
-	}))
+	if err := config.InitDatabase(cfg.Database); err != nil {
+		fatal("Database connection failed", err)
+	}
+
+	// Refuse to serve requests against a schema the code was not built for
+	if err := migration.Verify(config.DB); err != nil {
+		fatal("Database schema check failed (run go run ./migration/create up)", err)
+	}
+
+	// Expose the connection pool statistics to Prometheus
+	metrics.RegisterDBStats(config.DB)
+
+	// Set up the router, giving every request an ID first so the access log and error responses can carry it
+	r := gin.New()
+	r.Use(middlewares.RequestID(), middlewares.AccessLog(), middlewares.Recovery())
+
+	// Apply the security headers and the CORS policies of the admin web app and the kiosks
+	r.Use(middlewares.SecurityHeaders(cfg.Security), middlewares.CORS(cfg.CORS))
+
+	// Record request metrics (registered before the error handler so the final status is observed)
+	r.Use(middlewares.Metrics())
+
+	// Negotiate the response language before the error handler so its messages are localized too; the kiosks
+	// default to Arabic
+	r.Use(middlewares.Language(cfg.CORS.Admin.AllowedOrigins))
 
 	// Apply the global error handler middleware
 	r.Use(middlewares.ErrorHandler())
 
-	// Serve static files from the "public/images" directory
-	r.Static("/images", "./public/images")
-	r.Static("/assets", "./public/assets")
+	// Reject request bodies above the upload limit
+	r.Use(middlewares.BodyLimit(cfg.Uploads.MaxRequestSize))
+
+	// Keep the uploaded profile images and company logos in the configured storage
+	storageCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	stores, err := storage.NewStores(storageCtx, cfg.Storage, cfg.Uploads)
+	cancel()
+	if err != nil {
+		fatal("Storage initialization failed", err)
+	}
+
+	// Wire the PostgreSQL repositories into the services and the HTTP handler
+	repos := repository.NewPostgresRepositories(config.DB, cfg.Database.StatementTimeout)
+	handler := controllers.NewHandler(services.NewServices(repos, cfg.Uploads, stores), cfg.Uploads, stores)
+
+	routes.SetupRoutes(r, handler)
 
-	routes.SetupRoutes(r)
+	// Probes of the load balancer
+	checker, err := health.NewChecker(config.DB, stores.Images, stores.Assets)
+	if err != nil {
+		fatal("Failed to load migrations", err)
+	}
+	routes.SetupHealthRoutes(r, checker)
+
+	server := &http.Server{
+		Addr:              cfg.Server.Addr(),
+		Handler:           r,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+
+	// Start the server until it fails or a stop signal arrives
+	stopped, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info("Listening", "addr", server.Addr, "tls", cfg.Server.TLSEnabled())
+		serveErr <- serve(server, cfg.Server)
+	}()
+
+	select {
+	case err := <-serveErr:
+		fatal("Server stopped", err)
+	case <-stopped.Done():
+		stop() // A second signal kills the process
+	}
+
+	shutdown(server, checker, cfg.Server)
+	config.DB.Close()
+}
+
+// serve accepts connections until the server is shut down
+func serve(server *http.Server, settings config.Server) error {
+	var err error
+	if settings.TLSEnabled() {
+		err = server.ListenAndServeTLS(settings.TLSCertFile, settings.TLSKeyFile)
+	} else {
+		err = server.ListenAndServe()
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// shutdown stops the server gracefully: /readyz fails first so the load balancer stops sending requests,
+// then the in-flight requests (e.g. votes) complete within the shutdown timeout before connections close
+func shutdown(server *http.Server, checker *health.Checker, settings config.Server) {
+	slog.Info("Shutting down", "delay", settings.ShutdownDelay.String(), "timeout", settings.ShutdownTimeout.String())
+	checker.Drain()
+	time.Sleep(settings.ShutdownDelay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), settings.ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		slog.Error("In-flight requests did not complete before the shutdown timeout", "error", err)
+		server.Close()
+		return
+	}
+	slog.Info("Server stopped")
+}
 
-	// Start the server
-	r.Run(":3000")
+// fatal logs an error that prevents serving and exits
+func fatal(message string, err error) {
+	slog.Error(message, "error", err)
+	os.Exit(1)
 }
@@ -0,0 +1,95 @@
+package metrics
+
+import (
+	"database/sql"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const namespace = "saudi_airline"
+
+var (
+	// HTTPRequestDuration tracks request latency per route, method and status code
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of HTTP requests by route, method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	// HTTPResponseSize tracks the size of the responses written per route
+	HTTPResponseSize = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "response_size_bytes",
+		Help:      "Size of HTTP responses by route and status code.",
+		Buckets:   prometheus.ExponentialBuckets(128, 4, 8),
+	}, []string{"route", "status"})
+
+	// VotesTotal counts the votes received per branch and vote type
+	VotesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "votes_total",
+		Help:      "Number of votes received by branch and vote type.",
+	}, []string{"branch_id", "vote_type"})
+
+	// LoginsTotal counts successful logins per channel (web or mobile)
+	LoginsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logins_total",
+		Help:      "Number of successful logins by channel.",
+	}, []string{"channel"})
+
+	// FailedLoginsTotal counts rejected logins per channel
+	FailedLoginsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "failed_logins_total",
+		Help:      "Number of failed logins by channel.",
+	}, []string{"channel"})
+
+	// UploadSizeBytes tracks the size of accepted uploads per kind (profile image, company logo, import files)
+	UploadSizeBytes = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "upload_size_bytes",
+		Help:      "Size of accepted uploaded files by kind.",
+		Buckets:   prometheus.ExponentialBuckets(16<<10, 2, 12),
+	}, []string{"kind"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		HTTPRequestDuration,
+		HTTPResponseSize,
+		VotesTotal,
+		LoginsTotal,
+		FailedLoginsTotal,
+		UploadSizeBytes,
+	)
+}
+
+// RegisterDBStats exposes the connection pool statistics of the given database
+func RegisterDBStats(db *sql.DB) {
+	prometheus.MustRegister(collectors.NewDBStatsCollector(db, "postgres"))
+}
+
+// RecordVote increments the vote counter for a branch
+func RecordVote(branchID uint, voteType string) {
+	VotesTotal.WithLabelValues(strconv.FormatUint(uint64(branchID), 10), voteType).Inc()
+}
+
+// RecordLogin increments the successful or failed login counter for a channel
+func RecordLogin(channel string, success bool) {
+	if success {
+		LoginsTotal.WithLabelValues(channel).Inc()
+		return
+	}
+	FailedLoginsTotal.WithLabelValues(channel).Inc()
+}
+
+// RecordUpload observes the size of an uploaded file, once it passed the size and type checks
+func RecordUpload(kind string, size int64) {
+	UploadSizeBytes.WithLabelValues(kind).Observe(float64(size))
+}
@@ -0,0 +1,72 @@
+package middlewares
+
+import (
+	"api-server/helpers"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UserIDKey is the gin context key of the ID of the user a request is made by or for, set by handlers
+// authenticating a user without a bearer token (e.g. login)
+const UserIDKey = "user_id"
+
+// probeRoutes are polled by the load balancer
+var probeRoutes = map[string]bool{"/healthz": true, "/readyz": true}
+
+// AccessLog is a middleware logging one JSON record per request, with its route, status, latency and user
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		status := c.Writer.Status()
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("route", c.FullPath()),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.Int("bytes", c.Writer.Size()),
+			slog.String("client_ip", c.ClientIP()),
+		}
+		if userID := requestUserID(c); userID != 0 {
+			attrs = append(attrs, slog.Uint64("user_id", uint64(userID)))
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case probeRoutes[c.FullPath()] && status < 400:
+			level = slog.LevelDebug // Successful probes would drown the other requests
+		case status >= 500 && !probeRoutes[c.FullPath()]:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+
+		slog.LogAttrs(c.Request.Context(), level, "request", attrs...)
+	}
+}
+
+// requestUserID returns the ID of the user making the request, 0 when unknown. Tokens issued before
+// they carried the user ID identify nobody.
+func requestUserID(c *gin.Context) uint {
+	if userID, ok := c.Get(UserIDKey); ok {
+		if id, ok := userID.(uint); ok {
+			return id
+		}
+	}
+
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if token == "" {
+		return 0
+	}
+	claims, err := helpers.ValidateJWT(token)
+	if err != nil || claims == nil {
+		return 0
+	}
+	return claims.UserID
+}
@@ -0,0 +1,29 @@
+package middlewares
+
+import (
+	"api-server/apperror"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BodyLimit is a middleware rejecting request bodies above a size. Bodies announcing a larger
+// Content-Length are rejected upfront, the others fail to read past the limit.
+func BodyLimit(maxBytes int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > maxBytes {
+			c.Error(RequestTooLarge(maxBytes)) // Pass error to the middleware
+			c.Abort()
+			return
+		}
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+		c.Next()
+	}
+}
+
+// RequestTooLarge reports a request body above the upload limit
+func RequestTooLarge(maxBytes int64) *apperror.Error {
+	return apperror.PayloadTooLarge(fmt.Sprintf("the request body cannot exceed %d MB", maxBytes>>20))
+}
@@ -0,0 +1,46 @@
+package middlewares
+
+import (
+	"api-server/config"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+// corsExposedHeaders are the response headers browsers let the apps read
+var corsExposedHeaders = []string{"Content-Length", "Content-Language", "X-Request-ID", "Retry-After"}
+
+// CORS is a middleware applying the admin web app policy to requests from its origins and the kiosk
+// policy to requests from any other origin. Origins allowed by neither are answered 403.
+func CORS(policies config.CORS) gin.HandlerFunc {
+	admin := cors.New(corsConfig(policies.Admin, policies))
+	kiosk := cors.New(corsConfig(policies.Kiosk, policies))
+
+	adminOrigins := map[string]bool{}
+	for _, origin := range policies.Admin.AllowedOrigins {
+		adminOrigins[origin] = true
+	}
+
+	return func(c *gin.Context) {
+		// The answer depends on the origin even when the policy allows any, so caches must key on it
+		c.Writer.Header().Add("Vary", "Origin")
+
+		if adminOrigins[c.GetHeader("Origin")] {
+			admin(c)
+			return
+		}
+		kiosk(c)
+	}
+}
+
+// corsConfig turns a policy into the configuration of the cors middleware
+func corsConfig(policy config.CORSPolicy, policies config.CORS) cors.Config {
+	return cors.Config{
+		AllowOrigins:     policy.AllowedOrigins,
+		AllowMethods:     policy.AllowedMethods,
+		AllowHeaders:     policy.AllowedHeaders,
+		ExposeHeaders:    corsExposedHeaders,
+		AllowCredentials: policy.AllowCredentials,
+		MaxAge:           policies.MaxAge,
+	}
+}
@@ -0,0 +1,77 @@
+package middlewares
+
+import (
+	"api-server/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCORSPolicyChoice(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	headers := []string{"Origin", "Content-Type", "Authorization"}
+	policies := config.CORS{
+		Admin: config.CORSPolicy{
+			AllowedOrigins:   []string{"https://admin.example.com"},
+			AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+			AllowedHeaders:   headers,
+			AllowCredentials: true,
+		},
+		MaxAge: 10 * time.Minute,
+	}
+
+	tests := []struct {
+		name        string
+		kiosk       []string
+		method      string
+		origin      string
+		preflight   string // Access-Control-Request-Method, for a preflight request
+		status      int
+		allowOrigin string
+		credentials string
+		methods     string // Access-Control-Allow-Methods of the preflight responses
+	}{
+		{"admin preflight", []string{"*"}, http.MethodOptions, "https://admin.example.com", "DELETE", http.StatusNoContent, "https://admin.example.com", "true", "GET,POST,PUT,DELETE,OPTIONS"},
+		{"admin request", []string{"*"}, http.MethodPut, "https://admin.example.com", "", http.StatusOK, "https://admin.example.com", "true", ""},
+		{"kiosk request", []string{"*"}, http.MethodPost, "https://kiosk.example.net", "", http.StatusOK, "*", "", ""},
+		// The browser refuses the DELETE, the kiosk methods not including it
+		{"kiosk preflight", []string{"*"}, http.MethodOptions, "https://kiosk.example.net", "DELETE", http.StatusNoContent, "*", "", "GET,POST,OPTIONS"},
+		{"origin allowed by neither policy", []string{"https://kiosk.example.net"}, http.MethodGet, "https://evil.example.org", "", http.StatusForbidden, "", "", ""},
+		{"listed kiosk origin", []string{"https://kiosk.example.net"}, http.MethodGet, "https://kiosk.example.net", "", http.StatusOK, "https://kiosk.example.net", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policies.Kiosk = config.CORSPolicy{AllowedOrigins: tt.kiosk, AllowedMethods: []string{"GET", "POST", "OPTIONS"}, AllowedHeaders: headers}
+			r := gin.New()
+			r.Use(CORS(policies))
+			r.Any("/feedback", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			req := httptest.NewRequest(tt.method, "/feedback", nil)
+			req.Header.Set("Origin", tt.origin)
+			if tt.preflight != "" {
+				req.Header.Set("Access-Control-Request-Method", tt.preflight)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.allowOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.allowOrigin)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Credentials"); got != tt.credentials {
+				t.Errorf("Access-Control-Allow-Credentials = %q, want %q", got, tt.credentials)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Methods"); got != tt.methods {
+				t.Errorf("Access-Control-Allow-Methods = %q, want %q", got, tt.methods)
+			}
+			if vary := w.Header().Values("Vary"); len(vary) == 0 || vary[0] != "Origin" {
+				t.Errorf("Vary = %v, want Origin", vary)
+			}
+		})
+	}
+}
@@ -1,104 +1,181 @@
 package middlewares
 
 import (
-	"log"
-	"net/http"
+	"api-server/apperror"
+	"api-server/locale"
+	"api-server/repository/validation"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"runtime/debug"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/lib/pq"
 )
 
+// statusClientClosedRequest is logged for requests whose client went away before the response (nginx convention)
+const statusClientClosedRequest = 499
+
+// ErrorBody describes an error in responses. The code is stable whatever the language of the message.
+type ErrorBody struct {
+	Code      apperror.Code          `json:"code"`
+	Message   string                 `json:"message"`
+	RequestID string                 `json:"request_id,omitempty"`
+	Fields    map[string][]string    `json:"fields,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
+
+// ErrorResponse is the body of every error response, e.g.
+// {"error": {"code": "not_found", "message": "Route not found", "request_id": "..."}}
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
 // ErrorHandler is a middleware that captures errors from all routes and handles them centrally
 func ErrorHandler() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Next() // Process the request
 
-		// Check if any errors occurred
+		// Check if any errors occurred, unless the handler already answered
 		errs := c.Errors
-		if len(errs) > 0 {
-			err := errs[0].Err
-
-			// Log the error for debugging
-			log.Printf("Encountered error: %v", err)
-
-			// Handle specific PostgreSQL errors
-			if pqErr, ok := err.(*pq.Error); ok {
-				switch pqErr.Table {
-				case "users":
-					handlePostgresErrorUser(c, pqErr)
-				case "branch_offices":
-					handlePostgresErrorBranchOffice(c, pqErr)
-				case "branch_counters":
-					handlePostgresErrorBranchCounter(c, pqErr)
-				}
-				return
-			}
+		if len(errs) == 0 || c.Writer.Written() {
+			return
+		}
+		err := errs[0].Err
 
-			// Handle generic errors
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "An internal error occurred",
-			})
+		// Queries cut off by the client disconnecting have nobody to answer to
+		if errors.Is(c.Request.Context().Err(), context.Canceled) &&
+			(errors.Is(err, context.Canceled) || isQueryCanceled(err)) {
+			slog.DebugContext(c.Request.Context(), "Request canceled by the client", "error", err)
+			c.AbortWithStatus(statusClientClosedRequest)
 			return
 		}
+
+		// Unexpected failures are logged as errors, client errors only help debugging
+		appErr := resolveError(err)
+		if appErr.Status() >= 500 {
+			slog.ErrorContext(c.Request.Context(), "Request failed", "code", appErr.Code, "error", err)
+		} else {
+			slog.DebugContext(c.Request.Context(), "Request rejected", "code", appErr.Code, "error", err)
+		}
+
+		RespondError(c, appErr)
 	}
 }
 
-// handlePostgresError handles PostgreSQL specific errors and sends appropriate responses
-func handlePostgresErrorUser(c *gin.Context, pqErr *pq.Error) {
-	log.Printf("PostgreSQL error: %v", pqErr)
+// Recovery is a middleware answering requests whose handler panicked with an internal error
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, recovered interface{}) {
+		slog.ErrorContext(c.Request.Context(), "Request panicked", "panic", fmt.Sprint(recovered), "stack", string(debug.Stack()))
+		RespondError(c, apperror.Internal(fmt.Errorf("panic: %v", recovered)))
+	})
+}
 
-	switch pqErr.Code.Name() {
-	case "unique_violation":
-		c.JSON(http.StatusConflict, gin.H{"error": "Duplicate key: the email already exists"})
-	case "foreign_key_violation":
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Foreign key violation, Branch ID not found!"})
-	case "not_null_violation":
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing required field"})
-	case "check_violation":
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Data failed validation check"})
-	default:
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Database error: " + pqErr.Message,
-		})
+// RespondError answers with the error in the language negotiated by the Language middleware
+func RespondError(c *gin.Context, err *apperror.Error) {
+	lang := c.GetString(locale.ContextKey)
+
+	body := ErrorBody{
+		Code:      err.Code,
+		Message:   locale.Translate(lang, err.Message),
+		RequestID: c.GetString(RequestIDKey),
+		Details:   err.Details,
+	}
+	if len(err.Fields) > 0 {
+		translated := validation.FieldErrors{}
+		for field, messages := range err.Fields {
+			translated[field] = locale.TranslateAll(lang, messages)
+		}
+		body.Fields = translated
+
+		// The message of field errors joins the field messages, so join the translated ones instead
+		if err.Message == validation.FieldErrors(err.Fields).Error() {
+			body.Message = translated.Error()
+		}
 	}
+
+	if err.RetryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(err.RetryAfter.Seconds()))))
+	}
+
+	c.AbortWithStatusJSON(err.Status(), ErrorResponse{Error: body})
 }
 
-// handlePostgresError handles PostgreSQL specific errors and sends appropriate responses
-func handlePostgresErrorBranchOffice(c *gin.Context, pqErr *pq.Error) {
-	log.Printf("PostgreSQL error: %v", pqErr)
+// resolveError returns the typed error to answer for an error, hiding the details of unexpected ones
+func resolveError(err error) *apperror.Error {
+	var appErr *apperror.Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
 
-	switch pqErr.Code.Name() {
-	case "unique_violation":
-		c.JSON(http.StatusConflict, gin.H{"error": "Duplicate key: the email already exists"})
-	case "foreign_key_violation":
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Foreign key violation"})
-	case "not_null_violation":
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing required field"})
-	case "check_violation":
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Data failed validation check"})
-	default:
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Database error: " + pqErr.Message,
-		})
+	// Queries cut off by the statement timeout
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) || isQueryCanceled(err) {
+		return apperror.Timeout(err)
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return postgresError(pqErr)
 	}
+
+	return apperror.Internal(err)
+}
+
+// isQueryCanceled tells whether PostgreSQL cancelled a statement, which happens when the context of a
+// running query is done before it completes
+func isQueryCanceled(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code.Name() == "query_canceled"
 }
 
-// handlePostgresError handles PostgreSQL specific errors and sends appropriate responses
-func handlePostgresErrorBranchCounter(c *gin.Context, pqErr *pq.Error) {
-	log.Printf("PostgreSQL error: %v", pqErr)
+// duplicateMessages describes the unique constraint of each table
+var duplicateMessages = map[string]string{
+	"users":           "Duplicate key: the email already exists",
+	"regions":         "Duplicate key: the name already exists",
+	"cities":          "Duplicate key: the name already exists",
+	"branch_counters": "counter_number is already used in this branch office",
+}
+
+// userForeignKeyMessages describes the foreign keys of the users table, by constraint
+var userForeignKeyMessages = map[string]string{
+	"users_branch_id_fkey": "Foreign key violation, Branch ID not found!",
+	"users_region_id_fkey": "Foreign key violation, Region ID not found!",
+}
 
+// postgresError maps PostgreSQL constraint violations to typed errors, per table
+func postgresError(pqErr *pq.Error) *apperror.Error {
 	switch pqErr.Code.Name() {
 	case "unique_violation":
-		c.JSON(http.StatusConflict, gin.H{"error": "Duplicate key: the email already exists"})
+		if message, ok := duplicateMessages[pqErr.Table]; ok {
+			return apperror.Conflict(message).WithCause(pqErr)
+		}
+		return apperror.Conflict("Duplicate key: the record already exists").WithCause(pqErr)
 	case "foreign_key_violation":
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Foreign key violation, please check user_id or branch_id "})
+		switch pqErr.Table {
+		case "users":
+			if message, ok := userForeignKeyMessages[pqErr.Constraint]; ok {
+				return apperror.BadRequest(message).WithCause(pqErr)
+			}
+		case "branch_offices":
+			return apperror.BadRequest("Foreign key violation, City ID not found!").WithCause(pqErr)
+		case "branch_counters":
+			return apperror.BadRequest("Foreign key violation, please check user_id or branch_id ").WithCause(pqErr)
+		case "regions", "cities":
+			return apperror.Conflict("Foreign key violation, the region does not exist or still has cities").WithCause(pqErr)
+		}
+		return apperror.BadRequest("Foreign key violation, a referenced record does not exist").WithCause(pqErr)
 	case "not_null_violation":
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing required field"})
+		if pqErr.Column != "" {
+			return apperror.InvalidField(pqErr.Column, "Missing required field").WithCause(pqErr)
+		}
+		return apperror.Validation("Missing required field", nil).WithCause(pqErr)
 	case "check_violation":
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Data failed validation check"})
-	default:
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Database error: " + pqErr.Message,
-		})
+		return apperror.Validation("Data failed validation check", nil).WithCause(pqErr)
 	}
+
+	return apperror.Internal(pqErr)
 }
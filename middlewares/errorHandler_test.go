@@ -0,0 +1,29 @@
+package middlewares
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestPostgresErrorForeignKeys(t *testing.T) {
+	tests := []struct {
+		table      string
+		constraint string
+		message    string
+	}{
+		{"users", "users_branch_id_fkey", "Foreign key violation, Branch ID not found!"},
+		{"users", "users_region_id_fkey", "Foreign key violation, Region ID not found!"},
+		{"users", "users_future_fkey", "Foreign key violation, a referenced record does not exist"},
+		{"branch_offices", "branch_offices_city_id_fkey", "Foreign key violation, City ID not found!"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.constraint, func(t *testing.T) {
+			err := postgresError(&pq.Error{Code: "23503", Table: tt.table, Constraint: tt.constraint})
+			if err.Message != tt.message || err.Status() != http.StatusBadRequest {
+				t.Errorf("postgresError() = %d %q, want %d %q", err.Status(), err.Message, http.StatusBadRequest, tt.message)
+			}
+		})
+	}
+}
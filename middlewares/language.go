@@ -0,0 +1,34 @@
+package middlewares
+
+import (
+	"api-server/locale"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Language negotiates the response language from the Accept-Language header and stores it in the
+// context under locale.ContextKey, for handlers to pick the *_ar or *_en variants of names and messages.
+// Without a supported language in the header, requests from the admin origins keep the base fields and
+// requests from anywhere else, the kiosks, get Arabic.
+func Language(adminOrigins []string) gin.HandlerFunc {
+	admin := map[string]bool{}
+	for _, origin := range adminOrigins {
+		admin[origin] = true
+	}
+
+	return func(c *gin.Context) {
+		lang := locale.Parse(c.GetHeader("Accept-Language"))
+		if lang == "" && !admin[c.GetHeader("Origin")] {
+			lang = locale.Arabic
+		}
+		c.Set(locale.ContextKey, lang)
+
+		// Responses differ by language and origin, shared caches must key on both (CORS adds Origin)
+		c.Writer.Header().Add("Vary", "Accept-Language")
+		if lang != "" {
+			c.Header("Content-Language", lang)
+		}
+
+		c.Next()
+	}
+}
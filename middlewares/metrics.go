@@ -0,0 +1,30 @@
+package middlewares
+
+import (
+	"api-server/metrics"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics is a middleware that records latency, status and response size for every request
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		// Use the route template (e.g. /users/:id) to keep label cardinality bounded
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+
+		metrics.HTTPRequestDuration.WithLabelValues(route, c.Request.Method, status).Observe(time.Since(start).Seconds())
+		if size := c.Writer.Size(); size > 0 {
+			metrics.HTTPResponseSize.WithLabelValues(route, status).Observe(float64(size))
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package middlewares
+
+import (
+	"api-server/metrics"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// histogramCount returns the number of observations of a histogram series
+func histogramCount(t *testing.T, histogram *prometheus.HistogramVec, labels ...string) uint64 {
+	t.Helper()
+	var metric dto.Metric
+	if err := histogram.WithLabelValues(labels...).(prometheus.Metric).Write(&metric); err != nil {
+		t.Fatalf("reading the series %v: %v", labels, err)
+	}
+	return metric.GetHistogram().GetSampleCount()
+}
+
+func TestMetricsLabels(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Metrics())
+	r.GET("/metrics-test/users/:id", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"id": c.Param("id")}) })
+	r.DELETE("/metrics-test/users/:id", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	r.POST("/metrics-test/feedback", func(c *gin.Context) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "outside the operating hours"})
+	})
+
+	type series struct{ route, method, status string }
+	requests := []struct {
+		method string
+		path   string
+		series series
+	}{
+		// The route template is the label, not the path, to keep the number of series bounded
+		{http.MethodGet, "/metrics-test/users/42", series{"/metrics-test/users/:id", "GET", "200"}},
+		{http.MethodGet, "/metrics-test/users/1337", series{"/metrics-test/users/:id", "GET", "200"}},
+		{http.MethodDelete, "/metrics-test/users/42", series{"/metrics-test/users/:id", "DELETE", "204"}},
+		{http.MethodPost, "/metrics-test/feedback", series{"/metrics-test/feedback", "POST", "503"}},
+		{http.MethodGet, "/metrics-test/unknown/9", series{"unmatched", "GET", "404"}},
+	}
+
+	before := map[series]uint64{}
+	for _, request := range requests {
+		before[request.series] = histogramCount(t, metrics.HTTPRequestDuration, request.series.route, request.series.method, request.series.status)
+	}
+	sizesBefore := histogramCount(t, metrics.HTTPResponseSize, "/metrics-test/users/:id", "204")
+
+	for _, request := range requests {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(request.method, request.path, nil))
+	}
+
+	want := map[series]uint64{}
+	for _, request := range requests {
+		want[request.series]++
+	}
+	for s, count := range want {
+		if got := histogramCount(t, metrics.HTTPRequestDuration, s.route, s.method, s.status) - before[s]; got != count {
+			t.Errorf("%s %s %s observed %d times, want %d", s.method, s.route, s.status, got, count)
+		}
+	}
+
+	// An empty response has no size to record
+	if got := histogramCount(t, metrics.HTTPResponseSize, "/metrics-test/users/:id", "204"); got != sizesBefore {
+		t.Errorf("the size of a 204 response was recorded")
+	}
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	votes := testutil.ToFloat64(metrics.VotesTotal.WithLabelValues("907", "dislike"))
+	failedLogins := testutil.ToFloat64(metrics.FailedLoginsTotal.WithLabelValues("mobile"))
+
+	metrics.RecordVote(907, "dislike")
+	metrics.RecordVote(907, "dislike")
+	metrics.RecordLogin("mobile", false)
+	metrics.RecordUpload("company_logo", 48<<10)
+
+	if got := testutil.ToFloat64(metrics.VotesTotal.WithLabelValues("907", "dislike")) - votes; got != 2 {
+		t.Errorf("votes of branch 907 increased by %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(metrics.FailedLoginsTotal.WithLabelValues("mobile")) - failedLogins; got != 1 {
+		t.Errorf("failed mobile logins increased by %v, want 1", got)
+	}
+
+	r := gin.New()
+	r.Use(Metrics())
+	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	// The first scrape is recorded once answered, the second one exposes it
+	var w *httptest.ResponseRecorder
+	for i := 0; i < 2; i++ {
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET /metrics = %d", w.Code)
+		}
+	}
+	for _, line := range []string{
+		`saudi_airline_votes_total{branch_id="907",vote_type="dislike"}`,
+		`saudi_airline_failed_logins_total{channel="mobile"}`,
+		`saudi_airline_upload_size_bytes_bucket{kind="company_logo",le="65536"}`,
+		`saudi_airline_http_request_duration_seconds_count{method="GET",route="/metrics",status="200"}`,
+	} {
+		if !strings.Contains(w.Body.String(), line) {
+			t.Errorf("GET /metrics does not expose %s", line)
+		}
+	}
+}
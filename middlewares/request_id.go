@@ -0,0 +1,59 @@
+package middlewares
+
+import (
+	"api-server/logger"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader carries the ID of a request, kept from the client or the proxy when given
+const RequestIDHeader = "X-Request-ID"
+
+// RequestIDKey is the gin context key of the request ID
+const RequestIDKey = "request_id"
+
+// maxRequestIDLength bounds the IDs accepted from clients so they cannot flood the logs
+const maxRequestIDLength = 128
+
+// RequestID is a middleware giving every request an ID, answered in the X-Request-ID header and in
+// error responses so a client report can be matched with the server logs. The request context carries
+// the ID too, so the records logged with it down to the repositories are tagged with it.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		c.Set(RequestIDKey, id)
+		c.Header(RequestIDHeader, id)
+		c.Request = c.Request.WithContext(logger.WithRequestID(c.Request.Context(), id))
+
+		c.Next()
+	}
+}
+
+// validRequestID tells whether an ID given by the client is safe to log and echo back
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID generates a random 128-bit ID
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
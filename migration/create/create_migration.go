@@ -1,31 +1,28 @@
 package main
 
 import (
+	"api-server/config"
+	"api-server/migration"
 	"database/sql"
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"time"
 
-	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
 
 func main() {
-	// Load environment variables from .env file
-	err := godotenv.Load(".env")
+	// Load the database settings from the environment, .env or CONFIG_FILE
+	database, err := config.LoadDatabase()
 	if err != nil {
-		log.Fatalf("Error loading .env file: %v", err)
+		log.Fatal(err)
 	}
-
-	// Read environment variables
-	dbUser := os.Getenv("DB_USER")
-	dbPassword := os.Getenv("DB_PASSWORD")
-	dbName := os.Getenv("DB_NAME")
-	dbSslMode := os.Getenv("DB_SSLMODE")
-	dbHost := os.Getenv("DB_HOST") // Host of the PostgreSQL server
+	dbName := database.Name
 
 	// Connect to default PostgreSQL database
-	connStr := fmt.Sprintf("user=%s password=%s dbname=postgres sslmode=%s host=%s", dbUser, dbPassword, dbSslMode, dbHost)
+	connStr := database.MaintenanceDSN()
 	db, err := sql.Open("postgres", connStr)
 	if err != nil {
 		log.Fatal(err)
@@ -41,145 +38,105 @@ func main() {
 	}
 
 	// Connect to the new database
-	connStr = fmt.Sprintf("user=%s password=%s dbname=%s sslmode=%s host=%s", dbUser, dbPassword, dbName, dbSslMode, dbHost)
+	connStr = database.MigrationDSN()
 	db, err = sql.Open("postgres", connStr)
 	if err != nil {
 		log.Fatal(err)
 	}
 	defer db.Close()
 
-	// Define the SQL migration script
-	migrationSQL := `
-	-- Create branch_offices table
-	CREATE TABLE IF NOT EXISTS branch_offices (
-		id SERIAL PRIMARY KEY,
-		name VARCHAR(255) NOT NULL,
-		address TEXT,
-		total_counter INT DEFAULT 0,
-		createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);
-
-	-- Create users table
-	CREATE TABLE IF NOT EXISTS users (
-		id SERIAL PRIMARY KEY,
-		full_name VARCHAR(255) NOT NULL,
-		email VARCHAR(255) NOT NULL UNIQUE,
-		password VARCHAR(255) NOT NULL,
-		image VARCHAR(255),
-		role VARCHAR(50),
-		likes INT DEFAULT 0,
-		dislikes INT DEFAULT 0,
-		branch_id INT,
-		FOREIGN KEY (branch_id) REFERENCES branch_offices(id) ON DELETE CASCADE ON UPDATE CASCADE,
-		createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);
-
-	-- Add index on role column
-	CREATE INDEX idx_users_role ON users (role);
-
-	-- Create branch_counters table
-	CREATE TABLE IF NOT EXISTS branch_counters (
-		id SERIAL PRIMARY KEY,
-		counter_location VARCHAR(255) NOT NULL,
-		user_id INT NOT NULL,
-		branch_id INT NOT NULL,
-		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE ON UPDATE CASCADE,
-		FOREIGN KEY (branch_id) REFERENCES branch_offices(id) ON DELETE CASCADE ON UPDATE CASCADE,
-		createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);
-
-	-- Create company_profiles table
-	CREATE TABLE IF NOT EXISTS company_profiles (
-		id SERIAL PRIMARY KEY,
-		name VARCHAR(255) NOT NULL,
-		logo TEXT,
-		createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);
-
-	-- Create user_feedback_history table
-	CREATE TABLE IF NOT EXISTS user_feedback_history (
-		id SERIAL PRIMARY KEY,
-		likes INT DEFAULT 0,
-		dislikes INT DEFAULT 0,
-		officer_name VARCHAR(255) NOT NULL,
-		user_id INT NOT NULL,
-		branch_id INT NOT NULL,
-		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE ON UPDATE CASCADE,
-		FOREIGN KEY (branch_id) REFERENCES branch_offices(id) ON DELETE CASCADE ON UPDATE CASCADE,
-		createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);
-
-	-- Create total_data table
-	CREATE TABLE IF NOT EXISTS total_data (
-		id SERIAL PRIMARY KEY,
-		total_likes INT DEFAULT 0,
-		total_dislikes INT DEFAULT 0,
-		total_officer INT DEFAULT 0,
-		total_voted INT DEFAULT 0,
-		createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);
-
-	-- Create total_data_branch table
-	CREATE TABLE IF NOT EXISTS total_data_branch (
-		id SERIAL PRIMARY KEY,
-		name_office VARCHAR(255) NOT NULL,
-		total_likes INT DEFAULT 0,
-		total_dislikes INT DEFAULT 0,
-		branch_id INT NOT NULL,
-		FOREIGN KEY (branch_id) REFERENCES branch_offices(id) ON DELETE CASCADE ON UPDATE CASCADE,
-		createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);
-
-	-- Create function to automatically update 'updatedAt' timestamp
-	CREATE OR REPLACE FUNCTION update_timestamp_column()
-	RETURNS TRIGGER AS $$ 
-	BEGIN 
-		NEW.updatedAt = NOW(); 
-		RETURN NEW; 
-	END; 
-	$$ LANGUAGE plpgsql;
-
-	-- Create triggers to update 'updatedAt' on row update for all tables
-	CREATE TRIGGER update_users_updatedAt
-	BEFORE UPDATE ON users
-	FOR EACH ROW
-	EXECUTE FUNCTION update_timestamp_column();
-
-	CREATE TRIGGER update_branch_offices_updatedAt
-	BEFORE UPDATE ON branch_offices
-	FOR EACH ROW
-	EXECUTE FUNCTION update_timestamp_column();
-
-	CREATE TRIGGER update_branch_counters_updatedAt
-	BEFORE UPDATE ON branch_counters
-	FOR EACH ROW
-	EXECUTE FUNCTION update_timestamp_column();
-
-	CREATE TRIGGER update_company_profiles_updatedAt
-	BEFORE UPDATE ON company_profiles
-	FOR EACH ROW
-	EXECUTE FUNCTION update_timestamp_column();
-
-	CREATE TRIGGER update_user_feedback_history_updatedAt
-	BEFORE UPDATE ON user_feedback_history
-	FOR EACH ROW
-	EXECUTE FUNCTION update_timestamp_column();
-`
-
-	// Execute the migration script
-	_, err = db.Exec(migrationSQL)
+	runner, err := migration.NewRunner(db)
 	if err != nil {
-		log.Fatalf("Failed to execute migration: %v\n", err)
+		log.Fatalf("Failed to load migrations: %v\n", err)
+	}
+
+	if err := runCommand(runner, os.Args[1:]); err != nil {
+		log.Fatalf("Migration failed: %v\n", err)
+	}
+}
+
+// runCommand runs a migration command, "up" when none is given:
+//
+//	up                  apply every pending migration
+//	down [steps]        revert the last applied migration, or the last steps ones
+//	goto <version>      migrate up or down to a version (0 reverts everything)
+//	status              list the migrations and when they were applied
+//	baseline <version>  mark the migrations up to a version as applied, for databases created before versioning
+//
+// Databases created by the former version of this tool have the schema of version 1: "baseline 1" records
+// it, after which "up" applies the later migrations (running "up" directly works too).
+func runCommand(runner *migration.Runner, args []string) error {
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
 	}
 
-	fmt.Println("Migration executed successfully!")
+	switch command {
+	case "up":
+		if err := runner.Up(); err != nil {
+			return err
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			parsed, err := strconv.Atoi(args[1])
+			if err != nil || parsed < 1 {
+				return fmt.Errorf("down expects a positive number of steps, got %q", args[1])
+			}
+			steps = parsed
+		}
+		if err := runner.Down(steps); err != nil {
+			return err
+		}
+	case "goto", "baseline":
+		if len(args) < 2 {
+			return fmt.Errorf("%s expects a version", command)
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil || version < 0 {
+			return fmt.Errorf("%s expects a version number, got %q", command, args[1])
+		}
+		if command == "goto" {
+			err = runner.Goto(version)
+		} else {
+			err = runner.Baseline(version)
+		}
+		if err != nil {
+			return err
+		}
+	case "status":
+		return printStatus(runner)
+	default:
+		return fmt.Errorf("unknown command %q, expected up, down, goto, status or baseline", command)
+	}
+
+	version, err := runner.Version()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Migration executed successfully! The schema is at version %d\n", version)
+	return nil
+}
+
+// printStatus prints every migration with the time it was applied
+func printStatus(runner *migration.Runner) error {
+	statuses, err := runner.Status()
+	if err != nil {
+		return err
+	}
+
+	for _, status := range statuses {
+		state := "pending"
+		if status.AppliedAt != nil {
+			state = "applied " + status.AppliedAt.Format(time.RFC3339)
+		}
+		if status.Modified {
+			state += " (modified since it was applied)"
+		}
+		fmt.Printf("%04d_%s: %s\n", status.Version, status.Name, state)
+	}
+
+	return nil
 }
 
 // databaseExists checks if the database exists
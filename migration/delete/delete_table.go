@@ -1,31 +1,24 @@
 package main
 
 import (
+	"api-server/config"
 	"database/sql"
 	"fmt"
 	"log"
-	"os"
 
-	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
 
 func main() {
-	// Load environment variables from .env file
-	err := godotenv.Load(".env")
+	// Load the database settings from the environment, .env or CONFIG_FILE
+	database, err := config.LoadDatabase()
 	if err != nil {
-		log.Fatalf("Error loading .env file: %v", err)
+		log.Fatal(err)
 	}
-
-	// Read environment variables
-	dbUser := os.Getenv("DB_USER")
-	dbPassword := os.Getenv("DB_PASSWORD")
-	dbName := os.Getenv("DB_NAME")
-	dbSslMode := os.Getenv("DB_SSLMODE")
-	dbHost := os.Getenv("DB_HOST") // Host of the PostgreSQL server
+	dbName := database.Name
 
 	// Connect to default PostgreSQL database
-	connStr := fmt.Sprintf("user=%s password=%s dbname=postgres sslmode=%s host=%s", dbUser, dbPassword, dbSslMode, dbHost)
+	connStr := database.MaintenanceDSN()
 	db, err := sql.Open("postgres", connStr)
 	if err != nil {
 		log.Fatal(err)
@@ -0,0 +1,424 @@
+// Package migration applies the versioned SQL migrations embedded from migration/sql and records them in
+// the schema_migrations table. Files are named <version>_<name>.up.sql and <version>_<name>.down.sql.
+package migration
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// fileNamePattern matches the name of a migration file, e.g. 0002_add_branch_coordinates.up.sql
+var fileNamePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// lockID is the advisory lock held while migrating, so two runners never apply the same migration
+const lockID = 726354001
+
+// Errors reported when the schema does not match the embedded migrations
+var (
+	ErrSchemaOutdated   = errors.New("the database schema is not at the expected version, run the migrations first")
+	ErrChecksumMismatch = errors.New("an applied migration was changed after it ran")
+	ErrUnknownVersion   = errors.New("unknown migration version")
+)
+
+// Migration is one schema change with the SQL to apply and to revert it
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string // SHA-256 of the up SQL
+}
+
+// Status tells whether a migration is applied
+type Status struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+	Modified  bool // The applied checksum differs from the embedded file
+}
+
+// Load reads the embedded migrations ordered by version
+func Load() ([]Migration, error) {
+	entries, err := fs.ReadDir(files, "sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name: %s", entry.Name())
+		}
+
+		version, _ := strconv.Atoi(match[1])
+		content, err := files.ReadFile(path.Join("sql", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has files with different names", version)
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(content)
+			sum := sha256.Sum256(content)
+			migration.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := []Migration{}
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d needs both an up and a down file", migration.Version)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// LatestVersion returns the version the embedded migrations bring the schema to
+func LatestVersion(migrations []Migration) int {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+// Runner applies and reverts migrations on a database
+type Runner struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// NewRunner loads the embedded migrations and creates the schema_migrations table when missing
+func NewRunner(db *sql.DB) (*Runner, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			checksum CHAR(64) NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)`)
+	if err != nil {
+		return nil, fmt.Errorf("error creating schema_migrations: %w", err)
+	}
+
+	return &Runner{db: db, migrations: migrations}, nil
+}
+
+// applied returns the checksum and application time of every applied migration by version
+func (r *Runner) applied() (map[int]string, map[int]time.Time, error) {
+	rows, err := r.db.Query("SELECT version, checksum, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	checksums := map[int]string{}
+	appliedAt := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var checksum string
+		var at time.Time
+		if err := rows.Scan(&version, &checksum, &at); err != nil {
+			return nil, nil, err
+		}
+		checksums[version] = checksum
+		appliedAt[version] = at
+	}
+
+	return checksums, appliedAt, rows.Err()
+}
+
+// Version returns the highest applied version, 0 for an empty database
+func (r *Runner) Version() (int, error) {
+	var version int
+	err := r.db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version)
+	return version, err
+}
+
+// CurrentVersion returns the highest applied version of a database without loading the migrations, for
+// health checks; 0 when the database has no schema_migrations table
+func CurrentVersion(ctx context.Context, db *sql.DB) (int, error) {
+	var exists bool
+	if err := db.QueryRowContext(ctx, "SELECT to_regclass('schema_migrations') IS NOT NULL").Scan(&exists); err != nil || !exists {
+		return 0, err
+	}
+
+	var version int
+	err := db.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version)
+	return version, err
+}
+
+// Status lists every embedded migration with the time it was applied
+func (r *Runner) Status() ([]Status, error) {
+	checksums, appliedAt, err := r.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := []Status{}
+	for _, migration := range r.migrations {
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if at, ok := appliedAt[migration.Version]; ok {
+			status.AppliedAt = &at
+			status.Modified = checksums[migration.Version] != migration.Checksum
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// verifyChecksums fails when an applied migration no longer matches its embedded file
+func (r *Runner) verifyChecksums(checksums map[int]string) error {
+	for _, migration := range r.migrations {
+		if checksum, ok := checksums[migration.Version]; ok && checksum != migration.Checksum {
+			return fmt.Errorf("%w: %d_%s", ErrChecksumMismatch, migration.Version, migration.Name)
+		}
+	}
+	return nil
+}
+
+// Up applies every pending migration
+func (r *Runner) Up() error {
+	return r.Goto(LatestVersion(r.migrations))
+}
+
+// Down reverts the last steps applied migrations
+func (r *Runner) Down(steps int) error {
+	checksums, _, err := r.applied()
+	if err != nil {
+		return err
+	}
+
+	versions := []int{}
+	for version := range checksums {
+		versions = append(versions, version)
+	}
+	sort.Ints(versions)
+
+	target := 0
+	if steps < len(versions) {
+		target = versions[len(versions)-1-steps]
+	}
+
+	return r.Goto(target)
+}
+
+// Goto migrates up or down to the given version, 0 reverting every migration. Each migration runs in
+// its own transaction together with its schema_migrations row.
+func (r *Runner) Goto(target int) error {
+	if target != 0 && r.find(target) == nil {
+		return fmt.Errorf("%w: %d", ErrUnknownVersion, target)
+	}
+
+	conn, err := r.lock()
+	if err != nil {
+		return err
+	}
+	defer r.unlock(conn)
+
+	checksums, _, err := r.applied()
+	if err != nil {
+		return err
+	}
+	if err := r.verifyChecksums(checksums); err != nil {
+		return err
+	}
+
+	// Apply the pending migrations up to the target, oldest first
+	for _, migration := range r.migrations {
+		if migration.Version > target {
+			break
+		}
+		if _, ok := checksums[migration.Version]; ok {
+			continue
+		}
+
+		slog.Info("Applying migration", "version", migration.Version, "name", migration.Name)
+		err := r.run(migration.Up, "INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)",
+			migration.Version, migration.Name, migration.Checksum)
+		if err != nil {
+			return fmt.Errorf("migration %d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+	}
+
+	// Revert the applied migrations above the target, newest first
+	for i := len(r.migrations) - 1; i >= 0; i-- {
+		migration := r.migrations[i]
+		if migration.Version <= target {
+			break
+		}
+		if _, ok := checksums[migration.Version]; !ok {
+			continue
+		}
+
+		slog.Info("Reverting migration", "version", migration.Version, "name", migration.Name)
+		err := r.run(migration.Down, "DELETE FROM schema_migrations WHERE version = $1", migration.Version)
+		if err != nil {
+			return fmt.Errorf("reverting migration %d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Baseline records the migrations up to the given version as applied without running them, for
+// databases created before the migrations were versioned. It holds the migration lock like Goto, so a
+// runner starting meanwhile cannot apply the migrations being recorded.
+func (r *Runner) Baseline(target int) error {
+	if r.find(target) == nil {
+		return fmt.Errorf("%w: %d", ErrUnknownVersion, target)
+	}
+
+	conn, err := r.lock()
+	if err != nil {
+		return err
+	}
+	defer r.unlock(conn)
+
+	checksums, _, err := r.applied()
+	if err != nil {
+		return err
+	}
+	if err := r.verifyChecksums(checksums); err != nil {
+		return err
+	}
+
+	for _, migration := range r.migrations {
+		if migration.Version > target {
+			break
+		}
+		_, err := r.db.Exec("INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3) ON CONFLICT (version) DO NOTHING",
+			migration.Version, migration.Name, migration.Checksum)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Check fails unless every embedded migration is applied unchanged, so the server never runs against
+// a schema it was not built for
+func (r *Runner) Check() error {
+	checksums, _, err := r.applied()
+	if err != nil {
+		return err
+	}
+	if err := r.verifyChecksums(checksums); err != nil {
+		return err
+	}
+
+	current, err := r.Version()
+	if err != nil {
+		return err
+	}
+	if expected := LatestVersion(r.migrations); current != expected || len(checksums) != len(r.migrations) {
+		return fmt.Errorf("%w: at version %d, expected %d", ErrSchemaOutdated, current, expected)
+	}
+
+	return nil
+}
+
+// Verify checks the schema of a database is at the version of the embedded migrations without changing
+// anything, for the server to refuse to start against an outdated or modified schema
+func Verify(db *sql.DB) error {
+	migrations, err := Load()
+	if err != nil {
+		return err
+	}
+
+	var exists bool
+	if err := db.QueryRow("SELECT to_regclass('schema_migrations') IS NOT NULL").Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("%w: at version 0, expected %d", ErrSchemaOutdated, LatestVersion(migrations))
+	}
+
+	runner := &Runner{db: db, migrations: migrations}
+	return runner.Check()
+}
+
+// find returns the migration of a version, nil when there is none
+func (r *Runner) find(version int) *Migration {
+	for i := range r.migrations {
+		if r.migrations[i].Version == version {
+			return &r.migrations[i]
+		}
+	}
+	return nil
+}
+
+// run executes a migration script and its bookkeeping statement in one transaction
+func (r *Runner) run(script string, bookkeeping string, args ...interface{}) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Rollback in case of an error
+
+	if _, err = tx.Exec(script); err != nil {
+		return err
+	}
+	if _, err = tx.Exec(bookkeeping, args...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// lock takes the migration advisory lock on a dedicated connection
+func (r *Runner) lock() (*sql.Conn, error) {
+	conn, err := r.db.Conn(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_lock($1)", lockID); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("error taking the migration lock: %w", err)
+	}
+
+	return conn, nil
+}
+
+// unlock releases the migration advisory lock and its connection
+func (r *Runner) unlock(conn *sql.Conn) {
+	if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockID); err != nil {
+		slog.Error("Error releasing the migration lock", "error", err)
+	}
+	conn.Close()
+}
@@ -0,0 +1,327 @@
+package migration
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeDatabase stands in for PostgreSQL: it keeps the schema_migrations rows and logs the migration
+// scripts and lock calls in the order they run
+type fakeDatabase struct {
+	mu         sync.Mutex
+	migrations []Migration
+	rows       map[int]string // Checksum by version
+	log        []string
+	locked     bool
+}
+
+// record logs a statement, naming migration scripts by version and direction
+func (d *fakeDatabase) record(query string) {
+	for _, migration := range d.migrations {
+		switch query {
+		case migration.Up:
+			d.log = append(d.log, fmt.Sprintf("up %d", migration.Version))
+			return
+		case migration.Down:
+			d.log = append(d.log, fmt.Sprintf("down %d", migration.Version))
+			return
+		}
+	}
+	d.log = append(d.log, "unexpected: "+query)
+}
+
+func (d *fakeDatabase) exec(query string, args []driver.NamedValue) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	switch statement := strings.Join(strings.Fields(query), " "); {
+	case strings.HasPrefix(statement, "CREATE TABLE IF NOT EXISTS schema_migrations"):
+	case statement == "SELECT pg_advisory_lock($1)":
+		d.locked = true
+		d.log = append(d.log, "lock")
+	case statement == "SELECT pg_advisory_unlock($1)":
+		d.locked = false
+		d.log = append(d.log, "unlock")
+	case strings.HasPrefix(statement, "INSERT INTO schema_migrations"):
+		version := int(args[0].Value.(int64))
+		if !d.locked {
+			d.log = append(d.log, fmt.Sprintf("unlocked insert %d", version))
+		}
+		if _, ok := d.rows[version]; ok {
+			if strings.Contains(statement, "ON CONFLICT (version) DO NOTHING") {
+				return nil
+			}
+			return fmt.Errorf("duplicate version %d", version)
+		}
+		d.rows[version] = args[2].Value.(string)
+	case statement == "DELETE FROM schema_migrations WHERE version = $1":
+		delete(d.rows, int(args[0].Value.(int64)))
+	default:
+		d.record(query)
+	}
+	return nil
+}
+
+func (d *fakeDatabase) query(query string) (driver.Rows, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	switch query {
+	case "SELECT version, checksum, applied_at FROM schema_migrations":
+		rows := &fakeRows{columns: []string{"version", "checksum", "applied_at"}}
+		for version, checksum := range d.rows {
+			rows.values = append(rows.values, []driver.Value{int64(version), checksum, time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)})
+		}
+		return rows, nil
+	case "SELECT COALESCE(MAX(version), 0) FROM schema_migrations":
+		highest := 0
+		for version := range d.rows {
+			highest = max(highest, version)
+		}
+		return &fakeRows{columns: []string{"version"}, values: [][]driver.Value{{int64(highest)}}}, nil
+	}
+	return nil, fmt.Errorf("unexpected query: %s", query)
+}
+
+type fakeConnector struct{ database *fakeDatabase }
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) { return fakeConn(c), nil }
+func (c fakeConnector) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct{ database *fakeDatabase }
+
+func (c fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c fakeConn) Close() error                        { return nil }
+func (c fakeConn) Begin() (driver.Tx, error)           { return fakeTx{}, nil }
+
+func (c fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return driver.RowsAffected(1), c.database.exec(query, args)
+}
+
+func (c fakeConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	return c.database.query(query)
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+// newFakeRunner creates a runner on a fake database where the given versions are already applied
+func newFakeRunner(t *testing.T, applied ...int) (*Runner, *fakeDatabase) {
+	t.Helper()
+	migrations, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	database := &fakeDatabase{migrations: migrations, rows: map[int]string{}}
+	for _, version := range applied {
+		database.rows[version] = migrations[version-1].Checksum
+	}
+
+	db := sql.OpenDB(fakeConnector{database})
+	t.Cleanup(func() { db.Close() })
+	runner, err := NewRunner(db)
+	if err != nil {
+		t.Fatalf("NewRunner() error = %v", err)
+	}
+	return runner, database
+}
+
+// takeLog returns the statements logged since the last call
+func (d *fakeDatabase) takeLog() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	log := d.log
+	d.log = nil
+	return log
+}
+
+// steps returns the expected log of applying or reverting a range of versions under the lock
+func steps(direction string, from int, to int) []string {
+	log := []string{"lock"}
+	for version := from; ; {
+		log = append(log, fmt.Sprintf("%s %d", direction, version))
+		if version == to {
+			break
+		}
+		if from < to {
+			version++
+		} else {
+			version--
+		}
+	}
+	return append(log, "unlock")
+}
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			t.Fatalf("migration %d has version %d, want the versions to follow each other from 1", i, migration.Version)
+		}
+		content, err := files.ReadFile(fmt.Sprintf("sql/%04d_%s.up.sql", migration.Version, migration.Name))
+		if err != nil {
+			t.Fatalf("reading the up file of %d_%s: %v", migration.Version, migration.Name, err)
+		}
+		if sum := sha256.Sum256(content); migration.Checksum != hex.EncodeToString(sum[:]) {
+			t.Errorf("checksum of %d_%s = %s, want the SHA-256 of its up file", migration.Version, migration.Name, migration.Checksum)
+		}
+		if strings.TrimSpace(migration.Up) == "" || strings.TrimSpace(migration.Down) == "" {
+			t.Errorf("migration %d_%s has an empty script", migration.Version, migration.Name)
+		}
+	}
+	if latest := LatestVersion(migrations); latest != len(migrations) {
+		t.Errorf("LatestVersion() = %d, want %d", latest, len(migrations))
+	}
+}
+
+func TestRunnerOrdering(t *testing.T) {
+	runner, database := newFakeRunner(t)
+	latest := LatestVersion(runner.migrations)
+
+	if err := runner.Goto(3); err != nil {
+		t.Fatalf("Goto(3) error = %v", err)
+	}
+	if log := database.takeLog(); !reflect.DeepEqual(log, steps("up", 1, 3)) {
+		t.Errorf("Goto(3) ran %v, want %v", log, steps("up", 1, 3))
+	}
+
+	if err := runner.Up(); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	if log := database.takeLog(); !reflect.DeepEqual(log, steps("up", 4, latest)) {
+		t.Errorf("Up() ran %v, want %v", log, steps("up", 4, latest))
+	}
+	if err := runner.Check(); err != nil {
+		t.Errorf("Check() after Up() error = %v", err)
+	}
+
+	if err := runner.Down(2); err != nil {
+		t.Fatalf("Down(2) error = %v", err)
+	}
+	if log := database.takeLog(); !reflect.DeepEqual(log, steps("down", latest, latest-1)) {
+		t.Errorf("Down(2) ran %v, want %v", log, steps("down", latest, latest-1))
+	}
+	if err := runner.Check(); !errors.Is(err, ErrSchemaOutdated) {
+		t.Errorf("Check() after Down(2) error = %v, want %v", err, ErrSchemaOutdated)
+	}
+
+	if err := runner.Goto(5); err != nil {
+		t.Fatalf("Goto(5) error = %v", err)
+	}
+	if log := database.takeLog(); !reflect.DeepEqual(log, steps("down", latest-2, 6)) {
+		t.Errorf("Goto(5) ran %v, want %v", log, steps("down", latest-2, 6))
+	}
+
+	// Nothing left to do, the lock is still taken and released
+	if err := runner.Goto(5); err != nil {
+		t.Fatalf("Goto(5) again error = %v", err)
+	}
+	if log := database.takeLog(); !reflect.DeepEqual(log, []string{"lock", "unlock"}) {
+		t.Errorf("Goto(5) again ran %v, want nothing", log)
+	}
+
+	if err := runner.Goto(latest + 1); !errors.Is(err, ErrUnknownVersion) {
+		t.Errorf("Goto(%d) error = %v, want %v", latest+1, err, ErrUnknownVersion)
+	}
+}
+
+func TestRunnerChecksumMismatch(t *testing.T) {
+	runner, database := newFakeRunner(t, 1, 2, 3)
+	database.rows[2] = strings.Repeat("0", 64)
+
+	if err := runner.Up(); !errors.Is(err, ErrChecksumMismatch) || !strings.Contains(err.Error(), "2_") {
+		t.Errorf("Up() error = %v, want %v naming migration 2", err, ErrChecksumMismatch)
+	}
+	if err := runner.Down(1); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Down(1) error = %v, want %v", err, ErrChecksumMismatch)
+	}
+	if err := runner.Baseline(5); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Baseline(5) error = %v, want %v", err, ErrChecksumMismatch)
+	}
+	if err := runner.Check(); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Check() error = %v, want %v", err, ErrChecksumMismatch)
+	}
+	for _, entry := range database.takeLog() {
+		if entry != "lock" && entry != "unlock" {
+			t.Errorf("%s ran with a modified migration applied", entry)
+		}
+	}
+	if len(database.rows) != 3 {
+		t.Errorf("%d migrations recorded after the refused runs, want 3", len(database.rows))
+	}
+
+	statuses, err := runner.Status()
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	for _, status := range statuses {
+		if applied := status.Version <= 3; (status.AppliedAt != nil) != applied || status.Modified != (status.Version == 2) {
+			t.Errorf("Status() of %d_%s = applied at %v, modified %v", status.Version, status.Name, status.AppliedAt, status.Modified)
+		}
+	}
+}
+
+func TestRunnerBaseline(t *testing.T) {
+	// A database created before the migrations were versioned, with the schema of version 6
+	runner, database := newFakeRunner(t)
+
+	if err := runner.Baseline(6); err != nil {
+		t.Fatalf("Baseline(6) error = %v", err)
+	}
+	if log := database.takeLog(); !reflect.DeepEqual(log, []string{"lock", "unlock"}) {
+		t.Errorf("Baseline(6) logged %v, want the versions recorded under the lock without running them", log)
+	}
+	if version, err := runner.Version(); err != nil || version != 6 {
+		t.Errorf("Version() after Baseline(6) = %d, %v, want 6", version, err)
+	}
+
+	// Recording again is harmless, and the later migrations run as usual
+	if err := runner.Baseline(4); err != nil {
+		t.Fatalf("Baseline(4) error = %v", err)
+	}
+	if err := runner.Up(); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	latest := LatestVersion(runner.migrations)
+	if log := database.takeLog(); !reflect.DeepEqual(log, append([]string{"lock", "unlock"}, steps("up", 7, latest)...)) {
+		t.Errorf("Baseline(4) then Up() ran %v, want %v", log, steps("up", 7, latest))
+	}
+
+	if err := runner.Baseline(latest + 1); !errors.Is(err, ErrUnknownVersion) {
+		t.Errorf("Baseline(%d) error = %v, want %v", latest+1, err, ErrUnknownVersion)
+	}
+}
@@ -0,0 +1,11 @@
+-- Drop the tables in reverse dependency order, their indexes and triggers go with them
+DROP TABLE IF EXISTS total_data_branch;
+DROP TABLE IF EXISTS total_data;
+DROP TABLE IF EXISTS user_feedback_history;
+DROP TABLE IF EXISTS company_profiles;
+DROP TABLE IF EXISTS branch_counters;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS branch_offices;
+
+-- Drop the function shared by the updatedAt triggers
+DROP FUNCTION IF EXISTS update_timestamp_column();
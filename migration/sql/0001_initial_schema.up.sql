@@ -0,0 +1,129 @@
+-- The schema created by the former migration/create tool. Every statement tolerates objects that already
+-- exist, so databases created by that tool can run this migration, or record it with "baseline 1".
+
+-- Create branch_offices table
+CREATE TABLE IF NOT EXISTS branch_offices (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	address TEXT,
+	total_counter INT DEFAULT 0,
+	createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create users table
+CREATE TABLE IF NOT EXISTS users (
+	id SERIAL PRIMARY KEY,
+	full_name VARCHAR(255) NOT NULL,
+	email VARCHAR(255) NOT NULL UNIQUE,
+	password VARCHAR(255) NOT NULL,
+	image VARCHAR(255),
+	role VARCHAR(50),
+	likes INT DEFAULT 0,
+	dislikes INT DEFAULT 0,
+	branch_id INT,
+	FOREIGN KEY (branch_id) REFERENCES branch_offices(id) ON DELETE CASCADE ON UPDATE CASCADE,
+	createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Add index on role column
+CREATE INDEX IF NOT EXISTS idx_users_role ON users (role);
+
+-- Create branch_counters table
+CREATE TABLE IF NOT EXISTS branch_counters (
+	id SERIAL PRIMARY KEY,
+	counter_location VARCHAR(255) NOT NULL,
+	user_id INT NOT NULL,
+	branch_id INT NOT NULL,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE ON UPDATE CASCADE,
+	FOREIGN KEY (branch_id) REFERENCES branch_offices(id) ON DELETE CASCADE ON UPDATE CASCADE,
+	createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create company_profiles table
+CREATE TABLE IF NOT EXISTS company_profiles (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	logo TEXT,
+	createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create user_feedback_history table
+CREATE TABLE IF NOT EXISTS user_feedback_history (
+	id SERIAL PRIMARY KEY,
+	likes INT DEFAULT 0,
+	dislikes INT DEFAULT 0,
+	officer_name VARCHAR(255) NOT NULL,
+	user_id INT NOT NULL,
+	branch_id INT NOT NULL,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE ON UPDATE CASCADE,
+	FOREIGN KEY (branch_id) REFERENCES branch_offices(id) ON DELETE CASCADE ON UPDATE CASCADE,
+	createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create total_data table
+CREATE TABLE IF NOT EXISTS total_data (
+	id SERIAL PRIMARY KEY,
+	total_likes INT DEFAULT 0,
+	total_dislikes INT DEFAULT 0,
+	total_officer INT DEFAULT 0,
+	total_voted INT DEFAULT 0,
+	createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create total_data_branch table
+CREATE TABLE IF NOT EXISTS total_data_branch (
+	id SERIAL PRIMARY KEY,
+	name_office VARCHAR(255) NOT NULL,
+	total_likes INT DEFAULT 0,
+	total_dislikes INT DEFAULT 0,
+	branch_id INT NOT NULL,
+	FOREIGN KEY (branch_id) REFERENCES branch_offices(id) ON DELETE CASCADE ON UPDATE CASCADE,
+	createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create function to automatically update 'updatedAt' timestamp
+CREATE OR REPLACE FUNCTION update_timestamp_column()
+RETURNS TRIGGER AS $$ 
+BEGIN 
+	NEW.updatedAt = NOW(); 
+	RETURN NEW; 
+END; 
+$$ LANGUAGE plpgsql;
+
+-- Create triggers to update 'updatedAt' on row update for all tables
+DROP TRIGGER IF EXISTS update_users_updatedAt ON users;
+CREATE TRIGGER update_users_updatedAt
+BEFORE UPDATE ON users
+FOR EACH ROW
+EXECUTE FUNCTION update_timestamp_column();
+
+DROP TRIGGER IF EXISTS update_branch_offices_updatedAt ON branch_offices;
+CREATE TRIGGER update_branch_offices_updatedAt
+BEFORE UPDATE ON branch_offices
+FOR EACH ROW
+EXECUTE FUNCTION update_timestamp_column();
+
+DROP TRIGGER IF EXISTS update_branch_counters_updatedAt ON branch_counters;
+CREATE TRIGGER update_branch_counters_updatedAt
+BEFORE UPDATE ON branch_counters
+FOR EACH ROW
+EXECUTE FUNCTION update_timestamp_column();
+
+DROP TRIGGER IF EXISTS update_company_profiles_updatedAt ON company_profiles;
+CREATE TRIGGER update_company_profiles_updatedAt
+BEFORE UPDATE ON company_profiles
+FOR EACH ROW
+EXECUTE FUNCTION update_timestamp_column();
+
+DROP TRIGGER IF EXISTS update_user_feedback_history_updatedAt ON user_feedback_history;
+CREATE TRIGGER update_user_feedback_history_updatedAt
+BEFORE UPDATE ON user_feedback_history
+FOR EACH ROW
+EXECUTE FUNCTION update_timestamp_column();
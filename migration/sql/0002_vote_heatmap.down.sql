@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_user_feedback_history_branch_created;
+ALTER TABLE branch_offices DROP COLUMN IF EXISTS timezone;
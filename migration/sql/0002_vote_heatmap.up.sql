@@ -0,0 +1,5 @@
+-- Branch offices report their votes in their own time zone
+ALTER TABLE branch_offices ADD COLUMN timezone VARCHAR(64) NOT NULL DEFAULT 'Asia/Riyadh';
+
+-- Add index for time-range analytics on feedback history
+CREATE INDEX idx_user_feedback_history_branch_created ON user_feedback_history (branch_id, createdAt);
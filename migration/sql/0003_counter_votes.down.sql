@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_user_feedback_history_counter_created;
+ALTER TABLE user_feedback_history DROP COLUMN IF EXISTS counter_id;
@@ -0,0 +1,5 @@
+-- Votes are attributed to the counter where they were cast; older votes have none
+ALTER TABLE user_feedback_history
+	ADD COLUMN counter_id INT REFERENCES branch_counters(id) ON DELETE SET NULL ON UPDATE CASCADE;
+
+CREATE INDEX idx_user_feedback_history_counter_created ON user_feedback_history (counter_id, createdAt);
@@ -0,0 +1,25 @@
+-- Create counter_assignments table (time-bounded officer shifts on a counter)
+CREATE TABLE counter_assignments (
+	id SERIAL PRIMARY KEY,
+	counter_id INT NOT NULL,
+	user_id INT NOT NULL,
+	branch_id INT NOT NULL,
+	starts_at TIMESTAMPTZ NOT NULL,
+	ends_at TIMESTAMPTZ NOT NULL,
+	FOREIGN KEY (counter_id) REFERENCES branch_counters(id) ON DELETE CASCADE ON UPDATE CASCADE,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE ON UPDATE CASCADE,
+	FOREIGN KEY (branch_id) REFERENCES branch_offices(id) ON DELETE CASCADE ON UPDATE CASCADE,
+	CHECK (ends_at > starts_at),
+	createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Add indexes for roster and "who is on the counter now" lookups
+CREATE INDEX idx_counter_assignments_branch_time ON counter_assignments (branch_id, starts_at, ends_at);
+CREATE INDEX idx_counter_assignments_counter_time ON counter_assignments (counter_id, starts_at, ends_at);
+CREATE INDEX idx_counter_assignments_user_time ON counter_assignments (user_id, starts_at, ends_at);
+
+CREATE TRIGGER update_counter_assignments_updatedAt
+BEFORE UPDATE ON counter_assignments
+FOR EACH ROW
+EXECUTE FUNCTION update_timestamp_column();
@@ -0,0 +1,3 @@
+ALTER TABLE branch_counters
+	DROP COLUMN IF EXISTS counter_number,
+	DROP COLUMN IF EXISTS is_active;
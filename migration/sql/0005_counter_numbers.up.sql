@@ -0,0 +1,19 @@
+-- Counters get a stable number within their branch office, existing ones numbered in creation order
+ALTER TABLE branch_counters
+	ADD COLUMN counter_number INT CHECK (counter_number > 0),
+	ADD COLUMN is_active BOOLEAN NOT NULL DEFAULT TRUE;
+
+UPDATE branch_counters bc
+SET counter_number = numbered.n
+FROM (SELECT id, ROW_NUMBER() OVER (PARTITION BY branch_id ORDER BY id) AS n FROM branch_counters) AS numbered
+WHERE bc.id = numbered.id;
+
+ALTER TABLE branch_counters
+	ALTER COLUMN counter_number SET NOT NULL,
+	ADD UNIQUE (branch_id, counter_number);
+
+-- The capacity now bounds the counters, so it grows to fit the counters already in service
+UPDATE branch_offices bo
+SET total_counter = counters.total
+FROM (SELECT branch_id, COUNT(*) AS total FROM branch_counters GROUP BY branch_id) AS counters
+WHERE bo.id = counters.branch_id AND COALESCE(bo.total_counter, 0) < counters.total;
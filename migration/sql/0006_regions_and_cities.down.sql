@@ -0,0 +1,4 @@
+ALTER TABLE users DROP COLUMN IF EXISTS region_id;
+ALTER TABLE branch_offices DROP COLUMN IF EXISTS city_id;
+DROP TABLE IF EXISTS cities;
+DROP TABLE IF EXISTS regions;
@@ -0,0 +1,36 @@
+-- Create regions table
+CREATE TABLE regions (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL UNIQUE,
+	createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create cities table
+CREATE TABLE cities (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	region_id INT NOT NULL,
+	FOREIGN KEY (region_id) REFERENCES regions(id) ON DELETE RESTRICT ON UPDATE CASCADE,
+	UNIQUE (region_id, name),
+	createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Branch offices belong to a city, regional managers to a region
+ALTER TABLE branch_offices ADD COLUMN city_id INT REFERENCES cities(id) ON DELETE SET NULL ON UPDATE CASCADE;
+ALTER TABLE users ADD COLUMN region_id INT REFERENCES regions(id) ON DELETE SET NULL ON UPDATE CASCADE;
+
+-- Add indexes for region roll-ups
+CREATE INDEX idx_branch_offices_city ON branch_offices (city_id);
+CREATE INDEX idx_cities_region ON cities (region_id);
+
+CREATE TRIGGER update_regions_updatedAt
+BEFORE UPDATE ON regions
+FOR EACH ROW
+EXECUTE FUNCTION update_timestamp_column();
+
+CREATE TRIGGER update_cities_updatedAt
+BEFORE UPDATE ON cities
+FOR EACH ROW
+EXECUTE FUNCTION update_timestamp_column();
@@ -0,0 +1,5 @@
+ALTER TABLE user_feedback_history DROP COLUMN IF EXISTS out_of_hours;
+DROP TABLE IF EXISTS branch_closures;
+DROP TABLE IF EXISTS branch_calendar_exceptions;
+DROP TABLE IF EXISTS branch_opening_hours;
+ALTER TABLE branch_offices DROP COLUMN IF EXISTS out_of_hours_vote_policy;
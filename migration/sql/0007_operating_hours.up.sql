@@ -0,0 +1,66 @@
+-- What happens to votes received while the branch office is closed
+ALTER TABLE branch_offices
+	ADD COLUMN out_of_hours_vote_policy VARCHAR(16) NOT NULL DEFAULT 'flag' CHECK (out_of_hours_vote_policy IN ('accept', 'flag', 'reject'));
+
+-- Create branch_opening_hours table (weekly hours in the branch's local time)
+CREATE TABLE branch_opening_hours (
+	id SERIAL PRIMARY KEY,
+	branch_id INT NOT NULL,
+	day_of_week SMALLINT NOT NULL CHECK (day_of_week BETWEEN 0 AND 6),
+	opens_at TIME NOT NULL,
+	closes_at TIME NOT NULL,
+	FOREIGN KEY (branch_id) REFERENCES branch_offices(id) ON DELETE CASCADE ON UPDATE CASCADE,
+	createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create branch_calendar_exceptions table (holidays and special hours such as Ramadan)
+CREATE TABLE branch_calendar_exceptions (
+	id SERIAL PRIMARY KEY,
+	branch_id INT NOT NULL,
+	name VARCHAR(255) NOT NULL,
+	kind VARCHAR(16) NOT NULL CHECK (kind IN ('holiday', 'special_hours')),
+	starts_on DATE NOT NULL,
+	ends_on DATE NOT NULL,
+	opens_at TIME,
+	closes_at TIME,
+	FOREIGN KEY (branch_id) REFERENCES branch_offices(id) ON DELETE CASCADE ON UPDATE CASCADE,
+	CHECK (ends_on >= starts_on),
+	createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create branch_closures table (temporary closures)
+CREATE TABLE branch_closures (
+	id SERIAL PRIMARY KEY,
+	branch_id INT NOT NULL,
+	reason VARCHAR(255) NOT NULL,
+	starts_at TIMESTAMPTZ NOT NULL,
+	ends_at TIMESTAMPTZ NOT NULL,
+	FOREIGN KEY (branch_id) REFERENCES branch_offices(id) ON DELETE CASCADE ON UPDATE CASCADE,
+	CHECK (ends_at > starts_at),
+	createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_branch_opening_hours_branch ON branch_opening_hours (branch_id, day_of_week);
+CREATE INDEX idx_branch_calendar_exceptions_branch ON branch_calendar_exceptions (branch_id, starts_on, ends_on);
+CREATE INDEX idx_branch_closures_branch ON branch_closures (branch_id, starts_at, ends_at);
+
+-- Votes received while closed under the flag policy are kept but left out of the statistics
+ALTER TABLE user_feedback_history ADD COLUMN out_of_hours BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TRIGGER update_branch_opening_hours_updatedAt
+BEFORE UPDATE ON branch_opening_hours
+FOR EACH ROW
+EXECUTE FUNCTION update_timestamp_column();
+
+CREATE TRIGGER update_branch_calendar_exceptions_updatedAt
+BEFORE UPDATE ON branch_calendar_exceptions
+FOR EACH ROW
+EXECUTE FUNCTION update_timestamp_column();
+
+CREATE TRIGGER update_branch_closures_updatedAt
+BEFORE UPDATE ON branch_closures
+FOR EACH ROW
+EXECUTE FUNCTION update_timestamp_column();
@@ -0,0 +1,4 @@
+ALTER TABLE branch_offices
+	DROP CONSTRAINT IF EXISTS branch_offices_coordinates_check,
+	DROP COLUMN IF EXISTS latitude,
+	DROP COLUMN IF EXISTS longitude;
@@ -0,0 +1,5 @@
+-- Branch offices may have a position, both coordinates or none
+ALTER TABLE branch_offices
+	ADD COLUMN latitude DOUBLE PRECISION CHECK (latitude BETWEEN -90 AND 90),
+	ADD COLUMN longitude DOUBLE PRECISION CHECK (longitude BETWEEN -180 AND 180),
+	ADD CONSTRAINT branch_offices_coordinates_check CHECK ((latitude IS NULL) = (longitude IS NULL));
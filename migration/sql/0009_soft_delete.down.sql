@@ -0,0 +1,6 @@
+-- Fails while an archived user shares the email of another user; purge or rename them first
+DROP TABLE IF EXISTS purge_audit_log;
+DROP INDEX IF EXISTS idx_users_email_active;
+ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
+ALTER TABLE users DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE branch_offices DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,22 @@
+-- Branch offices and users are archived instead of deleted
+ALTER TABLE branch_offices ADD COLUMN deleted_at TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMPTZ;
+
+-- Archived users keep their email, only active users must have a unique one
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key;
+CREATE UNIQUE INDEX idx_users_email_active ON users (email) WHERE deleted_at IS NULL;
+
+-- Create purge_audit_log table, kept after the purged rows are gone
+CREATE TABLE purge_audit_log (
+	id SERIAL PRIMARY KEY,
+	entity_type VARCHAR(32) NOT NULL CHECK (entity_type IN ('branch_office', 'user')),
+	entity_id INT NOT NULL,
+	entity_name VARCHAR(255) NOT NULL,
+	reason TEXT NOT NULL,
+	purged_by VARCHAR(255) NOT NULL,
+	feedback_rows INT NOT NULL DEFAULT 0,
+	snapshot JSONB NOT NULL,
+	purged_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_purge_audit_log_entity ON purge_audit_log (entity_type, entity_id);
@@ -0,0 +1,6 @@
+-- Fails while a counter has no officer; assign or delete those counters first
+DROP TABLE IF EXISTS user_branch_memberships;
+ALTER TABLE branch_counters DROP CONSTRAINT IF EXISTS branch_counters_user_id_fkey;
+ALTER TABLE branch_counters
+	ADD CONSTRAINT branch_counters_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE ON UPDATE CASCADE;
+ALTER TABLE branch_counters ALTER COLUMN user_id SET NOT NULL;
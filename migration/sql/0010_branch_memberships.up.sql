@@ -0,0 +1,33 @@
+-- Counters outlive the officers transferred away from them
+ALTER TABLE branch_counters ALTER COLUMN user_id DROP NOT NULL;
+ALTER TABLE branch_counters DROP CONSTRAINT IF EXISTS branch_counters_user_id_fkey;
+ALTER TABLE branch_counters
+	ADD CONSTRAINT branch_counters_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL ON UPDATE CASCADE;
+
+-- Create user_branch_memberships table, the branch offices a user belonged to over time
+CREATE TABLE user_branch_memberships (
+	id SERIAL PRIMARY KEY,
+	user_id INT NOT NULL,
+	branch_id INT NOT NULL,
+	starts_at TIMESTAMPTZ NOT NULL,
+	ends_at TIMESTAMPTZ,
+	reason TEXT,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE ON UPDATE CASCADE,
+	FOREIGN KEY (branch_id) REFERENCES branch_offices(id) ON DELETE CASCADE ON UPDATE CASCADE,
+	CHECK (ends_at IS NULL OR ends_at > starts_at),
+	createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- A user has at most one open membership
+CREATE UNIQUE INDEX idx_user_branch_memberships_open ON user_branch_memberships (user_id) WHERE ends_at IS NULL;
+CREATE INDEX idx_user_branch_memberships_branch ON user_branch_memberships (branch_id, starts_at);
+
+-- Users already working in a branch office have been members since they were created
+INSERT INTO user_branch_memberships (user_id, branch_id, starts_at)
+SELECT id, branch_id, COALESCE(createdAt, now()) FROM users WHERE branch_id IS NOT NULL;
+
+CREATE TRIGGER update_user_branch_memberships_updatedAt
+BEFORE UPDATE ON user_branch_memberships
+FOR EACH ROW
+EXECUTE FUNCTION update_timestamp_column();
@@ -0,0 +1,4 @@
+-- Only active users must have a unique email, now compared case-insensitively. Fails while two active users
+-- have emails differing only by case; rename or archive one of them first.
+DROP INDEX IF EXISTS idx_users_email_active;
+CREATE UNIQUE INDEX idx_users_email_active ON users (LOWER(email)) WHERE deleted_at IS NULL;
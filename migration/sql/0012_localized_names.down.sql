@@ -0,0 +1,13 @@
+ALTER TABLE company_profiles
+	DROP COLUMN IF EXISTS name_ar,
+	DROP COLUMN IF EXISTS name_en;
+
+ALTER TABLE users
+	DROP COLUMN IF EXISTS full_name_ar,
+	DROP COLUMN IF EXISTS full_name_en;
+
+ALTER TABLE branch_offices
+	DROP COLUMN IF EXISTS name_ar,
+	DROP COLUMN IF EXISTS name_en,
+	DROP COLUMN IF EXISTS address_ar,
+	DROP COLUMN IF EXISTS address_en;
@@ -0,0 +1,14 @@
+-- Optional Arabic and English variants of the names shown to customers
+ALTER TABLE branch_offices
+	ADD COLUMN name_ar VARCHAR(255),
+	ADD COLUMN name_en VARCHAR(255),
+	ADD COLUMN address_ar TEXT,
+	ADD COLUMN address_en TEXT;
+
+ALTER TABLE users
+	ADD COLUMN full_name_ar VARCHAR(255),
+	ADD COLUMN full_name_en VARCHAR(255);
+
+ALTER TABLE company_profiles
+	ADD COLUMN name_ar VARCHAR(255),
+	ADD COLUMN name_en VARCHAR(255);
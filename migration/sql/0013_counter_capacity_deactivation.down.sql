@@ -0,0 +1 @@
+ALTER TABLE branch_counters DROP COLUMN IF EXISTS deactivated_by_capacity;
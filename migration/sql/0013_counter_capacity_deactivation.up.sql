@@ -0,0 +1,5 @@
+-- Counters deactivated by a capacity shrink are told apart, so growing the capacity again only brings those
+-- back. Shrinking was the only way to deactivate a counter so far.
+ALTER TABLE branch_counters ADD COLUMN deactivated_by_capacity BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE branch_counters SET deactivated_by_capacity = TRUE WHERE NOT is_active;
@@ -0,0 +1,11 @@
+ALTER TABLE counter_assignments DROP CONSTRAINT IF EXISTS counter_assignments_counter_id_fkey;
+ALTER TABLE counter_assignments ADD CONSTRAINT counter_assignments_counter_id_fkey
+	FOREIGN KEY (counter_id) REFERENCES branch_counters(id) ON DELETE CASCADE ON UPDATE CASCADE;
+
+-- The numbers of the retired counters may be taken again, they go with their shifts
+DELETE FROM branch_counters WHERE retired_at IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_branch_counters_number;
+ALTER TABLE branch_counters ADD UNIQUE (branch_id, counter_number);
+
+ALTER TABLE branch_counters DROP COLUMN IF EXISTS retired_at;
@@ -0,0 +1,12 @@
+-- Counters with shifts are retired instead of deleted, so the shift history keeps pointing at them. A
+-- retired counter gives its number back: only the counters in service have unique numbers.
+ALTER TABLE branch_counters ADD COLUMN retired_at TIMESTAMPTZ;
+
+ALTER TABLE branch_counters DROP CONSTRAINT branch_counters_branch_id_counter_number_key;
+CREATE UNIQUE INDEX idx_branch_counters_number ON branch_counters (branch_id, counter_number) WHERE retired_at IS NULL;
+
+-- Deleting a counter no longer erases its shifts. NO ACTION rather than RESTRICT: it is checked at the end
+-- of the statement, so purging a branch office still deletes its counters and shifts together.
+ALTER TABLE counter_assignments DROP CONSTRAINT counter_assignments_counter_id_fkey;
+ALTER TABLE counter_assignments ADD CONSTRAINT counter_assignments_counter_id_fkey
+	FOREIGN KEY (counter_id) REFERENCES branch_counters(id) ON DELETE NO ACTION ON UPDATE CASCADE;
@@ -0,0 +1,48 @@
+-- Converts the TIMESTAMP columns back to the time zone the sessions had before they were pinned to UTC,
+-- found as in the up migration
+CREATE FUNCTION pg_temp.legacy_timezone() RETURNS TEXT AS $$
+DECLARE
+	zone TEXT := NULLIF(current_setting('feedback.legacy_timezone', true), '');
+BEGIN
+	IF zone IS NULL THEN
+		SELECT split_part(config, '=', 2) INTO zone
+		FROM pg_db_role_setting s, unnest(s.setconfig) AS config
+		WHERE lower(split_part(config, '=', 1)) = 'timezone'
+			AND s.setdatabase IN (0, (SELECT oid FROM pg_database WHERE datname = current_database()))
+			AND s.setrole IN (0, (SELECT oid FROM pg_roles WHERE rolname = current_user))
+		ORDER BY (s.setdatabase <> 0 AND s.setrole <> 0) DESC, (s.setrole <> 0) DESC
+		LIMIT 1;
+	END IF;
+
+	IF zone IS NULL THEN
+		BEGIN
+			SELECT setting INTO zone FROM pg_file_settings WHERE lower(name) = 'timezone' AND applied;
+		EXCEPTION WHEN insufficient_privilege THEN
+			RAISE WARNING 'postgresql.conf is not readable, the TIMESTAMP columns are left in UTC';
+		END;
+	END IF;
+
+	RETURN COALESCE(zone, 'UTC');
+END;
+$$ LANGUAGE plpgsql;
+
+DO $$
+DECLARE
+	zone TEXT := pg_temp.legacy_timezone();
+	tbl RECORD;
+BEGIN
+	FOR tbl IN
+		SELECT table_name, string_agg(format('%1$I = (%1$I AT TIME ZONE ''UTC'') AT TIME ZONE %2$L', column_name, zone), ', ') AS assignments
+		FROM information_schema.columns
+		JOIN information_schema.tables USING (table_schema, table_name)
+		WHERE table_schema = current_schema() AND table_type = 'BASE TABLE' AND data_type = 'timestamp without time zone'
+		GROUP BY table_name
+	LOOP
+		EXECUTE format('ALTER TABLE %I DISABLE TRIGGER USER', tbl.table_name);
+		EXECUTE format('UPDATE %I SET %s', tbl.table_name, tbl.assignments);
+		EXECUTE format('ALTER TABLE %I ENABLE TRIGGER USER', tbl.table_name);
+	END LOOP;
+END;
+$$;
+
+DROP FUNCTION pg_temp.legacy_timezone();
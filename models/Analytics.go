@@ -0,0 +1,22 @@
+package models
+
+// HeatmapCell holds the vote counts for one day-of-week/hour-of-day slot
+type HeatmapCell struct {
+	Likes        uint    `json:"likes"`
+	Dislikes     uint    `json:"dislikes"`
+	Total        uint    `json:"total"`
+	DislikeRatio float64 `json:"dislike_ratio"`
+}
+
+// HeatmapResponse is a 7x24 matrix indexed by day of week (0 = Sunday) and hour of day,
+// computed in the branch's (or region's) local timezone
+type HeatmapResponse struct {
+	BranchID *uint              `json:"branch_id,omitempty"`
+	RegionID *uint              `json:"region_id,omitempty"`
+	UserID   *uint              `json:"user_id,omitempty"`
+	Timezone string             `json:"timezone"`
+	From     string             `json:"from"`
+	To       string             `json:"to"`
+	Days     []string           `json:"days"`
+	Cells    [7][24]HeatmapCell `json:"cells"`
+}
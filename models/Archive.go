@@ -0,0 +1,39 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Entity types recorded in the purge audit log
+const (
+	PurgeEntityBranchOffice = "branch_office"
+	PurgeEntityUser         = "user"
+)
+
+// ArchivedEntity is a soft-deleted branch office or user that can still be restored or purged
+type ArchivedEntity struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	Detail    string    `json:"detail"` // Address of a branch office, email of a user
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// PurgeRequest explains why archived data is permanently deleted
+type PurgeRequest struct {
+	Reason   string `json:"reason"`
+	PurgedBy string `json:"purged_by"`
+}
+
+// PurgeAuditEntry records a permanent deletion, with a snapshot of the purged rows
+type PurgeAuditEntry struct {
+	ID           uint            `json:"id"`
+	EntityType   string          `json:"entity_type"`
+	EntityID     uint            `json:"entity_id"`
+	EntityName   string          `json:"entity_name"`
+	Reason       string          `json:"reason"`
+	PurgedBy     string          `json:"purged_by"`
+	FeedbackRows int             `json:"feedback_rows"`
+	Snapshot     json.RawMessage `json:"snapshot"`
+	PurgedAt     time.Time       `json:"purged_at"`
+}
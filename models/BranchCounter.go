@@ -1,17 +1,82 @@
 package models
 
+import (
+	"api-server/locale"
+	"time"
+)
+
+// Counter capacity policies applied when total_counter of a branch office shrinks
+const (
+	CounterPolicyReject     = "reject"     // Refuse the update while counters above the new total exist
+	CounterPolicyDeactivate = "deactivate" // Deactivate the counters numbered above the new total
+)
+
 type BranchCounter struct {
 	ID              uint   `json:"id"`
 	CounterLocation string `json:"counter_location"`
-	UserID          uint   `json:"user_id"`
+	CounterNumber   uint   `json:"counter_number"`
+	IsActive        bool   `json:"is_active"`
+	UserID          uint   `json:"user_id"` // 0 when the counter has been released
 	BranchID        uint   `json:"branch_id"`
 }
 
 // BranchCounterWithNames includes additional fields for names from related tables
 type BranchCounterWithNames struct {
-	ID              uint   `json:"id"`
-	CounterLocation string `json:"counter_location"`
-	FullName        string `json:"full_name"` // Name from users table
-	Image           string `json:"image"`
-	UserId          uint   `json:"user_id"`
+	ID              uint    `json:"id"`
+	CounterLocation string  `json:"counter_location"`
+	CounterNumber   uint    `json:"counter_number"`
+	FullName        string  `json:"full_name"` // Name from users table
+	FullNameAr      *string `json:"full_name_ar"`
+	FullNameEn      *string `json:"full_name_en"`
+	Image           string  `json:"image"`
+	Thumbnail       string  `json:"thumbnail,omitempty"`
+	UserId          uint    `json:"user_id"`
+}
+
+// Localize replaces the officer name with its variant in the given language, when one is set
+func (b *BranchCounterWithNames) Localize(lang string) {
+	b.FullName = locale.Pick(lang, b.FullName, b.FullNameAr, b.FullNameEn)
+}
+
+// CounterSlot describes one numbered counter slot of a branch office
+type CounterSlot struct {
+	CounterNumber   uint   `json:"counter_number"`
+	Status          string `json:"status"` // "occupied", "vacant", "free" or "inactive"
+	CounterID       *uint  `json:"counter_id"`
+	CounterLocation string `json:"counter_location,omitempty"`
+	UserID          *uint  `json:"user_id"`
+	FullName        string `json:"full_name,omitempty"`
+}
+
+// BranchCounterStats holds the vote totals collected at a counter over a period
+type BranchCounterStats struct {
+	CounterID       uint    `json:"counter_id"`
+	CounterNumber   uint    `json:"counter_number"`
+	CounterLocation string  `json:"counter_location"`
+	BranchID        uint    `json:"branch_id"`
+	Likes           uint    `json:"likes"`
+	Dislikes        uint    `json:"dislikes"`
+	Total           uint    `json:"total"`
+	DislikeRatio    float64 `json:"dislike_ratio"`
+}
+
+// CounterOfficerStats holds the votes an officer received while serving at a counter. The vote times are nil
+// for an officer without votes in the period.
+type CounterOfficerStats struct {
+	UserID      uint       `json:"user_id"`
+	FullName    string     `json:"full_name"`
+	Likes       uint       `json:"likes"`
+	Dislikes    uint       `json:"dislikes"`
+	Total       uint       `json:"total"`
+	FirstVoteAt *time.Time `json:"first_vote_at"`
+	LastVoteAt  *time.Time `json:"last_vote_at"`
+}
+
+// CounterStatsResponse combines the totals of a counter with the officers that staffed it
+type CounterStatsResponse struct {
+	From     string                    `json:"from"`
+	To       string                    `json:"to"`
+	Counter  BranchCounterStats        `json:"counter"`
+	Officers []CounterOfficerStats     `json:"officers"`
+	Shifts   []CounterAssignmentDetail `json:"shifts"`
 }
@@ -1,20 +1,92 @@
 package models
 
+import "api-server/locale"
+
+// DefaultBranchTimezone is used when a branch office is created without an explicit timezone
+const DefaultBranchTimezone = "Asia/Riyadh"
+
 type BranchOfficeCreateRequest struct {
-	Name         string `json:"name"`
-	Address      string `json:"address"`
+	Name    string `json:"name"`
+	Address string `json:"address"`
+
+	// Optional Arabic and English variants of the name and address
+	NameAr    *string `json:"name_ar"`
+	NameEn    *string `json:"name_en"`
+	AddressAr *string `json:"address_ar"`
+	AddressEn *string `json:"address_en"`
+
 	TotalCounter uint   `json:"total_counter"`
+	Timezone     string `json:"timezone"`
+	CityID       *uint  `json:"city_id"`
+
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
 }
 
 type BranchOfficeResponse struct {
-	ID           uint   `json:"id"`
-	Name         string `json:"name"`
-	Address      string `json:"address"`
+	ID      uint   `json:"id"`
+	Name    string `json:"name"`
+	Address string `json:"address"`
+
+	NameAr    *string `json:"name_ar"`
+	NameEn    *string `json:"name_en"`
+	AddressAr *string `json:"address_ar"`
+	AddressEn *string `json:"address_en"`
+
 	TotalCounter uint   `json:"total_counter"`
+	Timezone     string `json:"timezone"`
+	CityID       *uint  `json:"city_id"`
+	CityName     string `json:"city_name"`
+	RegionID     *uint  `json:"region_id"`
+	RegionName   string `json:"region_name"`
+
+	OutOfHoursPolicy string `json:"out_of_hours_vote_policy"`
+
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
 }
 type BranchOfficeOptionResponse struct {
-	ID   uint   `json:"id"`
-	Name string `json:"name"`
+	ID        uint     `json:"id"`
+	Name      string   `json:"name"`
+	NameAr    *string  `json:"name_ar"`
+	NameEn    *string  `json:"name_en"`
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+}
+
+// NearbyBranchOffice is a branch office found by a geo search, with its distance from the search point
+// and its satisfaction score (share of likes among all votes, in percent; null while it has no votes)
+type NearbyBranchOffice struct {
+	ID                uint     `json:"id"`
+	Name              string   `json:"name"`
+	Address           string   `json:"address"`
+	NameAr            *string  `json:"name_ar"`
+	NameEn            *string  `json:"name_en"`
+	AddressAr         *string  `json:"address_ar"`
+	AddressEn         *string  `json:"address_en"`
+	Latitude          float64  `json:"latitude"`
+	Longitude         float64  `json:"longitude"`
+	DistanceKm        float64  `json:"distance_km"`
+	TotalLikes        int      `json:"total_likes"`
+	TotalDislikes     int      `json:"total_dislikes"`
+	SatisfactionScore *float64 `json:"satisfaction_score"`
+}
+
+// Localize replaces the name and address with their variant in the given language, when one is set
+func (b *BranchOfficeResponse) Localize(lang string) {
+	b.Name = locale.Pick(lang, b.Name, b.NameAr, b.NameEn)
+	b.Address = locale.Pick(lang, b.Address, b.AddressAr, b.AddressEn)
+}
+
+// Localize replaces the name with its variant in the given language, when one is set
+func (b *BranchOfficeOptionResponse) Localize(lang string) {
+	b.Name = locale.Pick(lang, b.Name, b.NameAr, b.NameEn)
+}
+
+// Localize replaces the name and address with their variant in the given language, when one is set
+func (b *NearbyBranchOffice) Localize(lang string) {
+	b.Name = locale.Pick(lang, b.Name, b.NameAr, b.NameEn)
+	b.Address = locale.Pick(lang, b.Address, b.AddressAr, b.AddressEn)
 }
 
 type BranchOfficeListResponse struct {
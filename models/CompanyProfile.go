@@ -1,6 +1,15 @@
 package models
 
+import "api-server/locale"
+
 type CompanyProfile struct {
-	Name string `json:"name"`
-	Logo string `json:"logo"`
+	Name   string  `json:"name"`
+	NameAr *string `json:"name_ar"`
+	NameEn *string `json:"name_en"`
+	Logo   string  `json:"logo"`
+}
+
+// Localize replaces the company name with its variant in the given language, when one is set
+func (c *CompanyProfile) Localize(lang string) {
+	c.Name = locale.Pick(lang, c.Name, c.NameAr, c.NameEn)
 }
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"api-server/locale"
+	"time"
+)
+
+// CounterAssignment is a time-bounded shift of an officer on a branch counter
+type CounterAssignment struct {
+	ID        uint      `json:"id"`
+	CounterID uint      `json:"counter_id"`
+	UserID    uint      `json:"user_id"`
+	BranchID  uint      `json:"branch_id"`
+	StartsAt  time.Time `json:"starts_at"`
+	EndsAt    time.Time `json:"ends_at"`
+}
+
+// CounterAssignmentDetail includes the counter location and officer name of a shift
+type CounterAssignmentDetail struct {
+	ID              uint      `json:"id"`
+	CounterID       uint      `json:"counter_id"`
+	CounterLocation string    `json:"counter_location"`
+	UserID          uint      `json:"user_id"`
+	FullName        string    `json:"full_name"`
+	BranchID        uint      `json:"branch_id"`
+	StartsAt        time.Time `json:"starts_at"`
+	EndsAt          time.Time `json:"ends_at"`
+}
+
+// AssignmentConflict describes two overlapping shifts sharing an officer or a counter
+type AssignmentConflict struct {
+	Reason string                  `json:"reason"`
+	First  CounterAssignmentDetail `json:"first"`
+	Second CounterAssignmentDetail `json:"second"`
+}
+
+// BranchRosterResponse is the weekly shift roster of a branch
+type BranchRosterResponse struct {
+	BranchID    uint                      `json:"branch_id"`
+	Timezone    string                    `json:"timezone"`
+	WeekStart   string                    `json:"week_start"`
+	WeekEnd     string                    `json:"week_end"`
+	Assignments []CounterAssignmentDetail `json:"assignments"`
+	Conflicts   []AssignmentConflict      `json:"conflicts"`
+}
+
+// CurrentCounterOfficerResponse tells who is serving at a counter at a given moment
+type CurrentCounterOfficerResponse struct {
+	CounterID       uint                     `json:"counter_id"`
+	CounterLocation string                   `json:"counter_location"`
+	At              time.Time                `json:"at"`
+	Source          string                   `json:"source"` // "shift", "static" or "none"
+	UserID          *uint                    `json:"user_id"`
+	FullName        string                   `json:"full_name"`
+	FullNameAr      *string                  `json:"full_name_ar"`
+	FullNameEn      *string                  `json:"full_name_en"`
+	Image           string                   `json:"image"`
+	Thumbnail       string                   `json:"thumbnail,omitempty"`
+	Assignment      *CounterAssignmentDetail `json:"assignment"`
+}
+
+// Localize replaces the officer name with its variant in the given language, when one is set
+func (r *CurrentCounterOfficerResponse) Localize(lang string) {
+	r.FullName = locale.Pick(lang, r.FullName, r.FullNameAr, r.FullNameEn)
+}
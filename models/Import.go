@@ -0,0 +1,27 @@
+package models
+
+// ImportRowError reports why a row of an imported file was rejected
+type ImportRowError struct {
+	Row     int    `json:"row"` // Line in the file, the header being line 1
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// ImportedCredential is the generated initial password of an imported user, returned once
+type ImportedCredential struct {
+	Row      int    `json:"row"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Image    string `json:"image,omitempty"`
+}
+
+// ImportResult summarizes an import. Nothing is written when DryRun is set or when any row has an error.
+type ImportResult struct {
+	DryRun          bool                 `json:"dry_run"`
+	TotalRows       int                  `json:"total_rows"`
+	ValidRows       int                  `json:"valid_rows"`
+	Created         int                  `json:"created"`
+	Errors          []ImportRowError     `json:"errors"`
+	Credentials     []ImportedCredential `json:"credentials,omitempty"`
+	UnmatchedImages []string             `json:"unmatched_images,omitempty"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// BranchMembership is a period during which a user belonged to a branch office.
+// The current membership has no end.
+type BranchMembership struct {
+	ID         uint       `json:"id"`
+	UserID     uint       `json:"user_id"`
+	BranchID   uint       `json:"branch_id"`
+	BranchName string     `json:"branch_name"`
+	StartsAt   time.Time  `json:"starts_at"`
+	EndsAt     *time.Time `json:"ends_at"`
+	Reason     *string    `json:"reason"`
+}
+
+// TransferRequest moves a user to another branch office from the effective date on
+type TransferRequest struct {
+	UserID      uint      `json:"user_id"`
+	BranchID    uint      `json:"branch_id"`
+	EffectiveAt time.Time `json:"effective_at"`
+	Reason      string    `json:"reason"`
+}
+
+// TransferResult summarizes what a transfer changed
+type TransferResult struct {
+	ClosedMembership BranchMembership `json:"closed_membership"`
+	OpenedMembership BranchMembership `json:"opened_membership"`
+	ReleasedCounters int              `json:"released_counters"`
+	CancelledShifts  int              `json:"cancelled_shifts"`
+	ShortenedShifts  int              `json:"shortened_shifts"`
+}
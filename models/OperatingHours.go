@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+// Policies for votes received while a branch office is closed
+const (
+	OutOfHoursVoteAccept = "accept" // Record the vote as usual
+	OutOfHoursVoteFlag   = "flag"   // Record the vote but flag it so reports can exclude it
+	OutOfHoursVoteReject = "reject" // Refuse the vote
+)
+
+// Kinds of calendar exceptions overriding the weekly opening hours
+const (
+	CalendarHoliday      = "holiday"       // Closed for the whole day(s)
+	CalendarSpecialHours = "special_hours" // Different hours for the day(s), e.g. Ramadan
+)
+
+// OpeningHours is a weekly opening interval of a branch office in its local time ("HH:MM").
+// A closing time at or before the opening time means the interval runs past midnight.
+type OpeningHours struct {
+	DayOfWeek uint   `json:"day_of_week"` // 0 = Sunday
+	OpensAt   string `json:"opens_at"`
+	ClosesAt  string `json:"closes_at"`
+}
+
+// CalendarException overrides the weekly hours of a branch office between two dates (inclusive)
+type CalendarException struct {
+	ID       uint    `json:"id"`
+	BranchID uint    `json:"branch_id"`
+	Name     string  `json:"name"`
+	Kind     string  `json:"kind"`
+	StartsOn string  `json:"starts_on"` // YYYY-MM-DD
+	EndsOn   string  `json:"ends_on"`   // YYYY-MM-DD
+	OpensAt  *string `json:"opens_at"`  // Only for special hours
+	ClosesAt *string `json:"closes_at"` // Only for special hours
+}
+
+// TemporaryClosure closes a branch office between two moments, e.g. for maintenance
+type TemporaryClosure struct {
+	ID       uint      `json:"id"`
+	BranchID uint      `json:"branch_id"`
+	Reason   string    `json:"reason"`
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+}
+
+// BranchSchedule gathers the opening hours configuration of a branch office
+type BranchSchedule struct {
+	BranchID         uint                `json:"branch_id"`
+	Timezone         string              `json:"timezone"`
+	OutOfHoursPolicy string              `json:"out_of_hours_vote_policy"`
+	Hours            []OpeningHours      `json:"hours"`
+	Exceptions       []CalendarException `json:"exceptions"`
+	Closures         []TemporaryClosure  `json:"closures"`
+}
+
+// BranchOpenStatus tells whether a branch office is open at a given moment and why
+type BranchOpenStatus struct {
+	BranchID uint      `json:"branch_id"`
+	At       time.Time `json:"at"`
+	LocalAt  string    `json:"local_at"`
+	IsOpen   bool      `json:"is_open"`
+	Reason   string    `json:"reason"` // "no_schedule", "regular_hours", "special_hours", "holiday", "closure" or "outside_hours"
+	Detail   string    `json:"detail,omitempty"`
+}
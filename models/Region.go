@@ -0,0 +1,33 @@
+package models
+
+type RegionCreateRequest struct {
+	Name string `json:"name"`
+}
+
+type RegionResponse struct {
+	ID          uint   `json:"id"`
+	Name        string `json:"name"`
+	TotalCities uint   `json:"total_cities"`
+}
+
+type CityCreateRequest struct {
+	Name     string `json:"name"`
+	RegionID uint   `json:"region_id"`
+}
+
+type CityResponse struct {
+	ID         uint   `json:"id"`
+	Name       string `json:"name"`
+	RegionID   uint   `json:"region_id"`
+	RegionName string `json:"region_name"`
+}
+
+// RegionData holds the dashboard roll-up of all branch offices in a region
+type RegionData struct {
+	RegionID      uint   `json:"region_id"`
+	RegionName    string `json:"region_name"`
+	TotalBranches int    `json:"total_branches"`
+	TotalOfficer  int    `json:"total_officer"`
+	TotalLikes    int    `json:"total_likes"`
+	TotalDislikes int    `json:"total_dislikes"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// OrphanedFile is an uploaded file that no row references
+type OrphanedFile struct {
+	Name    string    `json:"name"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// UploadGCStoreResult summarizes the garbage collection of one storage
+type UploadGCStoreResult struct {
+	Store      string         `json:"store"` // "images" or "assets"
+	Scanned    int            `json:"scanned"`
+	Referenced int            `json:"referenced"`
+	Recent     int            `json:"recent"` // Orphaned but too recent to tell from an upload in progress, kept
+	Orphaned   []OrphanedFile `json:"orphaned"`
+	Deleted    int            `json:"deleted"`
+	FreedBytes int64          `json:"freed_bytes"`
+	Errors     []string       `json:"errors,omitempty"`
+}
+
+// UploadGCResult summarizes a garbage collection of the uploaded files. Nothing is deleted when DryRun is set.
+type UploadGCResult struct {
+	DryRun bool                  `json:"dry_run"`
+	MinAge string                `json:"min_age"`
+	Stores []UploadGCStoreResult `json:"stores"`
+}
@@ -1,26 +1,62 @@
 package models
 
+import "api-server/locale"
+
 type User struct {
 	ID       uint   // FIXME: make Gin skip mapping this field!
 	FullName string `form:"full_name"`
-	Email    string `form:"email"`
-	Password string `form:"password"`
-	Role     string `form:"role"`
-	Likes    uint   `form:"likes"`
-	Dislikes uint   `form:"dislikes"`
-	Image    string `form:"image"`
-	BranchId uint   `form:"branch_id"`
+	// Optional Arabic and English variants of the display name
+	FullNameAr *string `form:"full_name_ar"`
+	FullNameEn *string `form:"full_name_en"`
+	Email      string  `form:"email"`
+	Password   string  `form:"password"`
+	Role       string  `form:"role"`
+	Likes      uint    `form:"likes"`
+	Dislikes   uint    `form:"dislikes"`
+	Image      string  `form:"image"`
+	BranchId   uint    `form:"branch_id"`
+	RegionId   *uint   `form:"region_id"` // Only set for regional managers
 }
 
 type UserAllResponse struct {
-	ID       uint   `json:"id"`
-	FullName string `json:"full_name"`
-	Email    string `json:"email"`
-	Role     string `json:"role"`
-	Likes    uint   `json:"likes"`
-	Dislikes uint   `json:"dislikes"`
-	Image    string `json:"image"`
-	BranchId *uint  `json:"branch_id"`
+	ID         uint    `json:"id"`
+	FullName   string  `json:"full_name"`
+	FullNameAr *string `json:"full_name_ar"`
+	FullNameEn *string `json:"full_name_en"`
+	Email      string  `json:"email"`
+	Role       string  `json:"role"`
+	Likes      uint    `json:"likes"`
+	Dislikes   uint    `json:"dislikes"`
+	Image      string  `json:"image"`
+	Thumbnail  string  `json:"thumbnail,omitempty"`
+	BranchId   *uint   `json:"branch_id"`
+	RegionId   *uint   `json:"region_id"`
+}
+
+// NewUserAllResponse copies a user without their password
+func NewUserAllResponse(user *User) UserAllResponse {
+	response := UserAllResponse{
+		ID:         user.ID,
+		FullName:   user.FullName,
+		FullNameAr: user.FullNameAr,
+		FullNameEn: user.FullNameEn,
+		Email:      user.Email,
+		Role:       user.Role,
+		Likes:      user.Likes,
+		Dislikes:   user.Dislikes,
+		Image:      user.Image,
+		RegionId:   user.RegionId,
+	}
+	if user.BranchId != 0 {
+		branchID := user.BranchId
+		response.BranchId = &branchID
+	}
+	return response
+}
+
+// Localize replaces the display name with its variant in the given language, when one is set
+func (u *UserAllResponse) Localize(lang string) {
+	u.FullName = locale.Pick(lang, u.FullName, u.FullNameAr, u.FullNameEn)
 }
 
 // UserResponse omits the password when retrieving user data (e.g., all users or by ID)
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"api-server/models"
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+)
+
+// PostgresAnalyticsRepository aggregates the feedback history stored in PostgreSQL
+type PostgresAnalyticsRepository struct {
+	postgres
+}
+
+// NewPostgresAnalyticsRepository creates the repository on the given database
+func NewPostgresAnalyticsRepository(db *sql.DB, statementTimeout time.Duration) *PostgresAnalyticsRepository {
+	return &PostgresAnalyticsRepository{postgres{db: db, statementTimeout: statementTimeout}}
+}
+
+// GetBranchVoteHeatmap aggregates the feedback of a branch office into day-of-week/hour-of-day buckets
+// in the given timezone. When userID is set only the votes of that officer are counted.
+func (r *PostgresAnalyticsRepository) GetBranchVoteHeatmap(ctx context.Context, branchID uint, userID *uint, timezone string, from, to time.Time) ([7][24]models.HeatmapCell, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return r.queryVoteHeatmap(ctx, "branch_id = $1", branchID, userID, timezone, from, to)
+}
+
+// GetRegionVoteHeatmap aggregates the feedback of all branch offices of a region into day-of-week/hour-of-day buckets
+func (r *PostgresAnalyticsRepository) GetRegionVoteHeatmap(ctx context.Context, regionID uint, timezone string, from, to time.Time) ([7][24]models.HeatmapCell, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return r.queryVoteHeatmap(ctx, `branch_id IN (
+		SELECT bo.id FROM branch_offices bo JOIN cities c ON bo.city_id = c.id WHERE c.region_id = $1
+	)`, regionID, nil, timezone, from, to)
+}
+
+// queryVoteHeatmap aggregates user_feedback_history rows matching the scope condition (bound to $1).
+// Feedback timestamps are stored in UTC, the time zone of every session (see config.Database). Votes
+// flagged as received outside operating hours are excluded.
+func (r *PostgresAnalyticsRepository) queryVoteHeatmap(ctx context.Context, scope string, scopeID uint, userID *uint, timezone string, from, to time.Time) ([7][24]models.HeatmapCell, error) {
+	var cells [7][24]models.HeatmapCell
+
+	query := `
+		SELECT
+			EXTRACT(DOW FROM local_ts)::int AS day_of_week,
+			EXTRACT(HOUR FROM local_ts)::int AS hour_of_day,
+			COALESCE(SUM(likes), 0),
+			COALESCE(SUM(dislikes), 0)
+		FROM (
+			SELECT (createdAt AT TIME ZONE 'UTC') AT TIME ZONE $2 AS local_ts, likes, dislikes
+			FROM user_feedback_history
+			WHERE ` + scope + `
+				AND createdAt >= $3
+				AND createdAt < $4
+				AND ($5::int IS NULL OR user_id = $5)
+				AND NOT out_of_hours
+		) AS feedback
+		GROUP BY day_of_week, hour_of_day
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, scopeID, timezone, from.UTC(), to.UTC(), userID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error querying vote heatmap", "error", err)
+		return cells, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var day, hour int
+		var likes, dislikes uint
+		if err := rows.Scan(&day, &hour, &likes, &dislikes); err != nil {
+			slog.ErrorContext(ctx, "Error scanning vote heatmap", "error", err)
+			return cells, err
+		}
+
+		cell := &cells[day][hour]
+		cell.Likes = likes
+		cell.Dislikes = dislikes
+		cell.Total = likes + dislikes
+		cell.DislikeRatio = dislikeRatio(likes, dislikes)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.ErrorContext(ctx, "Error after iterating vote heatmap", "error", err)
+		return cells, err
+	}
+
+	return cells, nil
+}
+
+// dislikeRatio returns the share of dislikes among all votes, or 0 when there are no votes
+func dislikeRatio(likes uint, dislikes uint) float64 {
+	if likes+dislikes == 0 {
+		return 0
+	}
+	return float64(dislikes) / float64(likes+dislikes)
+}
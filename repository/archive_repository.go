@@ -0,0 +1,357 @@
+package repository
+
+import (
+	"api-server/apperror"
+	"api-server/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// PostgresArchiveRepository restores and purges the archived records stored in PostgreSQL
+type PostgresArchiveRepository struct {
+	postgres
+}
+
+// NewPostgresArchiveRepository creates the repository on the given database
+func NewPostgresArchiveRepository(db *sql.DB, statementTimeout time.Duration) *PostgresArchiveRepository {
+	return &PostgresArchiveRepository{postgres{db: db, statementTimeout: statementTimeout}}
+}
+
+// ErrNotArchived is returned when purging a branch office or user that has not been archived first
+var ErrNotArchived = apperror.Conflict("only archived records can be purged, archive it first")
+
+// ErrBranchOfficeArchived is returned when restoring a user whose branch office is still archived
+var ErrBranchOfficeArchived = apperror.Conflict("the user's branch office is archived, restore the branch office first")
+
+// queryArchivedEntities runs a query selecting id, name, detail and deleted_at of archived rows
+func (r *PostgresArchiveRepository) queryArchivedEntities(ctx context.Context, query string, args ...interface{}) ([]models.ArchivedEntity, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error querying archived records", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	entities := []models.ArchivedEntity{}
+	for rows.Next() {
+		var entity models.ArchivedEntity
+		if err := rows.Scan(&entity.ID, &entity.Name, &entity.Detail, &entity.DeletedAt); err != nil {
+			slog.ErrorContext(ctx, "Error scanning archived record", "error", err)
+			return nil, err
+		}
+		entities = append(entities, entity)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.ErrorContext(ctx, "Error after iterating archived records", "error", err)
+		return nil, err
+	}
+
+	return entities, nil
+}
+
+// GetArchivedBranchOffices retrieves the archived branch offices, most recently archived first
+func (r *PostgresArchiveRepository) GetArchivedBranchOffices(ctx context.Context) ([]models.ArchivedEntity, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return r.queryArchivedEntities(ctx, `
+		SELECT id, name, COALESCE(address, ''), deleted_at
+		FROM branch_offices
+		WHERE deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC, id ASC`)
+}
+
+// GetArchivedUsers retrieves the archived users, most recently archived first
+func (r *PostgresArchiveRepository) GetArchivedUsers(ctx context.Context) ([]models.ArchivedEntity, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return r.queryArchivedEntities(ctx, `
+		SELECT id, full_name, email, deleted_at
+		FROM users
+		WHERE deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC, id ASC`)
+}
+
+// RestoreBranchOffice brings an archived branch office back, together with the users archived along with it
+func (r *PostgresArchiveRepository) RestoreBranchOffice(ctx context.Context, id uint) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error starting transaction", "error", err)
+		return err
+	}
+	defer tx.Rollback() // Rollback in case of an error
+
+	var deletedAt time.Time
+	err = tx.QueryRowContext(ctx, "SELECT deleted_at FROM branch_offices WHERE id = $1 AND deleted_at IS NOT NULL FOR UPDATE", id).Scan(&deletedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return apperror.NotFound(fmt.Sprintf("no archived branch office found with the given ID: %d", id))
+		}
+		slog.ErrorContext(ctx, "Error locking archived branch office", "error", err)
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, "UPDATE branch_offices SET deleted_at = NULL WHERE id = $1", id); err != nil {
+		slog.ErrorContext(ctx, "Error restoring branch office", "error", err)
+		return err
+	}
+
+	var restoredOfficers int
+	err = tx.QueryRowContext(ctx, `
+		WITH restored AS (
+			UPDATE users SET deleted_at = NULL WHERE branch_id = $1 AND deleted_at = $2 RETURNING role
+		)
+		SELECT COUNT(*) FROM restored WHERE role = 'officer'`, id, deletedAt).Scan(&restoredOfficers)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error restoring branch office users", "error", err)
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, "UPDATE total_data SET total_officer = total_officer + $1 WHERE id = 1", restoredOfficers); err != nil {
+		slog.ErrorContext(ctx, "Error updating total_officer", "error", err)
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		slog.ErrorContext(ctx, "Error committing transaction", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// RestoreUser brings an archived user back. Restoring fails with a unique violation if another
+// active user took the email in the meantime.
+func (r *PostgresArchiveRepository) RestoreUser(ctx context.Context, id uint) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error starting transaction", "error", err)
+		return err
+	}
+	defer tx.Rollback() // Rollback in case of an error
+
+	var role string
+	var branchArchived bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT u.role, COALESCE(bo.deleted_at IS NOT NULL, false)
+		FROM users u
+		LEFT JOIN branch_offices bo ON u.branch_id = bo.id
+		WHERE u.id = $1 AND u.deleted_at IS NOT NULL
+		FOR UPDATE OF u`, id).Scan(&role, &branchArchived)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return apperror.NotFound(fmt.Sprintf("no archived user found with the given ID: %d", id))
+		}
+		slog.ErrorContext(ctx, "Error locking archived user", "error", err)
+		return err
+	}
+
+	if branchArchived {
+		return ErrBranchOfficeArchived
+	}
+
+	if _, err = tx.ExecContext(ctx, "UPDATE users SET deleted_at = NULL WHERE id = $1", id); err != nil {
+		slog.ErrorContext(ctx, "Error restoring user", "error", err)
+		return err
+	}
+
+	if role == "officer" {
+		if _, err = tx.ExecContext(ctx, "UPDATE total_data SET total_officer = total_officer + 1 WHERE id = 1"); err != nil {
+			slog.ErrorContext(ctx, "Error updating total_officer", "error", err)
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		slog.ErrorContext(ctx, "Error committing transaction", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// PurgeBranchOffice permanently deletes an archived branch office with its users, counters and feedback
+// history, after recording a snapshot in the purge audit log. It returns the image names of the purged users.
+func (r *PostgresArchiveRepository) PurgeBranchOffice(ctx context.Context, id uint, request *models.PurgeRequest) ([]string, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error starting transaction", "error", err)
+		return nil, err
+	}
+	defer tx.Rollback() // Rollback in case of an error
+
+	var name string
+	var archived bool
+	err = tx.QueryRowContext(ctx, "SELECT name, deleted_at IS NOT NULL FROM branch_offices WHERE id = $1 FOR UPDATE", id).Scan(&name, &archived)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperror.NotFound(fmt.Sprintf("no branch office found with the given ID: %d", id))
+		}
+		slog.ErrorContext(ctx, "Error locking branch office", "error", err)
+		return nil, err
+	}
+	if !archived {
+		return nil, ErrNotArchived
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO purge_audit_log (entity_type, entity_id, entity_name, reason, purged_by, feedback_rows, snapshot)
+		SELECT $1, bo.id, bo.name, $2, $3,
+			(SELECT COUNT(*) FROM user_feedback_history WHERE branch_id = bo.id),
+			jsonb_build_object(
+				'branch_office', to_jsonb(bo),
+				'users', COALESCE((SELECT jsonb_agg(to_jsonb(u) - 'password') FROM users u WHERE u.branch_id = bo.id), '[]'::jsonb)
+			)
+		FROM branch_offices bo
+		WHERE bo.id = $4`,
+		models.PurgeEntityBranchOffice, request.Reason, request.PurgedBy, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error writing purge audit log", "error", err)
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, "SELECT image FROM users WHERE branch_id = $1 AND image IS NOT NULL AND image != ''", id)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error querying branch office user images", "error", err)
+		return nil, err
+	}
+	images := []string{}
+	for rows.Next() {
+		var image string
+		if err := rows.Scan(&image); err != nil {
+			rows.Close()
+			slog.ErrorContext(ctx, "Error scanning user image", "error", err)
+			return nil, err
+		}
+		images = append(images, image)
+	}
+	rows.Close()
+
+	// Users, counters, shifts and feedback history of the branch office go with it (ON DELETE CASCADE)
+	if _, err = tx.ExecContext(ctx, "DELETE FROM branch_offices WHERE id = $1", id); err != nil {
+		slog.ErrorContext(ctx, "Error purging branch office", "error", err)
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		slog.ErrorContext(ctx, "Error committing transaction", "error", err)
+		return nil, err
+	}
+
+	return images, nil
+}
+
+// PurgeUser permanently deletes an archived user with their counters and feedback history, after
+// recording a snapshot in the purge audit log. It returns the image name of the purged user.
+func (r *PostgresArchiveRepository) PurgeUser(ctx context.Context, id uint, request *models.PurgeRequest) (string, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error starting transaction", "error", err)
+		return "", err
+	}
+	defer tx.Rollback() // Rollback in case of an error
+
+	var image string
+	var archived bool
+	err = tx.QueryRowContext(ctx, "SELECT COALESCE(image, ''), deleted_at IS NOT NULL FROM users WHERE id = $1 FOR UPDATE", id).Scan(&image, &archived)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", apperror.NotFound(fmt.Sprintf("no user found with the given ID: %d", id))
+		}
+		slog.ErrorContext(ctx, "Error locking user", "error", err)
+		return "", err
+	}
+	if !archived {
+		return "", ErrNotArchived
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO purge_audit_log (entity_type, entity_id, entity_name, reason, purged_by, feedback_rows, snapshot)
+		SELECT $1, u.id, u.full_name, $2, $3,
+			(SELECT COUNT(*) FROM user_feedback_history WHERE user_id = u.id),
+			jsonb_build_object('user', to_jsonb(u) - 'password')
+		FROM users u
+		WHERE u.id = $4`,
+		models.PurgeEntityUser, request.Reason, request.PurgedBy, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error writing purge audit log", "error", err)
+		return "", err
+	}
+
+	// Counters, shifts and feedback history of the user go with it (ON DELETE CASCADE)
+	if _, err = tx.ExecContext(ctx, "DELETE FROM users WHERE id = $1", id); err != nil {
+		slog.ErrorContext(ctx, "Error purging user", "error", err)
+		return "", err
+	}
+
+	if err = tx.Commit(); err != nil {
+		slog.ErrorContext(ctx, "Error committing transaction", "error", err)
+		return "", err
+	}
+
+	return image, nil
+}
+
+// GetPurgeAuditLog retrieves the purge audit log with pagination, most recent first
+func (r *PostgresArchiveRepository) GetPurgeAuditLog(ctx context.Context, limit, offset int) ([]models.PurgeAuditEntry, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, entity_type, entity_id, entity_name, reason, purged_by, feedback_rows, snapshot, purged_at
+		FROM purge_audit_log
+		ORDER BY purged_at DESC, id DESC
+		LIMIT $1 OFFSET $2`, limit, offset)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error querying purge audit log", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.PurgeAuditEntry{}
+	for rows.Next() {
+		var entry models.PurgeAuditEntry
+		var snapshot []byte
+		if err := rows.Scan(
+			&entry.ID,
+			&entry.EntityType,
+			&entry.EntityID,
+			&entry.EntityName,
+			&entry.Reason,
+			&entry.PurgedBy,
+			&entry.FeedbackRows,
+			&snapshot,
+			&entry.PurgedAt,
+		); err != nil {
+			slog.ErrorContext(ctx, "Error scanning purge audit log entry", "error", err)
+			return nil, err
+		}
+		entry.Snapshot = snapshot
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.ErrorContext(ctx, "Error after iterating purge audit log", "error", err)
+		return nil, err
+	}
+
+	return entries, nil
+}
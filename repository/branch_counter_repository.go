@@ -1,40 +1,128 @@
 package repository
 
 import (
-	"api-server/config"
+	"api-server/apperror"
 	"api-server/models"
-	"log"
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
 )
 
-// CreateBranchCounter creates a new branch counter
-func CreateBranchCounter(branchCounter *models.BranchCounter) error {
-	_, err := config.DB.Exec("INSERT INTO branch_counters (counter_location, user_id, branch_id) VALUES ($1, $2, $3)",
-		branchCounter.CounterLocation, branchCounter.UserID, branchCounter.BranchID)
+// PostgresBranchCounterRepository stores branch counters in PostgreSQL
+type PostgresBranchCounterRepository struct {
+	postgres
+}
+
+// NewPostgresBranchCounterRepository creates the repository on the given database
+func NewPostgresBranchCounterRepository(db *sql.DB, statementTimeout time.Duration) *PostgresBranchCounterRepository {
+	return &PostgresBranchCounterRepository{postgres{db: db, statementTimeout: statementTimeout}}
+}
+
+// Errors returned when a branch counter does not fit the capacity of its branch office
+var (
+	ErrBranchOfficeNotFound    = apperror.NotFound("branch office not found")
+	ErrCounterCapacityReached  = apperror.Conflict("all counters of this branch office are already in use")
+	ErrCounterNumberOutOfRange = apperror.InvalidField("counter_number", "counter_number must be between 1 and the total_counter of the branch office")
+	ErrCounterNumberTaken      = apperror.Conflict("counter_number is already used in this branch office")
+)
 
+// CreateBranchCounter creates a new branch counter within the capacity (total_counter) of its branch office.
+// When no counter number is given, the lowest free number is assigned.
+func (r *PostgresBranchCounterRepository) CreateBranchCounter(ctx context.Context, branchCounter *models.BranchCounter) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
-		log.Println("Error creating branch counter:", err)
+		slog.ErrorContext(ctx, "Error starting transaction", "error", err)
+		return err
+	}
+	defer tx.Rollback() // Rollback in case of an error
+
+	// Lock the branch office so concurrent requests cannot exceed its capacity
+	var totalCounter uint
+	err = tx.QueryRowContext(ctx, "SELECT total_counter FROM branch_offices WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", branchCounter.BranchID).Scan(&totalCounter)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrBranchOfficeNotFound
+		}
+		slog.ErrorContext(ctx, "Error locking branch office", "error", err)
+		return err
+	}
+
+	if branchCounter.CounterNumber == 0 {
+		err = tx.QueryRowContext(ctx, `
+			SELECT n FROM generate_series(1, $2::int) AS n
+			WHERE NOT EXISTS (
+				SELECT 1 FROM branch_counters WHERE branch_id = $1 AND counter_number = n AND retired_at IS NULL
+			)
+			ORDER BY n ASC
+			LIMIT 1`, branchCounter.BranchID, totalCounter).Scan(&branchCounter.CounterNumber)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrCounterCapacityReached
+			}
+			slog.ErrorContext(ctx, "Error finding a free counter number", "error", err)
+			return err
+		}
+	} else {
+		if branchCounter.CounterNumber > totalCounter {
+			return ErrCounterNumberOutOfRange
+		}
+
+		var taken bool
+		err = tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM branch_counters WHERE branch_id = $1 AND counter_number = $2 AND retired_at IS NULL)",
+			branchCounter.BranchID, branchCounter.CounterNumber).Scan(&taken)
+		if err != nil {
+			slog.ErrorContext(ctx, "Error checking counter number", "error", err)
+			return err
+		}
+		if taken {
+			return ErrCounterNumberTaken
+		}
+	}
+
+	err = tx.QueryRowContext(ctx, "INSERT INTO branch_counters (counter_location, counter_number, user_id, branch_id) VALUES ($1, $2, $3, $4) RETURNING id",
+		branchCounter.CounterLocation, branchCounter.CounterNumber, nullableID(branchCounter.UserID), branchCounter.BranchID).Scan(&branchCounter.ID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error creating branch counter", "error", err)
+		return err
+	}
+	branchCounter.IsActive = true
+
+	if err = tx.Commit(); err != nil {
+		slog.ErrorContext(ctx, "Error committing transaction", "error", err)
 		return err
 	}
 
 	return nil
 }
 
-// GetBranchCountersByBranchID retrieves branch counters by branch ID, including names from related tables
-func GetBranchCountersByBranchID(id uint) ([]models.BranchCounterWithNames, error) {
+// GetBranchCountersByBranchID retrieves branch counters by branch ID, including names from related tables.
+// Counters released by a transferred officer are listed with user_id 0.
+func (r *PostgresBranchCounterRepository) GetBranchCountersByBranchID(ctx context.Context, id uint) ([]models.BranchCounterWithNames, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	query := `
         SELECT 
             bc.id, 
             bc.counter_location, 
-            u.id AS user_id, 
-            u.full_name AS full_name, 
-            u.image AS image 
+            bc.counter_number, 
+            COALESCE(u.id, 0) AS user_id, 
+            COALESCE(u.full_name, '') AS full_name, 
+            u.full_name_ar,
+            u.full_name_en,
+            COALESCE(u.image, '') AS image 
         FROM branch_counters bc
         JOIN branch_offices bo ON bc.branch_id = bo.id
-        JOIN users u ON bc.user_id = u.id
-        WHERE bc.branch_id = $1
+        LEFT JOIN users u ON bc.user_id = u.id AND u.deleted_at IS NULL
+        WHERE bc.branch_id = $1 AND bc.is_active
+        ORDER BY bc.counter_number ASC
     `
 
-	rows, err := config.DB.Query(query, id) // Use Query instead of Exec for SELECT statements
+	rows, err := r.db.QueryContext(ctx, query, id) // Use Query instead of Exec for SELECT statements
 	if err != nil {
 		return nil, err
 	}
@@ -46,8 +134,11 @@ func GetBranchCountersByBranchID(id uint) ([]models.BranchCounterWithNames, erro
 		if err := rows.Scan(
 			&counter.ID,
 			&counter.CounterLocation,
+			&counter.CounterNumber,
 			&counter.UserId,
 			&counter.FullName,
+			&counter.FullNameAr,
+			&counter.FullNameEn,
 			&counter.Image,
 		); err != nil {
 			return nil, err
@@ -59,8 +150,293 @@ func GetBranchCountersByBranchID(id uint) ([]models.BranchCounterWithNames, erro
 	return counters, nil
 }
 
-// DeleteBranchCounter deletes a branch counter by ID
-func DeleteBranchCounter(id uint) error {
-	_, err := config.DB.Exec("DELETE FROM branch_counters WHERE id = $1", id)
-	return err
+// DeleteBranchCounter deletes a branch counter by ID. Its upcoming shifts are cancelled and the running one
+// ends now; a counter with past shifts is retired instead, keeping them, and its number can be used again.
+func (r *PostgresBranchCounterRepository) DeleteBranchCounter(ctx context.Context, id uint) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error starting transaction", "error", err)
+		return err
+	}
+	defer tx.Rollback() // Rollback in case of an error
+
+	// Lock the counter so that no shift is added meanwhile
+	if _, err = tx.ExecContext(ctx, "SELECT 1 FROM branch_counters WHERE id = $1 FOR UPDATE", id); err != nil {
+		slog.ErrorContext(ctx, "Error locking branch counter", "error", err)
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, "DELETE FROM counter_assignments WHERE counter_id = $1 AND starts_at >= now()", id); err != nil {
+		slog.ErrorContext(ctx, "Error cancelling the upcoming shifts of the counter", "error", err)
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, "UPDATE counter_assignments SET ends_at = now() WHERE counter_id = $1 AND ends_at > now()", id); err != nil {
+		slog.ErrorContext(ctx, "Error ending the running shift of the counter", "error", err)
+		return err
+	}
+
+	var hasShifts bool
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM counter_assignments WHERE counter_id = $1)", id).Scan(&hasShifts)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error checking the shifts of the counter", "error", err)
+		return err
+	}
+
+	if hasShifts {
+		_, err = tx.ExecContext(ctx, `UPDATE branch_counters
+			SET is_active = false, user_id = NULL, deactivated_by_capacity = false, retired_at = now()
+			WHERE id = $1`, id)
+	} else {
+		_, err = tx.ExecContext(ctx, "DELETE FROM branch_counters WHERE id = $1", id)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Error deleting branch counter", "error", err)
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		slog.ErrorContext(ctx, "Error committing transaction", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// GetBranchCounterByID retrieves a single branch counter, returning nil when it does not exist
+func (r *PostgresBranchCounterRepository) GetBranchCounterByID(ctx context.Context, id uint) (*models.BranchCounter, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var counter models.BranchCounter
+
+	row := r.db.QueryRowContext(ctx, "SELECT id, counter_location, counter_number, is_active, COALESCE(user_id, 0), branch_id FROM branch_counters WHERE id = $1", id)
+	err := row.Scan(&counter.ID, &counter.CounterLocation, &counter.CounterNumber, &counter.IsActive, &counter.UserID, &counter.BranchID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		slog.ErrorContext(ctx, "Error querying branch counter by ID", "error", err)
+		return nil, err
+	}
+
+	return &counter, nil
+}
+
+// GetBranchCounterIDByUserID returns the active counter currently assigned to an officer, or nil if none
+func (r *PostgresBranchCounterRepository) GetBranchCounterIDByUserID(ctx context.Context, userID uint) (*uint, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var counterID uint
+
+	err := r.db.QueryRowContext(ctx, "SELECT id FROM branch_counters WHERE user_id = $1 AND is_active ORDER BY id DESC LIMIT 1", userID).Scan(&counterID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		slog.ErrorContext(ctx, "Error querying branch counter by user ID", "error", err)
+		return nil, err
+	}
+
+	return &counterID, nil
+}
+
+// GetBranchCounterStatsByBranchID returns vote totals for every counter of a branch within [from, to),
+// excluding votes received outside operating hours. Counters retired before the period are left out.
+func (r *PostgresBranchCounterRepository) GetBranchCounterStatsByBranchID(ctx context.Context, branchID uint, from time.Time, to time.Time) ([]models.BranchCounterStats, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT
+			bc.id,
+			bc.counter_number,
+			bc.counter_location,
+			bc.branch_id,
+			COALESCE(SUM(f.likes), 0),
+			COALESCE(SUM(f.dislikes), 0)
+		FROM branch_counters bc
+		LEFT JOIN user_feedback_history f
+			ON f.counter_id = bc.id AND f.createdAt >= $2 AND f.createdAt < $3 AND NOT f.out_of_hours
+		WHERE bc.branch_id = $1 AND (bc.retired_at IS NULL OR bc.retired_at > $2)
+		GROUP BY bc.id, bc.counter_number, bc.counter_location, bc.branch_id
+		ORDER BY bc.counter_number ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, branchID, from.UTC(), to.UTC())
+	if err != nil {
+		slog.ErrorContext(ctx, "Error querying branch counter stats", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := []models.BranchCounterStats{}
+	for rows.Next() {
+		var stat models.BranchCounterStats
+		if err := rows.Scan(&stat.CounterID, &stat.CounterNumber, &stat.CounterLocation, &stat.BranchID, &stat.Likes, &stat.Dislikes); err != nil {
+			slog.ErrorContext(ctx, "Error scanning branch counter stats", "error", err)
+			return nil, err
+		}
+		stat.Total = stat.Likes + stat.Dislikes
+		stat.DislikeRatio = dislikeRatio(stat.Likes, stat.Dislikes)
+		stats = append(stats, stat)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.ErrorContext(ctx, "Error after iterating branch counter stats", "error", err)
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// GetCounterOfficerStats returns, per officer, the votes received at a counter within [from, to),
+// excluding votes received outside operating hours. The officers are those with a shift overlapping the
+// period, the one linked to the counter and those who received votes there, with or without votes.
+func (r *PostgresBranchCounterRepository) GetCounterOfficerStats(ctx context.Context, counterID uint, from time.Time, to time.Time) ([]models.CounterOfficerStats, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		WITH votes AS (
+			SELECT
+				f.user_id,
+				MAX(f.officer_name) AS officer_name,
+				SUM(f.likes) AS likes,
+				SUM(f.dislikes) AS dislikes,
+				MIN(f.createdAt) AS first_vote_at,
+				MAX(f.createdAt) AS last_vote_at
+			FROM user_feedback_history f
+			WHERE f.counter_id = $1 AND f.createdAt >= $2 AND f.createdAt < $3 AND NOT f.out_of_hours
+			GROUP BY f.user_id
+		), officers AS (
+			SELECT ca.user_id FROM counter_assignments ca
+			WHERE ca.counter_id = $1 AND ca.starts_at < $3 AND ca.ends_at > $2
+			UNION
+			SELECT bc.user_id FROM branch_counters bc WHERE bc.id = $1 AND bc.user_id IS NOT NULL
+			UNION
+			SELECT user_id FROM votes
+		)
+		SELECT
+			o.user_id,
+			COALESCE(u.full_name, v.officer_name, ''),
+			COALESCE(v.likes, 0),
+			COALESCE(v.dislikes, 0),
+			v.first_vote_at,
+			v.last_vote_at
+		FROM officers o
+		LEFT JOIN votes v ON v.user_id = o.user_id
+		LEFT JOIN users u ON u.id = o.user_id
+		ORDER BY v.first_vote_at ASC NULLS LAST, o.user_id ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, counterID, from.UTC(), to.UTC())
+	if err != nil {
+		slog.ErrorContext(ctx, "Error querying counter officer stats", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	officers := []models.CounterOfficerStats{}
+	for rows.Next() {
+		var officer models.CounterOfficerStats
+		if err := rows.Scan(&officer.UserID, &officer.FullName, &officer.Likes, &officer.Dislikes, &officer.FirstVoteAt, &officer.LastVoteAt); err != nil {
+			slog.ErrorContext(ctx, "Error scanning counter officer stats", "error", err)
+			return nil, err
+		}
+		officer.Total = officer.Likes + officer.Dislikes
+		officers = append(officers, officer)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.ErrorContext(ctx, "Error after iterating counter officer stats", "error", err)
+		return nil, err
+	}
+
+	return officers, nil
+}
+
+// GetCounterSlotsByBranchID maps the numbered slots 1..total_counter of a branch office to their counters.
+// Inactive counters numbered above the current capacity are appended with status "inactive".
+func (r *PostgresBranchCounterRepository) GetCounterSlotsByBranchID(ctx context.Context, branchID uint, totalCounter uint) ([]models.CounterSlot, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT
+			slot.n,
+			bc.id,
+			bc.counter_location,
+			bc.is_active,
+			u.id,
+			u.full_name
+		FROM generate_series(1, GREATEST($2::int, (
+			SELECT COALESCE(MAX(counter_number), 0) FROM branch_counters WHERE branch_id = $1 AND retired_at IS NULL
+		))) AS slot(n)
+		LEFT JOIN branch_counters bc ON bc.branch_id = $1 AND bc.counter_number = slot.n AND bc.retired_at IS NULL
+		LEFT JOIN users u ON bc.user_id = u.id AND u.deleted_at IS NULL
+		ORDER BY slot.n ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, branchID, totalCounter)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error querying counter slots", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	slots := []models.CounterSlot{}
+	for rows.Next() {
+		var (
+			slot            models.CounterSlot
+			counterID       sql.NullInt64
+			counterLocation sql.NullString
+			isActive        sql.NullBool
+			userID          sql.NullInt64
+			fullName        sql.NullString
+		)
+		if err := rows.Scan(&slot.CounterNumber, &counterID, &counterLocation, &isActive, &userID, &fullName); err != nil {
+			slog.ErrorContext(ctx, "Error scanning counter slot", "error", err)
+			return nil, err
+		}
+
+		switch {
+		case !counterID.Valid:
+			// Only slots within capacity are reported as free
+			if slot.CounterNumber > totalCounter {
+				continue
+			}
+			slot.Status = "free"
+		case !isActive.Bool:
+			slot.Status = "inactive"
+		case !userID.Valid:
+			// The counter exists but its officer was transferred away or archived
+			slot.Status = "vacant"
+		default:
+			slot.Status = "occupied"
+		}
+
+		if counterID.Valid {
+			id := uint(counterID.Int64)
+			slot.CounterID = &id
+			slot.CounterLocation = counterLocation.String
+		}
+		if userID.Valid {
+			id := uint(userID.Int64)
+			slot.UserID = &id
+			slot.FullName = fullName.String
+		}
+
+		slots = append(slots, slot)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.ErrorContext(ctx, "Error after iterating counter slots", "error", err)
+		return nil, err
+	}
+
+	return slots, nil
 }
@@ -1,28 +1,81 @@
 package repository
 
 import (
-	"api-server/config"
+	"api-server/apperror"
 	"api-server/models"
+	"context"
+	"database/sql"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"time"
 )
 
+// PostgresBranchOfficeRepository stores branch offices in PostgreSQL
+type PostgresBranchOfficeRepository struct {
+	postgres
+}
+
+// NewPostgresBranchOfficeRepository creates the repository on the given database
+func NewPostgresBranchOfficeRepository(db *sql.DB, statementTimeout time.Duration) *PostgresBranchOfficeRepository {
+	return &PostgresBranchOfficeRepository{postgres{db: db, statementTimeout: statementTimeout}}
+}
+
+// branchOfficeColumns selects a branch office together with its city and region
+const branchOfficeColumns = `
+	bo.id, bo.name, bo.address, bo.name_ar, bo.name_en, bo.address_ar, bo.address_en, bo.total_counter, bo.timezone,
+	bo.city_id, COALESCE(c.name, ''), c.region_id, COALESCE(r.name, ''),
+	bo.out_of_hours_vote_policy, bo.latitude, bo.longitude
+	FROM branch_offices bo
+	LEFT JOIN cities c ON bo.city_id = c.id
+	LEFT JOIN regions r ON c.region_id = r.id
+`
+
+// scanner is implemented by both *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanBranchOffice scans a row selected with branchOfficeColumns
+func scanBranchOffice(row scanner, branchOffice *models.BranchOfficeResponse) error {
+	return row.Scan(
+		&branchOffice.ID,
+		&branchOffice.Name,
+		&branchOffice.Address,
+		&branchOffice.NameAr,
+		&branchOffice.NameEn,
+		&branchOffice.AddressAr,
+		&branchOffice.AddressEn,
+		&branchOffice.TotalCounter,
+		&branchOffice.Timezone,
+		&branchOffice.CityID,
+		&branchOffice.CityName,
+		&branchOffice.RegionID,
+		&branchOffice.RegionName,
+		&branchOffice.OutOfHoursPolicy,
+		&branchOffice.Latitude,
+		&branchOffice.Longitude,
+	)
+}
+
 // GetAllBranchOffices retrieves all branch offices with pagination
-func GetAllBranchOffices(limit, offset int) ([]models.BranchOfficeResponse, error) {
+func (r *PostgresBranchOfficeRepository) GetAllBranchOffices(ctx context.Context, limit, offset int) ([]models.BranchOfficeResponse, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	var branchOffices []models.BranchOfficeResponse
 
-	rows, err := config.DB.Query("SELECT id, name, address, total_counter FROM branch_offices ORDER BY id ASC LIMIT $1 OFFSET $2", limit, offset)
+	rows, err := r.db.QueryContext(ctx, "SELECT "+branchOfficeColumns+" WHERE bo.deleted_at IS NULL ORDER BY bo.id ASC LIMIT $1 OFFSET $2", limit, offset)
 	if err != nil {
-		log.Println("Error querying branch offices:", err)
+		slog.ErrorContext(ctx, "Error querying branch offices", "error", err)
 		return nil, err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
 		var branchOffice models.BranchOfficeResponse
-		if err := rows.Scan(&branchOffice.ID, &branchOffice.Name, &branchOffice.Address, &branchOffice.TotalCounter); err != nil {
-			log.Println("Error scanning branch office:", err)
+		if err := scanBranchOffice(rows, &branchOffice); err != nil {
+			slog.ErrorContext(ctx, "Error scanning branch office", "error", err)
 			return nil, err
 		}
 		branchOffices = append(branchOffices, branchOffice)
@@ -31,114 +84,300 @@ func GetAllBranchOffices(limit, offset int) ([]models.BranchOfficeResponse, erro
 	return branchOffices, nil
 }
 
-func GetAllBranchOfficesOption() ([]models.BranchOfficeOptionResponse, error) {
+func (r *PostgresBranchOfficeRepository) GetAllBranchOfficesOption(ctx context.Context) ([]models.BranchOfficeOptionResponse, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	var branchOffices []models.BranchOfficeOptionResponse
 
-	rows, err := config.DB.Query("SELECT id, name FROM branch_offices ORDER BY id ASC")
+	rows, err := r.db.QueryContext(ctx, "SELECT id, name, name_ar, name_en, latitude, longitude FROM branch_offices WHERE deleted_at IS NULL ORDER BY id ASC")
 	if err != nil {
-		log.Println("Error querying branch offices:", err)
+		slog.ErrorContext(ctx, "Error querying branch offices", "error", err)
 		return nil, err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
 		var branchOffice models.BranchOfficeOptionResponse
-		if err := rows.Scan(&branchOffice.ID, &branchOffice.Name); err != nil {
-			log.Println("Error scanning branch office:", err)
+		if err := rows.Scan(&branchOffice.ID, &branchOffice.Name, &branchOffice.NameAr, &branchOffice.NameEn, &branchOffice.Latitude, &branchOffice.Longitude); err != nil {
+			slog.ErrorContext(ctx, "Error scanning branch office", "error", err)
+			return nil, err
+		}
+		branchOffices = append(branchOffices, branchOffice)
+	}
+
+	return branchOffices, nil
+}
+
+// GetLocatedBranchOffices retrieves the branch offices that have coordinates, with their vote totals.
+// The distance is left for the caller to compute.
+func (r *PostgresBranchOfficeRepository) GetLocatedBranchOffices(ctx context.Context) ([]models.NearbyBranchOffice, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT
+			bo.id, bo.name, COALESCE(bo.address, ''), bo.name_ar, bo.name_en, bo.address_ar, bo.address_en,
+			bo.latitude, bo.longitude,
+			COALESCE(SUM(tdb.total_likes), 0), COALESCE(SUM(tdb.total_dislikes), 0)
+		FROM branch_offices bo
+		LEFT JOIN total_data_branch tdb ON tdb.branch_id = bo.id
+		WHERE bo.latitude IS NOT NULL AND bo.longitude IS NOT NULL AND bo.deleted_at IS NULL
+		GROUP BY bo.id
+		ORDER BY bo.id ASC`)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error querying located branch offices", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	branchOffices := []models.NearbyBranchOffice{}
+	for rows.Next() {
+		var branchOffice models.NearbyBranchOffice
+		if err := rows.Scan(
+			&branchOffice.ID,
+			&branchOffice.Name,
+			&branchOffice.Address,
+			&branchOffice.NameAr,
+			&branchOffice.NameEn,
+			&branchOffice.AddressAr,
+			&branchOffice.AddressEn,
+			&branchOffice.Latitude,
+			&branchOffice.Longitude,
+			&branchOffice.TotalLikes,
+			&branchOffice.TotalDislikes,
+		); err != nil {
+			slog.ErrorContext(ctx, "Error scanning located branch office", "error", err)
 			return nil, err
 		}
 		branchOffices = append(branchOffices, branchOffice)
 	}
 
+	if err := rows.Err(); err != nil {
+		slog.ErrorContext(ctx, "Error after iterating located branch offices", "error", err)
+		return nil, err
+	}
+
 	return branchOffices, nil
 }
 
 // GetBranchOfficesCount retrieves the total number of branch offices
-func GetBranchOfficesCount() (int, error) {
+func (r *PostgresBranchOfficeRepository) GetBranchOfficesCount(ctx context.Context) (int, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	var count int
-	row := config.DB.QueryRow("SELECT COUNT(*) FROM branch_offices")
+	row := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM branch_offices WHERE deleted_at IS NULL")
 	err := row.Scan(&count)
 	if err != nil {
-		log.Println("Error querying branch offices count:", err)
+		slog.ErrorContext(ctx, "Error querying branch offices count", "error", err)
 		return 0, err
 	}
 	return count, nil
 }
 
 // GetBranchOfficesById retrieves a branch office by its ID
-func GetBranchOfficesById(id uint) (*models.BranchOfficeResponse, error) {
+func (r *PostgresBranchOfficeRepository) GetBranchOfficesById(ctx context.Context, id uint) (*models.BranchOfficeResponse, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	var branchOffice models.BranchOfficeResponse
 
-	row := config.DB.QueryRow("SELECT id, name, address, total_counter FROM branch_offices WHERE id = $1", id)
-	err := row.Scan(&branchOffice.ID, &branchOffice.Name, &branchOffice.Address, &branchOffice.TotalCounter)
+	row := r.db.QueryRowContext(ctx, "SELECT "+branchOfficeColumns+" WHERE bo.id = $1 AND bo.deleted_at IS NULL", id)
+	err := scanBranchOffice(row, &branchOffice)
 	if err != nil {
-		log.Println("Error querying branch office by ID:", err)
-		return nil, errors.New("branch office not found")
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrBranchOfficeNotFound
+		}
+		slog.ErrorContext(ctx, "Error querying branch office by ID", "error", err)
+		return nil, err
 	}
 
 	return &branchOffice, nil
 }
 
 // CreateBranchOffice creates a new branch office and returns its ID.
-func CreateBranchOffice(branchOffice *models.BranchOfficeCreateRequest) error {
+func (r *PostgresBranchOfficeRepository) CreateBranchOffice(ctx context.Context, branchOffice *models.BranchOfficeCreateRequest) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return r.CreateBranchOffices(ctx, []models.BranchOfficeCreateRequest{*branchOffice})
+}
+
+// CreateBranchOffices creates several branch offices in one transaction, all or none. Like CreateUsers, only
+// each statement is bounded by the statement timeout, not the whole call.
+func (r *PostgresBranchOfficeRepository) CreateBranchOffices(ctx context.Context, branchOffices []models.BranchOfficeCreateRequest) error {
 	// Begin a new transaction
-	tx, err := config.DB.Begin()
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback() // Rollback in case of an error
 
+	for i := range branchOffices {
+		if err = insertBranchOffice(ctx, tx, &branchOffices[i]); err != nil {
+			return err
+		}
+	}
+
+	// Commit the transaction if everything is successful
+	if err = tx.Commit(); err != nil {
+		slog.ErrorContext(ctx, "Error committing transaction", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// insertBranchOffice inserts a branch office and its dashboard totals within a transaction
+func insertBranchOffice(ctx context.Context, tx *sql.Tx, branchOffice *models.BranchOfficeCreateRequest) error {
 	var branchID int
 	// Insert the branch office and retrieve the generated ID
-	err = tx.QueryRow(
-		"INSERT INTO branch_offices (name, address, total_counter) VALUES ($1, $2, $3) RETURNING id",
-		branchOffice.Name, branchOffice.Address, branchOffice.TotalCounter,
+	err := tx.QueryRowContext(ctx,
+		`INSERT INTO branch_offices (name, address, name_ar, name_en, address_ar, address_en, total_counter, timezone, city_id, latitude, longitude)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id`,
+		branchOffice.Name, branchOffice.Address, branchOffice.NameAr, branchOffice.NameEn, branchOffice.AddressAr, branchOffice.AddressEn,
+		branchOffice.TotalCounter, branchOffice.Timezone, branchOffice.CityID, branchOffice.Latitude, branchOffice.Longitude,
 	).Scan(&branchID)
 	if err != nil {
-		log.Println("Error creating branch office:", err)
+		slog.ErrorContext(ctx, "Error creating branch office", "error", err)
 		return err
 	}
 
 	// Insert into total_data_branch using the generated branch ID
-	_, err = tx.Exec(
+	_, err = tx.ExecContext(ctx,
 		"INSERT INTO total_data_branch (name_office, total_likes, total_dislikes, branch_id) VALUES ($1, $2, $3, $4)",
 		branchOffice.Name, 0, 0, branchID,
 	)
 	if err != nil {
-		log.Println("Error creating total data for branch office:", err)
+		slog.ErrorContext(ctx, "Error creating total data for branch office", "error", err)
 		return err
 	}
 
-	// Commit the transaction if everything is successful
-	if err = tx.Commit(); err != nil {
-		log.Println("Error committing transaction:", err)
+	return nil
+}
+
+// ErrCountersAboveCapacity is returned when total_counter shrinks below the highest used counter number
+// and the reject policy applies
+var ErrCountersAboveCapacity = apperror.Conflict("the branch office has active counters numbered above the new total_counter, retry with counter_policy=deactivate to deactivate them")
+
+// UpdateBranchOffices updates an existing branch office by ID. When total_counter shrinks, active counters
+// numbered above the new total are either rejected or deactivated depending on the counter policy.
+// Counters deactivated that way are reactivated once they fit the total again.
+func (r *PostgresBranchOfficeRepository) UpdateBranchOffices(ctx context.Context, id uint, branchOffice *models.BranchOfficeCreateRequest, counterPolicy string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error starting transaction", "error", err)
 		return err
 	}
+	defer tx.Rollback() // Rollback in case of an error
 
-	return nil // Return the branch ID on success
-}
+	// Lock the branch office so no counter is created while the capacity changes
+	var exists bool
+	err = tx.QueryRowContext(ctx, "SELECT true FROM branch_offices WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", id).Scan(&exists)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return apperror.NotFound(fmt.Sprintf("no branch office found with the given ID: %d", id))
+		}
+		slog.ErrorContext(ctx, "Error locking branch office", "error", err)
+		return err
+	}
+
+	var countersAbove int
+	err = tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM branch_counters WHERE branch_id = $1 AND counter_number > $2 AND is_active",
+		id, branchOffice.TotalCounter).Scan(&countersAbove)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error counting counters above capacity", "error", err)
+		return err
+	}
+
+	if countersAbove > 0 {
+		if counterPolicy != models.CounterPolicyDeactivate {
+			return ErrCountersAboveCapacity
+		}
+
+		_, err = tx.ExecContext(ctx, "UPDATE branch_counters SET is_active = false, deactivated_by_capacity = true WHERE branch_id = $1 AND counter_number > $2 AND is_active",
+			id, branchOffice.TotalCounter)
+		if err != nil {
+			slog.ErrorContext(ctx, "Error deactivating counters", "error", err)
+			return err
+		}
+	}
+
+	// Counters that fit the capacity again are brought back into service, unless deactivated for another reason
+	_, err = tx.ExecContext(ctx, "UPDATE branch_counters SET is_active = true, deactivated_by_capacity = false WHERE branch_id = $1 AND counter_number <= $2 AND deactivated_by_capacity",
+		id, branchOffice.TotalCounter)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error reactivating counters", "error", err)
+		return err
+	}
 
-// UpdateBranchOffices updates an existing branch office by ID
-func UpdateBranchOffices(id uint, branchOffice *models.BranchOfficeCreateRequest) error {
-	_, err := config.DB.Exec("UPDATE branch_offices SET name = $1, address = $2, total_counter = $3 WHERE id = $4",
-		branchOffice.Name, branchOffice.Address, branchOffice.TotalCounter, id)
+	_, err = tx.ExecContext(ctx, `
+		UPDATE branch_offices
+		SET name = $1, address = $2, name_ar = $3, name_en = $4, address_ar = $5, address_en = $6,
+			total_counter = $7, timezone = $8, city_id = $9, latitude = $10, longitude = $11
+		WHERE id = $12`,
+		branchOffice.Name, branchOffice.Address, branchOffice.NameAr, branchOffice.NameEn, branchOffice.AddressAr, branchOffice.AddressEn,
+		branchOffice.TotalCounter, branchOffice.Timezone, branchOffice.CityID, branchOffice.Latitude, branchOffice.Longitude, id)
 	if err != nil {
-		log.Println("Error updating branch office:", err)
+		slog.ErrorContext(ctx, "Error updating branch office", "error", err)
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		slog.ErrorContext(ctx, "Error committing transaction", "error", err)
 		return err
 	}
+
 	return nil
 }
 
-// DeleteBranchOffices deletes a branch office by ID
-func DeleteBranchOffices(id uint) error {
-	result, err := config.DB.Exec("DELETE FROM branch_offices WHERE id = $1", id)
+// DeleteBranchOffices archives a branch office by ID together with its active users. The rows and their
+// feedback history are kept for reporting until the branch office is restored or purged.
+func (r *PostgresBranchOfficeRepository) DeleteBranchOffices(ctx context.Context, id uint) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error starting transaction", "error", err)
+		return err
+	}
+	defer tx.Rollback() // Rollback in case of an error
+
+	var deletedAt time.Time
+	err = tx.QueryRowContext(ctx, "UPDATE branch_offices SET deleted_at = now() WHERE id = $1 AND deleted_at IS NULL RETURNING deleted_at", id).Scan(&deletedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return apperror.NotFound(fmt.Sprintf("no branch office found with the given ID: %d", id))
+		}
+		slog.ErrorContext(ctx, "Error archiving branch office", "error", err)
+		return err
+	}
+
+	// Users archived with the same timestamp are restored together with the branch office
+	var archivedOfficers int
+	err = tx.QueryRowContext(ctx, `
+		WITH archived AS (
+			UPDATE users SET deleted_at = $1 WHERE branch_id = $2 AND deleted_at IS NULL RETURNING role
+		)
+		SELECT COUNT(*) FROM archived WHERE role = 'officer'`, deletedAt, id).Scan(&archivedOfficers)
 	if err != nil {
-		log.Println("Error deleting branch office:", err)
+		slog.ErrorContext(ctx, "Error archiving branch office users", "error", err)
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, "UPDATE total_data SET total_officer = total_officer - $1 WHERE id = 1", archivedOfficers); err != nil {
+		slog.ErrorContext(ctx, "Error updating total_officer", "error", err)
 		return err
 	}
 
-	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
-		return fmt.Errorf("no branch office found with the given ID: %d", id)
+	if err = tx.Commit(); err != nil {
+		slog.ErrorContext(ctx, "Error committing transaction", "error", err)
+		return err
 	}
 
 	return nil
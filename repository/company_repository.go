@@ -1,19 +1,33 @@
 package repository
 
 import (
-	"api-server/config"
 	"api-server/models"
+	"context"
 	"database/sql"
-	"log"
+	"log/slog"
+	"time"
 )
 
+// PostgresCompanyRepository stores the company profile in PostgreSQL
+type PostgresCompanyRepository struct {
+	postgres
+}
+
+// NewPostgresCompanyRepository creates the repository on the given database
+func NewPostgresCompanyRepository(db *sql.DB, statementTimeout time.Duration) *PostgresCompanyRepository {
+	return &PostgresCompanyRepository{postgres{db: db, statementTimeout: statementTimeout}}
+}
+
 // GetCompanyProfile fetches the company profile from the database
-func GetCompanyProfile() (*models.CompanyProfile, error) {
+func (r *PostgresCompanyRepository) GetCompanyProfile(ctx context.Context) (*models.CompanyProfile, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	var company models.CompanyProfile
 
 	// Query to select the company profile with a specific ID (1 in this case)
-	row := config.DB.QueryRow(`SELECT name, logo FROM company_profiles WHERE id = $1`, 1)
-	err := row.Scan(&company.Name, &company.Logo)
+	row := r.db.QueryRowContext(ctx, `SELECT name, name_ar, name_en, logo FROM company_profiles WHERE id = $1`, 1)
+	err := row.Scan(&company.Name, &company.NameAr, &company.NameEn, &company.Logo)
 	// Check for errors during the scan
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -21,7 +35,7 @@ func GetCompanyProfile() (*models.CompanyProfile, error) {
 			return nil, nil
 		}
 		// Log any other errors encountered during scanning
-		log.Println("Error scanning company profile:", err)
+		slog.ErrorContext(ctx, "Error scanning company profile", "error", err)
 		return nil, err // Return the error
 	}
 
@@ -29,24 +43,26 @@ func GetCompanyProfile() (*models.CompanyProfile, error) {
 	return &company, nil
 }
 
-// UpdateCompanyProfile updates the company profile in the database
-func UpdateCompanyProfile(id uint, name string, logo string) error {
+// UpdateCompanyProfile updates the company profile in the database, keeping the logo when none is given
+func (r *PostgresCompanyRepository) UpdateCompanyProfile(ctx context.Context, id uint, company *models.CompanyProfile) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
 
 	// Prepare the SQL query
 	var query string
 
-	if logo != "" {
-		query = "UPDATE company_profiles SET name = $1, logo = $2 WHERE id = $3"
-		_, err := config.DB.Exec(query, name, logo, id)
+	if company.Logo != "" {
+		query = "UPDATE company_profiles SET name = $1, name_ar = $2, name_en = $3, logo = $4 WHERE id = $5"
+		_, err := r.db.ExecContext(ctx, query, company.Name, company.NameAr, company.NameEn, company.Logo, id)
 		if err != nil {
-			log.Println("Error updating user:", err)
+			slog.ErrorContext(ctx, "Error updating company profile", "error", err)
 			return err
 		}
 	} else {
-		query = "UPDATE company_profiles SET name = $1, WHERE id = $2"
-		_, err := config.DB.Exec(query, name, id)
+		query = "UPDATE company_profiles SET name = $1, name_ar = $2, name_en = $3 WHERE id = $4"
+		_, err := r.db.ExecContext(ctx, query, company.Name, company.NameAr, company.NameEn, id)
 		if err != nil {
-			log.Println("Error updating user:", err)
+			slog.ErrorContext(ctx, "Error updating company profile", "error", err)
 			return err
 		}
 	}
@@ -0,0 +1,207 @@
+package repository
+
+import (
+	"api-server/apperror"
+	"api-server/models"
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// PostgresCounterAssignmentRepository stores counter assignments in PostgreSQL
+type PostgresCounterAssignmentRepository struct {
+	postgres
+}
+
+// NewPostgresCounterAssignmentRepository creates the repository on the given database
+func NewPostgresCounterAssignmentRepository(db *sql.DB, statementTimeout time.Duration) *PostgresCounterAssignmentRepository {
+	return &PostgresCounterAssignmentRepository{postgres{db: db, statementTimeout: statementTimeout}}
+}
+
+const counterAssignmentDetailColumns = `
+	ca.id,
+	ca.counter_id,
+	bc.counter_location,
+	ca.user_id,
+	u.full_name,
+	ca.branch_id,
+	ca.starts_at,
+	ca.ends_at
+`
+
+const counterAssignmentDetailJoins = `
+	FROM counter_assignments ca
+	JOIN branch_counters bc ON ca.counter_id = bc.id
+	JOIN users u ON ca.user_id = u.id AND u.deleted_at IS NULL
+`
+
+// queryer is implemented by both *sql.DB and *sql.Tx
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// queryCounterAssignmentDetails runs a counter assignment query and scans the detailed rows
+func queryCounterAssignmentDetails(ctx context.Context, q queryer, query string, args ...interface{}) ([]models.CounterAssignmentDetail, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error querying counter assignments", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	assignments := []models.CounterAssignmentDetail{}
+	for rows.Next() {
+		var assignment models.CounterAssignmentDetail
+		if err := rows.Scan(
+			&assignment.ID,
+			&assignment.CounterID,
+			&assignment.CounterLocation,
+			&assignment.UserID,
+			&assignment.FullName,
+			&assignment.BranchID,
+			&assignment.StartsAt,
+			&assignment.EndsAt,
+		); err != nil {
+			slog.ErrorContext(ctx, "Error scanning counter assignment", "error", err)
+			return nil, err
+		}
+		assignments = append(assignments, assignment)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.ErrorContext(ctx, "Error after iterating counter assignments", "error", err)
+		return nil, err
+	}
+
+	return assignments, nil
+}
+
+// CreateCounterAssignment inserts a shift unless it overlaps another shift of the same officer or
+// on the same counter. Overlapping shifts are returned instead and nothing is inserted.
+func (r *PostgresCounterAssignmentRepository) CreateCounterAssignment(ctx context.Context, assignment *models.CounterAssignment) ([]models.CounterAssignmentDetail, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error starting transaction", "error", err)
+		return nil, err
+	}
+	defer tx.Rollback() // Rollback in case of an error
+
+	// Serialize scheduling per branch so two concurrent requests cannot both pass the conflict check
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", int64(assignment.BranchID)); err != nil {
+		slog.ErrorContext(ctx, "Error locking branch roster", "error", err)
+		return nil, err
+	}
+
+	conflicts, err := queryCounterAssignmentDetails(ctx, tx, `SELECT `+counterAssignmentDetailColumns+counterAssignmentDetailJoins+`
+		WHERE (ca.user_id = $1 OR ca.counter_id = $2)
+			AND ca.starts_at < $4
+			AND ca.ends_at > $3
+		ORDER BY ca.starts_at ASC`,
+		assignment.UserID, assignment.CounterID, assignment.StartsAt, assignment.EndsAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(conflicts) > 0 {
+		return conflicts, nil
+	}
+
+	err = tx.QueryRowContext(ctx,
+		"INSERT INTO counter_assignments (counter_id, user_id, branch_id, starts_at, ends_at) VALUES ($1, $2, $3, $4, $5) RETURNING id",
+		assignment.CounterID, assignment.UserID, assignment.BranchID, assignment.StartsAt, assignment.EndsAt,
+	).Scan(&assignment.ID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error creating counter assignment", "error", err)
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		slog.ErrorContext(ctx, "Error committing transaction", "error", err)
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// GetCounterAssignmentsByBranchID retrieves the shifts of a branch overlapping [from, to)
+func (r *PostgresCounterAssignmentRepository) GetCounterAssignmentsByBranchID(ctx context.Context, branchID uint, from time.Time, to time.Time) ([]models.CounterAssignmentDetail, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return queryCounterAssignmentDetails(ctx, r.db, `SELECT `+counterAssignmentDetailColumns+counterAssignmentDetailJoins+`
+		WHERE ca.branch_id = $1 AND ca.starts_at < $3 AND ca.ends_at > $2
+		ORDER BY ca.starts_at ASC, ca.counter_id ASC`,
+		branchID, from, to,
+	)
+}
+
+// GetCounterAssignmentsByCounterID retrieves the shifts on a counter overlapping [from, to)
+func (r *PostgresCounterAssignmentRepository) GetCounterAssignmentsByCounterID(ctx context.Context, counterID uint, from time.Time, to time.Time) ([]models.CounterAssignmentDetail, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return queryCounterAssignmentDetails(ctx, r.db, `SELECT `+counterAssignmentDetailColumns+counterAssignmentDetailJoins+`
+		WHERE ca.counter_id = $1 AND ca.starts_at < $3 AND ca.ends_at > $2
+		ORDER BY ca.starts_at ASC`,
+		counterID, from, to,
+	)
+}
+
+// GetActiveAssignmentByCounterID returns the shift running on a counter at the given moment, or nil
+func (r *PostgresCounterAssignmentRepository) GetActiveAssignmentByCounterID(ctx context.Context, counterID uint, at time.Time) (*models.CounterAssignmentDetail, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	assignments, err := queryCounterAssignmentDetails(ctx, r.db, `SELECT `+counterAssignmentDetailColumns+counterAssignmentDetailJoins+`
+		WHERE ca.counter_id = $1 AND ca.starts_at <= $2 AND ca.ends_at > $2
+		ORDER BY ca.starts_at DESC
+		LIMIT 1`,
+		counterID, at,
+	)
+	if err != nil || len(assignments) == 0 {
+		return nil, err
+	}
+
+	return &assignments[0], nil
+}
+
+// GetActiveAssignmentByUserID returns the shift an officer is working at the given moment, or nil
+func (r *PostgresCounterAssignmentRepository) GetActiveAssignmentByUserID(ctx context.Context, userID uint, at time.Time) (*models.CounterAssignmentDetail, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	assignments, err := queryCounterAssignmentDetails(ctx, r.db, `SELECT `+counterAssignmentDetailColumns+counterAssignmentDetailJoins+`
+		WHERE ca.user_id = $1 AND ca.starts_at <= $2 AND ca.ends_at > $2
+		ORDER BY ca.starts_at DESC
+		LIMIT 1`,
+		userID, at,
+	)
+	if err != nil || len(assignments) == 0 {
+		return nil, err
+	}
+
+	return &assignments[0], nil
+}
+
+// DeleteCounterAssignment deletes a shift by ID
+func (r *PostgresCounterAssignmentRepository) DeleteCounterAssignment(ctx context.Context, id uint) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, "DELETE FROM counter_assignments WHERE id = $1", id)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error deleting counter assignment", "error", err)
+		return err
+	}
+
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return apperror.NotFound(fmt.Sprintf("no counter assignment found with the given ID: %d", id))
+	}
+
+	return nil
+}
@@ -1,35 +1,94 @@
 package repository
 
 import (
-	"api-server/config"
+	"api-server/apperror"
 	"api-server/models"
+	"context"
+	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
+	"time"
 )
 
-func TotalDataDashboard() (int, int, int, int, error) {
+// PostgresDashboardRepository stores dashboard totals in PostgreSQL
+type PostgresDashboardRepository struct {
+	postgres
+}
+
+// NewPostgresDashboardRepository creates the repository on the given database
+func NewPostgresDashboardRepository(db *sql.DB, statementTimeout time.Duration) *PostgresDashboardRepository {
+	return &PostgresDashboardRepository{postgres{db: db, statementTimeout: statementTimeout}}
+}
+
+func (r *PostgresDashboardRepository) TotalDataDashboard(ctx context.Context) (int, int, int, int, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	var totalOfficer, totalLikes, totalDislikes, totalVoted int
 
 	// Combine all counts into a single query
 	query := `SELECT total_officer, total_likes, total_dislikes, total_voted FROM total_data WHERE id = 1;`
 
 	// Execute the query
-	row := config.DB.QueryRow(query)
+	row := r.db.QueryRowContext(ctx, query)
 
 	// Scan the results into the respective variables
 	err := row.Scan(&totalOfficer, &totalLikes, &totalDislikes, &totalVoted)
 	if err != nil {
-		log.Println("Error querying total data dashboard:", err)
+		slog.ErrorContext(ctx, "Error querying total data dashboard", "error", err)
 		return 0, 0, 0, 0, err
 	}
 
 	return totalOfficer, totalLikes, totalDislikes, totalVoted, nil
 }
 
-func TotalDataBranchDashboard() ([]models.BranchData, error) {
-	rows, err := config.DB.Query("SELECT id, name_office, total_likes, total_dislikes, branch_id FROM total_data_branch ORDER BY total_likes DESC")
+// regionBranchIDs selects the IDs of the branch offices of the region bound to $1
+const regionBranchIDs = `SELECT bo.id FROM branch_offices bo JOIN cities c ON bo.city_id = c.id WHERE c.region_id = $1`
+
+// regionBranchFilter restricts a branch_id column to the branch offices of a region when the region parameter is set
+const regionBranchFilter = `($1::int IS NULL OR branch_id IN (` + regionBranchIDs + `))`
+
+// regionOfficerFilter keeps the officers (aliased u) of the region bound to $1 when it is set, and those who
+// received votes there before being transferred away
+const regionOfficerFilter = `($1::int IS NULL OR u.branch_id IN (` + regionBranchIDs + `) OR EXISTS (
+	SELECT 1 FROM user_feedback_history rf
+	WHERE rf.user_id = u.id AND NOT rf.out_of_hours AND rf.branch_id IN (` + regionBranchIDs + `)))`
+
+// activeBranchFilter excludes the rows of archived branch offices
+const activeBranchFilter = `branch_id IN (SELECT id FROM branch_offices WHERE deleted_at IS NULL)`
+
+// TotalDataRegionDashboard computes the dashboard totals of the branch offices in a region
+func (r *PostgresDashboardRepository) TotalDataRegionDashboard(ctx context.Context, regionID uint) (int, int, int, int, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var totalOfficer, totalLikes, totalDislikes int
+
+	query := `
+		SELECT
+			(SELECT COUNT(*) FROM users WHERE role = 'officer' AND deleted_at IS NULL AND ` + regionBranchFilter + `),
+			COALESCE(SUM(total_likes), 0),
+			COALESCE(SUM(total_dislikes), 0)
+		FROM total_data_branch
+		WHERE ` + activeBranchFilter + ` AND ` + regionBranchFilter
+
+	err := r.db.QueryRowContext(ctx, query, regionID).Scan(&totalOfficer, &totalLikes, &totalDislikes)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error querying region data dashboard", "error", err)
+		return 0, 0, 0, 0, err
+	}
+
+	return totalOfficer, totalLikes, totalDislikes, totalLikes + totalDislikes, nil
+}
+
+// TotalDataBranchDashboard retrieves the vote totals per branch office, optionally only those of one region
+func (r *PostgresDashboardRepository) TotalDataBranchDashboard(ctx context.Context, regionID *uint) ([]models.BranchData, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, "SELECT id, name_office, total_likes, total_dislikes, branch_id FROM total_data_branch WHERE "+activeBranchFilter+" AND "+regionBranchFilter+" ORDER BY total_likes DESC", regionID)
 	if err != nil {
-		log.Println("Error querying total data dashboard:", err)
+		slog.ErrorContext(ctx, "Error querying total data dashboard", "error", err)
 		return nil, err
 	}
 	defer rows.Close()
@@ -40,24 +99,86 @@ func TotalDataBranchDashboard() ([]models.BranchData, error) {
 		var branchData models.BranchData
 		err := rows.Scan(&branchData.ID, &branchData.NameOffice, &branchData.TotalLikes, &branchData.TotalDislikes, &branchData.BranchID)
 		if err != nil {
-			log.Println("Error scanning row:", err)
+			slog.ErrorContext(ctx, "Error scanning row", "error", err)
 			return nil, err
 		}
 		branchDataList = append(branchDataList, branchData)
 	}
 
 	if err := rows.Err(); err != nil {
-		log.Println("Error after iterating rows:", err)
+		slog.ErrorContext(ctx, "Error after iterating rows", "error", err)
 		return nil, err
 	}
 
 	return branchDataList, nil
 }
 
-func DataOfficerDashboard(limit uint, offset uint) ([]models.DashboardUsers, error) {
-	// Prepare the SQL query to select the desired fields
-	query := "SELECT full_name, likes, dislikes FROM users WHERE role = 'officer' ORDER BY likes DESC LIMIT $1 OFFSET $2"
-	rows, err := config.DB.Query(query, limit, offset)
+// TotalDataRegionsDashboard rolls the branch office totals up per region
+func (r *PostgresDashboardRepository) TotalDataRegionsDashboard(ctx context.Context) ([]models.RegionData, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT
+			r.id,
+			r.name,
+			COUNT(DISTINCT bo.id),
+			(SELECT COUNT(*) FROM users u
+				JOIN branch_offices ubo ON u.branch_id = ubo.id
+				JOIN cities uc ON ubo.city_id = uc.id
+				WHERE u.role = 'officer' AND u.deleted_at IS NULL AND ubo.deleted_at IS NULL AND uc.region_id = r.id),
+			COALESCE(SUM(tdb.total_likes), 0),
+			COALESCE(SUM(tdb.total_dislikes), 0)
+		FROM regions r
+		LEFT JOIN cities c ON c.region_id = r.id
+		LEFT JOIN branch_offices bo ON bo.city_id = c.id AND bo.deleted_at IS NULL
+		LEFT JOIN total_data_branch tdb ON tdb.branch_id = bo.id
+		GROUP BY r.id, r.name
+		ORDER BY COALESCE(SUM(tdb.total_likes), 0) DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error querying region dashboard", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	regions := []models.RegionData{}
+	for rows.Next() {
+		var region models.RegionData
+		if err := rows.Scan(&region.RegionID, &region.RegionName, &region.TotalBranches, &region.TotalOfficer, &region.TotalLikes, &region.TotalDislikes); err != nil {
+			slog.ErrorContext(ctx, "Error scanning region dashboard", "error", err)
+			return nil, err
+		}
+		regions = append(regions, region)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.ErrorContext(ctx, "Error after iterating region dashboard", "error", err)
+		return nil, err
+	}
+
+	return regions, nil
+}
+
+// DataOfficerDashboard lists officers by likes, optionally only those of one region. Votes are counted from
+// the feedback history by the branch office they were cast in, so a transferred officer's past votes stay
+// with the region where they happened.
+func (r *PostgresDashboardRepository) DataOfficerDashboard(ctx context.Context, limit uint, offset uint, regionID *uint) ([]models.DashboardUsers, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT u.full_name, COALESCE(SUM(f.likes), 0), COALESCE(SUM(f.dislikes), 0)
+		FROM users u
+		LEFT JOIN user_feedback_history f
+			ON f.user_id = u.id AND NOT f.out_of_hours AND ($1::int IS NULL OR f.branch_id IN (` + regionBranchIDs + `))
+		WHERE u.role = 'officer' AND u.deleted_at IS NULL AND ` + regionOfficerFilter + `
+		GROUP BY u.id, u.full_name
+		ORDER BY COALESCE(SUM(f.likes), 0) DESC, u.id ASC
+		LIMIT $2 OFFSET $3`
+	rows, err := r.db.QueryContext(ctx, query, regionID, limit, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -82,7 +203,25 @@ func DataOfficerDashboard(limit uint, offset uint) ([]models.DashboardUsers, err
 	return users, nil
 }
 
-func UpdateDashboard(branchId uint, voteType string) error {
+// OfficerCountDashboard counts officers, optionally only those of one region. It keeps the officers
+// DataOfficerDashboard lists, so that the pages match the count.
+func (r *PostgresDashboardRepository) OfficerCountDashboard(ctx context.Context, regionID *uint) (int, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var count int
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM users u WHERE u.role = 'officer' AND u.deleted_at IS NULL AND "+regionOfficerFilter, regionID).Scan(&count)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error querying officer count", "error", err)
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *PostgresDashboardRepository) UpdateDashboard(ctx context.Context, branchId uint, voteType string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	var (
 		totalUpdateQuery  string
 		branchUpdateQuery string
@@ -97,15 +236,15 @@ func UpdateDashboard(branchId uint, voteType string) error {
 		totalUpdateQuery = `UPDATE total_data SET total_dislikes = total_dislikes + 1, total_voted = total_voted + 1 WHERE id = 1`
 		branchUpdateQuery = `UPDATE total_data_branch SET total_dislikes = total_dislikes + 1 WHERE branch_id = $1`
 	default:
-		return fmt.Errorf("invalid vote type")
+		return apperror.BadRequest("invalid vote type")
 	}
 	// Execute total_data update
-	if _, err := config.DB.Exec(totalUpdateQuery); err != nil {
+	if _, err := r.db.ExecContext(ctx, totalUpdateQuery); err != nil {
 		return fmt.Errorf("failed to update total_data: %v", err)
 	}
 
 	// Execute total_data_branch update
-	if _, err := config.DB.Exec(branchUpdateQuery, branchId); err != nil {
+	if _, err := r.db.ExecContext(ctx, branchUpdateQuery, branchId); err != nil {
 		return fmt.Errorf("failed to update total_data_branch: %v", err)
 	}
 
@@ -0,0 +1,204 @@
+package repository
+
+import (
+	"api-server/apperror"
+	"api-server/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// PostgresMembershipRepository stores branch memberships in PostgreSQL
+type PostgresMembershipRepository struct {
+	postgres
+}
+
+// NewPostgresMembershipRepository creates the repository on the given database
+func NewPostgresMembershipRepository(db *sql.DB, statementTimeout time.Duration) *PostgresMembershipRepository {
+	return &PostgresMembershipRepository{postgres{db: db, statementTimeout: statementTimeout}}
+}
+
+// Errors returned when a transfer cannot be applied
+var (
+	ErrTransferSameBranch        = apperror.Conflict("the user already belongs to this branch office")
+	ErrTransferNotBranchBound    = apperror.BadRequest("only users working in a branch office can be transferred")
+	ErrTransferBeforeStart       = apperror.InvalidField("effective_at", "effective_at must be after the start of the current branch membership")
+	ErrBranchChangeNeedsTransfer = apperror.Conflict("Use POST /users/:id/transfer to move a user to another branch office")
+)
+
+// GetBranchMembershipsByUserID retrieves the branch memberships of a user, most recent first
+func (r *PostgresMembershipRepository) GetBranchMembershipsByUserID(ctx context.Context, userID uint) ([]models.BranchMembership, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT m.id, m.user_id, m.branch_id, bo.name, m.starts_at, m.ends_at, m.reason
+		FROM user_branch_memberships m
+		JOIN branch_offices bo ON m.branch_id = bo.id
+		WHERE m.user_id = $1
+		ORDER BY m.starts_at DESC`, userID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error querying branch memberships", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	memberships := []models.BranchMembership{}
+	for rows.Next() {
+		var membership models.BranchMembership
+		if err := rows.Scan(
+			&membership.ID,
+			&membership.UserID,
+			&membership.BranchID,
+			&membership.BranchName,
+			&membership.StartsAt,
+			&membership.EndsAt,
+			&membership.Reason,
+		); err != nil {
+			slog.ErrorContext(ctx, "Error scanning branch membership", "error", err)
+			return nil, err
+		}
+		memberships = append(memberships, membership)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.ErrorContext(ctx, "Error after iterating branch memberships", "error", err)
+		return nil, err
+	}
+
+	return memberships, nil
+}
+
+// TransferUser moves a user to another branch office. The current membership is closed and a new one is
+// opened at the effective date, the user's counters in the old branch office are released and their shifts
+// there from the effective date on are cancelled or shortened. Past votes keep the branch office they were
+// cast in.
+func (r *PostgresMembershipRepository) TransferUser(ctx context.Context, transfer *models.TransferRequest) (*models.TransferResult, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error starting transaction", "error", err)
+		return nil, err
+	}
+	defer tx.Rollback() // Rollback in case of an error
+
+	var oldBranchID sql.NullInt64
+	var createdAt time.Time
+	err = tx.QueryRowContext(ctx, "SELECT branch_id, createdAt FROM users WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", transfer.UserID).Scan(&oldBranchID, &createdAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperror.NotFound(fmt.Sprintf("no user found with the given ID: %d", transfer.UserID))
+		}
+		slog.ErrorContext(ctx, "Error locking user", "error", err)
+		return nil, err
+	}
+	if !oldBranchID.Valid {
+		return nil, ErrTransferNotBranchBound
+	}
+	if uint(oldBranchID.Int64) == transfer.BranchID {
+		return nil, ErrTransferSameBranch
+	}
+
+	var exists bool
+	err = tx.QueryRowContext(ctx, "SELECT true FROM branch_offices WHERE id = $1 AND deleted_at IS NULL", transfer.BranchID).Scan(&exists)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrBranchOfficeNotFound
+		}
+		slog.ErrorContext(ctx, "Error checking branch office", "error", err)
+		return nil, err
+	}
+
+	result := &models.TransferResult{}
+
+	// Users created before memberships were tracked get one starting at their creation
+	closed := &result.ClosedMembership
+	err = tx.QueryRowContext(ctx, "SELECT id, branch_id, starts_at FROM user_branch_memberships WHERE user_id = $1 AND ends_at IS NULL FOR UPDATE",
+		transfer.UserID).Scan(&closed.ID, &closed.BranchID, &closed.StartsAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = tx.QueryRowContext(ctx, "INSERT INTO user_branch_memberships (user_id, branch_id, starts_at) VALUES ($1, $2, $3) RETURNING id, branch_id, starts_at",
+			transfer.UserID, oldBranchID.Int64, createdAt).Scan(&closed.ID, &closed.BranchID, &closed.StartsAt)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Error loading current branch membership", "error", err)
+		return nil, err
+	}
+
+	if !transfer.EffectiveAt.After(closed.StartsAt) {
+		return nil, ErrTransferBeforeStart
+	}
+
+	err = tx.QueryRowContext(ctx, "UPDATE user_branch_memberships SET ends_at = $1 WHERE id = $2 RETURNING user_id, ends_at, reason",
+		transfer.EffectiveAt, closed.ID).Scan(&closed.UserID, &closed.EndsAt, &closed.Reason)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error closing branch membership", "error", err)
+		return nil, err
+	}
+
+	opened := &result.OpenedMembership
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO user_branch_memberships (user_id, branch_id, starts_at, reason)
+		VALUES ($1, $2, $3, NULLIF($4, ''))
+		RETURNING id, user_id, branch_id, starts_at, reason`,
+		transfer.UserID, transfer.BranchID, transfer.EffectiveAt, transfer.Reason,
+	).Scan(&opened.ID, &opened.UserID, &opened.BranchID, &opened.StartsAt, &opened.Reason)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error opening branch membership", "error", err)
+		return nil, err
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		SELECT
+			(SELECT name FROM branch_offices WHERE id = $1),
+			(SELECT name FROM branch_offices WHERE id = $2)`,
+		closed.BranchID, opened.BranchID).Scan(&closed.BranchName, &opened.BranchName)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error querying branch office names", "error", err)
+		return nil, err
+	}
+
+	if _, err = tx.ExecContext(ctx, "UPDATE users SET branch_id = $1 WHERE id = $2", transfer.BranchID, transfer.UserID); err != nil {
+		slog.ErrorContext(ctx, "Error updating user branch office", "error", err)
+		return nil, err
+	}
+
+	// Release the counters the user was linked to in the old branch office
+	released, err := tx.ExecContext(ctx, "UPDATE branch_counters SET user_id = NULL WHERE user_id = $1 AND branch_id = $2", transfer.UserID, oldBranchID.Int64)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error releasing branch counters", "error", err)
+		return nil, err
+	}
+	releasedCounters, _ := released.RowsAffected()
+	result.ReleasedCounters = int(releasedCounters)
+
+	// Shifts in the old branch office starting from the effective date are cancelled, running ones end at it
+	cancelled, err := tx.ExecContext(ctx, "DELETE FROM counter_assignments WHERE user_id = $1 AND branch_id = $2 AND starts_at >= $3",
+		transfer.UserID, oldBranchID.Int64, transfer.EffectiveAt)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error cancelling counter assignments", "error", err)
+		return nil, err
+	}
+	cancelledShifts, _ := cancelled.RowsAffected()
+	result.CancelledShifts = int(cancelledShifts)
+
+	shortened, err := tx.ExecContext(ctx, "UPDATE counter_assignments SET ends_at = $3 WHERE user_id = $1 AND branch_id = $2 AND starts_at < $3 AND ends_at > $3",
+		transfer.UserID, oldBranchID.Int64, transfer.EffectiveAt)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error shortening counter assignments", "error", err)
+		return nil, err
+	}
+	shortenedShifts, _ := shortened.RowsAffected()
+	result.ShortenedShifts = int(shortenedShifts)
+
+	if err = tx.Commit(); err != nil {
+		slog.ErrorContext(ctx, "Error committing transaction", "error", err)
+		return nil, err
+	}
+
+	return result, nil
+}
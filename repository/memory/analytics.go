@@ -0,0 +1,59 @@
+package memory
+
+import (
+	"api-server/models"
+	"context"
+	"time"
+)
+
+// GetBranchVoteHeatmap aggregates the feedback of a branch office into day-of-week/hour-of-day buckets
+// in the given timezone. When userID is set only the votes of that officer are counted.
+func (s *Store) GetBranchVoteHeatmap(_ context.Context, branchID uint, userID *uint, timezone string, from, to time.Time) ([7][24]models.HeatmapCell, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.voteHeatmap(func(branch uint) bool { return branch == branchID }, userID, timezone, from, to)
+}
+
+// GetRegionVoteHeatmap aggregates the feedback of all branch offices of a region into day-of-week/hour-of-day buckets
+func (s *Store) GetRegionVoteHeatmap(_ context.Context, regionID uint, timezone string, from, to time.Time) ([7][24]models.HeatmapCell, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.voteHeatmap(func(branch uint) bool { return s.inRegion(branch, regionID) }, nil, timezone, from, to)
+}
+
+// voteHeatmap aggregates the feedback of the branch offices in scope. Votes flagged as received outside
+// operating hours are excluded.
+func (s *Store) voteHeatmap(inScope func(branchID uint) bool, userID *uint, timezone string, from, to time.Time) ([7][24]models.HeatmapCell, error) {
+	var cells [7][24]models.HeatmapCell
+
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return cells, err
+	}
+
+	for _, f := range s.feedback {
+		if !inScope(f.branchID) || f.outOfHours || f.createdAt.Before(from) || !f.createdAt.Before(to) {
+			continue
+		}
+		if userID != nil && f.userID != *userID {
+			continue
+		}
+
+		local := f.createdAt.In(location)
+		cell := &cells[local.Weekday()][local.Hour()]
+		cell.Likes += f.likes
+		cell.Dislikes += f.dislikes
+	}
+
+	for day := range cells {
+		for hour := range cells[day] {
+			cell := &cells[day][hour]
+			cell.Total = cell.Likes + cell.Dislikes
+			cell.DislikeRatio = dislikeRatio(cell.Likes, cell.Dislikes)
+		}
+	}
+
+	return cells, nil
+}
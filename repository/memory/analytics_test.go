@@ -0,0 +1,70 @@
+package memory
+
+import (
+	"api-server/models"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestVoteHeatmapBuckets(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore()
+	if err := store.CreateBranchOffice(ctx, &models.BranchOfficeCreateRequest{Name: "Frankfurt Consulate", Address: "Zeil 1", TotalCounter: 1, Timezone: "Europe/Berlin"}); err != nil {
+		t.Fatalf("creating the branch office: %v", err)
+	}
+
+	votes := []struct {
+		at       string
+		likes    uint
+		dislikes uint
+	}{
+		{"2026-03-29T00:30:00Z", 1, 0}, // 01:30 CET
+		{"2026-03-29T01:30:00Z", 0, 1}, // 03:30 CEST, the 02:00 hour does not exist that night
+		{"2026-10-25T00:30:00Z", 1, 0}, // 02:30 CEST
+		{"2026-10-25T01:30:00Z", 1, 0}, // 02:30 CET, the same hour of the day a second time
+		{"2026-09-22T22:30:00Z", 0, 1}, // Wednesday 00:30 CEST, still Tuesday in UTC
+		{"2026-11-01T00:00:00Z", 5, 5}, // The end of the range, excluded
+	}
+	for _, vote := range votes {
+		at, err := time.Parse(time.RFC3339, vote.at)
+		if err != nil {
+			t.Fatalf("parsing %s: %v", vote.at, err)
+		}
+		store.feedback = append(store.feedback, feedback{likes: vote.likes, dislikes: vote.dislikes, userID: 1, branchID: 1, createdAt: at})
+	}
+
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	cells, err := store.GetBranchVoteHeatmap(ctx, 1, nil, "Europe/Berlin", from, to)
+	if err != nil {
+		t.Fatalf("GetBranchVoteHeatmap() error = %v", err)
+	}
+
+	want := map[[2]int][2]uint{
+		{int(time.Sunday), 1}:    {1, 0},
+		{int(time.Sunday), 3}:    {0, 1},
+		{int(time.Sunday), 2}:    {2, 0},
+		{int(time.Wednesday), 0}: {0, 1},
+	}
+	for day := range cells {
+		for hour, cell := range cells[day] {
+			counts := want[[2]int{day, hour}]
+			if cell.Likes != counts[0] || cell.Dislikes != counts[1] || cell.Total != counts[0]+counts[1] {
+				t.Errorf("cell %s %02d:00 = %+v, want %d likes and %d dislikes", time.Weekday(day), hour, cell, counts[0], counts[1])
+			}
+		}
+	}
+
+	// In UTC the same votes fall on other cells
+	cells, err = store.GetBranchVoteHeatmap(ctx, 1, nil, "UTC", from, to)
+	if err != nil {
+		t.Fatalf("GetBranchVoteHeatmap() error = %v", err)
+	}
+	if cell := cells[time.Tuesday][22]; cell.Dislikes != 1 {
+		t.Errorf("cell Tuesday 22:00 in UTC = %+v, want the dislike", cell)
+	}
+	if cell := cells[time.Sunday][0]; cell.Likes != 2 || cell.Dislikes != 0 {
+		t.Errorf("cell Sunday 00:00 in UTC = %+v, want 2 likes", cell)
+	}
+}
@@ -0,0 +1,276 @@
+package memory
+
+import (
+	"api-server/apperror"
+	"api-server/models"
+	"api-server/repository"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// archivedEntities sorts archived records most recently archived first
+func archivedEntities(entities []models.ArchivedEntity) []models.ArchivedEntity {
+	sort.SliceStable(entities, func(i, j int) bool {
+		if !entities[i].DeletedAt.Equal(entities[j].DeletedAt) {
+			return entities[i].DeletedAt.After(entities[j].DeletedAt)
+		}
+		return entities[i].ID < entities[j].ID
+	})
+	return entities
+}
+
+// GetArchivedBranchOffices retrieves the archived branch offices, most recently archived first
+func (s *Store) GetArchivedBranchOffices(_ context.Context) ([]models.ArchivedEntity, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entities := []models.ArchivedEntity{}
+	for id, branchOffice := range s.branchOffices {
+		if branchOffice.deletedAt != nil {
+			entities = append(entities, models.ArchivedEntity{ID: id, Name: branchOffice.Name, Detail: branchOffice.Address, DeletedAt: *branchOffice.deletedAt})
+		}
+	}
+	return archivedEntities(entities), nil
+}
+
+// GetArchivedUsers retrieves the archived users, most recently archived first
+func (s *Store) GetArchivedUsers(_ context.Context) ([]models.ArchivedEntity, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entities := []models.ArchivedEntity{}
+	for id, user := range s.users {
+		if user.deletedAt != nil {
+			entities = append(entities, models.ArchivedEntity{ID: id, Name: user.FullName, Detail: user.Email, DeletedAt: *user.deletedAt})
+		}
+	}
+	return archivedEntities(entities), nil
+}
+
+// RestoreBranchOffice brings an archived branch office back, together with the users archived along with it
+func (s *Store) RestoreBranchOffice(_ context.Context, id uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	branchOffice, ok := s.branchOffices[id]
+	if !ok || branchOffice.deletedAt == nil {
+		return apperror.NotFound(fmt.Sprintf("no archived branch office found with the given ID: %d", id))
+	}
+
+	restored := []*user{}
+	for userID, user := range s.users {
+		if user.BranchId == id && user.deletedAt != nil && user.deletedAt.Equal(*branchOffice.deletedAt) {
+			if s.emailTaken(user.Email, userID) {
+				return uniqueViolation("users", "idx_users_email_active")
+			}
+			restored = append(restored, user)
+		}
+	}
+
+	branchOffice.deletedAt = nil
+	for _, user := range restored {
+		user.deletedAt = nil
+		if user.Role == "officer" {
+			s.totals.officer++
+		}
+	}
+
+	return nil
+}
+
+// RestoreUser brings an archived user back. Restoring fails with a unique violation if another
+// active user took the email in the meantime.
+func (s *Store) RestoreUser(_ context.Context, id uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[id]
+	if !ok || user.deletedAt == nil {
+		return apperror.NotFound(fmt.Sprintf("no archived user found with the given ID: %d", id))
+	}
+
+	if branchOffice, ok := s.branchOffices[user.BranchId]; ok && branchOffice.deletedAt != nil {
+		return repository.ErrBranchOfficeArchived
+	}
+	if s.emailTaken(user.Email, id) {
+		return uniqueViolation("users", "idx_users_email_active")
+	}
+
+	user.deletedAt = nil
+	if user.Role == "officer" {
+		s.totals.officer++
+	}
+
+	return nil
+}
+
+// userSnapshot describes a user in the purge audit log, without the password
+func userSnapshot(user *user) map[string]interface{} {
+	return map[string]interface{}{
+		"id":           user.ID,
+		"full_name":    user.FullName,
+		"full_name_ar": user.FullNameAr,
+		"full_name_en": user.FullNameEn,
+		"email":        user.Email,
+		"role":         user.Role,
+		"likes":        user.Likes,
+		"dislikes":     user.Dislikes,
+		"image":        user.Image,
+		"branch_id":    user.BranchId,
+		"region_id":    user.RegionId,
+		"deleted_at":   user.deletedAt,
+	}
+}
+
+// recordPurge appends an entry to the purge audit log
+func (s *Store) recordPurge(entityType string, entityID uint, entityName string, request *models.PurgeRequest, feedbackRows int, snapshot interface{}) error {
+	encoded, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	s.purgeAuditLog = append(s.purgeAuditLog, models.PurgeAuditEntry{
+		ID:           s.nextID("purge_audit_log"),
+		EntityType:   entityType,
+		EntityID:     entityID,
+		EntityName:   entityName,
+		Reason:       request.Reason,
+		PurgedBy:     request.PurgedBy,
+		FeedbackRows: feedbackRows,
+		Snapshot:     encoded,
+		PurgedAt:     time.Now(),
+	})
+	return nil
+}
+
+// PurgeBranchOffice permanently deletes an archived branch office with its users, counters and feedback
+// history, after recording a snapshot in the purge audit log. It returns the image names of the purged users.
+func (s *Store) PurgeBranchOffice(_ context.Context, id uint, request *models.PurgeRequest) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	branchOffice, ok := s.branchOffices[id]
+	if !ok {
+		return nil, apperror.NotFound(fmt.Sprintf("no branch office found with the given ID: %d", id))
+	}
+	if branchOffice.deletedAt == nil {
+		return nil, repository.ErrNotArchived
+	}
+
+	feedbackRows := 0
+	for _, f := range s.feedback {
+		if f.branchID == id {
+			feedbackRows++
+		}
+	}
+
+	users := []map[string]interface{}{}
+	images := []string{}
+	for _, userID := range sortedIDs(s.users) {
+		user := s.users[userID]
+		if user.BranchId != id {
+			continue
+		}
+		users = append(users, userSnapshot(user))
+		if user.Image != "" {
+			images = append(images, user.Image)
+		}
+	}
+
+	snapshot := map[string]interface{}{
+		"branch_office": s.branchOfficeResponse(branchOffice),
+		"users":         users,
+	}
+	if err := s.recordPurge(models.PurgeEntityBranchOffice, id, branchOffice.Name, request, feedbackRows, snapshot); err != nil {
+		return nil, err
+	}
+
+	// Users, counters, shifts, schedules and feedback history of the branch office go with it
+	for userID, user := range s.users {
+		if user.BranchId == id {
+			s.deleteUser(userID)
+		}
+	}
+	for counterID, counter := range s.counters {
+		if counter.BranchID == id {
+			delete(s.counters, counterID)
+			delete(s.capacityDeactivated, counterID)
+			delete(s.retiredCounters, counterID)
+		}
+	}
+	for assignmentID, assignment := range s.assignments {
+		if assignment.BranchID == id {
+			delete(s.assignments, assignmentID)
+		}
+	}
+	for membershipID, membership := range s.memberships {
+		if membership.BranchID == id {
+			delete(s.memberships, membershipID)
+		}
+	}
+	for exceptionID, exception := range s.exceptions {
+		if exception.BranchID == id {
+			delete(s.exceptions, exceptionID)
+		}
+	}
+	for closureID, closure := range s.closures {
+		if closure.BranchID == id {
+			delete(s.closures, closureID)
+		}
+	}
+	delete(s.openingHours, id)
+	s.deleteFeedback(func(f *feedback) bool { return f.branchID == id })
+	delete(s.branchOffices, id)
+
+	return images, nil
+}
+
+// PurgeUser permanently deletes an archived user with their shifts and feedback history, after
+// recording a snapshot in the purge audit log. It returns the image name of the purged user.
+func (s *Store) PurgeUser(_ context.Context, id uint, request *models.PurgeRequest) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return "", apperror.NotFound(fmt.Sprintf("no user found with the given ID: %d", id))
+	}
+	if user.deletedAt == nil {
+		return "", repository.ErrNotArchived
+	}
+
+	feedbackRows := 0
+	for _, f := range s.feedback {
+		if f.userID == id {
+			feedbackRows++
+		}
+	}
+
+	snapshot := map[string]interface{}{"user": userSnapshot(user)}
+	if err := s.recordPurge(models.PurgeEntityUser, id, user.FullName, request, feedbackRows, snapshot); err != nil {
+		return "", err
+	}
+
+	s.deleteUser(id)
+	return user.Image, nil
+}
+
+// GetPurgeAuditLog retrieves the purge audit log with pagination, most recent first
+func (s *Store) GetPurgeAuditLog(_ context.Context, limit, offset int) ([]models.PurgeAuditEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := append([]models.PurgeAuditEntry{}, s.purgeAuditLog...)
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].PurgedAt.Equal(entries[j].PurgedAt) {
+			return entries[i].PurgedAt.After(entries[j].PurgedAt)
+		}
+		return entries[i].ID > entries[j].ID
+	})
+
+	start, end := paginate(len(entries), limit, offset)
+	return entries[start:end], nil
+}
@@ -0,0 +1,342 @@
+package memory
+
+import (
+	"api-server/models"
+	"api-server/repository"
+	"context"
+	"sort"
+	"time"
+)
+
+// CreateBranchCounter creates a new branch counter within the capacity (total_counter) of its branch office.
+// When no counter number is given, the lowest free number is assigned.
+func (s *Store) CreateBranchCounter(_ context.Context, branchCounter *models.BranchCounter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	branchOffice := s.activeBranchOffice(branchCounter.BranchID)
+	if branchOffice == nil {
+		return repository.ErrBranchOfficeNotFound
+	}
+
+	used := map[uint]bool{}
+	for _, counter := range s.counters {
+		if _, retired := s.retiredCounters[counter.ID]; counter.BranchID == branchCounter.BranchID && !retired {
+			used[counter.CounterNumber] = true
+		}
+	}
+
+	if branchCounter.CounterNumber == 0 {
+		for n := uint(1); n <= branchOffice.TotalCounter; n++ {
+			if !used[n] {
+				branchCounter.CounterNumber = n
+				break
+			}
+		}
+		if branchCounter.CounterNumber == 0 {
+			return repository.ErrCounterCapacityReached
+		}
+	} else {
+		if branchCounter.CounterNumber > branchOffice.TotalCounter {
+			return repository.ErrCounterNumberOutOfRange
+		}
+		if used[branchCounter.CounterNumber] {
+			return repository.ErrCounterNumberTaken
+		}
+	}
+
+	if _, ok := s.users[branchCounter.UserID]; branchCounter.UserID != 0 && !ok {
+		return foreignKeyViolation("branch_counters", "branch_counters_user_id_fkey")
+	}
+
+	branchCounter.ID = s.nextID("branch_counters")
+	branchCounter.IsActive = true
+	stored := *branchCounter
+	s.counters[stored.ID] = &stored
+
+	return nil
+}
+
+// GetBranchCountersByBranchID retrieves the active counters of a branch with the names of their officers.
+// Counters released by a transferred officer are listed with user_id 0.
+func (s *Store) GetBranchCountersByBranchID(_ context.Context, id uint) ([]models.BranchCounterWithNames, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var counters []models.BranchCounterWithNames
+	for _, counter := range s.sortedCounters(id) {
+		if !counter.IsActive {
+			continue
+		}
+
+		withNames := models.BranchCounterWithNames{
+			ID:              counter.ID,
+			CounterLocation: counter.CounterLocation,
+			CounterNumber:   counter.CounterNumber,
+		}
+		if user := s.activeUser(counter.UserID); user != nil {
+			withNames.UserId = user.ID
+			withNames.FullName = user.FullName
+			withNames.FullNameAr = clone(user.FullNameAr)
+			withNames.FullNameEn = clone(user.FullNameEn)
+			withNames.Image = user.Image
+		}
+		counters = append(counters, withNames)
+	}
+
+	return counters, nil
+}
+
+// sortedCounters returns the counters of a branch office ordered by counter number
+func (s *Store) sortedCounters(branchID uint) []*models.BranchCounter {
+	counters := []*models.BranchCounter{}
+	for _, counter := range s.counters {
+		if counter.BranchID == branchID {
+			counters = append(counters, counter)
+		}
+	}
+	sort.Slice(counters, func(i, j int) bool { return counters[i].CounterNumber < counters[j].CounterNumber })
+	return counters
+}
+
+// DeleteBranchCounter deletes a branch counter by ID. Its upcoming shifts are cancelled and the running one
+// ends now; a counter with past shifts is retired instead, keeping them, and its number can be used again.
+func (s *Store) DeleteBranchCounter(_ context.Context, id uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counter, ok := s.counters[id]
+	if !ok {
+		return nil
+	}
+
+	now := time.Now()
+	hasShifts := false
+	for assignmentID, assignment := range s.assignments {
+		if assignment.CounterID != id {
+			continue
+		}
+		if !assignment.StartsAt.Before(now) {
+			delete(s.assignments, assignmentID)
+			continue
+		}
+		if assignment.EndsAt.After(now) {
+			assignment.EndsAt = now
+		}
+		hasShifts = true
+	}
+
+	delete(s.capacityDeactivated, id)
+	if hasShifts {
+		counter.IsActive = false
+		counter.UserID = 0
+		s.retiredCounters[id] = now
+		return nil
+	}
+
+	delete(s.counters, id)
+	for i := range s.feedback {
+		if counterID := s.feedback[i].counterID; counterID != nil && *counterID == id {
+			s.feedback[i].counterID = nil
+		}
+	}
+
+	return nil
+}
+
+// GetBranchCounterByID retrieves a single branch counter, returning nil when it does not exist
+func (s *Store) GetBranchCounterByID(_ context.Context, id uint) (*models.BranchCounter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counter, ok := s.counters[id]
+	if !ok {
+		return nil, nil
+	}
+
+	copied := *counter
+	return &copied, nil
+}
+
+// GetBranchCounterIDByUserID returns the active counter currently assigned to an officer, or nil if none
+func (s *Store) GetBranchCounterIDByUserID(_ context.Context, userID uint) (*uint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var counterID *uint
+	for _, id := range sortedIDs(s.counters) {
+		if counter := s.counters[id]; counter.UserID == userID && counter.IsActive {
+			latest := id
+			counterID = &latest
+		}
+	}
+	return counterID, nil
+}
+
+// countedFeedback returns the feedback given at a counter within [from, to), excluding votes received
+// outside operating hours
+func (s *Store) countedFeedback(counterID uint, from time.Time, to time.Time) []feedback {
+	counted := []feedback{}
+	for _, f := range s.feedback {
+		if f.counterID != nil && *f.counterID == counterID && !f.outOfHours && !f.createdAt.Before(from) && f.createdAt.Before(to) {
+			counted = append(counted, f)
+		}
+	}
+	return counted
+}
+
+// GetBranchCounterStatsByBranchID returns vote totals for every counter of a branch within [from, to),
+// excluding votes received outside operating hours. Counters retired before the period are left out.
+func (s *Store) GetBranchCounterStatsByBranchID(_ context.Context, branchID uint, from time.Time, to time.Time) ([]models.BranchCounterStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := []models.BranchCounterStats{}
+	for _, counter := range s.sortedCounters(branchID) {
+		if retiredAt, retired := s.retiredCounters[counter.ID]; retired && !retiredAt.After(from) {
+			continue
+		}
+		stat := models.BranchCounterStats{
+			CounterID:       counter.ID,
+			CounterNumber:   counter.CounterNumber,
+			CounterLocation: counter.CounterLocation,
+			BranchID:        counter.BranchID,
+		}
+		for _, f := range s.countedFeedback(counter.ID, from, to) {
+			stat.Likes += f.likes
+			stat.Dislikes += f.dislikes
+		}
+		stat.Total = stat.Likes + stat.Dislikes
+		stat.DislikeRatio = dislikeRatio(stat.Likes, stat.Dislikes)
+		stats = append(stats, stat)
+	}
+
+	return stats, nil
+}
+
+// GetCounterOfficerStats returns, per officer, the votes received at a counter within [from, to),
+// excluding votes received outside operating hours. The officers are those with a shift overlapping the
+// period, the one linked to the counter and those who received votes there, with or without votes.
+func (s *Store) GetCounterOfficerStats(_ context.Context, counterID uint, from time.Time, to time.Time) ([]models.CounterOfficerStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	byUser := map[uint]*models.CounterOfficerStats{}
+	officers := []*models.CounterOfficerStats{}
+	addOfficer := func(userID uint) *models.CounterOfficerStats {
+		officer, ok := byUser[userID]
+		if !ok {
+			officer = &models.CounterOfficerStats{UserID: userID}
+			if user, ok := s.users[userID]; ok {
+				officer.FullName = user.FullName
+			}
+			byUser[userID] = officer
+			officers = append(officers, officer)
+		}
+		return officer
+	}
+
+	for _, f := range s.countedFeedback(counterID, from, to) {
+		officer := addOfficer(f.userID)
+
+		// The name recorded with the votes is used once the user is purged
+		if _, ok := s.users[f.userID]; !ok && f.officerName > officer.FullName {
+			officer.FullName = f.officerName
+		}
+		officer.Likes += f.likes
+		officer.Dislikes += f.dislikes
+		if createdAt := f.createdAt; officer.FirstVoteAt == nil || createdAt.Before(*officer.FirstVoteAt) {
+			officer.FirstVoteAt = &createdAt
+		}
+		if createdAt := f.createdAt; officer.LastVoteAt == nil || createdAt.After(*officer.LastVoteAt) {
+			officer.LastVoteAt = &createdAt
+		}
+	}
+	for _, assignment := range s.assignments {
+		if assignment.CounterID == counterID && assignment.StartsAt.Before(to) && assignment.EndsAt.After(from) {
+			addOfficer(assignment.UserID)
+		}
+	}
+	if counter, ok := s.counters[counterID]; ok && counter.UserID != 0 {
+		addOfficer(counter.UserID)
+	}
+
+	// Officers with votes come first, by their first vote
+	sort.Slice(officers, func(i, j int) bool {
+		first, other := officers[i].FirstVoteAt, officers[j].FirstVoteAt
+		switch {
+		case first != nil && other != nil && !first.Equal(*other):
+			return first.Before(*other)
+		case (first == nil) != (other == nil):
+			return first != nil
+		}
+		return officers[i].UserID < officers[j].UserID
+	})
+
+	stats := []models.CounterOfficerStats{}
+	for _, officer := range officers {
+		officer.Total = officer.Likes + officer.Dislikes
+		stats = append(stats, *officer)
+	}
+	return stats, nil
+}
+
+// GetCounterSlotsByBranchID maps the numbered slots 1..total_counter of a branch office to their counters.
+// Inactive counters numbered above the current capacity are appended with status "inactive".
+func (s *Store) GetCounterSlotsByBranchID(_ context.Context, branchID uint, totalCounter uint) ([]models.CounterSlot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	byNumber := map[uint]*models.BranchCounter{}
+	highest := totalCounter
+	for _, counter := range s.sortedCounters(branchID) {
+		if _, retired := s.retiredCounters[counter.ID]; retired {
+			continue
+		}
+		byNumber[counter.CounterNumber] = counter
+		if counter.CounterNumber > highest {
+			highest = counter.CounterNumber
+		}
+	}
+
+	slots := []models.CounterSlot{}
+	for n := uint(1); n <= highest; n++ {
+		slot := models.CounterSlot{CounterNumber: n}
+		counter, ok := byNumber[n]
+		var officer *user
+		if ok {
+			officer = s.activeUser(counter.UserID)
+		}
+
+		switch {
+		case !ok:
+			// Only slots within capacity are reported as free
+			if n > totalCounter {
+				continue
+			}
+			slot.Status = "free"
+		case !counter.IsActive:
+			slot.Status = "inactive"
+		case officer == nil:
+			// The counter exists but its officer was transferred away or archived
+			slot.Status = "vacant"
+		default:
+			slot.Status = "occupied"
+		}
+
+		if ok {
+			id := counter.ID
+			slot.CounterID = &id
+			slot.CounterLocation = counter.CounterLocation
+		}
+		if officer != nil {
+			id := officer.ID
+			slot.UserID = &id
+			slot.FullName = officer.FullName
+		}
+
+		slots = append(slots, slot)
+	}
+
+	return slots, nil
+}
@@ -0,0 +1,256 @@
+package memory
+
+import (
+	"api-server/apperror"
+	"api-server/models"
+	"api-server/repository"
+	"context"
+	"fmt"
+	"time"
+)
+
+// branchOfficeResponse joins a branch office with its city and region
+func (s *Store) branchOfficeResponse(branchOffice *branchOffice) models.BranchOfficeResponse {
+	response := models.BranchOfficeResponse{
+		ID:               branchOffice.id,
+		Name:             branchOffice.Name,
+		Address:          branchOffice.Address,
+		NameAr:           clone(branchOffice.NameAr),
+		NameEn:           clone(branchOffice.NameEn),
+		AddressAr:        clone(branchOffice.AddressAr),
+		AddressEn:        clone(branchOffice.AddressEn),
+		TotalCounter:     branchOffice.TotalCounter,
+		Timezone:         branchOffice.Timezone,
+		CityID:           clone(branchOffice.CityID),
+		OutOfHoursPolicy: branchOffice.outOfHoursPolicy,
+		Latitude:         clone(branchOffice.Latitude),
+		Longitude:        clone(branchOffice.Longitude),
+	}
+
+	if branchOffice.CityID != nil {
+		if city, ok := s.cities[*branchOffice.CityID]; ok {
+			response.CityName = city.name
+			regionID := city.regionID
+			response.RegionID = &regionID
+			response.RegionName = s.regions[city.regionID].name
+		}
+	}
+
+	return response
+}
+
+// setBranchOfficeFields copies the fields of a create request into a stored branch office
+func setBranchOfficeFields(branchOffice *branchOffice, request *models.BranchOfficeCreateRequest) {
+	branchOffice.Name = request.Name
+	branchOffice.Address = request.Address
+	branchOffice.NameAr = clone(request.NameAr)
+	branchOffice.NameEn = clone(request.NameEn)
+	branchOffice.AddressAr = clone(request.AddressAr)
+	branchOffice.AddressEn = clone(request.AddressEn)
+	branchOffice.TotalCounter = request.TotalCounter
+	branchOffice.Timezone = request.Timezone
+	branchOffice.CityID = clone(request.CityID)
+	branchOffice.Latitude = clone(request.Latitude)
+	branchOffice.Longitude = clone(request.Longitude)
+}
+
+// checkCity checks the city of a branch office exists
+func (s *Store) checkCity(cityID *uint) error {
+	if cityID == nil {
+		return nil
+	}
+	if _, ok := s.cities[*cityID]; !ok {
+		return foreignKeyViolation("branch_offices", "branch_offices_city_id_fkey")
+	}
+	return nil
+}
+
+// GetAllBranchOffices retrieves the active branch offices with pagination
+func (s *Store) GetAllBranchOffices(_ context.Context, limit, offset int) ([]models.BranchOfficeResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var branchOffices []models.BranchOfficeResponse
+	for _, id := range sortedIDs(s.branchOffices) {
+		if branchOffice := s.branchOffices[id]; branchOffice.deletedAt == nil {
+			branchOffices = append(branchOffices, s.branchOfficeResponse(branchOffice))
+		}
+	}
+
+	start, end := paginate(len(branchOffices), limit, offset)
+	return branchOffices[start:end], nil
+}
+
+// GetAllBranchOfficesOption lists the active branch offices for selection lists
+func (s *Store) GetAllBranchOfficesOption(_ context.Context) ([]models.BranchOfficeOptionResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var branchOffices []models.BranchOfficeOptionResponse
+	for _, id := range sortedIDs(s.branchOffices) {
+		branchOffice := s.branchOffices[id]
+		if branchOffice.deletedAt != nil {
+			continue
+		}
+		branchOffices = append(branchOffices, models.BranchOfficeOptionResponse{
+			ID:        id,
+			Name:      branchOffice.Name,
+			NameAr:    clone(branchOffice.NameAr),
+			NameEn:    clone(branchOffice.NameEn),
+			Latitude:  clone(branchOffice.Latitude),
+			Longitude: clone(branchOffice.Longitude),
+		})
+	}
+	return branchOffices, nil
+}
+
+// GetLocatedBranchOffices retrieves the active branch offices that have coordinates, with their vote totals
+func (s *Store) GetLocatedBranchOffices(_ context.Context) ([]models.NearbyBranchOffice, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	branchOffices := []models.NearbyBranchOffice{}
+	for _, id := range sortedIDs(s.branchOffices) {
+		branchOffice := s.branchOffices[id]
+		if branchOffice.deletedAt != nil || branchOffice.Latitude == nil || branchOffice.Longitude == nil {
+			continue
+		}
+		branchOffices = append(branchOffices, models.NearbyBranchOffice{
+			ID:            id,
+			Name:          branchOffice.Name,
+			Address:       branchOffice.Address,
+			NameAr:        clone(branchOffice.NameAr),
+			NameEn:        clone(branchOffice.NameEn),
+			AddressAr:     clone(branchOffice.AddressAr),
+			AddressEn:     clone(branchOffice.AddressEn),
+			Latitude:      *branchOffice.Latitude,
+			Longitude:     *branchOffice.Longitude,
+			TotalLikes:    branchOffice.totalLikes,
+			TotalDislikes: branchOffice.totalDislikes,
+		})
+	}
+	return branchOffices, nil
+}
+
+// GetBranchOfficesCount counts the active branch offices
+func (s *Store) GetBranchOfficesCount(_ context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for _, branchOffice := range s.branchOffices {
+		if branchOffice.deletedAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// GetBranchOfficesById retrieves an active branch office by its ID
+func (s *Store) GetBranchOfficesById(_ context.Context, id uint) (*models.BranchOfficeResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	branchOffice := s.activeBranchOffice(id)
+	if branchOffice == nil {
+		return nil, repository.ErrBranchOfficeNotFound
+	}
+
+	response := s.branchOfficeResponse(branchOffice)
+	return &response, nil
+}
+
+// CreateBranchOffice creates a new branch office
+func (s *Store) CreateBranchOffice(ctx context.Context, branchOffice *models.BranchOfficeCreateRequest) error {
+	return s.CreateBranchOffices(ctx, []models.BranchOfficeCreateRequest{*branchOffice})
+}
+
+// CreateBranchOffices creates several branch offices with their dashboard totals, all or none
+func (s *Store) CreateBranchOffices(_ context.Context, branchOffices []models.BranchOfficeCreateRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range branchOffices {
+		if err := s.checkCity(branchOffices[i].CityID); err != nil {
+			return err
+		}
+	}
+
+	for i := range branchOffices {
+		stored := &branchOffice{
+			id:               s.nextID("branch_offices"),
+			outOfHoursPolicy: models.OutOfHoursVoteFlag,
+			nameOffice:       branchOffices[i].Name,
+		}
+		setBranchOfficeFields(stored, &branchOffices[i])
+		s.branchOffices[stored.id] = stored
+	}
+
+	return nil
+}
+
+// UpdateBranchOffices updates an active branch office by ID. When total_counter shrinks, active counters
+// numbered above the new total are either rejected or deactivated depending on the counter policy.
+// Counters within the new total are reactivated.
+func (s *Store) UpdateBranchOffices(_ context.Context, id uint, request *models.BranchOfficeCreateRequest, counterPolicy string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	branchOffice := s.activeBranchOffice(id)
+	if branchOffice == nil {
+		return apperror.NotFound(fmt.Sprintf("no branch office found with the given ID: %d", id))
+	}
+	if err := s.checkCity(request.CityID); err != nil {
+		return err
+	}
+
+	above := []*models.BranchCounter{}
+	for _, counter := range s.counters {
+		if counter.BranchID == id && counter.CounterNumber > request.TotalCounter && counter.IsActive {
+			above = append(above, counter)
+		}
+	}
+	if len(above) > 0 && counterPolicy != models.CounterPolicyDeactivate {
+		return repository.ErrCountersAboveCapacity
+	}
+
+	for _, counter := range above {
+		counter.IsActive = false
+		s.capacityDeactivated[counter.ID] = true
+	}
+	for _, counter := range s.counters {
+		if counter.BranchID == id && counter.CounterNumber <= request.TotalCounter && s.capacityDeactivated[counter.ID] {
+			counter.IsActive = true
+			delete(s.capacityDeactivated, counter.ID)
+		}
+	}
+
+	setBranchOfficeFields(branchOffice, request)
+	return nil
+}
+
+// DeleteBranchOffices archives an active branch office by ID together with its active users
+func (s *Store) DeleteBranchOffices(_ context.Context, id uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	branchOffice := s.activeBranchOffice(id)
+	if branchOffice == nil {
+		return apperror.NotFound(fmt.Sprintf("no branch office found with the given ID: %d", id))
+	}
+
+	now := time.Now()
+	branchOffice.deletedAt = &now
+
+	// Users archived with the same timestamp are restored together with the branch office
+	for _, user := range s.users {
+		if user.BranchId == id && user.deletedAt == nil {
+			user.deletedAt = &now
+			if user.Role == "officer" {
+				s.totals.officer--
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package memory
+
+import (
+	"api-server/models"
+	"context"
+)
+
+// GetCompanyProfile returns the company profile, or nil when none has been set
+func (s *Store) GetCompanyProfile(_ context.Context) (*models.CompanyProfile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.companyProfile == nil {
+		return nil, nil
+	}
+
+	company := *s.companyProfile
+	company.NameAr = clone(s.companyProfile.NameAr)
+	company.NameEn = clone(s.companyProfile.NameEn)
+	return &company, nil
+}
+
+// UpdateCompanyProfile sets the company profile, keeping the logo when none is given. The store holds a
+// single profile, so the ID is not used.
+func (s *Store) UpdateCompanyProfile(_ context.Context, id uint, company *models.CompanyProfile) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	updated := *company
+	updated.NameAr = clone(company.NameAr)
+	updated.NameEn = clone(company.NameEn)
+	if updated.Logo == "" && s.companyProfile != nil {
+		updated.Logo = s.companyProfile.Logo
+	}
+	s.companyProfile = &updated
+
+	return nil
+}
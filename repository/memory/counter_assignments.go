@@ -0,0 +1,145 @@
+package memory
+
+import (
+	"api-server/apperror"
+	"api-server/models"
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// assignmentDetails returns the shifts matching a condition with the location of their counter and the
+// name of their officer, ordered by start
+func (s *Store) assignmentDetails(match func(assignment *models.CounterAssignment) bool) []models.CounterAssignmentDetail {
+	assignments := []models.CounterAssignmentDetail{}
+	for _, id := range sortedIDs(s.assignments) {
+		assignment := s.assignments[id]
+		counter, counterExists := s.counters[assignment.CounterID]
+		user := s.activeUser(assignment.UserID)
+		if !counterExists || user == nil || !match(assignment) {
+			continue
+		}
+
+		assignments = append(assignments, models.CounterAssignmentDetail{
+			ID:              assignment.ID,
+			CounterID:       assignment.CounterID,
+			CounterLocation: counter.CounterLocation,
+			UserID:          assignment.UserID,
+			FullName:        user.FullName,
+			BranchID:        assignment.BranchID,
+			StartsAt:        assignment.StartsAt,
+			EndsAt:          assignment.EndsAt,
+		})
+	}
+
+	sort.SliceStable(assignments, func(i, j int) bool {
+		if !assignments[i].StartsAt.Equal(assignments[j].StartsAt) {
+			return assignments[i].StartsAt.Before(assignments[j].StartsAt)
+		}
+		return assignments[i].CounterID < assignments[j].CounterID
+	})
+	return assignments
+}
+
+// overlaps tells whether a shift overlaps [from, to)
+func overlaps(assignment *models.CounterAssignment, from time.Time, to time.Time) bool {
+	return assignment.StartsAt.Before(to) && assignment.EndsAt.After(from)
+}
+
+// runningAt tells whether a shift is running at the given moment
+func runningAt(assignment *models.CounterAssignment, at time.Time) bool {
+	return !assignment.StartsAt.After(at) && assignment.EndsAt.After(at)
+}
+
+// CreateCounterAssignment inserts a shift unless it overlaps another shift of the same officer or
+// on the same counter. Overlapping shifts are returned instead and nothing is inserted.
+func (s *Store) CreateCounterAssignment(_ context.Context, assignment *models.CounterAssignment) ([]models.CounterAssignmentDetail, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	conflicts := s.assignmentDetails(func(other *models.CounterAssignment) bool {
+		return (other.UserID == assignment.UserID || other.CounterID == assignment.CounterID) &&
+			overlaps(other, assignment.StartsAt, assignment.EndsAt)
+	})
+	if len(conflicts) > 0 {
+		return conflicts, nil
+	}
+
+	if _, ok := s.counters[assignment.CounterID]; !ok {
+		return nil, foreignKeyViolation("counter_assignments", "counter_assignments_counter_id_fkey")
+	}
+	if _, ok := s.users[assignment.UserID]; !ok {
+		return nil, foreignKeyViolation("counter_assignments", "counter_assignments_user_id_fkey")
+	}
+	if _, ok := s.branchOffices[assignment.BranchID]; !ok {
+		return nil, foreignKeyViolation("counter_assignments", "counter_assignments_branch_id_fkey")
+	}
+
+	assignment.ID = s.nextID("counter_assignments")
+	stored := *assignment
+	s.assignments[stored.ID] = &stored
+
+	return nil, nil
+}
+
+// GetCounterAssignmentsByBranchID retrieves the shifts of a branch overlapping [from, to)
+func (s *Store) GetCounterAssignmentsByBranchID(_ context.Context, branchID uint, from time.Time, to time.Time) ([]models.CounterAssignmentDetail, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.assignmentDetails(func(assignment *models.CounterAssignment) bool {
+		return assignment.BranchID == branchID && overlaps(assignment, from, to)
+	}), nil
+}
+
+// GetCounterAssignmentsByCounterID retrieves the shifts on a counter overlapping [from, to)
+func (s *Store) GetCounterAssignmentsByCounterID(_ context.Context, counterID uint, from time.Time, to time.Time) ([]models.CounterAssignmentDetail, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.assignmentDetails(func(assignment *models.CounterAssignment) bool {
+		return assignment.CounterID == counterID && overlaps(assignment, from, to)
+	}), nil
+}
+
+// GetActiveAssignmentByCounterID returns the shift running on a counter at the given moment, or nil
+func (s *Store) GetActiveAssignmentByCounterID(_ context.Context, counterID uint, at time.Time) (*models.CounterAssignmentDetail, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return latestAssignment(s.assignmentDetails(func(assignment *models.CounterAssignment) bool {
+		return assignment.CounterID == counterID && runningAt(assignment, at)
+	})), nil
+}
+
+// GetActiveAssignmentByUserID returns the shift an officer is working at the given moment, or nil
+func (s *Store) GetActiveAssignmentByUserID(_ context.Context, userID uint, at time.Time) (*models.CounterAssignmentDetail, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return latestAssignment(s.assignmentDetails(func(assignment *models.CounterAssignment) bool {
+		return assignment.UserID == userID && runningAt(assignment, at)
+	})), nil
+}
+
+// latestAssignment returns the shift that started last, or nil when there is none
+func latestAssignment(assignments []models.CounterAssignmentDetail) *models.CounterAssignmentDetail {
+	if len(assignments) == 0 {
+		return nil
+	}
+	return &assignments[len(assignments)-1]
+}
+
+// DeleteCounterAssignment deletes a shift by ID
+func (s *Store) DeleteCounterAssignment(_ context.Context, id uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.assignments[id]; !ok {
+		return apperror.NotFound(fmt.Sprintf("no counter assignment found with the given ID: %d", id))
+	}
+
+	delete(s.assignments, id)
+	return nil
+}
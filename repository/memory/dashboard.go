@@ -0,0 +1,194 @@
+package memory
+
+import (
+	"api-server/apperror"
+	"api-server/models"
+	"context"
+	"sort"
+)
+
+// TotalDataDashboard returns the overall officer count and vote totals
+func (s *Store) TotalDataDashboard(_ context.Context) (int, int, int, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.totals.officer, s.totals.likes, s.totals.dislikes, s.totals.voted, nil
+}
+
+// inOptionalRegion tells whether a branch office belongs to a region, always true when no region is given
+func (s *Store) inOptionalRegion(branchID uint, regionID *uint) bool {
+	return regionID == nil || s.inRegion(branchID, *regionID)
+}
+
+// officerInOptionalRegion tells whether an officer works in a region, or received votes there before being
+// transferred away. Every officer is accepted when no region is given.
+func (s *Store) officerInOptionalRegion(officer *user, regionID *uint) bool {
+	if s.inOptionalRegion(officer.BranchId, regionID) {
+		return true
+	}
+	for _, f := range s.feedback {
+		if f.userID == officer.ID && !f.outOfHours && s.inRegion(f.branchID, *regionID) {
+			return true
+		}
+	}
+	return false
+}
+
+// countOfficers counts the active officers working in a branch office accepted by the filter
+func (s *Store) countOfficers(accept func(branchID uint) bool) int {
+	count := 0
+	for _, user := range s.users {
+		if user.Role == "officer" && user.deletedAt == nil && accept(user.BranchId) {
+			count++
+		}
+	}
+	return count
+}
+
+// TotalDataRegionDashboard computes the dashboard totals of the branch offices in a region
+func (s *Store) TotalDataRegionDashboard(_ context.Context, regionID uint) (int, int, int, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	officers := s.countOfficers(func(branchID uint) bool { return s.inRegion(branchID, regionID) })
+
+	likes, dislikes := 0, 0
+	for id, branchOffice := range s.branchOffices {
+		if branchOffice.deletedAt == nil && s.inRegion(id, regionID) {
+			likes += branchOffice.totalLikes
+			dislikes += branchOffice.totalDislikes
+		}
+	}
+
+	return officers, likes, dislikes, likes + dislikes, nil
+}
+
+// TotalDataBranchDashboard retrieves the vote totals per active branch office, optionally only those of one region
+func (s *Store) TotalDataBranchDashboard(_ context.Context, regionID *uint) ([]models.BranchData, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var branchDataList []models.BranchData
+	for _, id := range sortedIDs(s.branchOffices) {
+		branchOffice := s.branchOffices[id]
+		if branchOffice.deletedAt != nil || !s.inOptionalRegion(id, regionID) {
+			continue
+		}
+		branchDataList = append(branchDataList, models.BranchData{
+			ID:            int(id),
+			NameOffice:    branchOffice.nameOffice,
+			TotalLikes:    branchOffice.totalLikes,
+			TotalDislikes: branchOffice.totalDislikes,
+			BranchID:      int(id),
+		})
+	}
+	sort.SliceStable(branchDataList, func(i, j int) bool { return branchDataList[i].TotalLikes > branchDataList[j].TotalLikes })
+
+	return branchDataList, nil
+}
+
+// TotalDataRegionsDashboard rolls the active branch office totals up per region
+func (s *Store) TotalDataRegionsDashboard(_ context.Context) ([]models.RegionData, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	regions := []models.RegionData{}
+	for _, regionID := range sortedIDs(s.regions) {
+		region := models.RegionData{RegionID: regionID, RegionName: s.regions[regionID].name}
+		for id, branchOffice := range s.branchOffices {
+			if branchOffice.deletedAt == nil && s.inRegion(id, regionID) {
+				region.TotalBranches++
+				region.TotalLikes += branchOffice.totalLikes
+				region.TotalDislikes += branchOffice.totalDislikes
+			}
+		}
+		region.TotalOfficer = s.countOfficers(func(branchID uint) bool {
+			return s.activeBranchOffice(branchID) != nil && s.inRegion(branchID, regionID)
+		})
+		regions = append(regions, region)
+	}
+	sort.SliceStable(regions, func(i, j int) bool { return regions[i].TotalLikes > regions[j].TotalLikes })
+
+	return regions, nil
+}
+
+// DataOfficerDashboard lists officers by likes, optionally only those of one region. Votes are counted from
+// the feedback history by the branch office they were cast in.
+func (s *Store) DataOfficerDashboard(_ context.Context, limit uint, offset uint, regionID *uint) ([]models.DashboardUsers, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	type officerVotes struct {
+		id    uint
+		votes models.DashboardUsers
+	}
+
+	officers := []officerVotes{}
+	for _, id := range sortedIDs(s.users) {
+		user := s.users[id]
+		if user.Role != "officer" || user.deletedAt != nil {
+			continue
+		}
+
+		if !s.officerInOptionalRegion(user, regionID) {
+			continue
+		}
+
+		officer := officerVotes{id: id, votes: models.DashboardUsers{Name: user.FullName}}
+		for _, f := range s.feedback {
+			if f.userID == id && !f.outOfHours && s.inOptionalRegion(f.branchID, regionID) {
+				officer.votes.Likes += f.likes
+				officer.votes.Dislikes += f.dislikes
+			}
+		}
+		officers = append(officers, officer)
+	}
+	sort.SliceStable(officers, func(i, j int) bool { return officers[i].votes.Likes > officers[j].votes.Likes })
+
+	var users []models.DashboardUsers
+	start, end := paginate(len(officers), int(limit), int(offset))
+	for _, officer := range officers[start:end] {
+		users = append(users, officer.votes)
+	}
+	return users, nil
+}
+
+// OfficerCountDashboard counts officers, optionally only those of one region. It keeps the officers
+// DataOfficerDashboard lists, so that the pages match the count.
+func (s *Store) OfficerCountDashboard(_ context.Context, regionID *uint) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for _, user := range s.users {
+		if user.Role == "officer" && user.deletedAt == nil && s.officerInOptionalRegion(user, regionID) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// UpdateDashboard counts a vote in the overall totals and in the totals of its branch office
+func (s *Store) UpdateDashboard(_ context.Context, branchId uint, voteType string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	branchOffice := s.branchOffices[branchId]
+	switch voteType {
+	case "like":
+		s.totals.likes++
+		if branchOffice != nil {
+			branchOffice.totalLikes++
+		}
+	case "dislike":
+		s.totals.dislikes++
+		if branchOffice != nil {
+			branchOffice.totalDislikes++
+		}
+	default:
+		return apperror.BadRequest("invalid vote type")
+	}
+	s.totals.voted++
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package memory
+
+import (
+	"api-server/apperror"
+	"api-server/models"
+	"api-server/repository"
+	"context"
+	"fmt"
+	"sort"
+)
+
+// membershipResponse copies a branch membership with the name of its branch office
+func (s *Store) membershipResponse(membership *models.BranchMembership) models.BranchMembership {
+	response := *membership
+	response.EndsAt = clone(membership.EndsAt)
+	response.Reason = clone(membership.Reason)
+	if branchOffice, ok := s.branchOffices[membership.BranchID]; ok {
+		response.BranchName = branchOffice.Name
+	}
+	return response
+}
+
+// GetBranchMembershipsByUserID retrieves the branch memberships of a user, most recent first
+func (s *Store) GetBranchMembershipsByUserID(_ context.Context, userID uint) ([]models.BranchMembership, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	memberships := []models.BranchMembership{}
+	for _, id := range sortedIDs(s.memberships) {
+		membership := s.memberships[id]
+		if _, ok := s.branchOffices[membership.BranchID]; ok && membership.UserID == userID {
+			memberships = append(memberships, s.membershipResponse(membership))
+		}
+	}
+	sort.SliceStable(memberships, func(i, j int) bool { return memberships[i].StartsAt.After(memberships[j].StartsAt) })
+
+	return memberships, nil
+}
+
+// TransferUser moves a user to another branch office. The current membership is closed and a new one is
+// opened at the effective date, the user's counters in the old branch office are released and their shifts
+// there from the effective date on are cancelled or shortened.
+func (s *Store) TransferUser(_ context.Context, transfer *models.TransferRequest) (*models.TransferResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user := s.activeUser(transfer.UserID)
+	if user == nil {
+		return nil, apperror.NotFound(fmt.Sprintf("no user found with the given ID: %d", transfer.UserID))
+	}
+	oldBranchID := user.BranchId
+	if oldBranchID == 0 {
+		return nil, repository.ErrTransferNotBranchBound
+	}
+	if oldBranchID == transfer.BranchID {
+		return nil, repository.ErrTransferSameBranch
+	}
+	if s.activeBranchOffice(transfer.BranchID) == nil {
+		return nil, repository.ErrBranchOfficeNotFound
+	}
+
+	// Users created before memberships were tracked get one starting at their creation
+	var current *models.BranchMembership
+	for _, membership := range s.memberships {
+		if membership.UserID == transfer.UserID && membership.EndsAt == nil {
+			current = membership
+		}
+	}
+	if current == nil {
+		id := s.nextID("user_branch_memberships")
+		current = &models.BranchMembership{ID: id, UserID: transfer.UserID, BranchID: oldBranchID, StartsAt: user.createdAt}
+		s.memberships[id] = current
+	}
+
+	if !transfer.EffectiveAt.After(current.StartsAt) {
+		return nil, repository.ErrTransferBeforeStart
+	}
+
+	effectiveAt := transfer.EffectiveAt
+	current.EndsAt = &effectiveAt
+
+	opened := &models.BranchMembership{
+		ID:       s.nextID("user_branch_memberships"),
+		UserID:   transfer.UserID,
+		BranchID: transfer.BranchID,
+		StartsAt: transfer.EffectiveAt,
+	}
+	if transfer.Reason != "" {
+		reason := transfer.Reason
+		opened.Reason = &reason
+	}
+	s.memberships[opened.ID] = opened
+
+	user.BranchId = transfer.BranchID
+
+	result := &models.TransferResult{
+		ClosedMembership: s.membershipResponse(current),
+		OpenedMembership: s.membershipResponse(opened),
+	}
+
+	// Release the counters the user was linked to in the old branch office
+	for _, counter := range s.counters {
+		if counter.UserID == transfer.UserID && counter.BranchID == oldBranchID {
+			counter.UserID = 0
+			result.ReleasedCounters++
+		}
+	}
+
+	// Shifts in the old branch office starting from the effective date are cancelled, running ones end at it
+	for id, assignment := range s.assignments {
+		if assignment.UserID != transfer.UserID || assignment.BranchID != oldBranchID {
+			continue
+		}
+		if !assignment.StartsAt.Before(transfer.EffectiveAt) {
+			delete(s.assignments, id)
+			result.CancelledShifts++
+		} else if assignment.EndsAt.After(transfer.EffectiveAt) {
+			assignment.EndsAt = transfer.EffectiveAt
+			result.ShortenedShifts++
+		}
+	}
+
+	return result, nil
+}
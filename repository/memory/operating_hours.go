@@ -0,0 +1,142 @@
+package memory
+
+import (
+	"api-server/apperror"
+	"api-server/models"
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// GetOpeningHours retrieves the weekly opening hours of a branch office
+func (s *Store) GetOpeningHours(_ context.Context, branchID uint) ([]models.OpeningHours, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hours := append([]models.OpeningHours{}, s.openingHours[branchID]...)
+	sort.SliceStable(hours, func(i, j int) bool {
+		if hours[i].DayOfWeek != hours[j].DayOfWeek {
+			return hours[i].DayOfWeek < hours[j].DayOfWeek
+		}
+		return hours[i].OpensAt < hours[j].OpensAt
+	})
+
+	return hours, nil
+}
+
+// ReplaceOpeningHours replaces the weekly opening hours and the out-of-hours vote policy of a branch office
+func (s *Store) ReplaceOpeningHours(_ context.Context, branchID uint, hours []models.OpeningHours, outOfHoursPolicy string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	branchOffice, ok := s.branchOffices[branchID]
+	if !ok {
+		return apperror.NotFound(fmt.Sprintf("no branch office found with the given ID: %d", branchID))
+	}
+
+	branchOffice.outOfHoursPolicy = outOfHoursPolicy
+	s.openingHours[branchID] = append([]models.OpeningHours{}, hours...)
+
+	return nil
+}
+
+// GetCalendarExceptions retrieves the holidays and special hours of a branch office overlapping two dates (YYYY-MM-DD, inclusive)
+func (s *Store) GetCalendarExceptions(_ context.Context, branchID uint, fromDate string, toDate string) ([]models.CalendarException, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	exceptions := []models.CalendarException{}
+	for _, id := range sortedIDs(s.exceptions) {
+		exception := s.exceptions[id]
+		// Dates in the YYYY-MM-DD format compare like strings
+		if exception.BranchID == branchID && exception.StartsOn <= toDate && exception.EndsOn >= fromDate {
+			copied := *exception
+			copied.OpensAt = clone(exception.OpensAt)
+			copied.ClosesAt = clone(exception.ClosesAt)
+			exceptions = append(exceptions, copied)
+		}
+	}
+	sort.SliceStable(exceptions, func(i, j int) bool { return exceptions[i].StartsOn < exceptions[j].StartsOn })
+
+	return exceptions, nil
+}
+
+// CreateCalendarException adds a holiday or special hours period to a branch office
+func (s *Store) CreateCalendarException(_ context.Context, exception *models.CalendarException) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.branchOffices[exception.BranchID]; !ok {
+		return foreignKeyViolation("branch_calendar_exceptions", "branch_calendar_exceptions_branch_id_fkey")
+	}
+
+	exception.ID = s.nextID("branch_calendar_exceptions")
+	stored := *exception
+	stored.OpensAt = clone(exception.OpensAt)
+	stored.ClosesAt = clone(exception.ClosesAt)
+	s.exceptions[stored.ID] = &stored
+
+	return nil
+}
+
+// DeleteCalendarException deletes a holiday or special hours period of a branch office
+func (s *Store) DeleteCalendarException(_ context.Context, branchID uint, id uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	exception, ok := s.exceptions[id]
+	if !ok || exception.BranchID != branchID {
+		return apperror.NotFound(fmt.Sprintf("no calendar exception found with the given ID: %d", id))
+	}
+
+	delete(s.exceptions, id)
+	return nil
+}
+
+// GetTemporaryClosures retrieves the temporary closures of a branch office overlapping [from, to)
+func (s *Store) GetTemporaryClosures(_ context.Context, branchID uint, from time.Time, to time.Time) ([]models.TemporaryClosure, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	closures := []models.TemporaryClosure{}
+	for _, id := range sortedIDs(s.closures) {
+		closure := s.closures[id]
+		if closure.BranchID == branchID && closure.StartsAt.Before(to) && closure.EndsAt.After(from) {
+			closures = append(closures, *closure)
+		}
+	}
+	sort.SliceStable(closures, func(i, j int) bool { return closures[i].StartsAt.Before(closures[j].StartsAt) })
+
+	return closures, nil
+}
+
+// CreateTemporaryClosure adds a temporary closure to a branch office
+func (s *Store) CreateTemporaryClosure(_ context.Context, closure *models.TemporaryClosure) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.branchOffices[closure.BranchID]; !ok {
+		return foreignKeyViolation("branch_closures", "branch_closures_branch_id_fkey")
+	}
+
+	closure.ID = s.nextID("branch_closures")
+	stored := *closure
+	s.closures[stored.ID] = &stored
+
+	return nil
+}
+
+// DeleteTemporaryClosure deletes a temporary closure of a branch office
+func (s *Store) DeleteTemporaryClosure(_ context.Context, branchID uint, id uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	closure, ok := s.closures[id]
+	if !ok || closure.BranchID != branchID {
+		return apperror.NotFound(fmt.Sprintf("no temporary closure found with the given ID: %d", id))
+	}
+
+	delete(s.closures, id)
+	return nil
+}
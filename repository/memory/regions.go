@@ -0,0 +1,204 @@
+package memory
+
+import (
+	"api-server/apperror"
+	"api-server/models"
+	"context"
+	"fmt"
+	"sort"
+)
+
+// regionResponse describes a region with the number of its cities
+func (s *Store) regionResponse(region *region) models.RegionResponse {
+	response := models.RegionResponse{ID: region.id, Name: region.name}
+	for _, city := range s.cities {
+		if city.regionID == region.id {
+			response.TotalCities++
+		}
+	}
+	return response
+}
+
+// regionNameTaken tells whether another region has the name
+func (s *Store) regionNameTaken(name string, exceptID uint) bool {
+	for id, region := range s.regions {
+		if id != exceptID && region.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// cityNameTaken tells whether another city of the region has the name
+func (s *Store) cityNameTaken(regionID uint, name string, exceptID uint) bool {
+	for id, city := range s.cities {
+		if id != exceptID && city.regionID == regionID && city.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// GetAllRegions retrieves all regions with the number of cities in each
+func (s *Store) GetAllRegions(_ context.Context) ([]models.RegionResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	regions := []models.RegionResponse{}
+	for _, id := range sortedIDs(s.regions) {
+		regions = append(regions, s.regionResponse(s.regions[id]))
+	}
+	sort.SliceStable(regions, func(i, j int) bool { return regions[i].Name < regions[j].Name })
+
+	return regions, nil
+}
+
+// GetRegionByID retrieves a region by ID, returning nil when it does not exist
+func (s *Store) GetRegionByID(_ context.Context, id uint) (*models.RegionResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	region, ok := s.regions[id]
+	if !ok {
+		return nil, nil
+	}
+
+	response := s.regionResponse(region)
+	return &response, nil
+}
+
+// CreateRegion creates a new region
+func (s *Store) CreateRegion(_ context.Context, request *models.RegionCreateRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.regionNameTaken(request.Name, 0) {
+		return uniqueViolation("regions", "regions_name_key")
+	}
+
+	id := s.nextID("regions")
+	s.regions[id] = &region{id: id, name: request.Name}
+	return nil
+}
+
+// UpdateRegion updates an existing region by ID
+func (s *Store) UpdateRegion(_ context.Context, id uint, request *models.RegionCreateRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.regions[id]
+	if !ok {
+		return apperror.NotFound(fmt.Sprintf("no region found with the given ID: %d", id))
+	}
+	if s.regionNameTaken(request.Name, id) {
+		return uniqueViolation("regions", "regions_name_key")
+	}
+
+	stored.name = request.Name
+	return nil
+}
+
+// DeleteRegion deletes a region by ID. Regions that still have cities cannot be deleted.
+func (s *Store) DeleteRegion(_ context.Context, id uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.regions[id]; !ok {
+		return apperror.NotFound(fmt.Sprintf("no region found with the given ID: %d", id))
+	}
+	for _, city := range s.cities {
+		if city.regionID == id {
+			return foreignKeyViolation("cities", "cities_region_id_fkey")
+		}
+	}
+
+	delete(s.regions, id)
+	for _, user := range s.users {
+		if user.RegionId != nil && *user.RegionId == id {
+			user.RegionId = nil
+		}
+	}
+
+	return nil
+}
+
+// GetAllCities retrieves all cities, optionally only those of one region
+func (s *Store) GetAllCities(_ context.Context, regionID *uint) ([]models.CityResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cities := []models.CityResponse{}
+	for _, id := range sortedIDs(s.cities) {
+		city := s.cities[id]
+		region, ok := s.regions[city.regionID]
+		if !ok || (regionID != nil && city.regionID != *regionID) {
+			continue
+		}
+		cities = append(cities, models.CityResponse{ID: id, Name: city.name, RegionID: region.id, RegionName: region.name})
+	}
+	sort.SliceStable(cities, func(i, j int) bool {
+		if cities[i].RegionName != cities[j].RegionName {
+			return cities[i].RegionName < cities[j].RegionName
+		}
+		return cities[i].Name < cities[j].Name
+	})
+
+	return cities, nil
+}
+
+// CreateCity creates a new city in a region
+func (s *Store) CreateCity(_ context.Context, request *models.CityCreateRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.regions[request.RegionID]; !ok {
+		return foreignKeyViolation("cities", "cities_region_id_fkey")
+	}
+	if s.cityNameTaken(request.RegionID, request.Name, 0) {
+		return uniqueViolation("cities", "cities_region_id_name_key")
+	}
+
+	id := s.nextID("cities")
+	s.cities[id] = &city{id: id, name: request.Name, regionID: request.RegionID}
+	return nil
+}
+
+// UpdateCity updates an existing city by ID
+func (s *Store) UpdateCity(_ context.Context, id uint, request *models.CityCreateRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.cities[id]
+	if !ok {
+		return apperror.NotFound(fmt.Sprintf("no city found with the given ID: %d", id))
+	}
+	if _, ok := s.regions[request.RegionID]; !ok {
+		return foreignKeyViolation("cities", "cities_region_id_fkey")
+	}
+	if s.cityNameTaken(request.RegionID, request.Name, id) {
+		return uniqueViolation("cities", "cities_region_id_name_key")
+	}
+
+	stored.name = request.Name
+	stored.regionID = request.RegionID
+	return nil
+}
+
+// DeleteCity deletes a city by ID. Its branch offices are detached from the city, not deleted.
+func (s *Store) DeleteCity(_ context.Context, id uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.cities[id]; !ok {
+		return apperror.NotFound(fmt.Sprintf("no city found with the given ID: %d", id))
+	}
+
+	delete(s.cities, id)
+	for _, branchOffice := range s.branchOffices {
+		if branchOffice.CityID != nil && *branchOffice.CityID == id {
+			branchOffice.CityID = nil
+		}
+	}
+
+	return nil
+}
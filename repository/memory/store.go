@@ -0,0 +1,292 @@
+// Package memory implements the repository interfaces on in-memory tables, for testing the services and
+// handlers without a database. It mirrors the constraints and cascades of the PostgreSQL schema the services
+// rely on, and reports constraint violations as *pq.Error like the database does.
+package memory
+
+import (
+	"api-server/models"
+	"api-server/repository"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type region struct {
+	id   uint
+	name string
+}
+
+type city struct {
+	id       uint
+	name     string
+	regionID uint
+}
+
+type branchOffice struct {
+	models.BranchOfficeCreateRequest
+	id               uint
+	outOfHoursPolicy string
+	deletedAt        *time.Time
+
+	// Dashboard totals of the branch office (total_data_branch)
+	nameOffice    string
+	totalLikes    int
+	totalDislikes int
+}
+
+type user struct {
+	models.User
+	createdAt time.Time
+	deletedAt *time.Time
+}
+
+type feedback struct {
+	id          uint
+	likes       uint
+	dislikes    uint
+	officerName string
+	userID      uint
+	branchID    uint
+	counterID   *uint
+	outOfHours  bool
+	createdAt   time.Time
+}
+
+// totals are the overall dashboard totals (total_data)
+type totals struct {
+	officer  int
+	likes    int
+	dislikes int
+	voted    int
+}
+
+// Store holds every table in memory. It implements all repository interfaces and is safe for concurrent use.
+type Store struct {
+	mu        sync.Mutex
+	sequences map[string]uint
+
+	regions        map[uint]*region
+	cities         map[uint]*city
+	branchOffices  map[uint]*branchOffice
+	users          map[uint]*user
+	counters       map[uint]*models.BranchCounter
+	assignments    map[uint]*models.CounterAssignment
+	memberships    map[uint]*models.BranchMembership
+	openingHours   map[uint][]models.OpeningHours
+	exceptions     map[uint]*models.CalendarException
+	closures       map[uint]*models.TemporaryClosure
+	feedback       []feedback
+	purgeAuditLog  []models.PurgeAuditEntry
+	totals         totals
+	companyProfile *models.CompanyProfile
+
+	// capacityDeactivated holds the counters deactivated by a capacity shrink, reactivated when it grows
+	capacityDeactivated map[uint]bool
+	// retiredCounters holds when the counters deleted with shift history were retired
+	retiredCounters map[uint]time.Time
+}
+
+// NewStore creates an empty store
+func NewStore() *Store {
+	return &Store{
+		sequences:     map[string]uint{},
+		regions:       map[uint]*region{},
+		cities:        map[uint]*city{},
+		branchOffices: map[uint]*branchOffice{},
+		users:         map[uint]*user{},
+		counters:      map[uint]*models.BranchCounter{},
+		assignments:   map[uint]*models.CounterAssignment{},
+		memberships:   map[uint]*models.BranchMembership{},
+		openingHours:  map[uint][]models.OpeningHours{},
+		exceptions:    map[uint]*models.CalendarException{},
+		closures:      map[uint]*models.TemporaryClosure{},
+
+		capacityDeactivated: map[uint]bool{},
+		retiredCounters:     map[uint]time.Time{},
+	}
+}
+
+// Repositories returns the store as every repository
+func (s *Store) Repositories() *repository.Repositories {
+	return &repository.Repositories{
+		Users:              s,
+		BranchOffices:      s,
+		BranchCounters:     s,
+		CounterAssignments: s,
+		Dashboard:          s,
+		Votes:              s,
+		Analytics:          s,
+		Archive:            s,
+		Memberships:        s,
+		OperatingHours:     s,
+		Regions:            s,
+		Company:            s,
+		Uploads:            s,
+	}
+}
+
+// NewRepositories creates the repositories on a new empty store
+func NewRepositories() *repository.Repositories {
+	return NewStore().Repositories()
+}
+
+// nextID returns the next value of the ID sequence of a table
+func (s *Store) nextID(table string) uint {
+	s.sequences[table]++
+	return s.sequences[table]
+}
+
+// Errors reported like the PostgreSQL constraint violations of the same table
+func uniqueViolation(table string, constraint string) error {
+	return &pq.Error{Code: "23505", Table: table, Constraint: constraint,
+		Message: `duplicate key value violates unique constraint "` + constraint + `"`}
+}
+
+func foreignKeyViolation(table string, constraint string) error {
+	return &pq.Error{Code: "23503", Table: table, Constraint: constraint,
+		Message: `insert or update on table "` + table + `" violates foreign key constraint "` + constraint + `"`}
+}
+
+// sortedIDs returns the keys of a table in ascending order
+func sortedIDs[T any](table map[uint]T) []uint {
+	ids := make([]uint, 0, len(table))
+	for id := range table {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// paginate returns the bounds of a LIMIT/OFFSET page of n rows
+func paginate(n, limit, offset int) (int, int) {
+	start := offset
+	if start > n {
+		start = n
+	}
+	end := n
+	if limit >= 0 && start+limit < n {
+		end = start + limit
+	}
+	return start, end
+}
+
+// clone copies an optional value so the store never shares memory with its callers
+func clone[T any](value *T) *T {
+	if value == nil {
+		return nil
+	}
+	copied := *value
+	return &copied
+}
+
+// sameEmail compares emails case-insensitively, like the unique index on LOWER(email)
+func sameEmail(a, b string) bool {
+	return strings.EqualFold(a, b)
+}
+
+// dislikeRatio returns the share of dislikes among all votes, or 0 when there are no votes
+func dislikeRatio(likes uint, dislikes uint) float64 {
+	if likes+dislikes == 0 {
+		return 0
+	}
+	return float64(dislikes) / float64(likes+dislikes)
+}
+
+// regionOf returns the region of a branch office through its city, nil when it has none
+func (s *Store) regionOf(branchOffice *branchOffice) *uint {
+	if branchOffice == nil || branchOffice.CityID == nil {
+		return nil
+	}
+	city, ok := s.cities[*branchOffice.CityID]
+	if !ok {
+		return nil
+	}
+	return &city.regionID
+}
+
+// inRegion tells whether a branch office (archived or not) belongs to a region
+func (s *Store) inRegion(branchID uint, regionID uint) bool {
+	regionOfBranch := s.regionOf(s.branchOffices[branchID])
+	return regionOfBranch != nil && *regionOfBranch == regionID
+}
+
+// activeBranchOffice returns a branch office unless it does not exist or is archived
+func (s *Store) activeBranchOffice(id uint) *branchOffice {
+	branchOffice, ok := s.branchOffices[id]
+	if !ok || branchOffice.deletedAt != nil {
+		return nil
+	}
+	return branchOffice
+}
+
+// activeUser returns a user unless they do not exist or are archived
+func (s *Store) activeUser(id uint) *user {
+	user, ok := s.users[id]
+	if !ok || user.deletedAt != nil {
+		return nil
+	}
+	return user
+}
+
+// emailTaken tells whether another active user has the email
+func (s *Store) emailTaken(email string, exceptID uint) bool {
+	for id, user := range s.users {
+		if id != exceptID && user.deletedAt == nil && sameEmail(user.Email, email) {
+			return true
+		}
+	}
+	return false
+}
+
+// deleteUser removes a user with the rows referencing them, as the ON DELETE rules of the schema do
+func (s *Store) deleteUser(id uint) {
+	delete(s.users, id)
+
+	for _, counter := range s.counters {
+		if counter.UserID == id {
+			counter.UserID = 0
+		}
+	}
+	for assignmentID, assignment := range s.assignments {
+		if assignment.UserID == id {
+			delete(s.assignments, assignmentID)
+		}
+	}
+	for membershipID, membership := range s.memberships {
+		if membership.UserID == id {
+			delete(s.memberships, membershipID)
+		}
+	}
+	s.deleteFeedback(func(f *feedback) bool { return f.userID == id })
+}
+
+// deleteFeedback removes the feedback history rows matching a condition
+func (s *Store) deleteFeedback(match func(f *feedback) bool) {
+	kept := s.feedback[:0]
+	for _, f := range s.feedback {
+		if !match(&f) {
+			kept = append(kept, f)
+		}
+	}
+	s.feedback = kept
+}
+
+// Ensure the store implements every repository interface
+var (
+	_ repository.UserRepository              = (*Store)(nil)
+	_ repository.BranchOfficeRepository      = (*Store)(nil)
+	_ repository.BranchCounterRepository     = (*Store)(nil)
+	_ repository.CounterAssignmentRepository = (*Store)(nil)
+	_ repository.DashboardRepository         = (*Store)(nil)
+	_ repository.VoteRepository              = (*Store)(nil)
+	_ repository.AnalyticsRepository         = (*Store)(nil)
+	_ repository.ArchiveRepository           = (*Store)(nil)
+	_ repository.MembershipRepository        = (*Store)(nil)
+	_ repository.OperatingHoursRepository    = (*Store)(nil)
+	_ repository.RegionRepository            = (*Store)(nil)
+	_ repository.CompanyRepository           = (*Store)(nil)
+	_ repository.UploadRepository            = (*Store)(nil)
+)
@@ -0,0 +1,45 @@
+package memory
+
+import (
+	"context"
+)
+
+// GetReferencedImages lists the profile images of the users, soft-deleted ones included
+func (s *Store) GetReferencedImages(_ context.Context) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen := map[string]bool{}
+	images := []string{}
+	for _, id := range sortedIDs(s.users) {
+		if image := s.users[id].Image; image != "" && !seen[image] {
+			seen[image] = true
+			images = append(images, image)
+		}
+	}
+	return images, nil
+}
+
+// GetReferencedLogos lists the logo of the company profile
+func (s *Store) GetReferencedLogos(_ context.Context) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.companyProfile == nil || s.companyProfile.Logo == "" {
+		return []string{}, nil
+	}
+	return []string{s.companyProfile.Logo}, nil
+}
+
+// IsImageReferenced tells whether a user has the profile image
+func (s *Store) IsImageReferenced(_ context.Context, image string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, user := range s.users {
+		if user.Image == image {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,373 @@
+package memory
+
+import (
+	"api-server/apperror"
+	"api-server/helpers"
+	"api-server/models"
+	"api-server/repository"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// GetAllUsers retrieves the active officers, or every other active user, with pagination
+func (s *Store) GetAllUsers(_ context.Context, limit, offset int, role string) ([]models.UserAllResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var users []models.UserAllResponse
+	for _, id := range sortedIDs(s.users) {
+		user := s.users[id]
+		if user.deletedAt != nil || (user.Role == "officer") != (role == "officer") {
+			continue
+		}
+
+		response := models.UserAllResponse{
+			ID:         user.ID,
+			FullName:   user.FullName,
+			FullNameAr: clone(user.FullNameAr),
+			FullNameEn: clone(user.FullNameEn),
+			Email:      user.Email,
+			Role:       user.Role,
+			Likes:      user.Likes,
+			Dislikes:   user.Dislikes,
+			Image:      user.Image,
+			RegionId:   clone(user.RegionId),
+		}
+		if user.BranchId != 0 {
+			branchID := user.BranchId
+			response.BranchId = &branchID
+		}
+		users = append(users, response)
+	}
+
+	start, end := paginate(len(users), limit, offset)
+	return users[start:end], nil
+}
+
+// GetUsersCount counts the active officers, or every other active user
+func (s *Store) GetUsersCount(_ context.Context, role string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for _, user := range s.users {
+		if user.deletedAt == nil && (user.Role == "officer") == (role == "officer") {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// GetUserByID retrieves an active user, returning nil when there is none
+func (s *Store) GetUserByID(_ context.Context, id uint) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user := s.activeUser(id)
+	if user == nil {
+		return nil, nil
+	}
+
+	copied := user.User
+	copied.FullNameAr = clone(user.FullNameAr)
+	copied.FullNameEn = clone(user.FullNameEn)
+	copied.RegionId = clone(user.RegionId)
+	return &copied, nil
+}
+
+// CreateUser inserts a user with an already hashed password
+func (s *Store) CreateUser(ctx context.Context, user *models.User) error {
+	users := []models.User{*user}
+	if err := s.CreateUsers(ctx, users); err != nil {
+		return err
+	}
+
+	*user = users[0]
+	return nil
+}
+
+// CreateUsers inserts several users with already hashed passwords, all or none
+func (s *Store) CreateUsers(_ context.Context, users []models.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Check every constraint first so nothing is inserted when one row fails
+	emails := map[string]bool{}
+	for _, user := range users {
+		email := strings.ToLower(user.Email)
+		if emails[email] || s.emailTaken(user.Email, 0) {
+			return uniqueViolation("users", "idx_users_email_active")
+		}
+		emails[email] = true
+
+		if err := s.checkUserReferences(&user); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	for i := range users {
+		users[i].ID = s.nextID("users")
+		stored := &user{User: users[i], createdAt: now}
+		stored.FullNameAr = clone(users[i].FullNameAr)
+		stored.FullNameEn = clone(users[i].FullNameEn)
+		stored.RegionId = clone(users[i].RegionId)
+		s.users[stored.ID] = stored
+
+		// Open the first branch membership of users working in a branch office
+		if stored.BranchId != 0 {
+			id := s.nextID("user_branch_memberships")
+			s.memberships[id] = &models.BranchMembership{ID: id, UserID: stored.ID, BranchID: stored.BranchId, StartsAt: now}
+		}
+		if stored.Role == "officer" {
+			s.totals.officer++
+		}
+	}
+
+	return nil
+}
+
+// checkUserReferences checks the branch office and region of a user exist
+func (s *Store) checkUserReferences(user *models.User) error {
+	if _, ok := s.branchOffices[user.BranchId]; user.BranchId != 0 && !ok {
+		return foreignKeyViolation("users", "users_branch_id_fkey")
+	}
+	if user.RegionId != nil {
+		if _, ok := s.regions[*user.RegionId]; !ok {
+			return foreignKeyViolation("users", "users_region_id_fkey")
+		}
+	}
+	return nil
+}
+
+// UpdateUser updates a user by ID, storing the password as given (already hashed). The image is kept
+// when none is given.
+func (s *Store) UpdateUser(_ context.Context, id uint, update *models.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return nil
+	}
+
+	if user.deletedAt == nil && s.emailTaken(update.Email, id) {
+		return uniqueViolation("users", "idx_users_email_active")
+	}
+	if err := s.checkUserReferences(update); err != nil {
+		return err
+	}
+	if user.BranchId != 0 && update.BranchId != 0 && user.BranchId != update.BranchId {
+		return repository.ErrBranchChangeNeedsTransfer
+	}
+
+	now := time.Now()
+	switch {
+	case user.BranchId == 0 && update.BranchId != 0:
+		membershipID := s.nextID("user_branch_memberships")
+		s.memberships[membershipID] = &models.BranchMembership{ID: membershipID, UserID: id, BranchID: update.BranchId, StartsAt: now}
+	case user.BranchId != 0 && update.BranchId == 0:
+		for _, membership := range s.memberships {
+			if membership.UserID == id && membership.EndsAt == nil {
+				membership.EndsAt = &now
+			}
+		}
+		s.releaseCountersAndShifts(id, now)
+	}
+
+	user.FullName = update.FullName
+	user.FullNameAr = clone(update.FullNameAr)
+	user.FullNameEn = clone(update.FullNameEn)
+	user.Email = update.Email
+	user.Password = update.Password
+	user.Role = update.Role
+	if update.Image != "" {
+		user.Image = update.Image
+	}
+	user.BranchId = update.BranchId
+	user.RegionId = clone(update.RegionId)
+
+	return nil
+}
+
+// DeleteUser archives a user and decrements the total officer count if the user is an officer
+func (s *Store) DeleteUser(_ context.Context, id uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user := s.activeUser(id)
+	if user == nil {
+		return apperror.NotFound(fmt.Sprintf("no user found with the given ID: %d", id))
+	}
+
+	now := time.Now()
+	user.deletedAt = &now
+	if user.Role == "officer" {
+		s.totals.officer--
+	}
+
+	s.releaseCountersAndShifts(id, now)
+
+	return nil
+}
+
+// releaseCountersAndShifts unlinks a user leaving service from their counters: shifts not started yet are
+// cancelled, running ones end now
+func (s *Store) releaseCountersAndShifts(userID uint, now time.Time) {
+	for _, counter := range s.counters {
+		if counter.UserID == userID {
+			counter.UserID = 0
+		}
+	}
+
+	for id, assignment := range s.assignments {
+		if assignment.UserID != userID {
+			continue
+		}
+		if !assignment.StartsAt.Before(now) {
+			delete(s.assignments, id)
+		} else if assignment.EndsAt.After(now) {
+			assignment.EndsAt = now
+		}
+	}
+}
+
+// GetAllUsersByBranchOfiice lists the active officers of a branch that are not linked to a counter
+func (s *Store) GetAllUsersByBranchOfiice(_ context.Context, branchId uint) ([]models.UserByBranchOfiiceResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	linked := map[uint]bool{}
+	for _, counter := range s.counters {
+		linked[counter.UserID] = true
+	}
+
+	var users []models.UserByBranchOfiiceResponse
+	for _, id := range sortedIDs(s.users) {
+		user := s.users[id]
+		if user.deletedAt == nil && user.BranchId == branchId && user.Role == "officer" && !linked[id] {
+			users = append(users, models.UserByBranchOfiiceResponse{ID: id, FullName: user.FullName})
+		}
+	}
+	return users, nil
+}
+
+// GetAvailableOfficersByBranchOffice lists the officers of a branch without any shift overlapping [from, to)
+func (s *Store) GetAvailableOfficersByBranchOffice(_ context.Context, branchId uint, from time.Time, to time.Time) ([]models.UserByBranchOfiiceResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	busy := map[uint]bool{}
+	for _, assignment := range s.assignments {
+		if assignment.StartsAt.Before(to) && assignment.EndsAt.After(from) {
+			busy[assignment.UserID] = true
+		}
+	}
+
+	var users []models.UserByBranchOfiiceResponse
+	for _, id := range sortedIDs(s.users) {
+		user := s.users[id]
+		if user.deletedAt == nil && user.BranchId == branchId && user.Role == "officer" && !busy[id] {
+			users = append(users, models.UserByBranchOfiiceResponse{ID: id, FullName: user.FullName})
+		}
+	}
+	sort.SliceStable(users, func(i, j int) bool { return users[i].FullName < users[j].FullName })
+
+	return users, nil
+}
+
+// findActiveUserByEmail returns the active user with an email, compared case-insensitively
+func (s *Store) findActiveUserByEmail(email string) *user {
+	for _, id := range sortedIDs(s.users) {
+		if user := s.users[id]; user.deletedAt == nil && sameEmail(user.Email, email) {
+			return user
+		}
+	}
+	return nil
+}
+
+// CheckUserAuthentication checks the credentials of an active user
+func (s *Store) CheckUserAuthentication(ctx context.Context, email string, password string) (models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := s.findActiveUserByEmail(email)
+	if stored == nil {
+		return models.User{}, apperror.Unauthorized("invalid email")
+	}
+
+	user := models.User{
+		ID:         stored.ID,
+		FullName:   stored.FullName,
+		FullNameAr: clone(stored.FullNameAr),
+		FullNameEn: clone(stored.FullNameEn),
+		Email:      stored.Email,
+		Role:       stored.Role,
+		Password:   stored.Password,
+	}
+	if !helpers.CheckPasswordHashFunc(ctx, password, user.Password) {
+		return user, apperror.Unauthorized("invalid password")
+	}
+
+	return user, nil
+}
+
+// CheckUserAuthenticationMobile checks the credentials of an active user signing in on a branch office.
+// Regional managers may sign in on any active branch office of their region.
+func (s *Store) CheckUserAuthenticationMobile(ctx context.Context, email string, password string, branchID uint) (models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := s.findActiveUserByEmail(email)
+	if stored == nil {
+		return models.User{}, apperror.Unauthorized("email not found")
+	}
+
+	user := models.User{
+		ID:         stored.ID,
+		FullName:   stored.FullName,
+		FullNameAr: clone(stored.FullNameAr),
+		FullNameEn: clone(stored.FullNameEn),
+		Email:      stored.Email,
+		Role:       stored.Role,
+		Password:   stored.Password,
+		BranchId:   stored.BranchId,
+		RegionId:   clone(stored.RegionId),
+	}
+
+	if user.Role == "regional_manager" {
+		if s.activeBranchOffice(branchID) == nil || user.RegionId == nil || !s.inRegion(branchID, *user.RegionId) {
+			return user, apperror.Unauthorized("invalid branch office")
+		}
+	} else if user.BranchId != branchID {
+		return user, apperror.Unauthorized("invalid branch office")
+	}
+
+	if !helpers.CheckPasswordHashFunc(ctx, password, user.Password) {
+		return user, apperror.Unauthorized("invalid password")
+	}
+
+	return user, nil
+}
+
+// GetActiveUserEmails returns which of the given emails already belong to an active user
+func (s *Store) GetActiveUserEmails(_ context.Context, emails []string) (map[string]bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wanted := map[string]bool{}
+	for _, email := range emails {
+		wanted[email] = true
+	}
+
+	taken := map[string]bool{}
+	for _, user := range s.users {
+		if email := strings.ToLower(user.Email); user.deletedAt == nil && wanted[email] {
+			taken[email] = true
+		}
+	}
+	return taken, nil
+}
@@ -0,0 +1,52 @@
+package memory
+
+import (
+	"api-server/apperror"
+	"api-server/models"
+	"context"
+	"time"
+)
+
+// VotedUserLike records a vote for an officer, attributing it to the counter where the service happened (if known).
+// Votes flagged as out of hours are kept in the history but do not count towards the officer's likes/dislikes.
+func (s *Store) VotedUserLike(_ context.Context, voteType string, data *models.User, counterID *uint, outOfHours bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var likes, dislikes uint
+	switch voteType {
+	case "like":
+		likes = 1
+	case "dislike":
+		dislikes = 1
+	default:
+		return apperror.BadRequest("invalid vote type")
+	}
+
+	user, ok := s.users[data.ID]
+	if !ok {
+		return foreignKeyViolation("user_feedback_history", "user_feedback_history_user_id_fkey")
+	}
+	if _, ok := s.branchOffices[data.BranchId]; !ok {
+		return foreignKeyViolation("user_feedback_history", "user_feedback_history_branch_id_fkey")
+	}
+
+	if !outOfHours {
+		user.Likes += likes
+		user.Dislikes += dislikes
+	}
+
+	s.feedback = append(s.feedback, feedback{
+		id:          s.nextID("user_feedback_history"),
+		likes:       likes,
+		dislikes:    dislikes,
+		officerName: data.FullName,
+		userID:      data.ID,
+		branchID:    data.BranchId,
+		counterID:   clone(counterID),
+		outOfHours:  outOfHours,
+		createdAt:   time.Now().UTC(),
+	})
+
+	return nil
+}
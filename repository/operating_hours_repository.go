@@ -0,0 +1,250 @@
+package repository
+
+import (
+	"api-server/apperror"
+	"api-server/models"
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// PostgresOperatingHoursRepository stores operating hours in PostgreSQL
+type PostgresOperatingHoursRepository struct {
+	postgres
+}
+
+// NewPostgresOperatingHoursRepository creates the repository on the given database
+func NewPostgresOperatingHoursRepository(db *sql.DB, statementTimeout time.Duration) *PostgresOperatingHoursRepository {
+	return &PostgresOperatingHoursRepository{postgres{db: db, statementTimeout: statementTimeout}}
+}
+
+// GetOpeningHours retrieves the weekly opening hours of a branch office
+func (r *PostgresOperatingHoursRepository) GetOpeningHours(ctx context.Context, branchID uint) ([]models.OpeningHours, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT day_of_week, to_char(opens_at, 'HH24:MI'), to_char(closes_at, 'HH24:MI')
+		FROM branch_opening_hours
+		WHERE branch_id = $1
+		ORDER BY day_of_week ASC, opens_at ASC`, branchID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error querying opening hours", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	hours := []models.OpeningHours{}
+	for rows.Next() {
+		var interval models.OpeningHours
+		if err := rows.Scan(&interval.DayOfWeek, &interval.OpensAt, &interval.ClosesAt); err != nil {
+			slog.ErrorContext(ctx, "Error scanning opening hours", "error", err)
+			return nil, err
+		}
+		hours = append(hours, interval)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.ErrorContext(ctx, "Error after iterating opening hours", "error", err)
+		return nil, err
+	}
+
+	return hours, nil
+}
+
+// ReplaceOpeningHours replaces the weekly opening hours and the out-of-hours vote policy of a branch office
+func (r *PostgresOperatingHoursRepository) ReplaceOpeningHours(ctx context.Context, branchID uint, hours []models.OpeningHours, outOfHoursPolicy string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error starting transaction", "error", err)
+		return err
+	}
+	defer tx.Rollback() // Rollback in case of an error
+
+	result, err := tx.ExecContext(ctx, "UPDATE branch_offices SET out_of_hours_vote_policy = $1 WHERE id = $2", outOfHoursPolicy, branchID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error updating out-of-hours vote policy", "error", err)
+		return err
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return apperror.NotFound(fmt.Sprintf("no branch office found with the given ID: %d", branchID))
+	}
+
+	if _, err = tx.ExecContext(ctx, "DELETE FROM branch_opening_hours WHERE branch_id = $1", branchID); err != nil {
+		slog.ErrorContext(ctx, "Error deleting opening hours", "error", err)
+		return err
+	}
+
+	for _, interval := range hours {
+		_, err = tx.ExecContext(ctx, "INSERT INTO branch_opening_hours (branch_id, day_of_week, opens_at, closes_at) VALUES ($1, $2, $3, $4)",
+			branchID, interval.DayOfWeek, interval.OpensAt, interval.ClosesAt)
+		if err != nil {
+			slog.ErrorContext(ctx, "Error inserting opening hours", "error", err)
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		slog.ErrorContext(ctx, "Error committing transaction", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// GetCalendarExceptions retrieves the holidays and special hours of a branch office overlapping two dates (YYYY-MM-DD, inclusive)
+func (r *PostgresOperatingHoursRepository) GetCalendarExceptions(ctx context.Context, branchID uint, fromDate string, toDate string) ([]models.CalendarException, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT
+			id, branch_id, name, kind,
+			to_char(starts_on, 'YYYY-MM-DD'), to_char(ends_on, 'YYYY-MM-DD'),
+			to_char(opens_at, 'HH24:MI'), to_char(closes_at, 'HH24:MI')
+		FROM branch_calendar_exceptions
+		WHERE branch_id = $1 AND starts_on <= $3::date AND ends_on >= $2::date
+		ORDER BY starts_on ASC`, branchID, fromDate, toDate)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error querying calendar exceptions", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	exceptions := []models.CalendarException{}
+	for rows.Next() {
+		var exception models.CalendarException
+		if err := rows.Scan(
+			&exception.ID,
+			&exception.BranchID,
+			&exception.Name,
+			&exception.Kind,
+			&exception.StartsOn,
+			&exception.EndsOn,
+			&exception.OpensAt,
+			&exception.ClosesAt,
+		); err != nil {
+			slog.ErrorContext(ctx, "Error scanning calendar exception", "error", err)
+			return nil, err
+		}
+		exceptions = append(exceptions, exception)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.ErrorContext(ctx, "Error after iterating calendar exceptions", "error", err)
+		return nil, err
+	}
+
+	return exceptions, nil
+}
+
+// CreateCalendarException adds a holiday or special hours period to a branch office
+func (r *PostgresOperatingHoursRepository) CreateCalendarException(ctx context.Context, exception *models.CalendarException) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO branch_calendar_exceptions (branch_id, name, kind, starts_on, ends_on, opens_at, closes_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id`,
+		exception.BranchID, exception.Name, exception.Kind, exception.StartsOn, exception.EndsOn, exception.OpensAt, exception.ClosesAt,
+	).Scan(&exception.ID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error creating calendar exception", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// DeleteCalendarException deletes a holiday or special hours period of a branch office
+func (r *PostgresOperatingHoursRepository) DeleteCalendarException(ctx context.Context, branchID uint, id uint) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, "DELETE FROM branch_calendar_exceptions WHERE id = $1 AND branch_id = $2", id, branchID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error deleting calendar exception", "error", err)
+		return err
+	}
+
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return apperror.NotFound(fmt.Sprintf("no calendar exception found with the given ID: %d", id))
+	}
+
+	return nil
+}
+
+// GetTemporaryClosures retrieves the temporary closures of a branch office overlapping [from, to)
+func (r *PostgresOperatingHoursRepository) GetTemporaryClosures(ctx context.Context, branchID uint, from time.Time, to time.Time) ([]models.TemporaryClosure, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, branch_id, reason, starts_at, ends_at
+		FROM branch_closures
+		WHERE branch_id = $1 AND starts_at < $3 AND ends_at > $2
+		ORDER BY starts_at ASC`, branchID, from, to)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error querying temporary closures", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	closures := []models.TemporaryClosure{}
+	for rows.Next() {
+		var closure models.TemporaryClosure
+		if err := rows.Scan(&closure.ID, &closure.BranchID, &closure.Reason, &closure.StartsAt, &closure.EndsAt); err != nil {
+			slog.ErrorContext(ctx, "Error scanning temporary closure", "error", err)
+			return nil, err
+		}
+		closures = append(closures, closure)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.ErrorContext(ctx, "Error after iterating temporary closures", "error", err)
+		return nil, err
+	}
+
+	return closures, nil
+}
+
+// CreateTemporaryClosure adds a temporary closure to a branch office
+func (r *PostgresOperatingHoursRepository) CreateTemporaryClosure(ctx context.Context, closure *models.TemporaryClosure) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	err := r.db.QueryRowContext(ctx,
+		"INSERT INTO branch_closures (branch_id, reason, starts_at, ends_at) VALUES ($1, $2, $3, $4) RETURNING id",
+		closure.BranchID, closure.Reason, closure.StartsAt, closure.EndsAt,
+	).Scan(&closure.ID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error creating temporary closure", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// DeleteTemporaryClosure deletes a temporary closure of a branch office
+func (r *PostgresOperatingHoursRepository) DeleteTemporaryClosure(ctx context.Context, branchID uint, id uint) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, "DELETE FROM branch_closures WHERE id = $1 AND branch_id = $2", id, branchID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error deleting temporary closure", "error", err)
+		return err
+	}
+
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return apperror.NotFound(fmt.Sprintf("no temporary closure found with the given ID: %d", id))
+	}
+
+	return nil
+}
@@ -0,0 +1,209 @@
+package repository
+
+import (
+	"api-server/apperror"
+	"api-server/models"
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// PostgresRegionRepository stores regions and cities in PostgreSQL
+type PostgresRegionRepository struct {
+	postgres
+}
+
+// NewPostgresRegionRepository creates the repository on the given database
+func NewPostgresRegionRepository(db *sql.DB, statementTimeout time.Duration) *PostgresRegionRepository {
+	return &PostgresRegionRepository{postgres{db: db, statementTimeout: statementTimeout}}
+}
+
+// GetAllRegions retrieves all regions with the number of cities in each
+func (r *PostgresRegionRepository) GetAllRegions(ctx context.Context) ([]models.RegionResponse, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT r.id, r.name, COUNT(c.id)
+		FROM regions r
+		LEFT JOIN cities c ON c.region_id = r.id
+		GROUP BY r.id, r.name
+		ORDER BY r.name ASC`)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error querying regions", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	regions := []models.RegionResponse{}
+	for rows.Next() {
+		var region models.RegionResponse
+		if err := rows.Scan(&region.ID, &region.Name, &region.TotalCities); err != nil {
+			slog.ErrorContext(ctx, "Error scanning region", "error", err)
+			return nil, err
+		}
+		regions = append(regions, region)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.ErrorContext(ctx, "Error after iterating regions", "error", err)
+		return nil, err
+	}
+
+	return regions, nil
+}
+
+// GetRegionByID retrieves a region by ID, returning nil when it does not exist
+func (r *PostgresRegionRepository) GetRegionByID(ctx context.Context, id uint) (*models.RegionResponse, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var region models.RegionResponse
+
+	err := r.db.QueryRowContext(ctx, `
+		SELECT r.id, r.name, (SELECT COUNT(*) FROM cities c WHERE c.region_id = r.id)
+		FROM regions r
+		WHERE r.id = $1`, id).Scan(&region.ID, &region.Name, &region.TotalCities)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		slog.ErrorContext(ctx, "Error querying region by ID", "error", err)
+		return nil, err
+	}
+
+	return &region, nil
+}
+
+// CreateRegion creates a new region
+func (r *PostgresRegionRepository) CreateRegion(ctx context.Context, region *models.RegionCreateRequest) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	if _, err := r.db.ExecContext(ctx, "INSERT INTO regions (name) VALUES ($1)", region.Name); err != nil {
+		slog.ErrorContext(ctx, "Error creating region", "error", err)
+		return err
+	}
+	return nil
+}
+
+// UpdateRegion updates an existing region by ID
+func (r *PostgresRegionRepository) UpdateRegion(ctx context.Context, id uint, region *models.RegionCreateRequest) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, "UPDATE regions SET name = $1 WHERE id = $2", region.Name, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error updating region", "error", err)
+		return err
+	}
+
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return apperror.NotFound(fmt.Sprintf("no region found with the given ID: %d", id))
+	}
+
+	return nil
+}
+
+// DeleteRegion deletes a region by ID. Regions that still have cities cannot be deleted.
+func (r *PostgresRegionRepository) DeleteRegion(ctx context.Context, id uint) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, "DELETE FROM regions WHERE id = $1", id)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error deleting region", "error", err)
+		return err
+	}
+
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return apperror.NotFound(fmt.Sprintf("no region found with the given ID: %d", id))
+	}
+
+	return nil
+}
+
+// GetAllCities retrieves all cities, optionally only those of one region
+func (r *PostgresRegionRepository) GetAllCities(ctx context.Context, regionID *uint) ([]models.CityResponse, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT c.id, c.name, r.id, r.name
+		FROM cities c
+		JOIN regions r ON c.region_id = r.id
+		WHERE ($1::int IS NULL OR c.region_id = $1)
+		ORDER BY r.name ASC, c.name ASC`, regionID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error querying cities", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	cities := []models.CityResponse{}
+	for rows.Next() {
+		var city models.CityResponse
+		if err := rows.Scan(&city.ID, &city.Name, &city.RegionID, &city.RegionName); err != nil {
+			slog.ErrorContext(ctx, "Error scanning city", "error", err)
+			return nil, err
+		}
+		cities = append(cities, city)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.ErrorContext(ctx, "Error after iterating cities", "error", err)
+		return nil, err
+	}
+
+	return cities, nil
+}
+
+// CreateCity creates a new city in a region
+func (r *PostgresRegionRepository) CreateCity(ctx context.Context, city *models.CityCreateRequest) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	if _, err := r.db.ExecContext(ctx, "INSERT INTO cities (name, region_id) VALUES ($1, $2)", city.Name, city.RegionID); err != nil {
+		slog.ErrorContext(ctx, "Error creating city", "error", err)
+		return err
+	}
+	return nil
+}
+
+// UpdateCity updates an existing city by ID
+func (r *PostgresRegionRepository) UpdateCity(ctx context.Context, id uint, city *models.CityCreateRequest) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, "UPDATE cities SET name = $1, region_id = $2 WHERE id = $3", city.Name, city.RegionID, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error updating city", "error", err)
+		return err
+	}
+
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return apperror.NotFound(fmt.Sprintf("no city found with the given ID: %d", id))
+	}
+
+	return nil
+}
+
+// DeleteCity deletes a city by ID. Its branch offices are detached from the city, not deleted.
+func (r *PostgresRegionRepository) DeleteCity(ctx context.Context, id uint) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, "DELETE FROM cities WHERE id = $1", id)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error deleting city", "error", err)
+		return err
+	}
+
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return apperror.NotFound(fmt.Sprintf("no city found with the given ID: %d", id))
+	}
+
+	return nil
+}
@@ -0,0 +1,209 @@
+// Package repository defines the data access interfaces of the server with their PostgreSQL implementation.
+// An in-memory implementation for tests lives in repository/memory.
+package repository
+
+import (
+	"api-server/models"
+	"context"
+	"database/sql"
+	"time"
+)
+
+// UserRepository stores users and checks their credentials
+type UserRepository interface {
+	GetAllUsers(ctx context.Context, limit, offset int, role string) ([]models.UserAllResponse, error)
+	GetUsersCount(ctx context.Context, role string) (int, error)
+	GetUserByID(ctx context.Context, id uint) (*models.User, error)
+	CreateUser(ctx context.Context, user *models.User) error
+	CreateUsers(ctx context.Context, users []models.User) error
+	UpdateUser(ctx context.Context, id uint, user *models.User) error
+	DeleteUser(ctx context.Context, id uint) error
+	GetAllUsersByBranchOfiice(ctx context.Context, branchId uint) ([]models.UserByBranchOfiiceResponse, error)
+	GetAvailableOfficersByBranchOffice(ctx context.Context, branchId uint, from time.Time, to time.Time) ([]models.UserByBranchOfiiceResponse, error)
+	CheckUserAuthentication(ctx context.Context, email string, password string) (models.User, error)
+	CheckUserAuthenticationMobile(ctx context.Context, email string, password string, branchID uint) (models.User, error)
+	GetActiveUserEmails(ctx context.Context, emails []string) (map[string]bool, error)
+}
+
+// BranchOfficeRepository stores branch offices
+type BranchOfficeRepository interface {
+	GetAllBranchOffices(ctx context.Context, limit, offset int) ([]models.BranchOfficeResponse, error)
+	GetAllBranchOfficesOption(ctx context.Context) ([]models.BranchOfficeOptionResponse, error)
+	GetLocatedBranchOffices(ctx context.Context) ([]models.NearbyBranchOffice, error)
+	GetBranchOfficesCount(ctx context.Context) (int, error)
+	GetBranchOfficesById(ctx context.Context, id uint) (*models.BranchOfficeResponse, error)
+	CreateBranchOffice(ctx context.Context, branchOffice *models.BranchOfficeCreateRequest) error
+	CreateBranchOffices(ctx context.Context, branchOffices []models.BranchOfficeCreateRequest) error
+	UpdateBranchOffices(ctx context.Context, id uint, branchOffice *models.BranchOfficeCreateRequest, counterPolicy string) error
+	DeleteBranchOffices(ctx context.Context, id uint) error
+}
+
+// BranchCounterRepository stores the counters of branch offices and their vote statistics
+type BranchCounterRepository interface {
+	CreateBranchCounter(ctx context.Context, branchCounter *models.BranchCounter) error
+	GetBranchCountersByBranchID(ctx context.Context, id uint) ([]models.BranchCounterWithNames, error)
+	DeleteBranchCounter(ctx context.Context, id uint) error
+	GetBranchCounterByID(ctx context.Context, id uint) (*models.BranchCounter, error)
+	GetBranchCounterIDByUserID(ctx context.Context, userID uint) (*uint, error)
+	GetBranchCounterStatsByBranchID(ctx context.Context, branchID uint, from time.Time, to time.Time) ([]models.BranchCounterStats, error)
+	GetCounterOfficerStats(ctx context.Context, counterID uint, from time.Time, to time.Time) ([]models.CounterOfficerStats, error)
+	GetCounterSlotsByBranchID(ctx context.Context, branchID uint, totalCounter uint) ([]models.CounterSlot, error)
+}
+
+// CounterAssignmentRepository stores the shifts of officers on counters
+type CounterAssignmentRepository interface {
+	CreateCounterAssignment(ctx context.Context, assignment *models.CounterAssignment) ([]models.CounterAssignmentDetail, error)
+	GetCounterAssignmentsByBranchID(ctx context.Context, branchID uint, from time.Time, to time.Time) ([]models.CounterAssignmentDetail, error)
+	GetCounterAssignmentsByCounterID(ctx context.Context, counterID uint, from time.Time, to time.Time) ([]models.CounterAssignmentDetail, error)
+	GetActiveAssignmentByCounterID(ctx context.Context, counterID uint, at time.Time) (*models.CounterAssignmentDetail, error)
+	GetActiveAssignmentByUserID(ctx context.Context, userID uint, at time.Time) (*models.CounterAssignmentDetail, error)
+	DeleteCounterAssignment(ctx context.Context, id uint) error
+}
+
+// DashboardRepository reads and updates the dashboard totals
+type DashboardRepository interface {
+	TotalDataDashboard(ctx context.Context) (int, int, int, int, error)
+	TotalDataRegionDashboard(ctx context.Context, regionID uint) (int, int, int, int, error)
+	TotalDataBranchDashboard(ctx context.Context, regionID *uint) ([]models.BranchData, error)
+	TotalDataRegionsDashboard(ctx context.Context) ([]models.RegionData, error)
+	DataOfficerDashboard(ctx context.Context, limit uint, offset uint, regionID *uint) ([]models.DashboardUsers, error)
+	OfficerCountDashboard(ctx context.Context, regionID *uint) (int, error)
+	UpdateDashboard(ctx context.Context, branchId uint, voteType string) error
+}
+
+// VoteRepository records the votes cast for officers
+type VoteRepository interface {
+	VotedUserLike(ctx context.Context, voteType string, data *models.User, counterID *uint, outOfHours bool) error
+}
+
+// AnalyticsRepository aggregates the feedback history
+type AnalyticsRepository interface {
+	GetBranchVoteHeatmap(ctx context.Context, branchID uint, userID *uint, timezone string, from, to time.Time) ([7][24]models.HeatmapCell, error)
+	GetRegionVoteHeatmap(ctx context.Context, regionID uint, timezone string, from, to time.Time) ([7][24]models.HeatmapCell, error)
+}
+
+// ArchiveRepository restores and purges archived branch offices and users
+type ArchiveRepository interface {
+	GetArchivedBranchOffices(ctx context.Context) ([]models.ArchivedEntity, error)
+	GetArchivedUsers(ctx context.Context) ([]models.ArchivedEntity, error)
+	RestoreBranchOffice(ctx context.Context, id uint) error
+	RestoreUser(ctx context.Context, id uint) error
+	PurgeBranchOffice(ctx context.Context, id uint, request *models.PurgeRequest) ([]string, error)
+	PurgeUser(ctx context.Context, id uint, request *models.PurgeRequest) (string, error)
+	GetPurgeAuditLog(ctx context.Context, limit, offset int) ([]models.PurgeAuditEntry, error)
+}
+
+// MembershipRepository stores the branch memberships of users and transfers them
+type MembershipRepository interface {
+	GetBranchMembershipsByUserID(ctx context.Context, userID uint) ([]models.BranchMembership, error)
+	TransferUser(ctx context.Context, transfer *models.TransferRequest) (*models.TransferResult, error)
+}
+
+// OperatingHoursRepository stores the opening hours, calendar exceptions and closures of branch offices
+type OperatingHoursRepository interface {
+	GetOpeningHours(ctx context.Context, branchID uint) ([]models.OpeningHours, error)
+	ReplaceOpeningHours(ctx context.Context, branchID uint, hours []models.OpeningHours, outOfHoursPolicy string) error
+	GetCalendarExceptions(ctx context.Context, branchID uint, fromDate string, toDate string) ([]models.CalendarException, error)
+	CreateCalendarException(ctx context.Context, exception *models.CalendarException) error
+	DeleteCalendarException(ctx context.Context, branchID uint, id uint) error
+	GetTemporaryClosures(ctx context.Context, branchID uint, from time.Time, to time.Time) ([]models.TemporaryClosure, error)
+	CreateTemporaryClosure(ctx context.Context, closure *models.TemporaryClosure) error
+	DeleteTemporaryClosure(ctx context.Context, branchID uint, id uint) error
+}
+
+// RegionRepository stores regions and their cities
+type RegionRepository interface {
+	GetAllRegions(ctx context.Context) ([]models.RegionResponse, error)
+	GetRegionByID(ctx context.Context, id uint) (*models.RegionResponse, error)
+	CreateRegion(ctx context.Context, region *models.RegionCreateRequest) error
+	UpdateRegion(ctx context.Context, id uint, region *models.RegionCreateRequest) error
+	DeleteRegion(ctx context.Context, id uint) error
+	GetAllCities(ctx context.Context, regionID *uint) ([]models.CityResponse, error)
+	CreateCity(ctx context.Context, city *models.CityCreateRequest) error
+	UpdateCity(ctx context.Context, id uint, city *models.CityCreateRequest) error
+	DeleteCity(ctx context.Context, id uint) error
+}
+
+// CompanyRepository stores the company profile
+type CompanyRepository interface {
+	GetCompanyProfile(ctx context.Context) (*models.CompanyProfile, error)
+	UpdateCompanyProfile(ctx context.Context, id uint, company *models.CompanyProfile) error
+}
+
+// UploadRepository tells which uploaded files the rows reference, soft-deleted rows included
+type UploadRepository interface {
+	GetReferencedImages(ctx context.Context) ([]string, error)
+	GetReferencedLogos(ctx context.Context) ([]string, error)
+	IsImageReferenced(ctx context.Context, image string) (bool, error)
+}
+
+// Repositories bundles one implementation of every repository, for wiring the services
+type Repositories struct {
+	Users              UserRepository
+	BranchOffices      BranchOfficeRepository
+	BranchCounters     BranchCounterRepository
+	CounterAssignments CounterAssignmentRepository
+	Dashboard          DashboardRepository
+	Votes              VoteRepository
+	Analytics          AnalyticsRepository
+	Archive            ArchiveRepository
+	Memberships        MembershipRepository
+	OperatingHours     OperatingHoursRepository
+	Regions            RegionRepository
+	Company            CompanyRepository
+	Uploads            UploadRepository
+}
+
+// NewPostgresRepositories creates the PostgreSQL repositories on the given database. Every repository call
+// is cancelled after statementTimeout, zero meaning no timeout.
+func NewPostgresRepositories(db *sql.DB, statementTimeout time.Duration) *Repositories {
+	return &Repositories{
+		Users:              NewPostgresUserRepository(db, statementTimeout),
+		BranchOffices:      NewPostgresBranchOfficeRepository(db, statementTimeout),
+		BranchCounters:     NewPostgresBranchCounterRepository(db, statementTimeout),
+		CounterAssignments: NewPostgresCounterAssignmentRepository(db, statementTimeout),
+		Dashboard:          NewPostgresDashboardRepository(db, statementTimeout),
+		Votes:              NewPostgresVoteRepository(db, statementTimeout),
+		Analytics:          NewPostgresAnalyticsRepository(db, statementTimeout),
+		Archive:            NewPostgresArchiveRepository(db, statementTimeout),
+		Memberships:        NewPostgresMembershipRepository(db, statementTimeout),
+		OperatingHours:     NewPostgresOperatingHoursRepository(db, statementTimeout),
+		Regions:            NewPostgresRegionRepository(db, statementTimeout),
+		Company:            NewPostgresCompanyRepository(db, statementTimeout),
+		Uploads:            NewPostgresUploadRepository(db, statementTimeout),
+	}
+}
+
+// postgres is the database connection shared by the PostgreSQL repositories
+type postgres struct {
+	db               *sql.DB
+	statementTimeout time.Duration
+}
+
+// withTimeout bounds a repository call by the statement timeout, on top of the deadline of the caller's
+// context, in case the connection hangs; PostgreSQL enforces the timeout on each statement too (see
+// config.Database.DSN). Bulk calls running a statement per row skip it and rely on the latter. The returned
+// cancel function must be called once the rows have been read.
+func (p postgres) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if p.statementTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, p.statementTimeout)
+}
+
+// Compile-time checks that the PostgreSQL repositories implement their interfaces
+var (
+	_ UserRepository              = (*PostgresUserRepository)(nil)
+	_ BranchOfficeRepository      = (*PostgresBranchOfficeRepository)(nil)
+	_ BranchCounterRepository     = (*PostgresBranchCounterRepository)(nil)
+	_ CounterAssignmentRepository = (*PostgresCounterAssignmentRepository)(nil)
+	_ DashboardRepository         = (*PostgresDashboardRepository)(nil)
+	_ VoteRepository              = (*PostgresVoteRepository)(nil)
+	_ AnalyticsRepository         = (*PostgresAnalyticsRepository)(nil)
+	_ ArchiveRepository           = (*PostgresArchiveRepository)(nil)
+	_ MembershipRepository        = (*PostgresMembershipRepository)(nil)
+	_ OperatingHoursRepository    = (*PostgresOperatingHoursRepository)(nil)
+	_ RegionRepository            = (*PostgresRegionRepository)(nil)
+	_ CompanyRepository           = (*PostgresCompanyRepository)(nil)
+	_ UploadRepository            = (*PostgresUploadRepository)(nil)
+)
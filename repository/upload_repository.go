@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+)
+
+// PostgresUploadRepository reads the uploaded files referenced by the users and the company profile
+type PostgresUploadRepository struct {
+	postgres
+}
+
+// NewPostgresUploadRepository creates the repository on the given database
+func NewPostgresUploadRepository(db *sql.DB, statementTimeout time.Duration) *PostgresUploadRepository {
+	return &PostgresUploadRepository{postgres{db: db, statementTimeout: statementTimeout}}
+}
+
+// GetReferencedImages lists the profile images of the users. Soft-deleted users keep theirs until purged.
+func (r *PostgresUploadRepository) GetReferencedImages(ctx context.Context) ([]string, error) {
+	return r.names(ctx, "SELECT DISTINCT image FROM users WHERE image IS NOT NULL AND image != ''")
+}
+
+// GetReferencedLogos lists the logos of the company profiles
+func (r *PostgresUploadRepository) GetReferencedLogos(ctx context.Context) ([]string, error) {
+	return r.names(ctx, "SELECT DISTINCT logo FROM company_profiles WHERE logo IS NOT NULL AND logo != ''")
+}
+
+// IsImageReferenced tells whether a user has the profile image, as identical images share their name
+func (r *PostgresUploadRepository) IsImageReferenced(ctx context.Context, image string) (bool, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var referenced bool
+	if err := r.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM users WHERE image = $1)", image).Scan(&referenced); err != nil {
+		slog.ErrorContext(ctx, "Error checking image references", "error", err)
+		return false, err
+	}
+	return referenced, nil
+}
+
+// names runs a query returning one file name per row
+func (r *PostgresUploadRepository) names(ctx context.Context, query string) ([]string, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error querying referenced files", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			slog.ErrorContext(ctx, "Error scanning referenced file", "error", err)
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
@@ -1,36 +1,53 @@
 package repository
 
 import (
-	"api-server/config"
+	"api-server/apperror"
 	"api-server/helpers"
 	"api-server/models"
-	"api-server/repository/validation"
+	"context"
 	"database/sql"
 	"errors"
-	"log"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/lib/pq"
 )
 
+// PostgresUserRepository stores users in PostgreSQL
+type PostgresUserRepository struct {
+	postgres
+}
+
+// NewPostgresUserRepository creates the repository on the given database
+func NewPostgresUserRepository(db *sql.DB, statementTimeout time.Duration) *PostgresUserRepository {
+	return &PostgresUserRepository{postgres{db: db, statementTimeout: statementTimeout}}
+}
+
 // GetAllUsers retrieves all users from the database with pagination
-func GetAllUsers(limit, offset int, role string) ([]models.UserAllResponse, error) {
+func (r *PostgresUserRepository) GetAllUsers(ctx context.Context, limit, offset int, role string) ([]models.UserAllResponse, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	var users []models.UserAllResponse
 	var rows *sql.Rows
 	var err error
 
 	// Handle role-based query: "officer" or not "officer"
 	if role == "officer" {
-		rows, err = config.DB.Query(
-			"SELECT id, full_name, email, role, likes, dislikes, image, branch_id FROM users WHERE role = $3 ORDER BY id ASC LIMIT $1 OFFSET $2",
+		rows, err = r.db.QueryContext(ctx,
+			"SELECT id, full_name, full_name_ar, full_name_en, email, role, likes, dislikes, image, branch_id, region_id FROM users WHERE role = $3 AND deleted_at IS NULL ORDER BY id ASC LIMIT $1 OFFSET $2",
 			limit, offset, role,
 		)
 	} else {
-		rows, err = config.DB.Query(
-			"SELECT id, full_name, email, role, likes, dislikes, image, branch_id FROM users WHERE role != $3 ORDER BY id ASC LIMIT $1 OFFSET $2",
+		rows, err = r.db.QueryContext(ctx,
+			"SELECT id, full_name, full_name_ar, full_name_en, email, role, likes, dislikes, image, branch_id, region_id FROM users WHERE role != $3 AND deleted_at IS NULL ORDER BY id ASC LIMIT $1 OFFSET $2",
 			limit, offset, "officer",
 		)
 	}
 
 	if err != nil {
-		log.Println("Error querying users:", err)
+		slog.ErrorContext(ctx, "Error querying users", "error", err)
 		return nil, err
 	}
 	defer rows.Close()
@@ -38,8 +55,8 @@ func GetAllUsers(limit, offset int, role string) ([]models.UserAllResponse, erro
 	for rows.Next() {
 		var user models.UserAllResponse
 
-		if err := rows.Scan(&user.ID, &user.FullName, &user.Email, &user.Role, &user.Likes, &user.Dislikes, &user.Image, &user.BranchId); err != nil {
-			log.Println("Error scanning user:", err)
+		if err := rows.Scan(&user.ID, &user.FullName, &user.FullNameAr, &user.FullNameEn, &user.Email, &user.Role, &user.Likes, &user.Dislikes, &user.Image, &user.BranchId, &user.RegionId); err != nil {
+			slog.ErrorContext(ctx, "Error scanning user", "error", err)
 			return nil, err
 		}
 
@@ -47,7 +64,7 @@ func GetAllUsers(limit, offset int, role string) ([]models.UserAllResponse, erro
 	}
 
 	if err := rows.Err(); err != nil {
-		log.Println("Error after iteration:", err)
+		slog.ErrorContext(ctx, "Error after iteration", "error", err)
 		return nil, err
 	}
 
@@ -55,22 +72,25 @@ func GetAllUsers(limit, offset int, role string) ([]models.UserAllResponse, erro
 }
 
 // GetUsersCount retrieves the total number of users
-func GetUsersCount(role string) (int, error) {
+func (r *PostgresUserRepository) GetUsersCount(ctx context.Context, role string) (int, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	var count int
 	var row *sql.Row
 
 	if role == "officer" {
 		// Use a parameterized query to safely query based on role
-		row = config.DB.QueryRow("SELECT COUNT(*) FROM users WHERE role = 'officer'")
+		row = r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM users WHERE role = 'officer' AND deleted_at IS NULL")
 	} else {
 		// When role is not provided, count all users
-		row = config.DB.QueryRow("SELECT COUNT(*) FROM users WHERE role != 'officer'")
+		row = r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM users WHERE role != 'officer' AND deleted_at IS NULL")
 	}
 
 	// Scan the result into the count variable
 	err := row.Scan(&count)
 	if err != nil {
-		log.Println("Error querying users count:", err)
+		slog.ErrorContext(ctx, "Error querying users count", "error", err)
 		return 0, err
 	}
 
@@ -78,19 +98,23 @@ func GetUsersCount(role string) (int, error) {
 }
 
 // GetUserByID retrieves a user by ID from the database
-func GetUserByID(id uint) (*models.User, error) {
+func (r *PostgresUserRepository) GetUserByID(ctx context.Context, id uint) (*models.User, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	var user models.User
 
 	// Query to retrieve the user by ID
-	row := config.DB.QueryRow("SELECT id, full_name, email, role, likes, dislikes, image, branch_id, password  FROM users WHERE id = $1", id)
-	err := row.Scan(&user.ID, &user.FullName, &user.Email, &user.Role, &user.Likes, &user.Dislikes, &user.Image, &user.BranchId, &user.Password) // Scan the image name into imageName
+	// Administrators and regional managers have no branch, scan it as 0
+	row := r.db.QueryRowContext(ctx, "SELECT id, full_name, full_name_ar, full_name_en, email, role, likes, dislikes, COALESCE(image, ''), COALESCE(branch_id, 0), region_id, password FROM users WHERE id = $1 AND deleted_at IS NULL", id)
+	err := row.Scan(&user.ID, &user.FullName, &user.FullNameAr, &user.FullNameEn, &user.Email, &user.Role, &user.Likes, &user.Dislikes, &user.Image, &user.BranchId, &user.RegionId, &user.Password) // Scan the image name into imageName
 
 	// If no rows are found
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // No user found with this ID
 		}
-		log.Println("Error scanning user:", err)
+		slog.ErrorContext(ctx, "Error scanning user", "error", err)
 		return nil, err
 	}
 
@@ -99,151 +123,225 @@ func GetUserByID(id uint) (*models.User, error) {
 }
 
 // CreateUser inserts a new user into the database with an optional image path
-func CreateUser(user *models.User) error {
-	// Hash the user's password before saving to the database
-	hashedPassword, err := helpers.HashingPasswordFunc(user.Password)
-	if err != nil {
+func (r *PostgresUserRepository) CreateUser(ctx context.Context, user *models.User) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	users := []models.User{*user}
+	if err := r.CreateUsers(ctx, users); err != nil {
 		return err
 	}
-	user.Password = hashedPassword
 
+	*user = users[0]
+	return nil
+}
+
+// CreateUsers inserts several users with already hashed passwords in one transaction, all or none.
+// The total officer count is updated for officers. An import inserts thousands of rows, so only each
+// statement is bounded by the statement timeout, not the whole call.
+func (r *PostgresUserRepository) CreateUsers(ctx context.Context, users []models.User) error {
 	// Begin a transaction to ensure atomicity
-	tx, err := config.DB.Begin()
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
-		log.Println("Error starting transaction:", err)
+		slog.ErrorContext(ctx, "Error starting transaction", "error", err)
 		return err
 	}
+	defer tx.Rollback() // Rollback in case of an error
 
-	// Defer rollback to ensure any error during execution rolls back the transaction
-	defer func() {
-		if err != nil {
-			tx.Rollback()
+	officers := 0
+	for i := range users {
+		if err = insertUser(ctx, tx, &users[i]); err != nil {
+			return err
+		}
+		if users[i].Role == "officer" {
+			officers++
 		}
-	}()
-
-	// Insert the user into the database, including the image path
-	_, err = tx.Exec(
-		"INSERT INTO users (full_name, email, password, role, likes, dislikes, image, branch_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
-		user.FullName, user.Email, user.Password, user.Role, user.Likes, user.Dislikes, user.Image, user.BranchId,
-	)
-	if err != nil {
-		log.Println("Error inserting user:", err)
-		return err
 	}
 
-	// Update total officer count only if the user's role is "officer"
-	if user.Role == "officer" {
-		_, err = tx.Exec("UPDATE total_data SET total_officer = total_officer + 1 WHERE id = 1")
+	// Update total officer count only for users whose role is "officer"
+	if officers > 0 {
+		_, err = tx.ExecContext(ctx, "UPDATE total_data SET total_officer = total_officer + $1 WHERE id = 1", officers)
 		if err != nil {
-			log.Println("Error updating total_officer:", err)
+			slog.ErrorContext(ctx, "Error updating total_officer", "error", err)
 			return err
 		}
 	}
 
 	// Commit the transaction if no errors
 	if err = tx.Commit(); err != nil {
-		log.Println("Error committing transaction:", err)
+		slog.ErrorContext(ctx, "Error committing transaction", "error", err)
 		return err
 	}
 
 	return nil
 }
 
-// UpdateUser updates an existing user by ID in the database
-func UpdateUser(id uint, user *models.User) error {
-	// Perform validation before updating
-	if err := validation.ValidateUser(user); err != nil {
+// insertUser inserts a user with an already hashed password within a transaction, opening its first
+// branch membership when the user works in a branch office
+func insertUser(ctx context.Context, tx *sql.Tx, user *models.User) error {
+	// Insert the user into the database, including the image path
+	err := tx.QueryRowContext(ctx,
+		"INSERT INTO users (full_name, full_name_ar, full_name_en, email, password, role, likes, dislikes, image, branch_id, region_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id",
+		user.FullName, user.FullNameAr, user.FullNameEn, user.Email, user.Password, user.Role, user.Likes, user.Dislikes, user.Image, nullableID(user.BranchId), user.RegionId,
+	).Scan(&user.ID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error inserting user", "error", err)
 		return err
 	}
 
-	// Hash the password if it's not empty (meaning it's being updated)
-	if user.Password != "" {
-		hashedPassword, err := helpers.HashingPasswordFunc(user.Password)
+	// Open the first branch membership of users working in a branch office
+	if user.BranchId != 0 {
+		_, err = tx.ExecContext(ctx, "INSERT INTO user_branch_memberships (user_id, branch_id, starts_at) VALUES ($1, $2, now())", user.ID, user.BranchId)
 		if err != nil {
-			log.Println("Error hashing password:", err)
-			return errors.New("failed to hash password")
+			slog.ErrorContext(ctx, "Error opening branch membership", "error", err)
+			return err
 		}
-		user.Password = string(hashedPassword) // Store the hashed password
+	}
+
+	return nil
+}
+
+// UpdateUser updates an existing user by ID in the database, storing the password as given (already hashed).
+// A user joining a branch office gets a membership opened, one leaving any branch office gets it closed with
+// their counters released and shifts cancelled; moving between branch offices needs a transfer.
+func (r *PostgresUserRepository) UpdateUser(ctx context.Context, id uint, user *models.User) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error starting transaction", "error", err)
+		return err
+	}
+	defer tx.Rollback() // Rollback in case of an error
+
+	var oldBranchID sql.NullInt64
+	err = tx.QueryRowContext(ctx, "SELECT branch_id FROM users WHERE id = $1 FOR UPDATE", id).Scan(&oldBranchID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Error locking user", "error", err)
+		return err
+	}
+	if oldBranchID.Valid && user.BranchId != 0 && uint(oldBranchID.Int64) != user.BranchId {
+		return ErrBranchChangeNeedsTransfer
 	}
 
 	// Prepare the SQL query
 	var query string
 	if user.Image != "" {
-		query = "UPDATE users SET full_name = $1, email = $2, password = $3, role = $4, image = $5, branch_id = $6 WHERE id = $7"
-		_, err := config.DB.Exec(query, user.FullName, user.Email, user.Password, user.Role, user.Image, user.BranchId, id)
+		query = "UPDATE users SET full_name = $1, email = $2, password = $3, role = $4, image = $5, branch_id = $6, region_id = $7, full_name_ar = $8, full_name_en = $9 WHERE id = $10"
+		_, err = tx.ExecContext(ctx, query, user.FullName, user.Email, user.Password, user.Role, user.Image, nullableID(user.BranchId), user.RegionId, user.FullNameAr, user.FullNameEn, id)
+	} else {
+		query = "UPDATE users SET full_name = $1, email = $2, password = $3, role = $4, branch_id = $5, region_id = $6, full_name_ar = $7, full_name_en = $8 WHERE id = $9"
+		_, err = tx.ExecContext(ctx, query, user.FullName, user.Email, user.Password, user.Role, nullableID(user.BranchId), user.RegionId, user.FullNameAr, user.FullNameEn, id)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Error updating user", "error", err)
+		return err
+	}
+
+	switch {
+	case !oldBranchID.Valid && user.BranchId != 0:
+		_, err = tx.ExecContext(ctx, "INSERT INTO user_branch_memberships (user_id, branch_id, starts_at) VALUES ($1, $2, now())", id, user.BranchId)
 		if err != nil {
-			log.Println("Error updating user:", err)
+			slog.ErrorContext(ctx, "Error opening branch membership", "error", err)
 			return err
 		}
-	} else {
-		query = "UPDATE users SET full_name = $1, email = $2, password = $3, role = $4, branch_id = $5 WHERE id = $6"
-		_, err := config.DB.Exec(query, user.FullName, user.Email, user.Password, user.Role, user.BranchId, id)
+	case oldBranchID.Valid && user.BranchId == 0:
+		_, err = tx.ExecContext(ctx, "UPDATE user_branch_memberships SET ends_at = now() WHERE user_id = $1 AND ends_at IS NULL", id)
 		if err != nil {
-			log.Println("Error updating user:", err)
+			slog.ErrorContext(ctx, "Error closing branch membership", "error", err)
+			return err
+		}
+		if err = releaseCountersAndShifts(ctx, tx, id); err != nil {
 			return err
 		}
 	}
 
+	if err = tx.Commit(); err != nil {
+		slog.ErrorContext(ctx, "Error committing transaction", "error", err)
+		return err
+	}
+
 	// No need to query the database again to get the existing image
 	return nil
 }
 
-// DeleteUser removes a user from the database and decrements the total officer count if the user is an officer
-func DeleteUser(id uint) error {
-	// Begin a transaction to ensure atomicity
-	tx, err := config.DB.Begin()
-	if err != nil {
-		log.Println("Error starting transaction:", err)
-		return err
-	}
+// DeleteUser archives a user and decrements the total officer count if the user is an officer. Like a
+// transfer, it releases the user's counters and cancels their shifts from now on, so kiosks stop offering
+// them. The user and their feedback history are kept for reporting until the user is restored or purged.
+func (r *PostgresUserRepository) DeleteUser(ctx context.Context, id uint) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
 
-	// Defer rollback to ensure any error during execution rolls back the transaction
-	defer func() {
-		if err != nil {
-			tx.Rollback()
-		}
-	}()
-
-	// Get the user's role before deletion
-	var role string
-	err = tx.QueryRow("SELECT role FROM users WHERE id = $1", id).Scan(&role)
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
-		log.Println("Error fetching user role:", err)
+		slog.ErrorContext(ctx, "Error starting transaction", "error", err)
 		return err
 	}
+	defer tx.Rollback() // Rollback in case of an error
 
-	// Delete the user from the users table
-	_, err = tx.Exec("DELETE FROM users WHERE id = $1", id)
+	var role string
+	err = tx.QueryRowContext(ctx, "UPDATE users SET deleted_at = now() WHERE id = $1 AND deleted_at IS NULL RETURNING role", id).Scan(&role)
 	if err != nil {
-		log.Println("Error deleting user:", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return apperror.NotFound(fmt.Sprintf("no user found with the given ID: %d", id))
+		}
+		slog.ErrorContext(ctx, "Error archiving user", "error", err)
 		return err
 	}
 
 	// Decrement total officer count only if the user's role is "officer"
 	if role == "officer" {
-		_, err = tx.Exec("UPDATE total_data SET total_officer = total_officer - 1 WHERE id = 1")
+		_, err = tx.ExecContext(ctx, "UPDATE total_data SET total_officer = total_officer - 1 WHERE id = 1")
 		if err != nil {
-			log.Println("Error updating total_officer:", err)
+			slog.ErrorContext(ctx, "Error updating total_officer", "error", err)
 			return err
 		}
 	}
 
-	// Commit the transaction if no errors
+	if err = releaseCountersAndShifts(ctx, tx, id); err != nil {
+		return err
+	}
+
 	if err = tx.Commit(); err != nil {
-		log.Println("Error committing transaction:", err)
+		slog.ErrorContext(ctx, "Error committing transaction", "error", err)
 		return err
 	}
 
 	return nil
 }
 
-func GetAllUsersByBranchOfiice(branchId uint) ([]models.UserByBranchOfiiceResponse, error) {
+// releaseCountersAndShifts unlinks a user leaving service from their counters within a transaction: shifts
+// not started yet are cancelled, running ones end now
+func releaseCountersAndShifts(ctx context.Context, tx *sql.Tx, userID uint) error {
+	if _, err := tx.ExecContext(ctx, "UPDATE branch_counters SET user_id = NULL WHERE user_id = $1", userID); err != nil {
+		slog.ErrorContext(ctx, "Error releasing branch counters", "error", err)
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM counter_assignments WHERE user_id = $1 AND starts_at >= now()", userID); err != nil {
+		slog.ErrorContext(ctx, "Error cancelling counter assignments", "error", err)
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE counter_assignments SET ends_at = now() WHERE user_id = $1 AND starts_at < now() AND ends_at > now()", userID); err != nil {
+		slog.ErrorContext(ctx, "Error shortening counter assignments", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (r *PostgresUserRepository) GetAllUsersByBranchOfiice(ctx context.Context, branchId uint) ([]models.UserByBranchOfiiceResponse, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	var users []models.UserByBranchOfiiceResponse
 
 	// Execute a SELECT query to fetch users by branch_id
-	rows, err := config.DB.Query("SELECT id, full_name FROM users WHERE branch_id = $1 AND role = 'officer' AND NOT EXISTS ( SELECT 1 FROM	branch_counters WHERE branch_counters.user_id = users.id);", branchId)
+	rows, err := r.db.QueryContext(ctx, "SELECT id, full_name FROM users WHERE branch_id = $1 AND role = 'officer' AND deleted_at IS NULL AND NOT EXISTS ( SELECT 1 FROM	branch_counters WHERE branch_counters.user_id = users.id);", branchId)
 	if err != nil {
-		log.Println("Error fetching users by branch:", err)
+		slog.ErrorContext(ctx, "Error fetching users by branch", "error", err)
 		return nil, err
 	}
 	defer rows.Close()
@@ -252,7 +350,7 @@ func GetAllUsersByBranchOfiice(branchId uint) ([]models.UserByBranchOfiiceRespon
 	for rows.Next() {
 		var user models.UserByBranchOfiiceResponse
 		if err := rows.Scan(&user.ID, &user.FullName); err != nil {
-			log.Println("Error scanning user:", err)
+			slog.ErrorContext(ctx, "Error scanning user", "error", err)
 			return nil, err
 		}
 		users = append(users, user)
@@ -260,60 +358,160 @@ func GetAllUsersByBranchOfiice(branchId uint) ([]models.UserByBranchOfiiceRespon
 
 	// Check for any errors during row iteration
 	if err := rows.Err(); err != nil {
-		log.Println("Error during row iteration:", err)
+		slog.ErrorContext(ctx, "Error during row iteration", "error", err)
 		return nil, err
 	}
 
 	return users, nil
 }
 
-func CheckUserAuthentication(email string, password string) (models.User, error) {
+// GetAvailableOfficersByBranchOffice lists the officers of a branch without any shift overlapping [from, to)
+func (r *PostgresUserRepository) GetAvailableOfficersByBranchOffice(ctx context.Context, branchId uint, from time.Time, to time.Time) ([]models.UserByBranchOfiiceResponse, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var users []models.UserByBranchOfiiceResponse
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, full_name FROM users
+		WHERE branch_id = $1 AND role = 'officer' AND deleted_at IS NULL AND NOT EXISTS (
+			SELECT 1 FROM counter_assignments ca
+			WHERE ca.user_id = users.id AND ca.starts_at < $3 AND ca.ends_at > $2
+		)
+		ORDER BY full_name ASC`, branchId, from, to)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error fetching available officers by branch", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var user models.UserByBranchOfiiceResponse
+		if err := rows.Scan(&user.ID, &user.FullName); err != nil {
+			slog.ErrorContext(ctx, "Error scanning user", "error", err)
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.ErrorContext(ctx, "Error during row iteration", "error", err)
+		return nil, err
+	}
+
+	return users, nil
+}
+
+func (r *PostgresUserRepository) CheckUserAuthentication(ctx context.Context, email string, password string) (models.User, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	var user models.User
 
 	// Query to retrieve the user by email
-	row := config.DB.QueryRow("SELECT id, full_name, email, role, password FROM users WHERE email = $1", email)
-	err := row.Scan(&user.ID, &user.FullName, &user.Email, &user.Role, &user.Password)
+	row := r.db.QueryRowContext(ctx, "SELECT id, full_name, full_name_ar, full_name_en, email, role, password FROM users WHERE LOWER(email) = LOWER($1) AND deleted_at IS NULL", email)
+	err := row.Scan(&user.ID, &user.FullName, &user.FullNameAr, &user.FullNameEn, &user.Email, &user.Role, &user.Password)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return user, errors.New("invalid email")
+			return user, apperror.Unauthorized("invalid email")
 		}
 		return user, err
 	}
 
 	// Validate the password
-	isValid := helpers.CheckPasswordHashFunc(password, user.Password)
+	isValid := helpers.CheckPasswordHashFunc(ctx, password, user.Password)
 	if !isValid {
-		return user, errors.New("invalid password")
+		return user, apperror.Unauthorized("invalid password")
 	}
 
 	return user, nil
 }
 
-func CheckUserAuthenticationMobile(email string, password string, branchID uint) (models.User, error) {
+func (r *PostgresUserRepository) CheckUserAuthenticationMobile(ctx context.Context, email string, password string, branchID uint) (models.User, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	var user models.User
 
-	err := config.DB.QueryRow(`
-		SELECT id, full_name, email, role, password, branch_id 
-		FROM users 
-		WHERE email = $1`, email).Scan(&user.ID, &user.FullName, &user.Email, &user.Role, &user.Password, &user.BranchId)
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, full_name, full_name_ar, full_name_en, email, role, password, COALESCE(branch_id, 0), region_id
+		FROM users
+		WHERE LOWER(email) = LOWER($1) AND deleted_at IS NULL`, email).Scan(&user.ID, &user.FullName, &user.FullNameAr, &user.FullNameEn, &user.Email, &user.Role, &user.Password, &user.BranchId, &user.RegionId)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return user, errors.New("email not found")
+			return user, apperror.Unauthorized("email not found")
 		}
 		return user, err
 	}
 
-	if user.BranchId != branchID {
-		return user, errors.New("invalid branch office")
+	// Regional managers may sign in on any branch office of their region
+	if user.Role == "regional_manager" {
+		var inRegion bool
+		err = r.db.QueryRowContext(ctx, `
+			SELECT EXISTS (
+				SELECT 1 FROM branch_offices bo
+				JOIN cities c ON bo.city_id = c.id
+				WHERE bo.id = $1 AND c.region_id = $2 AND bo.deleted_at IS NULL
+			)`, branchID, user.RegionId).Scan(&inRegion)
+		if err != nil {
+			return user, err
+		}
+		if !inRegion {
+			return user, apperror.Unauthorized("invalid branch office")
+		}
+	} else if user.BranchId != branchID {
+		return user, apperror.Unauthorized("invalid branch office")
 	}
 
-	isValid := helpers.CheckPasswordHashFunc(password, user.Password)
+	isValid := helpers.CheckPasswordHashFunc(ctx, password, user.Password)
 	if !isValid {
-		log.Printf("Invalid password for user: %s", email)
-		return user, errors.New("invalid password")
+		slog.InfoContext(ctx, "Invalid password", "email", email)
+		return user, apperror.Unauthorized("invalid password")
 	}
 
 	return user, nil
 }
+
+// nullableID stores a zero ID as NULL, for users that do not belong to a branch office
+func nullableID(id uint) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+// GetActiveUserEmails returns which of the given emails already belong to an active user
+func (r *PostgresUserRepository) GetActiveUserEmails(ctx context.Context, emails []string) (map[string]bool, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	taken := map[string]bool{}
+	if len(emails) == 0 {
+		return taken, nil
+	}
+
+	rows, err := r.db.QueryContext(ctx, "SELECT LOWER(email) FROM users WHERE LOWER(email) = ANY($1) AND deleted_at IS NULL", pq.Array(emails))
+	if err != nil {
+		slog.ErrorContext(ctx, "Error querying user emails", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var email string
+		if err := rows.Scan(&email); err != nil {
+			slog.ErrorContext(ctx, "Error scanning user email", "error", err)
+			return nil, err
+		}
+		taken[email] = true
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.ErrorContext(ctx, "Error during row iteration", "error", err)
+		return nil, err
+	}
+
+	return taken, nil
+}
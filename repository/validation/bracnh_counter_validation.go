@@ -1,36 +1,33 @@
 package validation
 
-import (
-	"errors"
-)
-
 // ValidateBranchCounter validates the input data for the BranchCounter model
 func ValidateBranchCounter(input map[string]interface{}) error {
+	errs := FieldErrors{}
+
 	// Validate "counter_location" must be a non-empty string
-	counterLocation, ok := input["counter_location"].(string)
-	if !ok || counterLocation == "" {
-		return errors.New("counter_location must be a non-empty string")
+	if counterLocation, ok := normalizeInput(input, "counter_location"); !ok || counterLocation == "" {
+		errs.Add("counter_location", "counter_location must be a non-empty string")
+	} else {
+		validateText(errs, "counter_location", "counter_location", counterLocation, 255)
 	}
 
-	// Validate "user_id" must be a number (convertible to uint
-	userIDStr, ok := input["branch_id"].(float64)
-
-	if !ok {
-		return errors.New("user_id must be a non-empty string")
+	// Validate "user_id" must be a number (convertible to uint)
+	if userID, ok := input["user_id"].(float64); !ok || userID <= 0 {
+		errs.Add("user_id", "user_id must be a valid number")
 	}
 
-	// Use branchIDFloat or convert it to uint if needed
-	_ = uint(userIDStr)
-
 	// Validate "branch_id" must be a number (convertible to uint)
-	branchIDFloat, ok := input["branch_id"].(float64)
-	if !ok {
-		return errors.New("branch_id must be a valid number")
+	if branchID, ok := input["branch_id"].(float64); !ok || branchID <= 0 {
+		errs.Add("branch_id", "branch_id must be a valid number")
 	}
 
-	// Use branchIDFloat or convert it to uint if needed
-	_ = uint(branchIDFloat)
+	// Validate the optional "counter_number" is a positive whole number
+	if val, exists := input["counter_number"]; exists && val != nil {
+		counterNumber, ok := val.(float64)
+		if !ok || counterNumber < 1 || counterNumber != float64(uint(counterNumber)) {
+			errs.Add("counter_number", "counter_number must be a positive whole number")
+		}
+	}
 
-	// Everything is valid
-	return nil
+	return errs.OrNil()
 }
@@ -1,50 +1,87 @@
 package validation
 
 import (
-	"errors"
-	"regexp"
+	"time"
 )
 
-// ValidateBranchOffices validates the input for the BranchOffice model
+// ValidateBranchOffices validates the input for the BranchOffice model. Text fields are normalized in place.
 func ValidateBranchOffices(input map[string]interface{}) error {
-	// Validate name
-	if name, ok := input["name"].(string); !ok || name == "" {
-		return errors.New("branch office name cannot be empty")
+	errs := FieldErrors{}
+
+	// Validate name, letters of any script are allowed (e.g. "فرع الرياض")
+	name, ok := normalizeInput(input, "name")
+	if !ok {
+		errs.Add("name", "branch office name cannot be empty")
+	} else {
+		validateName(errs, "name", "branch office name", name, 255)
 	}
 
 	// Validate address
-	if address, ok := input["address"].(string); !ok || address == "" {
-		return errors.New("address cannot be empty")
+	address, ok := normalizeInput(input, "address")
+	if !ok {
+		errs.Add("address", "address cannot be empty")
+	} else {
+		validateText(errs, "address", "address", address, 500)
 	}
 
-	// Validate total_counter
-	var totalCounter float64
-	if val, ok := input["total_counter"].(float64); !ok || val <= 0 {
-		return errors.New("total counter must be greater than 0")
-	} else {
-		totalCounter = val
+	// Validate the optional Arabic and English variants of the name and address
+	for _, field := range []string{"name_ar", "name_en"} {
+		if translation, ok := normalizeOptionalInput(errs, input, field); ok {
+			validateName(errs, field, field, translation, 255)
+		}
+	}
+	for _, field := range []string{"address_ar", "address_en"} {
+		if translation, ok := normalizeOptionalInput(errs, input, field); ok {
+			validateText(errs, field, field, translation, 500)
+		}
 	}
 
-	// Additional validations
-	// 1. Validate that the name does not exceed a certain length
-	if len(input["name"].(string)) > 255 {
-		return errors.New("branch office name cannot exceed 255 characters")
+	// Validate total_counter, not excessively large
+	if totalCounter, ok := input["total_counter"].(float64); !ok || totalCounter <= 0 {
+		errs.Add("total_counter", "total counter must be greater than 0")
+	} else if totalCounter > 100 { // Adjust the limit as necessary
+		errs.Add("total_counter", "total counter cannot exceed 100")
+	} else if totalCounter != float64(uint(totalCounter)) {
+		errs.Add("total_counter", "total counter must be a whole number")
 	}
 
-	// 2. Validate that the address does not exceed a certain length
-	if len(input["address"].(string)) > 500 {
-		return errors.New("address cannot exceed 500 characters")
+	// Validate the optional city_id is a positive number
+	if val, exists := input["city_id"]; exists && val != nil {
+		if cityID, ok := val.(float64); !ok || cityID <= 0 {
+			errs.Add("city_id", "city_id must be a valid number")
+		}
 	}
 
-	// 3. Validate name format (e.g., only letters, numbers, and spaces)
-	if !regexp.MustCompile(`^[a-zA-Z0-9\s]+$`).MatchString(input["name"].(string)) {
-		return errors.New("branch office name can only contain letters, numbers, and spaces")
+	// Validate the optional timezone is a known IANA zone (e.g. "Asia/Riyadh")
+	if val, exists := input["timezone"]; exists && val != nil {
+		timezone, ok := normalizeInput(input, "timezone")
+		if !ok {
+			errs.Add("timezone", "timezone must be a string")
+		} else if timezone != "" {
+			if _, err := time.LoadLocation(timezone); err != nil {
+				errs.Add("timezone", "timezone must be a valid IANA timezone name")
+			}
+		}
 	}
 
-	// 4. Ensure total_counter is not excessively large
-	if totalCounter > 100 { // Adjust the limit as necessary
-		return errors.New("total counter cannot exceed 100")
+	// Validate the optional coordinates, given together and within range
+	latitude, hasLatitude := input["latitude"]
+	longitude, hasLongitude := input["longitude"]
+	hasLatitude = hasLatitude && latitude != nil
+	hasLongitude = hasLongitude && longitude != nil
+	if hasLatitude != hasLongitude {
+		errs.Add("latitude", "latitude and longitude must be given together")
+	}
+	if hasLatitude {
+		if lat, ok := latitude.(float64); !ok || lat < -90 || lat > 90 {
+			errs.Add("latitude", "latitude must be a number between -90 and 90")
+		}
+	}
+	if hasLongitude {
+		if lng, ok := longitude.(float64); !ok || lng < -180 || lng > 180 {
+			errs.Add("longitude", "longitude must be a number between -180 and 180")
+		}
 	}
 
-	return nil
+	return errs.OrNil()
 }
@@ -0,0 +1,23 @@
+package validation
+
+import "api-server/models"
+
+// ValidateCompanyProfile validates the company name and its optional Arabic and English variants.
+// The names are normalized in place.
+func ValidateCompanyProfile(company *models.CompanyProfile) error {
+	errs := FieldErrors{}
+
+	company.Name = NormalizeText(company.Name)
+	company.NameAr = NormalizeOptionalText(company.NameAr)
+	company.NameEn = NormalizeOptionalText(company.NameEn)
+
+	validateText(errs, "name", "company name", company.Name, 255)
+	if company.NameAr != nil {
+		validateText(errs, "name_ar", "name_ar", *company.NameAr, 255)
+	}
+	if company.NameEn != nil {
+		validateText(errs, "name_en", "name_en", *company.NameEn, 255)
+	}
+
+	return errs.OrNil()
+}
@@ -0,0 +1,54 @@
+package validation
+
+import (
+	"time"
+)
+
+// maxShiftDuration caps a single shift so typos (e.g. a wrong month) are caught early
+const maxShiftDuration = 24 * time.Hour
+
+// ValidateCounterAssignment validates the input for a counter assignment (shift)
+func ValidateCounterAssignment(input map[string]interface{}) error {
+	errs := FieldErrors{}
+
+	// Validate "counter_id" must be a number (convertible to uint)
+	if counterID, ok := input["counter_id"].(float64); !ok || counterID <= 0 {
+		errs.Add("counter_id", "counter_id must be a valid number")
+	}
+
+	// Validate "user_id" must be a number (convertible to uint)
+	if userID, ok := input["user_id"].(float64); !ok || userID <= 0 {
+		errs.Add("user_id", "user_id must be a valid number")
+	}
+
+	// Validate "starts_at" and "ends_at" are RFC 3339 timestamps
+	startsAt, startsOk := parseTimestamp(errs, input, "starts_at")
+	endsAt, endsOk := parseTimestamp(errs, input, "ends_at")
+
+	if startsOk && endsOk {
+		if !endsAt.After(startsAt) {
+			errs.Add("ends_at", "ends_at must be after starts_at")
+		} else if endsAt.Sub(startsAt) > maxShiftDuration {
+			errs.Add("ends_at", "a shift cannot be longer than 24 hours")
+		}
+	}
+
+	return errs.OrNil()
+}
+
+// parseTimestamp reads a required RFC 3339 timestamp field, recording an error when it is invalid
+func parseTimestamp(errs FieldErrors, input map[string]interface{}, field string) (time.Time, bool) {
+	value, ok := input[field].(string)
+	if !ok {
+		errs.Add(field, field+" must be an RFC 3339 timestamp")
+		return time.Time{}, false
+	}
+
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		errs.Add(field, field+" must be an RFC 3339 timestamp")
+		return time.Time{}, false
+	}
+
+	return parsed, true
+}
@@ -0,0 +1,43 @@
+package validation
+
+import (
+	"sort"
+	"strings"
+)
+
+// FieldErrors collects every validation message per input field, so clients can show all problems at once
+type FieldErrors map[string][]string
+
+// Add records a message for a field
+func (e FieldErrors) Add(field string, message string) {
+	e[field] = append(e[field], message)
+}
+
+// Has tells whether a field already has a message, to skip checks that depend on it
+func (e FieldErrors) Has(field string) bool {
+	return len(e[field]) > 0
+}
+
+// Error joins the messages, ordered by field name
+func (e FieldErrors) Error() string {
+	fields := make([]string, 0, len(e))
+	for field := range e {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	messages := []string{}
+	for _, field := range fields {
+		messages = append(messages, e[field]...)
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+// OrNil returns nil when no message was recorded, so validators can end with "return errs.OrNil()"
+func (e FieldErrors) OrNil() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
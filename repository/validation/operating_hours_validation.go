@@ -0,0 +1,137 @@
+package validation
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// clockPattern matches a 24-hour "HH:MM" time
+var clockPattern = regexp.MustCompile(`^([01][0-9]|2[0-3]):[0-5][0-9]$`)
+
+// ValidateOpeningHours validates the weekly opening hours and out-of-hours vote policy of a branch office
+func ValidateOpeningHours(input map[string]interface{}) error {
+	errs := FieldErrors{}
+
+	// Validate "out_of_hours_vote_policy" is one of the supported policies
+	policy, ok := input["out_of_hours_vote_policy"].(string)
+	if !ok || (policy != "accept" && policy != "flag" && policy != "reject") {
+		errs.Add("out_of_hours_vote_policy", "out_of_hours_vote_policy must be 'accept', 'flag' or 'reject'")
+	}
+
+	// Validate "hours" is a list (an empty list removes the weekly schedule)
+	hours, ok := input["hours"].([]interface{})
+	if !ok {
+		errs.Add("hours", "hours must be a list of opening intervals")
+		return errs
+	}
+
+	for i, item := range hours {
+		prefix := fmt.Sprintf("hours[%d]", i)
+
+		interval, ok := item.(map[string]interface{})
+		if !ok {
+			errs.Add(prefix, prefix+" must be an object")
+			continue
+		}
+
+		day, ok := interval["day_of_week"].(float64)
+		if !ok || day < 0 || day > 6 || day != float64(int(day)) {
+			errs.Add(prefix+".day_of_week", prefix+".day_of_week must be a number between 0 (Sunday) and 6 (Saturday)")
+		}
+
+		validateClockRange(errs, interval, prefix+".")
+	}
+
+	return errs.OrNil()
+}
+
+// ValidateCalendarException validates a holiday or special hours period. The name is normalized in place.
+func ValidateCalendarException(input map[string]interface{}) error {
+	errs := FieldErrors{}
+
+	// Validate "name" is a non-empty text (e.g. "Ramadan" or "رمضان")
+	if name, ok := normalizeInput(input, "name"); !ok || name == "" {
+		errs.Add("name", "name is required")
+	} else {
+		validateText(errs, "name", "name", name, 255)
+	}
+
+	kind, ok := input["kind"].(string)
+	if !ok || (kind != "holiday" && kind != "special_hours") {
+		errs.Add("kind", "kind must be 'holiday' or 'special_hours'")
+	}
+
+	// Validate "starts_on" and "ends_on" are dates with ends_on not before starts_on
+	startsOn, startsOk := parseDate(errs, input, "starts_on")
+	endsOn, endsOk := parseDate(errs, input, "ends_on")
+	if startsOk && endsOk && endsOn.Before(startsOn) {
+		errs.Add("ends_on", "ends_on cannot be before starts_on")
+	}
+
+	// Special hours need their own opening and closing times, holidays must not have any
+	if kind == "special_hours" {
+		validateClockRange(errs, input, "")
+	} else if kind == "holiday" && (input["opens_at"] != nil || input["closes_at"] != nil) {
+		errs.Add("opens_at", "a holiday cannot have opening hours")
+	}
+
+	return errs.OrNil()
+}
+
+// ValidateTemporaryClosure validates a temporary closure of a branch office. The reason is normalized in place.
+func ValidateTemporaryClosure(input map[string]interface{}) error {
+	errs := FieldErrors{}
+
+	// Validate "reason" is a non-empty text
+	if reason, ok := normalizeInput(input, "reason"); !ok || reason == "" {
+		errs.Add("reason", "reason is required")
+	} else {
+		validateText(errs, "reason", "reason", reason, 500)
+	}
+
+	// Validate "starts_at" and "ends_at" are RFC 3339 timestamps
+	startsAt, startsOk := parseTimestamp(errs, input, "starts_at")
+	endsAt, endsOk := parseTimestamp(errs, input, "ends_at")
+	if startsOk && endsOk && !endsAt.After(startsAt) {
+		errs.Add("ends_at", "ends_at must be after starts_at")
+	}
+
+	return errs.OrNil()
+}
+
+// parseDate reads a required YYYY-MM-DD date field, recording an error when it is invalid
+func parseDate(errs FieldErrors, input map[string]interface{}, field string) (time.Time, bool) {
+	value, ok := input[field].(string)
+	if !ok {
+		errs.Add(field, field+" must be a date (YYYY-MM-DD)")
+		return time.Time{}, false
+	}
+
+	parsed, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		errs.Add(field, field+" must be a date (YYYY-MM-DD)")
+		return time.Time{}, false
+	}
+
+	return parsed, true
+}
+
+// validateClockRange checks the "opens_at" and "closes_at" fields of an object are "HH:MM" times
+func validateClockRange(errs FieldErrors, input map[string]interface{}, prefix string) {
+	opensAt, opensOk := input["opens_at"].(string)
+	if !opensOk || !clockPattern.MatchString(opensAt) {
+		errs.Add(prefix+"opens_at", prefix+"opens_at must be a time (HH:MM)")
+		opensOk = false
+	}
+
+	closesAt, closesOk := input["closes_at"].(string)
+	if !closesOk || !clockPattern.MatchString(closesAt) {
+		errs.Add(prefix+"closes_at", prefix+"closes_at must be a time (HH:MM)")
+		closesOk = false
+	}
+
+	if opensOk && closesOk && opensAt == closesAt {
+		errs.Add(prefix+"closes_at", prefix+"closes_at must differ from opens_at")
+	}
+}
@@ -1,40 +1,41 @@
 package validation
 
 import (
-	"errors"
 	"regexp"
 )
 
 // ValidatePassword checks the password against common policies.
 func ValidatePassword(password string) error {
+	errs := FieldErrors{}
+
 	// Password must be at least 6 characters long
-	if len(password) < 6 {
-		return errors.New("password must be at least 6 characters")
+	if runeLength(password) < 6 {
+		errs.Add("password", "password must be at least 6 characters")
 	}
 
 	// At least one uppercase letter
-	upperCaseRegex := `[A-Z]`
+	upperCaseRegex := `\p{Lu}`
 	if !regexp.MustCompile(upperCaseRegex).MatchString(password) {
-		return errors.New("password must contain at least one uppercase letter")
+		errs.Add("password", "password must contain at least one uppercase letter")
 	}
 
 	// At least one lowercase letter
-	lowerCaseRegex := `[a-z]`
+	lowerCaseRegex := `\p{Ll}`
 	if !regexp.MustCompile(lowerCaseRegex).MatchString(password) {
-		return errors.New("password must contain at least one lowercase letter")
+		errs.Add("password", "password must contain at least one lowercase letter")
 	}
 
 	// At least one digit
-	digitRegex := `[0-9]`
+	digitRegex := `\p{Nd}`
 	if !regexp.MustCompile(digitRegex).MatchString(password) {
-		return errors.New("password must contain at least one digit")
+		errs.Add("password", "password must contain at least one digit")
 	}
 
 	// At least one special character
 	specialCharRegex := `[!@#\$%^&*()_+{}\[\]:;"'<>,.?/\\|]`
 	if !regexp.MustCompile(specialCharRegex).MatchString(password) {
-		return errors.New("password must contain at least one special character")
+		errs.Add("password", "password must contain at least one special character")
 	}
 
-	return nil
+	return errs.OrNil()
 }
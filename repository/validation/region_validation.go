@@ -0,0 +1,33 @@
+package validation
+
+// ValidateRegion validates the input for the Region model. The name is normalized in place.
+func ValidateRegion(input map[string]interface{}) error {
+	errs := FieldErrors{}
+
+	name, ok := normalizeInput(input, "name")
+	if !ok {
+		errs.Add("name", "region name cannot be empty")
+	} else {
+		validateName(errs, "name", "region name", name, 255)
+	}
+
+	return errs.OrNil()
+}
+
+// ValidateCity validates the input for the City model. The name is normalized in place.
+func ValidateCity(input map[string]interface{}) error {
+	errs := FieldErrors{}
+
+	name, ok := normalizeInput(input, "name")
+	if !ok {
+		errs.Add("name", "city name cannot be empty")
+	} else {
+		validateName(errs, "name", "city name", name, 255)
+	}
+
+	if regionID, ok := input["region_id"].(float64); !ok || regionID <= 0 {
+		errs.Add("region_id", "region_id must be a valid number")
+	}
+
+	return errs.OrNil()
+}
@@ -0,0 +1,117 @@
+package validation
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// namePattern accepts names in any script: letters with their combining marks (e.g. Arabic diacritics),
+// digits, spaces and a few punctuation marks found in place names ("King Fahd Rd.", "Al-Khobar")
+var namePattern = regexp.MustCompile(`^[\p{L}\p{M}\p{N}\p{Zs}'.\-]+$`)
+
+// personNamePattern is namePattern without digits
+var personNamePattern = regexp.MustCompile(`^[\p{L}\p{M}\p{Zs}'.\-]+$`)
+
+// NormalizeText trims surrounding white space and converts the text to Unicode NFC, so the same
+// name typed on different keyboards is stored and compared identically
+func NormalizeText(text string) string {
+	return norm.NFC.String(strings.TrimSpace(text))
+}
+
+// normalizeInput normalizes a string field of a JSON input in place and returns it. The second result
+// is false when the field is missing or not a string.
+func normalizeInput(input map[string]interface{}, field string) (string, bool) {
+	value, ok := input[field].(string)
+	if !ok {
+		return "", false
+	}
+
+	value = NormalizeText(value)
+	input[field] = value
+	return value, true
+}
+
+// normalizeOptionalInput normalizes an optional translation of a JSON input in place. A blank value is
+// replaced by null so it is stored as NULL, the second result is false when there is nothing to validate.
+func normalizeOptionalInput(errs FieldErrors, input map[string]interface{}, field string) (string, bool) {
+	raw, exists := input[field]
+	if !exists || raw == nil {
+		return "", false
+	}
+
+	value, ok := raw.(string)
+	if !ok {
+		errs.Add(field, field+" must be a string")
+		return "", false
+	}
+
+	value = NormalizeText(value)
+	if value == "" {
+		input[field] = nil
+		return "", false
+	}
+
+	input[field] = value
+	return value, true
+}
+
+// NormalizeOptionalText normalizes an optional translation, returning nil when it is blank
+func NormalizeOptionalText(text *string) *string {
+	if text == nil {
+		return nil
+	}
+
+	normalized := NormalizeText(*text)
+	if normalized == "" {
+		return nil
+	}
+	return &normalized
+}
+
+// runeLength measures text in characters rather than bytes, an Arabic letter being 2 bytes in UTF-8
+func runeLength(text string) int {
+	return utf8.RuneCountInString(text)
+}
+
+// hasControlCharacters reports characters that have no place in single-line text (tabs, newlines, NUL...)
+func hasControlCharacters(text string) bool {
+	for _, r := range text {
+		if unicode.IsControl(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateName checks a required name made of letters of any script, digits and spaces
+func validateName(errs FieldErrors, field string, label string, name string, maxLength int) {
+	if name == "" {
+		errs.Add(field, label+" cannot be empty")
+		return
+	}
+	if runeLength(name) > maxLength {
+		errs.Add(field, label+" cannot exceed "+strconv.Itoa(maxLength)+" characters")
+	}
+	if !namePattern.MatchString(name) {
+		errs.Add(field, label+" can only contain letters, numbers, spaces and the characters ' . -")
+	}
+}
+
+// validateText checks a required free text of at most maxLength characters on a single line
+func validateText(errs FieldErrors, field string, label string, text string, maxLength int) {
+	if text == "" {
+		errs.Add(field, label+" cannot be empty")
+		return
+	}
+	if runeLength(text) > maxLength {
+		errs.Add(field, label+" cannot exceed "+strconv.Itoa(maxLength)+" characters")
+	}
+	if hasControlCharacters(text) {
+		errs.Add(field, label+" cannot contain control characters")
+	}
+}
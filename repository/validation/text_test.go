@@ -0,0 +1,127 @@
+package validation
+
+import (
+	"api-server/models"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeText(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"decomposed accent", " Jose\u0301 ", "Jos\u00e9"},
+		{"Arabic with a decomposed hamza", "\u0627\u0654\u062d\u0645\u062f", "\u0623\u062d\u0645\u062f"},
+		{"already composed", "فرع الرياض", "فرع الرياض"},
+		{"inner spaces kept", "King  Fahd\tRd.", "King  Fahd\tRd."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeText(tt.text); got != tt.want {
+				t.Errorf("NormalizeText(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+// fieldMessages returns the messages of a validation error by field, nil when it passed
+func fieldMessages(t *testing.T, err error) FieldErrors {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var fieldErrors FieldErrors
+	if !errors.As(err, &fieldErrors) {
+		t.Fatalf("validation error %v is not a FieldErrors", err)
+	}
+	return fieldErrors
+}
+
+func TestValidateBranchOfficesUnicode(t *testing.T) {
+	// 255 Arabic letters take 510 bytes and are still within the limit of 255 characters
+	longest := strings.Repeat("ب", 255)
+
+	tests := []struct {
+		name   string
+		input  map[string]interface{}
+		fields []string // Fields with an error, in alphabetical order
+	}{
+		{"Arabic name and address", map[string]interface{}{"name": "فرع الرياض", "address": "طريق الملك فهد، الرياض", "total_counter": 4.0}, nil},
+		{"Arabic name at the length limit", map[string]interface{}{"name": longest, "address": "Olaya", "total_counter": 4.0}, nil},
+		{"Arabic name over the length limit", map[string]interface{}{"name": longest + "ب", "address": "Olaya", "total_counter": 4.0}, []string{"name"}},
+		{"name with an emoji", map[string]interface{}{"name": "Riyadh 🏢", "address": "Olaya", "total_counter": 4.0}, []string{"name"}},
+		{"multi-line address", map[string]interface{}{"name": "Dammam", "address": "King Saud St\nDammam", "total_counter": 4.0}, []string{"address"}},
+		{
+			"every problem reported",
+			map[string]interface{}{"name": "  ", "address": "Olaya", "name_en": "<Riyadh>", "address_ar": 12.0, "total_counter": 2.5},
+			[]string{"address_ar", "name", "name_en", "total_counter"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages := fieldMessages(t, ValidateBranchOffices(tt.input))
+			fields := []string{}
+			for field := range messages {
+				fields = append(fields, field)
+			}
+			if len(fields) == 0 {
+				fields = nil
+			}
+			sort.Strings(fields)
+			if !reflect.DeepEqual(fields, tt.fields) {
+				t.Errorf("ValidateBranchOffices() fields = %v, want %v (%v)", fields, tt.fields, messages)
+			}
+		})
+	}
+
+	// The names are stored in NFC and a blank translation becomes NULL
+	input := map[string]interface{}{"name": " Jedda\u0300h ", "address": "Corniche", "name_ar": "  ", "total_counter": 2.0}
+	if err := ValidateBranchOffices(input); err != nil {
+		t.Fatalf("ValidateBranchOffices() error = %v", err)
+	}
+	if input["name"] != "Jedd\u00e0h" || input["name_ar"] != nil {
+		t.Errorf("normalized input = %q and name_ar %v, want %q and nil", input["name"], input["name_ar"], "Jedd\u00e0h")
+	}
+}
+
+func TestValidateUserUnicode(t *testing.T) {
+	user := func(fullName string) *models.User {
+		return &models.User{FullName: fullName, Email: " Officer@Example.com ", Password: "secret1", Role: "officer", BranchId: 1}
+	}
+
+	valid := user("عبد الله بن محمد")
+	if err := ValidateUser(valid); err != nil {
+		t.Errorf("ValidateUser() with an Arabic name error = %v", err)
+	}
+	if valid.Email != "officer@example.com" {
+		t.Errorf("email normalized to %q, want officer@example.com", valid.Email)
+	}
+
+	// Three Arabic letters are three characters, though six bytes
+	if err := ValidateUser(user("علي")); err != nil {
+		t.Errorf("ValidateUser() with a three-letter Arabic name error = %v", err)
+	}
+
+	// A short name with a digit gets both messages
+	messages := fieldMessages(t, ValidateUser(user("J7")))
+	if len(messages) != 1 || len(messages["full_name"]) != 2 {
+		t.Errorf("ValidateUser(J7) messages = %v, want two full_name messages", messages)
+	}
+
+	invalid := user("Ḿ")
+	invalid.FullNameAr = new(string)
+	*invalid.FullNameAr = "محمد 2"
+	invalid.Email = "officer"
+	invalid.Role = "guest"
+	messages = fieldMessages(t, ValidateUser(invalid))
+	for _, field := range []string{"full_name", "full_name_ar", "email", "role"} {
+		if !messages.Has(field) {
+			t.Errorf("ValidateUser() did not report %s: %v", field, messages)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package validation
+
+import (
+	"time"
+)
+
+// ValidateTransfer validates the input for transferring a user to another branch office.
+// The reason is normalized in place.
+func ValidateTransfer(input map[string]interface{}) error {
+	errs := FieldErrors{}
+
+	// Validate "branch_id" must be a number (convertible to uint)
+	if branchID, ok := input["branch_id"].(float64); !ok || branchID <= 0 {
+		errs.Add("branch_id", "branch_id must be a valid number")
+	}
+
+	// Validate the optional "effective_at" is an RFC 3339 timestamp
+	if val, exists := input["effective_at"]; exists && val != nil {
+		effectiveAt, ok := val.(string)
+		if !ok {
+			errs.Add("effective_at", "effective_at must be an RFC 3339 timestamp")
+		} else if _, err := time.Parse(time.RFC3339, effectiveAt); err != nil {
+			errs.Add("effective_at", "effective_at must be an RFC 3339 timestamp")
+		}
+	}
+
+	// Validate the optional "reason" is a string of reasonable length
+	if val, exists := input["reason"]; exists && val != nil {
+		reason, ok := normalizeInput(input, "reason")
+		if !ok {
+			errs.Add("reason", "reason must be a string")
+		} else if runeLength(reason) > 500 {
+			errs.Add("reason", "reason cannot exceed 500 characters")
+		}
+	}
+
+	return errs.OrNil()
+}
@@ -2,59 +2,92 @@ package validation
 
 import (
 	"api-server/models"
-	"errors"
 	"regexp"
+	"strings"
 )
 
-// ValidateUser validates the input for the User model
+// emailPattern accepts the lower-case addresses the login form sends
+var emailPattern = regexp.MustCompile(`^[a-z0-9._%+\-]+@[a-z0-9.\-]+\.[a-z]{2,}$`)
+
+// NormalizeEmail trims an email and lower-cases it, addresses being case-insensitive in practice
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// ValidateUser validates the input for the User model. The names and email are normalized in place.
 func ValidateUser(user *models.User) error {
-	if user.FullName == "" {
-		return errors.New("full name cannot be empty")
-	}
+	errs := FieldErrors{}
 
-	if user.Email == "" {
-		return errors.New("email cannot be empty")
+	user.FullName = NormalizeText(user.FullName)
+	user.FullNameAr = NormalizeOptionalText(user.FullNameAr)
+	user.FullNameEn = NormalizeOptionalText(user.FullNameEn)
+	user.Email = NormalizeEmail(user.Email)
+
+	if user.FullName == "" {
+		errs.Add("full_name", "full name cannot be empty")
+	} else {
+		if runeLength(user.FullName) < 3 {
+			errs.Add("full_name", "full name must be at least 3 characters")
+		}
+		if runeLength(user.FullName) > 255 {
+			errs.Add("full_name", "full name cannot exceed 255 characters")
+		}
+		if !personNamePattern.MatchString(user.FullName) {
+			errs.Add("full_name", "full name can only contain letters, spaces and the characters ' . -")
+		}
 	}
 
-	if user.Password == "" {
-		return errors.New("password cannot be empty")
+	// The Arabic and English display names are optional and follow the rules of full_name
+	translations := map[string]*string{"full_name_ar": user.FullNameAr, "full_name_en": user.FullNameEn}
+	for field, translation := range translations {
+		if translation == nil {
+			continue
+		}
+		if runeLength(*translation) > 255 {
+			errs.Add(field, field+" cannot exceed 255 characters")
+		}
+		if !personNamePattern.MatchString(*translation) {
+			errs.Add(field, field+" can only contain letters, spaces and the characters ' . -")
+		}
 	}
 
-	if len(user.FullName) < 3 {
-		return errors.New("full name must be at least 3 characters")
+	if user.Email == "" {
+		errs.Add("email", "email cannot be empty")
+	} else if !emailPattern.MatchString(user.Email) {
+		errs.Add("email", "invalid email format")
 	}
 
-	if len(user.Password) < 6 {
-		return errors.New("password must be at least 6 characters")
+	if user.Password == "" {
+		errs.Add("password", "password cannot be empty")
+	} else if runeLength(user.Password) < 6 {
+		errs.Add("password", "password must be at least 6 characters")
 	}
 
-	emailRegex := `^[a-z0-9._%+\-]+@[a-z0-9.\-]+\.[a-z]{2,}$`
-	re := regexp.MustCompile(emailRegex)
-	if !re.MatchString(user.Email) {
-		return errors.New("invalid email format")
+	if user.Role != "administrator" && user.Role != "admin" && user.Role != "supervisor" && user.Role != "officer" && user.Role != "regional_manager" {
+		errs.Add("role", "role must be either 'administrator' 'admin', 'supervisor', 'regional_manager', or 'officer'")
 	}
 
-	if user.Role != "administrator" && user.Role != "admin" && user.Role != "supervisor" && user.Role != "officer" {
-		return errors.New("role must be either 'administrator' 'admin', 'supervisor', or 'officer'")
+	if user.Role == "regional_manager" && user.RegionId == nil {
+		errs.Add("region_id", "region_id is required for a regional manager")
 	}
 
-	return nil
+	return errs.OrNil()
 }
 
+// CheckLoginUserInput validates the login form. The email is normalized, the password is taken as typed.
 func CheckLoginUserInput(email string, password string) error {
+	errs := FieldErrors{}
+
+	email = NormalizeEmail(email)
 	if email == "" {
-		return errors.New("email cannot be empty")
+		errs.Add("email", "email cannot be empty")
+	} else if !emailPattern.MatchString(email) {
+		errs.Add("email", "invalid email format")
 	}
 
 	if password == "" {
-		return errors.New("password cannot be empty")
-	}
-
-	emailRegex := `^[a-z0-9._%+\-]+@[a-z0-9.\-]+\.[a-z]{2,}$`
-	re := regexp.MustCompile(emailRegex)
-	if !re.MatchString(email) {
-		return errors.New("invalid email format")
+		errs.Add("password", "password cannot be empty")
 	}
 
-	return nil
+	return errs.OrNil()
 }
@@ -1,12 +1,29 @@
 package repository
 
 import (
-	"api-server/config"
+	"api-server/apperror"
 	"api-server/models"
-	"fmt"
+	"context"
+	"database/sql"
+	"time"
 )
 
-func VotedUserLike(voteType string, data *models.User) error {
+// PostgresVoteRepository stores votes in PostgreSQL
+type PostgresVoteRepository struct {
+	postgres
+}
+
+// NewPostgresVoteRepository creates the repository on the given database
+func NewPostgresVoteRepository(db *sql.DB, statementTimeout time.Duration) *PostgresVoteRepository {
+	return &PostgresVoteRepository{postgres{db: db, statementTimeout: statementTimeout}}
+}
+
+// VotedUserLike records a vote for an officer, attributing it to the counter where the service happened (if known).
+// Votes flagged as out of hours are kept in the history but do not count towards the officer's likes/dislikes.
+func (r *PostgresVoteRepository) VotedUserLike(ctx context.Context, voteType string, data *models.User, counterID *uint, outOfHours bool) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	// Get the user ID from data
 	userId := data.ID
 	branchId := data.BranchId
@@ -19,16 +36,18 @@ func VotedUserLike(voteType string, data *models.User) error {
 	} else if voteType == "dislike" {
 		updateQuery = `UPDATE users SET dislikes = dislikes + 1 WHERE id = $1`
 	} else {
-		return fmt.Errorf("invalid vote type")
+		return apperror.BadRequest("invalid vote type")
 	}
 
 	// Execute the update query
-	if _, err := config.DB.Exec(updateQuery, userId); err != nil {
-		return err
+	if !outOfHours {
+		if _, err := r.db.ExecContext(ctx, updateQuery, userId); err != nil {
+			return err
+		}
 	}
 
 	// Prepare the insert query for user_feedback_history
-	insertQuery := `INSERT INTO user_feedback_history (likes, dislikes, officer_name, user_id, branch_id) VALUES ($1, $2, $3, $4, $5)`
+	insertQuery := `INSERT INTO user_feedback_history (likes, dislikes, officer_name, user_id, branch_id, counter_id, out_of_hours) VALUES ($1, $2, $3, $4, $5, $6, $7)`
 	likes := 0
 	dislikes := 0
 
@@ -39,7 +58,7 @@ func VotedUserLike(voteType string, data *models.User) error {
 	}
 
 	// Insert feedback history
-	if _, err := config.DB.Exec(insertQuery, likes, dislikes, officerName, userId, branchId); err != nil {
+	if _, err := r.db.ExecContext(ctx, insertQuery, likes, dislikes, officerName, userId, branchId, counterID, outOfHours); err != nil {
 		return err
 	}
 
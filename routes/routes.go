@@ -1,12 +1,16 @@
 package routes
 
 import (
+	"api-server/apperror"
 	"api-server/controllers"
+	"api-server/health"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-func SetupRoutes(r *gin.Engine) {
+// SetupRoutes registers the API routes on the router, served by the given handler
+func SetupRoutes(r *gin.Engine, h *controllers.Handler) {
 	// Welcome to API
 	r.GET("/", func(c *gin.Context) {
 		c.JSON(200, gin.H{
@@ -14,59 +18,145 @@ func SetupRoutes(r *gin.Engine) {
 		})
 	})
 
+	// Unknown routes answer the usual error response
+	r.NoRoute(func(c *gin.Context) {
+		c.Error(apperror.NotFound("Route not found")) // Pass error to the middleware
+	})
+
+	// Prometheus metrics
+	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
+	// Uploaded profile images and company logos, served from the storage
+	r.GET("/images/:name", h.ServeImageHandler)
+	r.HEAD("/images/:name", h.ServeImageHandler)
+	r.GET("/assets/:name", h.ServeAssetHandler)
+	r.HEAD("/assets/:name", h.ServeAssetHandler)
+
+	// Region routes
+	regionRoutes := r.Group("/regions")
+	{
+		regionRoutes.GET("", h.GetRegionsHandler)
+		regionRoutes.GET("/:id", h.GetRegionHandler)
+		regionRoutes.POST("", h.CreateRegionHandler)
+		regionRoutes.PUT("/:id", h.UpdateRegionHandler)
+		regionRoutes.DELETE("/:id", h.DeleteRegionHandler)
+	}
+
+	// City routes
+	cityRoutes := r.Group("/cities")
+	{
+		cityRoutes.GET("", h.GetCitiesHandler)
+		cityRoutes.POST("", h.CreateCityHandler)
+		cityRoutes.PUT("/:id", h.UpdateCityHandler)
+		cityRoutes.DELETE("/:id", h.DeleteCityHandler)
+	}
+
 	// BranchOffice routes
 	branchOfficeRoutes := r.Group("/branch_offices")
 	{
-		branchOfficeRoutes.GET("", controllers.GetBranchOfficesHandler)
-		branchOfficeRoutes.GET("/option-list", controllers.GetBranchOfficesOptionHandler)
-		branchOfficeRoutes.GET("/:id", controllers.GetBranchOfficeHandler)
-		branchOfficeRoutes.POST("", controllers.CreateBranchOfficeHandler)
-		branchOfficeRoutes.PUT("/:id", controllers.UpdateBranchOfficeHandler)
-		branchOfficeRoutes.DELETE("/:id", controllers.DeleteBranchOfficeHandler)
+		branchOfficeRoutes.GET("", h.GetBranchOfficesHandler)
+		branchOfficeRoutes.GET("/option-list", h.GetBranchOfficesOptionHandler)
+		branchOfficeRoutes.GET("/nearby", h.GetNearbyBranchOfficesHandler)
+		branchOfficeRoutes.GET("/archived", h.GetArchivedBranchOfficesHandler)
+		branchOfficeRoutes.GET("/:id", h.GetBranchOfficeHandler)
+		branchOfficeRoutes.POST("", h.CreateBranchOfficeHandler)
+		branchOfficeRoutes.PUT("/:id", h.UpdateBranchOfficeHandler)
+		branchOfficeRoutes.DELETE("/:id", h.DeleteBranchOfficeHandler)
+		branchOfficeRoutes.PATCH("/:id/restore", h.RestoreBranchOfficeHandler)
+		branchOfficeRoutes.DELETE("/:id/purge", h.PurgeBranchOfficeHandler)
+		branchOfficeRoutes.GET("/:id/hours", h.GetBranchScheduleHandler)
+		branchOfficeRoutes.PUT("/:id/hours", h.UpdateOpeningHoursHandler)
+		branchOfficeRoutes.GET("/:id/status", h.GetBranchOpenStatusHandler)
+		branchOfficeRoutes.POST("/:id/exceptions", h.CreateCalendarExceptionHandler)
+		branchOfficeRoutes.DELETE("/:id/exceptions/:exceptionId", h.DeleteCalendarExceptionHandler)
+		branchOfficeRoutes.POST("/:id/closures", h.CreateTemporaryClosureHandler)
+		branchOfficeRoutes.DELETE("/:id/closures/:closureId", h.DeleteTemporaryClosureHandler)
 	}
 
 	// User routes
 	userRoutes := r.Group("/users")
 	{
-		userRoutes.GET("", controllers.GetUsersHandler)
-		userRoutes.GET("/:id", controllers.GetUserHandler)
-		userRoutes.POST("", controllers.CreateUserHandler)
-		userRoutes.PUT("/:id", controllers.UpdateUserHandler)
-		userRoutes.DELETE("/:id", controllers.DeleteUserHandler)
-		userRoutes.GET("/branch-office/:id", controllers.GetUsersByBranchOffice)
+		userRoutes.GET("", h.GetUsersHandler)
+		userRoutes.GET("/:id", h.GetUserHandler)
+		userRoutes.POST("", h.CreateUserHandler)
+		userRoutes.PUT("/:id", h.UpdateUserHandler)
+		userRoutes.DELETE("/:id", h.DeleteUserHandler)
+		userRoutes.GET("/archived", h.GetArchivedUsersHandler)
+		userRoutes.PATCH("/:id/restore", h.RestoreUserHandler)
+		userRoutes.DELETE("/:id/purge", h.PurgeUserHandler)
+		userRoutes.POST("/:id/transfer", h.TransferUserHandler)
+		userRoutes.GET("/:id/memberships", h.GetUserMembershipsHandler)
+		userRoutes.GET("/branch-office/:id", h.GetUsersByBranchOffice)
 	}
 
 	// BranchCounter routes
 	branchCounterRoutes := r.Group("/branch_counters")
 	{
-		branchCounterRoutes.GET("/:branch_id", controllers.GetBranchCounterHandlerByBranchId)
-		branchCounterRoutes.POST("", controllers.CreateBranchCounterHandler)
-		branchCounterRoutes.DELETE("/:id", controllers.DeleteBranchCounterHandler)
+		branchCounterRoutes.GET("/:branch_id", h.GetBranchCounterHandlerByBranchId)
+		branchCounterRoutes.GET("/:branch_id/stats", h.GetBranchCounterStatsHandler)
+		branchCounterRoutes.GET("/:branch_id/slots", h.GetCounterSlotsHandler)
+		branchCounterRoutes.GET("/counter/:id/stats", h.GetCounterStatsHandler)
+		branchCounterRoutes.GET("/counter/:id/current", h.GetCurrentCounterOfficerHandler)
+		branchCounterRoutes.POST("", h.CreateBranchCounterHandler)
+		branchCounterRoutes.DELETE("/:id", h.DeleteBranchCounterHandler)
+	}
+
+	// CounterAssignment (shift) routes
+	counterAssignmentRoutes := r.Group("/counter_assignments")
+	{
+		counterAssignmentRoutes.GET("/roster/:branch_id", h.GetBranchRosterHandler)
+		counterAssignmentRoutes.POST("", h.CreateCounterAssignmentHandler)
+		counterAssignmentRoutes.DELETE("/:id", h.DeleteCounterAssignmentHandler)
+	}
+
+	// Import routes
+	importRoutes := r.Group("/import")
+	{
+		importRoutes.POST("/branch_offices", h.ImportBranchOfficesHandler)
+		importRoutes.POST("/users", h.ImportUsersHandler)
 	}
 
+	// Purge audit log routes
+	r.GET("/purge_audit_log", h.GetPurgeAuditLogHandler)
+
 	// CompanyProfile routes
 	companyProfileRoutes := r.Group("/company_profiles")
 	{
-		companyProfileRoutes.GET("", controllers.GetCompanyProfileHandler)
-		companyProfileRoutes.PUT("", controllers.UpdateCompanyProfileHandler)
+		companyProfileRoutes.GET("", h.GetCompanyProfileHandler)
+		companyProfileRoutes.PUT("", h.UpdateCompanyProfileHandler)
 	}
 
 	// Vote User routes
 	votedUserRoutes := r.Group("/voted-user")
 	{
-		votedUserRoutes.POST("/:userId", controllers.VotedUserHandler)
+		votedUserRoutes.POST("/:userId", h.VotedUserHandler)
+		votedUserRoutes.POST("/counter/:counterId", h.VotedCounterHandler)
 	}
 
 	// Dashboard
 	dashboardRoutes := r.Group("/dashboard")
 	{
-		dashboardRoutes.GET("/total-data", controllers.TotalDataDashboard)
-		dashboardRoutes.GET("/total-vote-office", controllers.TotalLikeDislikeBranchOfficeHandler)
-		dashboardRoutes.GET("/total-vote-officer", controllers.TotalDataOfficerHandler)
-		dashboardRoutes.PATCH("/update/:branchId", controllers.UpdateDataDashboardHandler)
+		dashboardRoutes.GET("/total-data", h.TotalDataDashboard)
+		dashboardRoutes.GET("/total-vote-office", h.TotalLikeDislikeBranchOfficeHandler)
+		dashboardRoutes.GET("/total-vote-region", h.TotalLikeDislikeRegionHandler)
+		dashboardRoutes.GET("/total-vote-officer", h.TotalDataOfficerHandler)
+		dashboardRoutes.PATCH("/update/:branchId", h.UpdateDataDashboardHandler)
+	}
+
+	// Analytics
+	analyticsRoutes := r.Group("/analytics")
+	{
+		analyticsRoutes.GET("/heatmap/:branchId", h.BranchVoteHeatmapHandler)
+		analyticsRoutes.GET("/heatmap/region/:regionId", h.RegionVoteHeatmapHandler)
 	}
 
 	// Authentication
-	r.POST("/login", controllers.LoginWebServerHandler)
-	r.POST("/login-mobile", controllers.LoginMobileHandler)
+	r.POST("/login", h.LoginWebServerHandler)
+	r.POST("/login-mobile", h.LoginMobileHandler)
+}
+
+// SetupHealthRoutes registers the liveness and readiness probes of the load balancer
+func SetupHealthRoutes(r *gin.Engine, checker *health.Checker) {
+	r.GET("/healthz", checker.Live)
+	r.GET("/readyz", checker.Ready)
 }
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"api-server/models"
+	"api-server/repository"
+	"context"
+	"errors"
+	"time"
+)
+
+// AnalyticsService builds vote heatmaps from the feedback history
+type AnalyticsService struct {
+	analytics repository.AnalyticsRepository
+}
+
+// NewAnalyticsService creates an AnalyticsService reading from the given repository
+func NewAnalyticsService(analytics repository.AnalyticsRepository) *AnalyticsService {
+	return &AnalyticsService{analytics: analytics}
+}
+
+const analyticsDateLayout = "2006-01-02"
+
+// maxAnalyticsRange caps the date range of analytics queries
+const maxAnalyticsRange = 366 * 24 * time.Hour
+
+var weekDays = []string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"}
+
+// ParseDateRange parses an inclusive YYYY-MM-DD range in the given timezone and returns the
+// half-open interval [from, to) used by the analytics queries. Empty dates default to the last 30 days.
+func ParseDateRange(fromStr string, toStr string, timezone string) (time.Time, time.Time, error) {
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("invalid timezone: " + timezone)
+	}
+
+	now := time.Now().In(location)
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, location)
+	if toStr != "" {
+		to, err = time.ParseInLocation(analyticsDateLayout, toStr, location)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("invalid 'to' date, expected format YYYY-MM-DD")
+		}
+	}
+
+	from := to.AddDate(0, 0, -29)
+	if fromStr != "" {
+		from, err = time.ParseInLocation(analyticsDateLayout, fromStr, location)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("invalid 'from' date, expected format YYYY-MM-DD")
+		}
+	}
+
+	if from.After(to) {
+		return time.Time{}, time.Time{}, errors.New("'from' date must not be after 'to' date")
+	}
+
+	// Make the end of the range exclusive so the whole 'to' day is included
+	to = to.AddDate(0, 0, 1)
+	if to.Sub(from) > maxAnalyticsRange {
+		return time.Time{}, time.Time{}, errors.New("date range cannot exceed one year")
+	}
+
+	return from, to, nil
+}
+
+// GetBranchVoteHeatmap builds the 7x24 vote heatmap of a branch in its local timezone,
+// optionally restricted to a single officer
+func (s *AnalyticsService) GetBranchVoteHeatmap(ctx context.Context, branchOffice *models.BranchOfficeResponse, userID *uint, from time.Time, to time.Time) (*models.HeatmapResponse, error) {
+	cells, err := s.analytics.GetBranchVoteHeatmap(ctx, branchOffice.ID, userID, branchOffice.Timezone, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.HeatmapResponse{
+		BranchID: &branchOffice.ID,
+		UserID:   userID,
+		Timezone: branchOffice.Timezone,
+		From:     from.Format(analyticsDateLayout),
+		To:       to.AddDate(0, 0, -1).Format(analyticsDateLayout),
+		Days:     weekDays,
+		Cells:    cells,
+	}, nil
+}
+
+// GetRegionVoteHeatmap builds the 7x24 vote heatmap of all branch offices in a region
+func (s *AnalyticsService) GetRegionVoteHeatmap(ctx context.Context, region *models.RegionResponse, timezone string, from time.Time, to time.Time) (*models.HeatmapResponse, error) {
+	cells, err := s.analytics.GetRegionVoteHeatmap(ctx, region.ID, timezone, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.HeatmapResponse{
+		RegionID: &region.ID,
+		Timezone: timezone,
+		From:     from.Format(analyticsDateLayout),
+		To:       to.AddDate(0, 0, -1).Format(analyticsDateLayout),
+		Days:     weekDays,
+		Cells:    cells,
+	}, nil
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDateRange(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to string
+		timezone string
+		start    string // RFC 3339, in UTC
+		length   time.Duration
+	}{
+		{"one day", "2026-09-23", "2026-09-23", "Asia/Riyadh", "2026-09-22T21:00:00Z", 24 * time.Hour},
+		{"day the clocks go forward", "2026-03-29", "2026-03-29", "Europe/Berlin", "2026-03-28T23:00:00Z", 23 * time.Hour},
+		{"day the clocks go back", "2026-10-25", "2026-10-25", "Europe/Berlin", "2026-10-24T22:00:00Z", 25 * time.Hour},
+		{"week over the change to summer time", "2026-03-08", "2026-03-14", "America/New_York", "2026-03-08T05:00:00Z", 7*24*time.Hour - time.Hour},
+		{"quarter-hour offset", "2026-01-01", "2026-01-02", "Asia/Kathmandu", "2025-12-31T18:15:00Z", 48 * time.Hour},
+		{"first zone of the day", "2026-01-01", "2026-01-01", "Pacific/Kiritimati", "2025-12-31T10:00:00Z", 24 * time.Hour},
+		{"last zone of the day", "2026-01-01", "2026-01-01", "Pacific/Pago_Pago", "2026-01-01T11:00:00Z", 24 * time.Hour},
+		{"a whole year", "2024-01-01", "2024-12-31", "UTC", "2024-01-01T00:00:00Z", 366 * 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to, err := ParseDateRange(tt.from, tt.to, tt.timezone)
+			if err != nil {
+				t.Fatalf("ParseDateRange(%s, %s, %s) error = %v", tt.from, tt.to, tt.timezone, err)
+			}
+			if got := from.UTC().Format(time.RFC3339); got != tt.start {
+				t.Errorf("from = %s, want %s", got, tt.start)
+			}
+			if length := to.Sub(from); length != tt.length {
+				t.Errorf("the range lasts %v, want %v", length, tt.length)
+			}
+			// Both ends are local midnights
+			if local := to.In(from.Location()); local.Hour() != 0 || local.Minute() != 0 {
+				t.Errorf("to = %v, want a midnight in %s", to, tt.timezone)
+			}
+		})
+	}
+}
+
+func TestParseDateRangeDefaults(t *testing.T) {
+	from, to, err := ParseDateRange("", "", "Asia/Riyadh")
+	if err != nil {
+		t.Fatalf("ParseDateRange() error = %v", err)
+	}
+	now := time.Now()
+	if !from.Before(now) || !now.Before(to) || to.Sub(now) > 24*time.Hour {
+		t.Errorf("ParseDateRange() = [%v, %v), want it to end with today", from, to)
+	}
+	if days := to.Sub(from); days != 30*24*time.Hour {
+		t.Errorf("the default range lasts %v, want 30 days", days)
+	}
+}
+
+func TestParseDateRangeRejected(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to string
+		timezone string
+		message  string
+	}{
+		{"unknown time zone", "2026-01-01", "2026-01-31", "Mars/Olympus_Mons", "invalid timezone"},
+		{"invalid from", "01/01/2026", "2026-01-31", "UTC", "invalid 'from' date"},
+		{"invalid to", "2026-01-01", "2026-02-30", "UTC", "invalid 'to' date"},
+		{"reversed", "2026-02-01", "2026-01-31", "UTC", "must not be after"},
+		{"longer than a year", "2024-01-01", "2025-01-01", "UTC", "cannot exceed one year"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := ParseDateRange(tt.from, tt.to, tt.timezone)
+			if err == nil || !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("ParseDateRange(%s, %s, %s) error = %v, want it to contain %q", tt.from, tt.to, tt.timezone, err, tt.message)
+			}
+		})
+	}
+}
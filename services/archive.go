@@ -0,0 +1,52 @@
+package services
+
+import (
+	"api-server/models"
+	"api-server/repository"
+	"context"
+)
+
+// ArchiveService lists, restores and purges archived branch offices and users
+type ArchiveService struct {
+	archive repository.ArchiveRepository
+}
+
+// NewArchiveService creates an ArchiveService on the given repository
+func NewArchiveService(archive repository.ArchiveRepository) *ArchiveService {
+	return &ArchiveService{archive: archive}
+}
+
+// GetArchivedBranchOffices retrieves the archived branch offices
+func (s *ArchiveService) GetArchivedBranchOffices(ctx context.Context) ([]models.ArchivedEntity, error) {
+	return s.archive.GetArchivedBranchOffices(ctx)
+}
+
+// GetArchivedUsers retrieves the archived users
+func (s *ArchiveService) GetArchivedUsers(ctx context.Context) ([]models.ArchivedEntity, error) {
+	return s.archive.GetArchivedUsers(ctx)
+}
+
+// RestoreBranchOffice restores an archived branch office and the users archived with it
+func (s *ArchiveService) RestoreBranchOffice(ctx context.Context, id uint) error {
+	return s.archive.RestoreBranchOffice(ctx, id)
+}
+
+// RestoreUser restores an archived user
+func (s *ArchiveService) RestoreUser(ctx context.Context, id uint) error {
+	return s.archive.RestoreUser(ctx, id)
+}
+
+// PurgeBranchOffice permanently deletes an archived branch office, returning the images of its purged users
+func (s *ArchiveService) PurgeBranchOffice(ctx context.Context, id uint, request *models.PurgeRequest) ([]string, error) {
+	return s.archive.PurgeBranchOffice(ctx, id, request)
+}
+
+// PurgeUser permanently deletes an archived user, returning their image
+func (s *ArchiveService) PurgeUser(ctx context.Context, id uint, request *models.PurgeRequest) (string, error) {
+	return s.archive.PurgeUser(ctx, id, request)
+}
+
+// GetPurgeAuditLog retrieves the purge audit log with pagination
+func (s *ArchiveService) GetPurgeAuditLog(ctx context.Context, limit, offset int) ([]models.PurgeAuditEntry, error) {
+	return s.archive.GetPurgeAuditLog(ctx, limit, offset)
+}
@@ -3,26 +3,91 @@ package services
 import (
 	"api-server/models"
 	"api-server/repository"
+	"context"
 	"strconv"
+	"time"
 )
 
+// BranchCounterService manages the counters of branch offices and their statistics
+type BranchCounterService struct {
+	branchCounters     repository.BranchCounterRepository
+	counterAssignments repository.CounterAssignmentRepository
+}
+
+// NewBranchCounterService creates a BranchCounterService; the counter assignments provide the shifts
+// reported with the statistics of a counter
+func NewBranchCounterService(branchCounters repository.BranchCounterRepository, counterAssignments repository.CounterAssignmentRepository) *BranchCounterService {
+	return &BranchCounterService{branchCounters: branchCounters, counterAssignments: counterAssignments}
+}
+
 // GetBranchCountersByBranchID retrieves branch counters for a specific branch ID
-func GetBranchCountersByBranchID(id uint) ([]models.BranchCounterWithNames, error) {
+func (s *BranchCounterService) GetBranchCountersByBranchID(ctx context.Context, id uint) ([]models.BranchCounterWithNames, error) {
 	// Convert id from int to uint
 	uintID := uint(id)
-	return repository.GetBranchCountersByBranchID(uintID)
+	return s.branchCounters.GetBranchCountersByBranchID(ctx, uintID)
 }
 
 // CreateBranchCounter creates a new branch counter
-func CreateBranchCounter(branchCounter *models.BranchCounter) error {
-	return repository.CreateBranchCounter(branchCounter)
+func (s *BranchCounterService) CreateBranchCounter(ctx context.Context, branchCounter *models.BranchCounter) error {
+	return s.branchCounters.CreateBranchCounter(ctx, branchCounter)
+}
+
+// GetCounterSlotsByBranchID maps the numbered counter slots of a branch office to occupied or free
+func (s *BranchCounterService) GetCounterSlotsByBranchID(ctx context.Context, branchOffice *models.BranchOfficeResponse) ([]models.CounterSlot, error) {
+	return s.branchCounters.GetCounterSlotsByBranchID(ctx, branchOffice.ID, branchOffice.TotalCounter)
 }
 
 // DeleteBranchCounter deletes a branch counter by ID
-func DeleteBranchCounter(id string) error {
+func (s *BranchCounterService) DeleteBranchCounter(ctx context.Context, id string) error {
 	idInt, err := strconv.Atoi(id)
 	if err != nil {
 		return err
 	}
-	return repository.DeleteBranchCounter(uint(idInt))
+	return s.branchCounters.DeleteBranchCounter(ctx, uint(idInt))
+}
+
+// GetBranchCounterByID retrieves a branch counter by ID
+func (s *BranchCounterService) GetBranchCounterByID(ctx context.Context, id uint) (*models.BranchCounter, error) {
+	return s.branchCounters.GetBranchCounterByID(ctx, id)
+}
+
+// GetBranchCounterStatsByBranchID retrieves vote totals per counter of a branch
+func (s *BranchCounterService) GetBranchCounterStatsByBranchID(ctx context.Context, branchID uint, from time.Time, to time.Time) ([]models.BranchCounterStats, error) {
+	return s.branchCounters.GetBranchCounterStatsByBranchID(ctx, branchID, from, to)
+}
+
+// GetCounterStats retrieves the vote totals of a single counter and the officers that staffed it
+func (s *BranchCounterService) GetCounterStats(ctx context.Context, counter *models.BranchCounter, from time.Time, to time.Time) (*models.CounterStatsResponse, error) {
+	officers, err := s.branchCounters.GetCounterOfficerStats(ctx, counter.ID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	shifts, err := s.counterAssignments.GetCounterAssignmentsByCounterID(ctx, counter.ID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := models.BranchCounterStats{
+		CounterID:       counter.ID,
+		CounterNumber:   counter.CounterNumber,
+		CounterLocation: counter.CounterLocation,
+		BranchID:        counter.BranchID,
+	}
+	for _, officer := range officers {
+		stats.Likes += officer.Likes
+		stats.Dislikes += officer.Dislikes
+	}
+	stats.Total = stats.Likes + stats.Dislikes
+	if stats.Total > 0 {
+		stats.DislikeRatio = float64(stats.Dislikes) / float64(stats.Total)
+	}
+
+	return &models.CounterStatsResponse{
+		From:     from.Format(analyticsDateLayout),
+		To:       to.AddDate(0, 0, -1).Format(analyticsDateLayout),
+		Counter:  stats,
+		Officers: officers,
+		Shifts:   shifts,
+	}, nil
 }
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"api-server/models"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDeleteBranchCounterKeepsShiftHistory(t *testing.T) {
+	ctx := context.Background()
+	services, store := newTestServices(t)
+	branchID := createBranchOffice(t, store, "Dammam Seafront")
+	officer := createOfficer(t, services, branchID, "officer@example.com")
+
+	worked := &models.BranchCounter{CounterLocation: "Window 1", BranchID: branchID, UserID: officer.ID}
+	unused := &models.BranchCounter{CounterLocation: "Window 2", BranchID: branchID}
+	for _, counter := range []*models.BranchCounter{worked, unused} {
+		if err := services.BranchCounters.CreateBranchCounter(ctx, counter); err != nil {
+			t.Fatalf("creating counter %q: %v", counter.CounterLocation, err)
+		}
+	}
+
+	now := time.Now()
+	past := &models.CounterAssignment{CounterID: worked.ID, UserID: officer.ID, BranchID: branchID, StartsAt: now.Add(-26 * time.Hour), EndsAt: now.Add(-20 * time.Hour)}
+	upcoming := &models.CounterAssignment{CounterID: unused.ID, UserID: officer.ID, BranchID: branchID, StartsAt: now.Add(20 * time.Hour), EndsAt: now.Add(26 * time.Hour)}
+	for _, shift := range []*models.CounterAssignment{past, upcoming} {
+		if conflicts, err := store.CreateCounterAssignment(ctx, shift); err != nil || len(conflicts) > 0 {
+			t.Fatalf("creating the shift: %v, conflicts %+v", err, conflicts)
+		}
+	}
+
+	for _, id := range []string{"1", "2"} {
+		if err := services.BranchCounters.DeleteBranchCounter(ctx, id); err != nil {
+			t.Fatalf("DeleteBranchCounter(%s) error = %v", id, err)
+		}
+	}
+
+	// The counter with a past shift is retired, the other one only had an upcoming shift and is gone
+	retired, err := services.BranchCounters.GetBranchCounterByID(ctx, worked.ID)
+	if err != nil || retired == nil || retired.IsActive || retired.UserID != 0 {
+		t.Errorf("worked counter after the delete = %+v, %v, want it kept inactive and released", retired, err)
+	}
+	if deleted, err := services.BranchCounters.GetBranchCounterByID(ctx, unused.ID); err != nil || deleted != nil {
+		t.Errorf("unused counter after the delete = %+v, %v, want it deleted", deleted, err)
+	}
+
+	shifts, err := store.GetCounterAssignmentsByBranchID(ctx, branchID, now.Add(-48*time.Hour), now.Add(48*time.Hour))
+	if err != nil {
+		t.Fatalf("GetCounterAssignmentsByBranchID() error = %v", err)
+	}
+	if len(shifts) != 1 || shifts[0].ID != past.ID {
+		t.Errorf("shifts after the delete = %+v, want the past shift only", shifts)
+	}
+
+	// The number of the retired counter is free again
+	slots, err := services.BranchCounters.GetCounterSlotsByBranchID(ctx, &models.BranchOfficeResponse{ID: branchID, TotalCounter: 3})
+	if err != nil {
+		t.Fatalf("GetCounterSlotsByBranchID() error = %v", err)
+	}
+	for _, slot := range slots {
+		if slot.Status != "free" {
+			t.Errorf("slot %d is %s after deleting every counter, want free", slot.CounterNumber, slot.Status)
+		}
+	}
+	replacement := &models.BranchCounter{CounterLocation: "Window 1", CounterNumber: worked.CounterNumber, BranchID: branchID}
+	if err := services.BranchCounters.CreateBranchCounter(ctx, replacement); err != nil {
+		t.Errorf("creating a counter with the number of the retired one: %v", err)
+	}
+}
+
+func TestGetCounterStatsListsOfficersWithoutVotes(t *testing.T) {
+	ctx := context.Background()
+	services, store := newTestServices(t)
+	branchID := createBranchOffice(t, store, "Abha Downtown")
+	linked := createOfficer(t, services, branchID, "linked@example.com")
+	onShift := createOfficer(t, services, branchID, "shift@example.com")
+	voted := createOfficer(t, services, branchID, "voted@example.com")
+	createOfficer(t, services, branchID, "elsewhere@example.com")
+
+	counter := &models.BranchCounter{CounterLocation: "Front desk", BranchID: branchID, UserID: linked.ID}
+	if err := services.BranchCounters.CreateBranchCounter(ctx, counter); err != nil {
+		t.Fatalf("creating the counter: %v", err)
+	}
+	now := time.Now()
+	shift := &models.CounterAssignment{CounterID: counter.ID, UserID: onShift.ID, BranchID: branchID, StartsAt: now.Add(-2 * time.Hour), EndsAt: now.Add(-time.Hour)}
+	if conflicts, err := store.CreateCounterAssignment(ctx, shift); err != nil || len(conflicts) > 0 {
+		t.Fatalf("creating the shift: %v, conflicts %+v", err, conflicts)
+	}
+	for _, vote := range []string{"like", "dislike", "like"} {
+		if err := store.VotedUserLike(ctx, vote, voted, &counter.ID, false); err != nil {
+			t.Fatalf("voting for the officer: %v", err)
+		}
+	}
+
+	stats, err := services.BranchCounters.GetCounterStats(ctx, counter, now.Add(-24*time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("GetCounterStats() error = %v", err)
+	}
+	if stats.Counter.Likes != 2 || stats.Counter.Dislikes != 1 {
+		t.Errorf("counter totals = %d likes, %d dislikes, want 2 and 1", stats.Counter.Likes, stats.Counter.Dislikes)
+	}
+
+	// The officer with votes comes first, then the others by ID
+	want := []uint{voted.ID, linked.ID, onShift.ID}
+	if len(stats.Officers) != len(want) {
+		t.Fatalf("officers = %+v, want users %v", stats.Officers, want)
+	}
+	for i, officer := range stats.Officers {
+		if officer.UserID != want[i] {
+			t.Errorf("officer %d = user %d, want %d", i, officer.UserID, want[i])
+		}
+		if hasVotes := officer.UserID == voted.ID; hasVotes != (officer.FirstVoteAt != nil) || hasVotes != (officer.Total == 3) {
+			t.Errorf("officer %d = %+v, want vote times and totals only for the voted officer", officer.UserID, officer)
+		}
+	}
+
+	// A shift outside the period does not list its officer
+	stats, err = services.BranchCounters.GetCounterStats(ctx, counter, now.Add(-30*time.Minute), now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("GetCounterStats() error = %v", err)
+	}
+	for _, officer := range stats.Officers {
+		if officer.UserID == onShift.ID {
+			t.Errorf("officer of a shift before the period listed: %+v", officer)
+		}
+	}
+}
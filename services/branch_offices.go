@@ -3,37 +3,53 @@ package services
 import (
 	"api-server/models"
 	"api-server/repository"
+	"context"
 )
 
+// BranchOfficeService manages branch offices and searches them by location
+type BranchOfficeService struct {
+	branchOffices repository.BranchOfficeRepository
+}
+
+// NewBranchOfficeService creates a BranchOfficeService on the given repository
+func NewBranchOfficeService(branchOffices repository.BranchOfficeRepository) *BranchOfficeService {
+	return &BranchOfficeService{branchOffices: branchOffices}
+}
+
 // GetAllBranchOffices retrieves all branch offices with pagination
-func GetAllBranchOffices(limit, offset int) ([]models.BranchOfficeResponse, error) {
-	return repository.GetAllBranchOffices(limit, offset)
+func (s *BranchOfficeService) GetAllBranchOffices(ctx context.Context, limit, offset int) ([]models.BranchOfficeResponse, error) {
+	return s.branchOffices.GetAllBranchOffices(ctx, limit, offset)
 }
 
-func GetAllBranchOfficesOptionList() ([]models.BranchOfficeOptionResponse, error) {
-	return repository.GetAllBranchOfficesOption()
+func (s *BranchOfficeService) GetAllBranchOfficesOptionList(ctx context.Context) ([]models.BranchOfficeOptionResponse, error) {
+	return s.branchOffices.GetAllBranchOfficesOption(ctx)
 }
 
 // GetBranchOfficeByID retrieves a branch office by ID
-func GetBranchOfficeByID(id uint) (*models.BranchOfficeResponse, error) {
-	return repository.GetBranchOfficesById(id)
+func (s *BranchOfficeService) GetBranchOfficeByID(ctx context.Context, id uint) (*models.BranchOfficeResponse, error) {
+	return s.branchOffices.GetBranchOfficesById(ctx, id)
 }
 
 // CreateBranchOffice creates a new branch office
-func CreateBranchOffice(branchOffice *models.BranchOfficeCreateRequest) error {
-	if err := repository.CreateBranchOffice(branchOffice); err != nil {
+func (s *BranchOfficeService) CreateBranchOffice(ctx context.Context, branchOffice *models.BranchOfficeCreateRequest) error {
+	if err := s.branchOffices.CreateBranchOffice(ctx, branchOffice); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-// UpdateBranchOffice updates an existing branch office by ID
-func UpdateBranchOffice(id uint, branchOffice *models.BranchOfficeCreateRequest) error {
-	return repository.UpdateBranchOffices(id, branchOffice)
+// UpdateBranchOffice updates an existing branch office by ID, applying the counter policy if the capacity shrinks
+func (s *BranchOfficeService) UpdateBranchOffice(ctx context.Context, id uint, branchOffice *models.BranchOfficeCreateRequest, counterPolicy string) error {
+	return s.branchOffices.UpdateBranchOffices(ctx, id, branchOffice, counterPolicy)
 }
 
 // DeleteBranchOffice deletes a branch office by ID
-func DeleteBranchOffice(id uint) error {
-	return repository.DeleteBranchOffices(id)
+func (s *BranchOfficeService) DeleteBranchOffice(ctx context.Context, id uint) error {
+	return s.branchOffices.DeleteBranchOffices(ctx, id)
+}
+
+// GetBranchOfficesCount counts the active branch offices
+func (s *BranchOfficeService) GetBranchOfficesCount(ctx context.Context) (int, error) {
+	return s.branchOffices.GetBranchOfficesCount(ctx)
 }
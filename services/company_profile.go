@@ -1,16 +1,58 @@
 package services
 
 import (
+	"api-server/imaging"
 	"api-server/models"
 	"api-server/repository"
+	"api-server/storage"
+	"context"
+	"log/slog"
 )
 
+// CompanyProfileService reads and updates the company profile
+type CompanyProfileService struct {
+	company repository.CompanyRepository
+	assets  storage.Storage
+}
+
+// NewCompanyProfileService creates a CompanyProfileService on the given repository, the logos are kept in
+// assets
+func NewCompanyProfileService(company repository.CompanyRepository, assets storage.Storage) *CompanyProfileService {
+	return &CompanyProfileService{company: company, assets: assets}
+}
+
 // GetCompanyProfile retrieves the company profile from the repository
-func GetCompanyProfile() (*models.CompanyProfile, error) {
-	return repository.GetCompanyProfile()
+func (s *CompanyProfileService) GetCompanyProfile(ctx context.Context) (*models.CompanyProfile, error) {
+	return s.company.GetCompanyProfile(ctx)
 }
 
-func UpdateCompanyProfile(name string, logo string) error {
+// UpdateCompanyProfile updates the company profile. company.Logo holds the stored logo, replaced by logo
+// when one is given: the new logo is stored before the update and deleted again when it fails, the old
+// one is deleted once replaced.
+func (s *CompanyProfileService) UpdateCompanyProfile(ctx context.Context, company *models.CompanyProfile, logo *imaging.Image) error {
 	const companyID = 1 // Fixed ID
-	return repository.UpdateCompanyProfile(companyID, name, logo)
+
+	oldLogo := company.Logo
+	tx := storage.Begin(s.assets)
+	if logo != nil {
+		if err := imaging.Save(ctx, tx, logo); err != nil {
+			rollbackFiles(ctx, tx)
+			return err
+		}
+		company.Logo = logo.Name
+	}
+
+	if err := s.company.UpdateCompanyProfile(ctx, companyID, company); err != nil {
+		rollbackFiles(ctx, tx)
+		return err
+	}
+
+	// The company profile is the only one referencing logos
+	if oldLogo != "" && oldLogo != company.Logo {
+		if err := imaging.Remove(ctx, tx, oldLogo); err != nil {
+			slog.ErrorContext(ctx, "Error deleting old logo", "error", err)
+		}
+	}
+	commitFiles(ctx, tx)
+	return nil
 }
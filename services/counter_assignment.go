@@ -0,0 +1,135 @@
+package services
+
+import (
+	"api-server/models"
+	"api-server/repository"
+	"api-server/storage"
+	"context"
+	"errors"
+	"time"
+)
+
+// CounterAssignmentService schedules the shifts of officers on counters
+type CounterAssignmentService struct {
+	counterAssignments repository.CounterAssignmentRepository
+	users              repository.UserRepository
+	images             storage.Storage
+}
+
+// NewCounterAssignmentService creates a CounterAssignmentService; the users provide the officer
+// serving at a counter, whose profile image is linked from images
+func NewCounterAssignmentService(counterAssignments repository.CounterAssignmentRepository, users repository.UserRepository, images storage.Storage) *CounterAssignmentService {
+	return &CounterAssignmentService{counterAssignments: counterAssignments, users: users, images: images}
+}
+
+// CreateCounterAssignment schedules a shift and returns the overlapping shifts if it conflicts
+func (s *CounterAssignmentService) CreateCounterAssignment(ctx context.Context, assignment *models.CounterAssignment) ([]models.CounterAssignmentDetail, error) {
+	return s.counterAssignments.CreateCounterAssignment(ctx, assignment)
+}
+
+// DeleteCounterAssignment removes a shift by ID
+func (s *CounterAssignmentService) DeleteCounterAssignment(ctx context.Context, id uint) error {
+	return s.counterAssignments.DeleteCounterAssignment(ctx, id)
+}
+
+// WeekRange returns the week (Sunday to Saturday) containing the given YYYY-MM-DD date in the
+// given timezone as a half-open interval. An empty date means the current week.
+func WeekRange(dateStr string, timezone string) (time.Time, time.Time, error) {
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("invalid timezone: " + timezone)
+	}
+
+	day := time.Now().In(location)
+	if dateStr != "" {
+		day, err = time.ParseInLocation(analyticsDateLayout, dateStr, location)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("invalid 'week' date, expected format YYYY-MM-DD")
+		}
+	}
+
+	start := time.Date(day.Year(), day.Month(), day.Day()-int(day.Weekday()), 0, 0, 0, 0, location)
+	return start, start.AddDate(0, 0, 7), nil
+}
+
+// GetBranchRoster retrieves the shifts of a branch for one week, with any conflicting shifts
+func (s *CounterAssignmentService) GetBranchRoster(ctx context.Context, branchOffice *models.BranchOfficeResponse, weekStart time.Time, weekEnd time.Time) (*models.BranchRosterResponse, error) {
+	assignments, err := s.counterAssignments.GetCounterAssignmentsByBranchID(ctx, branchOffice.ID, weekStart, weekEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.BranchRosterResponse{
+		BranchID:    branchOffice.ID,
+		Timezone:    branchOffice.Timezone,
+		WeekStart:   weekStart.Format(analyticsDateLayout),
+		WeekEnd:     weekEnd.AddDate(0, 0, -1).Format(analyticsDateLayout),
+		Assignments: assignments,
+		Conflicts:   FindAssignmentConflicts(assignments),
+	}, nil
+}
+
+// FindAssignmentConflicts returns every pair of overlapping shifts that share an officer or a counter
+func FindAssignmentConflicts(assignments []models.CounterAssignmentDetail) []models.AssignmentConflict {
+	conflicts := []models.AssignmentConflict{}
+
+	for i := 0; i < len(assignments); i++ {
+		for j := i + 1; j < len(assignments); j++ {
+			first, second := assignments[i], assignments[j]
+			if !first.StartsAt.Before(second.EndsAt) || !second.StartsAt.Before(first.EndsAt) {
+				continue
+			}
+
+			switch {
+			case first.UserID == second.UserID:
+				conflicts = append(conflicts, models.AssignmentConflict{Reason: "officer assigned to two counters", First: first, Second: second})
+			case first.CounterID == second.CounterID:
+				conflicts = append(conflicts, models.AssignmentConflict{Reason: "two officers assigned to one counter", First: first, Second: second})
+			}
+		}
+	}
+
+	return conflicts
+}
+
+// GetCurrentCounterOfficer resolves who serves at a counter at the given moment: the officer on
+// shift if any, otherwise the officer statically linked to the counter, with the URLs of their image
+func (s *CounterAssignmentService) GetCurrentCounterOfficer(ctx context.Context, counter *models.BranchCounter, at time.Time) (*models.CurrentCounterOfficerResponse, error) {
+	response := &models.CurrentCounterOfficerResponse{
+		CounterID:       counter.ID,
+		CounterLocation: counter.CounterLocation,
+		At:              at,
+		Source:          "none",
+	}
+
+	assignment, err := s.counterAssignments.GetActiveAssignmentByCounterID(ctx, counter.ID, at)
+	if err != nil {
+		return nil, err
+	}
+
+	userID := counter.UserID
+	if assignment != nil {
+		userID = assignment.UserID
+		response.Assignment = assignment
+	}
+
+	user, err := s.users.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return response, nil
+	}
+
+	response.Source = "static"
+	if assignment != nil {
+		response.Source = "shift"
+	}
+	response.UserID = &user.ID
+	response.FullName = user.FullName
+	response.FullNameAr = user.FullNameAr
+	response.FullNameEn = user.FullNameEn
+	response.Image, response.Thumbnail = imageURLs(s.images, user.Image)
+
+	return response, nil
+}
@@ -3,22 +3,64 @@ package services
 import (
 	"api-server/models"
 	"api-server/repository"
+	"context"
+	"time"
 )
 
-func TotalDataDashboard() (int, int, int, int, error) {
-	return repository.TotalDataDashboard()
+// DashboardService reads and updates the dashboard totals
+type DashboardService struct {
+	dashboard      repository.DashboardRepository
+	branchOffices  repository.BranchOfficeRepository
+	operatingHours *OperatingHoursService
 }
 
-func TotalDataBranchOfficeDashboard() ([]models.BranchData, error) {
-	return repository.TotalDataBranchDashboard()
+// NewDashboardService creates a DashboardService on the given repository. The branch offices and operating
+// hours apply the out-of-hours vote policy to the totals, as to the votes.
+func NewDashboardService(dashboard repository.DashboardRepository, branchOffices repository.BranchOfficeRepository, operatingHours *OperatingHoursService) *DashboardService {
+	return &DashboardService{dashboard: dashboard, branchOffices: branchOffices, operatingHours: operatingHours}
 }
 
-func UpdateDataDashboard(branchId uint, voteType string) error {
-	return repository.UpdateDashboard(branchId, voteType)
+// TotalDataDashboard returns the overall totals, or the totals of one region when regionID is set
+func (s *DashboardService) TotalDataDashboard(ctx context.Context, regionID *uint) (int, int, int, int, error) {
+	if regionID != nil {
+		return s.dashboard.TotalDataRegionDashboard(ctx, *regionID)
+	}
+	return s.dashboard.TotalDataDashboard(ctx)
+}
+
+func (s *DashboardService) TotalDataBranchOfficeDashboard(ctx context.Context, regionID *uint) ([]models.BranchData, error) {
+	return s.dashboard.TotalDataBranchDashboard(ctx, regionID)
+}
+
+// TotalDataRegionDashboard rolls the branch office totals up per region
+func (s *DashboardService) TotalDataRegionDashboard(ctx context.Context) ([]models.RegionData, error) {
+	return s.dashboard.TotalDataRegionsDashboard(ctx)
+}
+
+// UpdateDataDashboard adds a vote to the overall and branch office totals under the out-of-hours policy of
+// the branch office, like the vote itself: a vote that would be flagged is left out of the totals (returned
+// as true), one that would be rejected returns ErrVoteOutsideOperatingHours.
+func (s *DashboardService) UpdateDataDashboard(ctx context.Context, branchId uint, voteType string) (bool, error) {
+	branchOffice, err := s.branchOffices.GetBranchOfficesById(ctx, branchId)
+	if err != nil {
+		return false, err
+	}
+
+	outOfHours, err := s.operatingHours.CheckVoteOperatingHours(ctx, branchOffice, time.Now())
+	if err != nil || outOfHours {
+		return outOfHours, err
+	}
+
+	return false, s.dashboard.UpdateDashboard(ctx, branchId, voteType)
 }
 
 // GetAllBranchOffices retrieves all branch offices with pagination
 
-func GetAllOfficers(limit uint, offset uint) ([]models.DashboardUsers, error) {
-	return repository.DataOfficerDashboard(limit, offset)
+func (s *DashboardService) GetAllOfficers(ctx context.Context, limit uint, offset uint, regionID *uint) ([]models.DashboardUsers, error) {
+	return s.dashboard.DataOfficerDashboard(ctx, limit, offset, regionID)
+}
+
+// GetOfficersCount counts officers, optionally only those of one region
+func (s *DashboardService) GetOfficersCount(ctx context.Context, regionID *uint) (int, error) {
+	return s.dashboard.OfficerCountDashboard(ctx, regionID)
 }
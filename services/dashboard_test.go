@@ -0,0 +1,67 @@
+package services
+
+import (
+	"api-server/models"
+	"context"
+	"testing"
+)
+
+func TestOfficersCountMatchesRegionPages(t *testing.T) {
+	ctx := context.Background()
+	services, store := newTestServices(t)
+
+	// Two regions with a city and a branch office each
+	branchIDs := map[string]uint{}
+	for i, name := range []string{"Eastern", "Asir"} {
+		id := uint(i + 1)
+		if err := store.CreateRegion(ctx, &models.RegionCreateRequest{Name: name}); err != nil {
+			t.Fatalf("creating region %s: %v", name, err)
+		}
+		if err := store.CreateCity(ctx, &models.CityCreateRequest{Name: name + " City", RegionID: id}); err != nil {
+			t.Fatalf("creating the city of %s: %v", name, err)
+		}
+		request := &models.BranchOfficeCreateRequest{Name: name + " Branch", Address: name + " Road", TotalCounter: 2, Timezone: "Asia/Riyadh", CityID: &id}
+		if err := store.CreateBranchOffice(ctx, request); err != nil {
+			t.Fatalf("creating the branch office of %s: %v", name, err)
+		}
+		branchIDs[name] = id
+	}
+
+	createOfficer(t, services, branchIDs["Eastern"], "stays@example.com")
+	moved := createOfficer(t, services, branchIDs["Eastern"], "moved@example.com")
+	createOfficer(t, services, branchIDs["Asir"], "asir@example.com")
+
+	// Voted in the Eastern region, then transferred to Asir
+	if err := store.VotedUserLike(ctx, "like", moved, nil, false); err != nil {
+		t.Fatalf("voting for the officer: %v", err)
+	}
+	if _, err := services.Users.TransferUser(ctx, &models.TransferRequest{UserID: moved.ID, BranchID: branchIDs["Asir"]}); err != nil {
+		t.Fatalf("TransferUser() error = %v", err)
+	}
+
+	eastern, asir := branchIDs["Eastern"], branchIDs["Asir"]
+	tests := []struct {
+		name     string
+		regionID *uint
+		want     int
+	}{
+		{"every region", nil, 3},
+		{"the region the officer left", &eastern, 2},
+		{"the region the officer joined", &asir, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, err := services.Dashboard.GetOfficersCount(ctx, tt.regionID)
+			if err != nil {
+				t.Fatalf("GetOfficersCount() error = %v", err)
+			}
+			officers, err := services.Dashboard.GetAllOfficers(ctx, 10, 0, tt.regionID)
+			if err != nil {
+				t.Fatalf("GetAllOfficers() error = %v", err)
+			}
+			if count != tt.want || len(officers) != count {
+				t.Errorf("GetOfficersCount() = %d and GetAllOfficers() listed %d, want %d", count, len(officers), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"api-server/models"
+	"context"
+	"math"
+	"sort"
+)
+
+// earthRadiusKm is the mean radius of the Earth used by the Haversine formula
+const earthRadiusKm = 6371.0
+
+// GetNearbyBranchOffices returns up to limit branch offices within radiusKm of a point, nearest first
+func (s *BranchOfficeService) GetNearbyBranchOffices(ctx context.Context, latitude, longitude, radiusKm float64, limit int) ([]models.NearbyBranchOffice, error) {
+	branchOffices, err := s.branchOffices.GetLocatedBranchOffices(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	nearby := []models.NearbyBranchOffice{}
+	for _, branchOffice := range branchOffices {
+		branchOffice.DistanceKm = haversineKm(latitude, longitude, branchOffice.Latitude, branchOffice.Longitude)
+		if branchOffice.DistanceKm > radiusKm {
+			continue
+		}
+
+		if total := branchOffice.TotalLikes + branchOffice.TotalDislikes; total > 0 {
+			score := math.Round(float64(branchOffice.TotalLikes)/float64(total)*10000) / 100
+			branchOffice.SatisfactionScore = &score
+		}
+		branchOffice.DistanceKm = math.Round(branchOffice.DistanceKm*1000) / 1000
+
+		nearby = append(nearby, branchOffice)
+	}
+
+	sort.SliceStable(nearby, func(i, j int) bool {
+		return nearby[i].DistanceKm < nearby[j].DistanceKm
+	})
+
+	if len(nearby) > limit {
+		nearby = nearby[:limit]
+	}
+
+	return nearby, nil
+}
+
+// haversineKm returns the great-circle distance in kilometres between two points given in degrees
+func haversineKm(lat1, lng1, lat2, lng2 float64) float64 {
+	toRadians := func(degrees float64) float64 { return degrees * math.Pi / 180 }
+
+	deltaLat := toRadians(lat2 - lat1)
+	deltaLng := toRadians(lng2 - lng1)
+
+	a := math.Sin(deltaLat/2)*math.Sin(deltaLat/2) +
+		math.Cos(toRadians(lat1))*math.Cos(toRadians(lat2))*math.Sin(deltaLng/2)*math.Sin(deltaLng/2)
+
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
@@ -0,0 +1,408 @@
+package services
+
+import (
+	"api-server/config"
+	"api-server/helpers"
+	"api-server/imaging"
+	"api-server/models"
+	"api-server/repository"
+	"api-server/repository/validation"
+	"api-server/storage"
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ImportService creates branch offices and users in bulk from spreadsheets
+type ImportService struct {
+	users         repository.UserRepository
+	branchOffices repository.BranchOfficeRepository
+	regions       repository.RegionRepository
+	uploads       config.Uploads
+	images        storage.Storage
+}
+
+// NewImportService creates an ImportService; the regions resolve the city and region names of the rows,
+// the profile images are accepted as configured by uploads and kept in images
+func NewImportService(users repository.UserRepository, branchOffices repository.BranchOfficeRepository, regions repository.RegionRepository, uploads config.Uploads, images storage.Storage) *ImportService {
+	return &ImportService{users: users, branchOffices: branchOffices, regions: regions, uploads: uploads, images: images}
+}
+
+// Limits of bulk imports
+const (
+	MaxImportRows        = 5000
+	importPasswordLength = 12
+)
+
+// importImageExtensions lists the image types accepted in the ZIP of profile images
+var importImageExtensions = map[string]bool{".jpg": true, ".jpeg": true, ".png": true, ".webp": true}
+
+// ImportBranchOffices validates every row with validation.ValidateBranchOffices and, unless it is a dry run
+// or a row is invalid, creates all branch offices in one transaction.
+// Columns: name, address, total_counter, timezone, city_id or city_name, latitude, longitude and the
+// optional translations name_ar, name_en, address_ar, address_en.
+func (s *ImportService) ImportBranchOffices(ctx context.Context, rows []helpers.SpreadsheetRow, dryRun bool) (*models.ImportResult, error) {
+	result := &models.ImportResult{DryRun: dryRun, TotalRows: len(rows), Errors: []models.ImportRowError{}}
+
+	cities, err := s.regions.GetAllCities(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	cityIDs := map[uint]bool{}
+	cityNames := map[string][]uint{}
+	for _, city := range cities {
+		cityIDs[city.ID] = true
+		key := strings.ToLower(validation.NormalizeText(city.Name))
+		cityNames[key] = append(cityNames[key], city.ID)
+	}
+
+	branchOffices := []models.BranchOfficeCreateRequest{}
+	for _, row := range rows {
+		input, rowErrors := branchOfficeImportInput(row)
+
+		if cityName := row.Values["city_name"]; cityName != "" && input["city_id"] == nil {
+			switch ids := cityNames[strings.ToLower(validation.NormalizeText(cityName))]; len(ids) {
+			case 0:
+				rowErrors = append(rowErrors, models.ImportRowError{Row: row.Line, Field: "city_name", Message: "city not found"})
+			case 1:
+				input["city_id"] = float64(ids[0])
+			default:
+				rowErrors = append(rowErrors, models.ImportRowError{Row: row.Line, Field: "city_name", Message: "several cities have this name, use city_id"})
+			}
+		}
+
+		if len(rowErrors) == 0 {
+			if err := validation.ValidateBranchOffices(input); err != nil {
+				rowErrors = append(rowErrors, validationRowErrors(row.Line, err)...)
+			}
+		}
+		if len(rowErrors) == 0 {
+			if cityID, ok := input["city_id"].(float64); ok && !cityIDs[uint(cityID)] {
+				rowErrors = append(rowErrors, models.ImportRowError{Row: row.Line, Field: "city_id", Message: "city not found"})
+			}
+		}
+
+		if len(rowErrors) > 0 {
+			result.Errors = append(result.Errors, rowErrors...)
+			continue
+		}
+
+		branchOffice := models.BranchOfficeCreateRequest{
+			Name:         input["name"].(string),
+			Address:      input["address"].(string),
+			NameAr:       optionalImportValue(input, "name_ar"),
+			NameEn:       optionalImportValue(input, "name_en"),
+			AddressAr:    optionalImportValue(input, "address_ar"),
+			AddressEn:    optionalImportValue(input, "address_en"),
+			TotalCounter: uint(input["total_counter"].(float64)),
+			Timezone:     models.DefaultBranchTimezone,
+		}
+		if timezone, ok := input["timezone"].(string); ok && timezone != "" {
+			branchOffice.Timezone = timezone
+		}
+		if cityID, ok := input["city_id"].(float64); ok {
+			city := uint(cityID)
+			branchOffice.CityID = &city
+		}
+		if latitude, ok := input["latitude"].(float64); ok {
+			longitude := input["longitude"].(float64)
+			branchOffice.Latitude = &latitude
+			branchOffice.Longitude = &longitude
+		}
+		branchOffices = append(branchOffices, branchOffice)
+	}
+
+	result.ValidRows = len(branchOffices)
+	if dryRun || len(result.Errors) > 0 {
+		return result, nil
+	}
+
+	if err := s.branchOffices.CreateBranchOffices(ctx, branchOffices); err != nil {
+		return nil, err
+	}
+	result.Created = len(branchOffices)
+
+	return result, nil
+}
+
+// branchOfficeImportInput converts a row into the JSON-like input expected by validation.ValidateBranchOffices
+func branchOfficeImportInput(row helpers.SpreadsheetRow) (map[string]interface{}, []models.ImportRowError) {
+	input := map[string]interface{}{
+		"name":    row.Values["name"],
+		"address": row.Values["address"],
+	}
+	for _, field := range []string{"timezone", "name_ar", "name_en", "address_ar", "address_en"} {
+		if value := row.Values[field]; value != "" {
+			input[field] = value
+		}
+	}
+
+	var rowErrors []models.ImportRowError
+	for _, field := range []string{"total_counter", "city_id", "latitude", "longitude"} {
+		value := row.Values[field]
+		if value == "" {
+			continue
+		}
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			rowErrors = append(rowErrors, models.ImportRowError{Row: row.Line, Field: field, Message: "must be a number"})
+			continue
+		}
+		input[field] = number
+	}
+
+	return input, rowErrors
+}
+
+// optionalImportValue reads an optional text of a validated row input, nil when the cell was empty
+func optionalImportValue(input map[string]interface{}, field string) *string {
+	if value, ok := input[field].(string); ok {
+		return &value
+	}
+	return nil
+}
+
+// ImportUsers validates every row with validation.ValidateUser and, unless it is a dry run or a row is
+// invalid, creates all users in one transaction with generated initial passwords. Profile images are
+// taken from the optional ZIP, named after the user's email (e.g. jane@example.com.jpg).
+// Columns: full_name, full_name_ar, full_name_en, email, role, branch_id or branch_name, region_id.
+func (s *ImportService) ImportUsers(ctx context.Context, rows []helpers.SpreadsheetRow, images *zip.Reader, dryRun bool) (*models.ImportResult, error) {
+	result := &models.ImportResult{DryRun: dryRun, TotalRows: len(rows), Errors: []models.ImportRowError{}}
+
+	branchOffices, err := s.branchOffices.GetAllBranchOfficesOption(ctx)
+	if err != nil {
+		return nil, err
+	}
+	branchIDs := map[uint]bool{}
+	branchNames := map[string][]uint{}
+	for _, branchOffice := range branchOffices {
+		branchIDs[branchOffice.ID] = true
+		key := strings.ToLower(validation.NormalizeText(branchOffice.Name))
+		branchNames[key] = append(branchNames[key], branchOffice.ID)
+	}
+
+	regions, err := s.regions.GetAllRegions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	regionIDs := map[uint]bool{}
+	for _, region := range regions {
+		regionIDs[region.ID] = true
+	}
+
+	emails := []string{}
+	for _, row := range rows {
+		emails = append(emails, validation.NormalizeEmail(row.Values["email"]))
+	}
+	takenEmails, err := s.users.GetActiveUserEmails(ctx, emails)
+	if err != nil {
+		return nil, err
+	}
+
+	imageFiles := map[string]*zip.File{}
+	if images != nil {
+		for _, file := range images.File {
+			name := filepath.Base(file.Name)
+			extension := strings.ToLower(filepath.Ext(name))
+			if file.FileInfo().IsDir() || strings.HasPrefix(name, ".") || !importImageExtensions[extension] {
+				continue
+			}
+			imageFiles[strings.ToLower(strings.TrimSuffix(name, filepath.Ext(name)))] = file
+		}
+	}
+
+	users := []models.User{}
+	credentials := []models.ImportedCredential{}
+	rowImages := []*imaging.Image{}
+	seenEmails := map[string]int{}
+
+	for _, row := range rows {
+		var rowErrors []models.ImportRowError
+		addError := func(field string, message string) {
+			rowErrors = append(rowErrors, models.ImportRowError{Row: row.Line, Field: field, Message: message})
+		}
+
+		user := models.User{
+			FullName: row.Values["full_name"],
+			Email:    row.Values["email"],
+			Role:     row.Values["role"],
+		}
+		if fullNameAr := row.Values["full_name_ar"]; fullNameAr != "" {
+			user.FullNameAr = &fullNameAr
+		}
+		if fullNameEn := row.Values["full_name_en"]; fullNameEn != "" {
+			user.FullNameEn = &fullNameEn
+		}
+
+		if regionIDStr := row.Values["region_id"]; regionIDStr != "" {
+			parsed, err := strconv.ParseUint(regionIDStr, 10, 32)
+			if err != nil {
+				addError("region_id", "must be a number")
+			} else if !regionIDs[uint(parsed)] {
+				addError("region_id", "region not found")
+			} else {
+				regionID := uint(parsed)
+				user.RegionId = &regionID
+			}
+		}
+
+		// Regional managers have no branch office, every other role needs one
+		if branchIDStr := row.Values["branch_id"]; branchIDStr != "" {
+			parsed, err := strconv.ParseUint(branchIDStr, 10, 32)
+			if err != nil {
+				addError("branch_id", "must be a number")
+			} else if !branchIDs[uint(parsed)] {
+				addError("branch_id", "branch office not found")
+			} else {
+				user.BranchId = uint(parsed)
+			}
+		} else if branchName := row.Values["branch_name"]; branchName != "" {
+			switch ids := branchNames[strings.ToLower(validation.NormalizeText(branchName))]; len(ids) {
+			case 0:
+				addError("branch_name", "branch office not found")
+			case 1:
+				user.BranchId = ids[0]
+			default:
+				addError("branch_name", "several branch offices have this name, use branch_id")
+			}
+		} else if user.Role != "regional_manager" {
+			addError("branch_id", "branch_id or branch_name is required")
+		}
+
+		password, err := helpers.GeneratePassword(importPasswordLength)
+		if err != nil {
+			return nil, err
+		}
+		user.Password = password
+
+		if err := validation.ValidateUser(&user); err != nil {
+			rowErrors = append(rowErrors, validationRowErrors(row.Line, err)...)
+		}
+
+		if user.Email != "" {
+			if line, duplicate := seenEmails[user.Email]; duplicate {
+				addError("email", fmt.Sprintf("duplicate of row %d", line))
+			} else {
+				seenEmails[user.Email] = row.Line
+			}
+			if takenEmails[user.Email] {
+				addError("email", "the email already exists")
+			}
+		}
+
+		var image *imaging.Image
+		if file := imageFiles[user.Email]; file != nil {
+			delete(imageFiles, user.Email)
+			processed, err := s.processImportImage(file)
+			var invalid *imaging.Error
+			switch {
+			case errors.As(err, &invalid):
+				addError("image", invalid.Error())
+			case err != nil:
+				return nil, err
+			default:
+				image = processed
+			}
+		}
+
+		if len(rowErrors) > 0 {
+			result.Errors = append(result.Errors, rowErrors...)
+			continue
+		}
+
+		users = append(users, user)
+		rowImages = append(rowImages, image)
+		credentials = append(credentials, models.ImportedCredential{Row: row.Line, Email: user.Email, Password: password})
+	}
+
+	for name, file := range imageFiles {
+		slog.WarnContext(ctx, "Import image without a matching user", "file", file.Name)
+		result.UnmatchedImages = append(result.UnmatchedImages, name)
+	}
+
+	result.ValidRows = len(users)
+	if dryRun || len(result.Errors) > 0 {
+		return result, nil
+	}
+
+	// Images are stored first and deleted again if the users cannot be created. Identical images share
+	// their content-hash name and are stored once.
+	tx := storage.Begin(s.images)
+	usedNames := map[string]bool{}
+	for i, image := range rowImages {
+		if image == nil {
+			continue
+		}
+
+		if !usedNames[image.Name] {
+			if err := imaging.Save(ctx, tx, image); err != nil {
+				rollbackFiles(ctx, tx)
+				return nil, err
+			}
+			usedNames[image.Name] = true
+		}
+
+		users[i].Image = image.Name
+		credentials[i].Image = image.Name
+	}
+
+	// Hash the passwords outside the transaction as hashing is slow
+	for i := range users {
+		if err := hashPassword(ctx, &users[i]); err != nil {
+			rollbackFiles(ctx, tx)
+			return nil, err
+		}
+	}
+
+	if err := s.users.CreateUsers(ctx, users); err != nil {
+		rollbackFiles(ctx, tx)
+		return nil, err
+	}
+	commitFiles(ctx, tx)
+
+	result.Created = len(users)
+	result.Credentials = credentials
+
+	return result, nil
+}
+
+// validationRowErrors turns a validation error into one row error per message, ordered by field
+func validationRowErrors(line int, err error) []models.ImportRowError {
+	var fieldErrors validation.FieldErrors
+	if !errors.As(err, &fieldErrors) {
+		return []models.ImportRowError{{Row: line, Message: err.Error()}}
+	}
+
+	fields := make([]string, 0, len(fieldErrors))
+	for field := range fieldErrors {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	rowErrors := []models.ImportRowError{}
+	for _, field := range fields {
+		for _, message := range fieldErrors[field] {
+			rowErrors = append(rowErrors, models.ImportRowError{Row: line, Field: field, Message: message})
+		}
+	}
+
+	return rowErrors
+}
+
+// processImportImage processes a profile image of the ZIP; the size it declares is not trusted, it is
+// read at most to the image size limit
+func (s *ImportService) processImportImage(file *zip.File) (*imaging.Image, error) {
+	src, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	return imaging.Process(src, imaging.Limits{MaxBytes: s.uploads.MaxImageSize, MaxPixels: s.uploads.MaxImagePixels}, imaging.Avatar)
+}
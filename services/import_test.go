@@ -0,0 +1,257 @@
+package services
+
+import (
+	"api-server/helpers"
+	"api-server/models"
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+// userRow returns a spreadsheet row of an officer of a branch office
+func userRow(line int, email string, branchID uint) helpers.SpreadsheetRow {
+	return helpers.SpreadsheetRow{Line: line, Values: map[string]string{
+		"full_name": "Imported Officer",
+		"email":     email,
+		"role":      "officer",
+		"branch_id": strconv.FormatUint(uint64(branchID), 10),
+	}}
+}
+
+// usersCount returns the number of active officers
+func usersCount(t *testing.T, services *Services) int {
+	t.Helper()
+	count, err := services.Users.GetUsersCount(context.Background(), "officer")
+	if err != nil {
+		t.Fatalf("GetUsersCount() error = %v", err)
+	}
+	return count
+}
+
+func TestImportUsersAllOrNothing(t *testing.T) {
+	ctx := context.Background()
+	services, store := newTestServices(t)
+	branchID := createBranchOffice(t, store, "Riyadh Main")
+	createOfficer(t, services, branchID, "taken@example.com")
+
+	rows := []helpers.SpreadsheetRow{
+		userRow(2, "first@example.com", branchID),
+		userRow(3, "Taken@Example.com", branchID),
+		userRow(4, "second@example.com", branchID+100),
+		userRow(5, "third@example.com", branchID),
+	}
+	result, err := services.Import.ImportUsers(ctx, rows, nil, false)
+	if err != nil {
+		t.Fatalf("ImportUsers() error = %v", err)
+	}
+	if result.Created != 0 || result.ValidRows != 2 || len(result.Credentials) != 0 {
+		t.Errorf("ImportUsers() created %d of %d valid rows with %d credentials, want none of 2",
+			result.Created, result.ValidRows, len(result.Credentials))
+	}
+
+	failed := map[int]string{}
+	for _, rowError := range result.Errors {
+		failed[rowError.Row] = rowError.Field
+	}
+	if len(failed) != 2 || failed[3] != "email" || failed[4] != "branch_id" {
+		t.Errorf("ImportUsers() errors = %+v, want the email of row 3 and the branch_id of row 4", result.Errors)
+	}
+	if count := usersCount(t, services); count != 1 {
+		t.Errorf("%d officers after a failed import, want the 1 created before", count)
+	}
+
+	// Once the rows are fixed, they are all created
+	rows[1] = userRow(3, "fourth@example.com", branchID)
+	rows[2] = userRow(4, "second@example.com", branchID)
+	result, err = services.Import.ImportUsers(ctx, rows, nil, false)
+	if err != nil {
+		t.Fatalf("ImportUsers() error = %v", err)
+	}
+	if result.Created != len(rows) || len(result.Errors) != 0 || len(result.Credentials) != len(rows) {
+		t.Fatalf("ImportUsers() = %+v, want %d users created", result, len(rows))
+	}
+	if count := usersCount(t, services); count != 1+len(rows) {
+		t.Errorf("%d officers after the import, want %d", count, 1+len(rows))
+	}
+
+	// The generated passwords are hashed before being stored
+	user, err := store.CheckUserAuthentication(ctx, result.Credentials[0].Email, result.Credentials[0].Password)
+	if err != nil {
+		t.Errorf("logging in with the generated credentials: %v", err)
+	} else if user.Password == result.Credentials[0].Password {
+		t.Errorf("the generated password was stored in clear")
+	}
+}
+
+func TestImportUsersDryRun(t *testing.T) {
+	ctx := context.Background()
+	services, store := newTestServices(t)
+	branchID := createBranchOffice(t, store, "Riyadh Main")
+
+	rows := []helpers.SpreadsheetRow{userRow(2, "first@example.com", branchID), userRow(3, "second@example.com", branchID)}
+	result, err := services.Import.ImportUsers(ctx, rows, nil, true)
+	if err != nil {
+		t.Fatalf("ImportUsers() error = %v", err)
+	}
+	if !result.DryRun || result.ValidRows != 2 || result.Created != 0 {
+		t.Errorf("ImportUsers() dry run = %+v, want 2 valid rows and none created", result)
+	}
+	if count := usersCount(t, services); count != 0 {
+		t.Errorf("%d officers after a dry run, want 0", count)
+	}
+}
+
+func TestCreateUsersAllOrNothing(t *testing.T) {
+	// A row can still fail in the repository after the import validated it, e.g. when another request took
+	// its email meanwhile: the rows before it must not be left behind
+	ctx := context.Background()
+	services, store := newTestServices(t)
+	branchID := createBranchOffice(t, store, "Riyadh Main")
+
+	users := []models.User{
+		{FullName: "First Officer", Email: "first@example.com", Password: "hashed", Role: "officer", BranchId: branchID},
+		{FullName: "Second Officer", Email: "second@example.com", Password: "hashed", Role: "officer", BranchId: branchID + 100},
+	}
+	var pqErr *pq.Error
+	if err := store.CreateUsers(ctx, users); !errors.As(err, &pqErr) || pqErr.Code != "23503" {
+		t.Fatalf("CreateUsers() with a missing branch office = %v, want a foreign key violation", err)
+	}
+
+	users[1].BranchId = branchID
+	users[1].Email = "FIRST@example.com"
+	if err := store.CreateUsers(ctx, users); !isUniqueViolation(err) {
+		t.Fatalf("CreateUsers() with the same email twice = %v, want a unique violation", err)
+	}
+
+	if count := usersCount(t, services); count != 0 {
+		t.Errorf("%d officers after the failed inserts, want 0", count)
+	}
+	memberships, err := services.Users.GetBranchMembershipsByUserID(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetBranchMembershipsByUserID() error = %v", err)
+	}
+	if len(memberships) != 0 {
+		t.Errorf("the failed inserts left the memberships %+v", memberships)
+	}
+}
+
+// rowErrorFields lists the fields of the errors of each row, in the order they were reported
+func rowErrorFields(rowErrors []models.ImportRowError) map[int][]string {
+	fields := map[int][]string{}
+	for _, rowError := range rowErrors {
+		fields[rowError.Row] = append(fields[rowError.Row], rowError.Field)
+	}
+	return fields
+}
+
+func TestImportUsersRowErrors(t *testing.T) {
+	ctx := context.Background()
+	services, store := newTestServices(t)
+	branchID := createBranchOffice(t, store, "Jeddah Corniche")
+	branch := strconv.FormatUint(uint64(branchID), 10)
+
+	rows := []helpers.SpreadsheetRow{
+		{Line: 2, Values: map[string]string{"full_name": "Layla Haddad", "email": "layla.example.com", "role": "officer", "branch_id": branch}},
+		{Line: 3, Values: map[string]string{"full_name": "  ", "email": "omar@example.com", "role": "supervisor", "branch_id": branch}},
+		{Line: 4, Values: map[string]string{"full_name": "Sami Nasser", "email": "sami@example.com", "role": "superuser", "branch_name": " jeddah corniche "}},
+		{Line: 5, Values: map[string]string{"full_name": "Nora Aziz", "email": "Nora@Example.com", "role": "officer", "branch_id": branch}},
+		{Line: 6, Values: map[string]string{"full_name": "Nora Saleh", "email": " nora@example.com", "role": "admin", "branch_id": branch}},
+		// Every problem of a row is reported, not only the first one
+		{Line: 7, Values: map[string]string{"full_name": "J", "email": "", "role": "clerk", "branch_id": "Jeddah", "region_id": "7"}},
+		{Line: 8, Values: map[string]string{"full_name": "Huda Fares", "email": "huda@example.com", "role": "regional_manager"}},
+	}
+	result, err := services.Import.ImportUsers(ctx, rows, nil, false)
+	if err != nil {
+		t.Fatalf("ImportUsers() error = %v", err)
+	}
+
+	want := map[int][]string{
+		2: {"email"},
+		3: {"full_name"},
+		4: {"role"},
+		6: {"email"},
+		7: {"region_id", "branch_id", "email", "full_name", "role"},
+		8: {"region_id"},
+	}
+	if got := rowErrorFields(result.Errors); !reflect.DeepEqual(got, want) {
+		t.Errorf("ImportUsers() error fields = %v, want %v\n%+v", got, want, result.Errors)
+	}
+	for _, rowError := range result.Errors {
+		if rowError.Message == "" {
+			t.Errorf("row %d: the error of %s has no message", rowError.Row, rowError.Field)
+		}
+		if rowError.Row == 6 && rowError.Message != "duplicate of row 5" {
+			t.Errorf("row 6: email error %q, want a duplicate of row 5", rowError.Message)
+		}
+	}
+	if result.TotalRows != len(rows) || result.ValidRows != 1 || result.Created != 0 {
+		t.Errorf("ImportUsers() = %d rows, %d valid, %d created, want %d rows, 1 valid, none created",
+			result.TotalRows, result.ValidRows, result.Created, len(rows))
+	}
+	if count := usersCount(t, services); count != 0 {
+		t.Errorf("%d officers after an import with invalid rows, want 0", count)
+	}
+}
+
+func TestImportBranchOfficesRowErrors(t *testing.T) {
+	ctx := context.Background()
+	services, store := newTestServices(t)
+
+	// "Al Salam" exists in both regions, "Tabuk" only in the second one
+	for i, region := range []string{"Makkah", "Tabuk"} {
+		if err := store.CreateRegion(ctx, &models.RegionCreateRequest{Name: region}); err != nil {
+			t.Fatalf("creating region %s: %v", region, err)
+		}
+		for _, city := range []string{"Al Salam", region} {
+			if err := store.CreateCity(ctx, &models.CityCreateRequest{Name: city, RegionID: uint(i + 1)}); err != nil {
+				t.Fatalf("creating city %s: %v", city, err)
+			}
+		}
+	}
+
+	row := func(line int, values map[string]string) helpers.SpreadsheetRow {
+		row := helpers.SpreadsheetRow{Line: line, Values: map[string]string{"name": "Branch " + strconv.Itoa(line), "address": "King Fahd Road", "total_counter": "4"}}
+		for key, value := range values {
+			row.Values[key] = value
+		}
+		return row
+	}
+	rows := []helpers.SpreadsheetRow{
+		row(2, map[string]string{"city_name": "tabuk"}),
+		row(3, map[string]string{"city_name": "Atlantis"}),
+		row(4, map[string]string{"city_name": "Al Salam"}),
+		row(5, map[string]string{"total_counter": "four", "latitude": "north", "longitude": "39.2"}),
+		row(6, map[string]string{"total_counter": "0", "name": ""}),
+		row(7, map[string]string{"city_id": "99"}),
+		row(8, map[string]string{"name_ar": "فرع تبوك", "timezone": "Asia/Riyadh", "city_id": "4"}),
+	}
+	result, err := services.Import.ImportBranchOffices(ctx, rows, false)
+	if err != nil {
+		t.Fatalf("ImportBranchOffices() error = %v", err)
+	}
+
+	want := map[int][]string{
+		3: {"city_name"},
+		4: {"city_name"},
+		5: {"total_counter", "latitude"},
+		6: {"name", "total_counter"},
+		7: {"city_id"},
+	}
+	if got := rowErrorFields(result.Errors); !reflect.DeepEqual(got, want) {
+		t.Errorf("ImportBranchOffices() error fields = %v, want %v\n%+v", got, want, result.Errors)
+	}
+	if result.ValidRows != 2 || result.Created != 0 {
+		t.Errorf("ImportBranchOffices() = %d valid, %d created, want 2 valid and none created", result.ValidRows, result.Created)
+	}
+	options, err := store.GetAllBranchOfficesOption(ctx)
+	if err != nil {
+		t.Fatalf("listing the branch offices: %v", err)
+	}
+	if len(options) != 0 {
+		t.Errorf("%d branch offices after an import with invalid rows, want 0", len(options))
+	}
+}
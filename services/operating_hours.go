@@ -0,0 +1,231 @@
+package services
+
+import (
+	"api-server/apperror"
+	"api-server/models"
+	"api-server/repository"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// OperatingHoursService manages the schedules of branch offices and tells whether they are open
+type OperatingHoursService struct {
+	operatingHours repository.OperatingHoursRepository
+}
+
+// NewOperatingHoursService creates an OperatingHoursService on the given repository
+func NewOperatingHoursService(operatingHours repository.OperatingHoursRepository) *OperatingHoursService {
+	return &OperatingHoursService{operatingHours: operatingHours}
+}
+
+// ErrVoteOutsideOperatingHours is returned when a vote arrives while the branch office is closed
+// and its out-of-hours policy rejects such votes
+var ErrVoteOutsideOperatingHours = apperror.Forbidden("the branch office is closed, votes outside operating hours are rejected")
+
+// scheduleLookahead is how far ahead GetBranchSchedule lists holidays, special hours and closures
+const scheduleLookahead = 366 * 24 * time.Hour
+
+// GetBranchSchedule retrieves the weekly hours and the upcoming holidays, special hours and closures of a branch office
+func (s *OperatingHoursService) GetBranchSchedule(ctx context.Context, branchOffice *models.BranchOfficeResponse) (*models.BranchSchedule, error) {
+	location, err := time.LoadLocation(branchOffice.Timezone)
+	if err != nil {
+		return nil, errors.New("branch office has an invalid timezone")
+	}
+
+	now := time.Now()
+	today := now.In(location).Format(analyticsDateLayout)
+	until := now.Add(scheduleLookahead)
+
+	hours, err := s.operatingHours.GetOpeningHours(ctx, branchOffice.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	exceptions, err := s.operatingHours.GetCalendarExceptions(ctx, branchOffice.ID, today, until.In(location).Format(analyticsDateLayout))
+	if err != nil {
+		return nil, err
+	}
+
+	closures, err := s.operatingHours.GetTemporaryClosures(ctx, branchOffice.ID, now, until)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.BranchSchedule{
+		BranchID:         branchOffice.ID,
+		Timezone:         branchOffice.Timezone,
+		OutOfHoursPolicy: branchOffice.OutOfHoursPolicy,
+		Hours:            hours,
+		Exceptions:       exceptions,
+		Closures:         closures,
+	}, nil
+}
+
+// ReplaceOpeningHours replaces the weekly opening hours and out-of-hours vote policy of a branch office
+func (s *OperatingHoursService) ReplaceOpeningHours(ctx context.Context, branchID uint, hours []models.OpeningHours, outOfHoursPolicy string) error {
+	return s.operatingHours.ReplaceOpeningHours(ctx, branchID, hours, outOfHoursPolicy)
+}
+
+// CreateCalendarException adds a holiday or special hours period to a branch office
+func (s *OperatingHoursService) CreateCalendarException(ctx context.Context, exception *models.CalendarException) error {
+	return s.operatingHours.CreateCalendarException(ctx, exception)
+}
+
+// DeleteCalendarException removes a holiday or special hours period from a branch office
+func (s *OperatingHoursService) DeleteCalendarException(ctx context.Context, branchID uint, id uint) error {
+	return s.operatingHours.DeleteCalendarException(ctx, branchID, id)
+}
+
+// CreateTemporaryClosure adds a temporary closure to a branch office
+func (s *OperatingHoursService) CreateTemporaryClosure(ctx context.Context, closure *models.TemporaryClosure) error {
+	return s.operatingHours.CreateTemporaryClosure(ctx, closure)
+}
+
+// DeleteTemporaryClosure removes a temporary closure from a branch office
+func (s *OperatingHoursService) DeleteTemporaryClosure(ctx context.Context, branchID uint, id uint) error {
+	return s.operatingHours.DeleteTemporaryClosure(ctx, branchID, id)
+}
+
+// GetBranchOpenStatus tells whether a branch office is open at the given moment. Temporary closures win over
+// holidays, holidays and special hours win over the weekly hours. A branch office without weekly hours is
+// considered always open so existing branches keep accepting votes until they are configured.
+func (s *OperatingHoursService) GetBranchOpenStatus(ctx context.Context, branchOffice *models.BranchOfficeResponse, at time.Time) (*models.BranchOpenStatus, error) {
+	location, err := time.LoadLocation(branchOffice.Timezone)
+	if err != nil {
+		return nil, errors.New("branch office has an invalid timezone")
+	}
+
+	local := at.In(location)
+	status := &models.BranchOpenStatus{
+		BranchID: branchOffice.ID,
+		At:       at,
+		LocalAt:  local.Format(time.RFC3339),
+	}
+
+	closures, err := s.operatingHours.GetTemporaryClosures(ctx, branchOffice.ID, at, at.Add(time.Nanosecond))
+	if err != nil {
+		return nil, err
+	}
+	if len(closures) > 0 {
+		status.Reason = "closure"
+		status.Detail = closures[0].Reason
+		return status, nil
+	}
+
+	hours, err := s.operatingHours.GetOpeningHours(ctx, branchOffice.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Intervals of the previous day may run past midnight into today
+	today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, location)
+	yesterday := today.AddDate(0, 0, -1)
+
+	exceptions, err := s.operatingHours.GetCalendarExceptions(ctx, branchOffice.ID, yesterday.Format(analyticsDateLayout), today.Format(analyticsDateLayout))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, day := range []time.Time{today, yesterday} {
+		intervals, reason, detail := dayIntervals(day, hours, exceptions)
+		if day.Equal(today) && reason == "holiday" {
+			status.Reason = reason
+			status.Detail = detail
+		}
+
+		for _, interval := range intervals {
+			if !local.Before(interval[0]) && local.Before(interval[1]) {
+				status.IsOpen = true
+				status.Reason = reason
+				status.Detail = detail
+				return status, nil
+			}
+		}
+	}
+
+	if status.Reason == "" {
+		status.Reason = "outside_hours"
+	}
+
+	return status, nil
+}
+
+// CheckVoteOperatingHours applies the out-of-hours vote policy of a branch office to a vote received at the
+// given moment. It returns whether the vote must be flagged, or ErrVoteOutsideOperatingHours to reject it.
+func (s *OperatingHoursService) CheckVoteOperatingHours(ctx context.Context, branchOffice *models.BranchOfficeResponse, at time.Time) (bool, error) {
+	if branchOffice.OutOfHoursPolicy == models.OutOfHoursVoteAccept {
+		return false, nil
+	}
+
+	status, err := s.GetBranchOpenStatus(ctx, branchOffice, at)
+	if err != nil {
+		return false, err
+	}
+	if status.IsOpen {
+		return false, nil
+	}
+
+	if branchOffice.OutOfHoursPolicy == models.OutOfHoursVoteReject {
+		return false, ErrVoteOutsideOperatingHours
+	}
+
+	return true, nil
+}
+
+// dayIntervals returns the opening intervals of a local day with the reason they apply
+func dayIntervals(day time.Time, hours []models.OpeningHours, exceptions []models.CalendarException) ([][2]time.Time, string, string) {
+	date := day.Format(analyticsDateLayout)
+
+	for _, exception := range exceptions {
+		if date < exception.StartsOn || date > exception.EndsOn {
+			continue
+		}
+
+		if exception.Kind == models.CalendarHoliday {
+			return nil, "holiday", exception.Name
+		}
+		if exception.OpensAt != nil && exception.ClosesAt != nil {
+			if interval, ok := clockInterval(day, *exception.OpensAt, *exception.ClosesAt); ok {
+				return [][2]time.Time{interval}, "special_hours", exception.Name
+			}
+		}
+	}
+
+	if len(hours) == 0 {
+		return [][2]time.Time{{day, day.AddDate(0, 0, 1)}}, "no_schedule", ""
+	}
+
+	var intervals [][2]time.Time
+	for _, opening := range hours {
+		if opening.DayOfWeek != uint(day.Weekday()) {
+			continue
+		}
+		if interval, ok := clockInterval(day, opening.OpensAt, opening.ClosesAt); ok {
+			intervals = append(intervals, interval)
+		}
+	}
+
+	return intervals, "regular_hours", ""
+}
+
+// clockInterval turns "HH:MM" opening and closing times into an interval on the given day.
+// A closing time at or before the opening time ends on the next day.
+func clockInterval(day time.Time, opensAt string, closesAt string) ([2]time.Time, bool) {
+	var openHour, openMinute, closeHour, closeMinute int
+	if _, err := fmt.Sscanf(opensAt, "%d:%d", &openHour, &openMinute); err != nil {
+		return [2]time.Time{}, false
+	}
+	if _, err := fmt.Sscanf(closesAt, "%d:%d", &closeHour, &closeMinute); err != nil {
+		return [2]time.Time{}, false
+	}
+
+	start := time.Date(day.Year(), day.Month(), day.Day(), openHour, openMinute, 0, 0, day.Location())
+	end := time.Date(day.Year(), day.Month(), day.Day(), closeHour, closeMinute, 0, 0, day.Location())
+	if !end.After(start) {
+		end = end.AddDate(0, 0, 1)
+	}
+
+	return [2]time.Time{start, end}, true
+}
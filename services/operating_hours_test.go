@@ -0,0 +1,181 @@
+package services
+
+import (
+	"api-server/models"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// ramadanHours are the special hours of a branch office during Ramadan, running past midnight
+func ramadanHours() models.CalendarException {
+	opensAt, closesAt := "21:00", "03:00"
+	return models.CalendarException{Name: "Ramadan", Kind: models.CalendarSpecialHours, StartsOn: "2026-02-18", EndsOn: "2026-03-19", OpensAt: &opensAt, ClosesAt: &closesAt}
+}
+
+// weeklyHours open a branch office on Wednesday with a lunch break and on Thursday morning
+var weeklyHours = []models.OpeningHours{
+	{DayOfWeek: 3, OpensAt: "08:00", ClosesAt: "12:00"},
+	{DayOfWeek: 3, OpensAt: "16:00", ClosesAt: "20:00"},
+	{DayOfWeek: 4, OpensAt: "08:00", ClosesAt: "14:00"},
+}
+
+func TestClockInterval(t *testing.T) {
+	riyadh, err := time.LoadLocation("Asia/Riyadh")
+	if err != nil {
+		t.Fatalf("loading the time zone: %v", err)
+	}
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("loading the time zone: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		day      time.Time
+		opensAt  string
+		closesAt string
+		ok       bool
+		length   time.Duration
+		endDay   int
+	}{
+		{"same day", time.Date(2026, 9, 23, 0, 0, 0, 0, riyadh), "08:00", "16:30", true, 8*time.Hour + 30*time.Minute, 23},
+		{"past midnight", time.Date(2026, 9, 23, 0, 0, 0, 0, riyadh), "22:00", "02:00", true, 4 * time.Hour, 24},
+		{"whole day", time.Date(2026, 9, 23, 0, 0, 0, 0, riyadh), "00:00", "00:00", true, 24 * time.Hour, 24},
+		// Clocks go forward at 02:00 on the last Sunday of March: the night is an hour shorter
+		{"into summer time", time.Date(2026, 3, 29, 0, 0, 0, 0, berlin), "01:00", "04:00", true, 2 * time.Hour, 29},
+		{"overnight into summer time", time.Date(2026, 3, 28, 0, 0, 0, 0, berlin), "22:00", "06:00", true, 7 * time.Hour, 29},
+		{"into winter time", time.Date(2026, 10, 25, 0, 0, 0, 0, berlin), "01:00", "04:00", true, 4 * time.Hour, 25},
+		{"invalid opening", time.Date(2026, 9, 23, 0, 0, 0, 0, riyadh), "8am", "16:00", false, 0, 0},
+		{"invalid closing", time.Date(2026, 9, 23, 0, 0, 0, 0, riyadh), "08:00", "", false, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interval, ok := clockInterval(tt.day, tt.opensAt, tt.closesAt)
+			if ok != tt.ok {
+				t.Fatalf("clockInterval(%s, %s) ok = %v, want %v", tt.opensAt, tt.closesAt, ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if length := interval[1].Sub(interval[0]); length != tt.length {
+				t.Errorf("clockInterval(%s, %s) lasts %v, want %v", tt.opensAt, tt.closesAt, length, tt.length)
+			}
+			if interval[1].Day() != tt.endDay || interval[1].Location() != tt.day.Location() {
+				t.Errorf("clockInterval(%s, %s) ends at %v, want on day %d in %v", tt.opensAt, tt.closesAt, interval[1], tt.endDay, tt.day.Location())
+			}
+		})
+	}
+}
+
+func TestDayIntervals(t *testing.T) {
+	riyadh, err := time.LoadLocation("Asia/Riyadh")
+	if err != nil {
+		t.Fatalf("loading the time zone: %v", err)
+	}
+	exceptions := []models.CalendarException{
+		ramadanHours(),
+		{Name: "Eid al-Fitr", Kind: models.CalendarHoliday, StartsOn: "2026-03-20", EndsOn: "2026-03-22"},
+	}
+	at := func(month time.Month, day int, hour int) time.Time {
+		return time.Date(2026, month, day, hour, 0, 0, 0, riyadh)
+	}
+
+	tests := []struct {
+		name      string
+		day       time.Time
+		hours     []models.OpeningHours
+		intervals [][2]time.Time
+		reason    string
+		detail    string
+	}{
+		{"weekly hours with a break", at(9, 23, 0), weeklyHours, [][2]time.Time{{at(9, 23, 8), at(9, 23, 12)}, {at(9, 23, 16), at(9, 23, 20)}}, "regular_hours", ""},
+		{"no weekly hours that day", at(9, 25, 0), weeklyHours, nil, "regular_hours", ""},
+		{"special hours instead of the weekly ones", at(2, 19, 0), weeklyHours, [][2]time.Time{{at(2, 19, 21), at(2, 20, 3)}}, "special_hours", "Ramadan"},
+		{"last day of the special hours", at(3, 19, 0), weeklyHours, [][2]time.Time{{at(3, 19, 21), at(3, 20, 3)}}, "special_hours", "Ramadan"},
+		{"holiday on a working day", at(3, 22, 0), weeklyHours, nil, "holiday", "Eid al-Fitr"},
+		{"no schedule", at(9, 23, 0), nil, [][2]time.Time{{at(9, 23, 0), at(9, 24, 0)}}, "no_schedule", ""},
+		{"holiday without a schedule", at(3, 20, 0), nil, nil, "holiday", "Eid al-Fitr"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			intervals, reason, detail := dayIntervals(tt.day, tt.hours, exceptions)
+			if reason != tt.reason || detail != tt.detail {
+				t.Errorf("dayIntervals() reason = %q %q, want %q %q", reason, detail, tt.reason, tt.detail)
+			}
+			if len(intervals) != len(tt.intervals) {
+				t.Fatalf("dayIntervals() = %v, want %v", intervals, tt.intervals)
+			}
+			for i, interval := range intervals {
+				if !interval[0].Equal(tt.intervals[i][0]) || !interval[1].Equal(tt.intervals[i][1]) {
+					t.Errorf("interval %d = %v, want %v", i, interval, tt.intervals[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCheckVoteOperatingHours(t *testing.T) {
+	ctx := context.Background()
+	services, store := newTestServices(t)
+	branchID := createBranchOffice(t, store, "Khobar Corniche")
+	riyadh, err := time.LoadLocation("Asia/Riyadh")
+	if err != nil {
+		t.Fatalf("loading the time zone: %v", err)
+	}
+
+	ramadan := ramadanHours()
+	ramadan.BranchID = branchID
+	if err := store.CreateCalendarException(ctx, &ramadan); err != nil {
+		t.Fatalf("creating the special hours: %v", err)
+	}
+	if err := store.CreateCalendarException(ctx, &models.CalendarException{BranchID: branchID, Name: "Eid al-Fitr", Kind: models.CalendarHoliday, StartsOn: "2026-03-20", EndsOn: "2026-03-22"}); err != nil {
+		t.Fatalf("creating the holiday: %v", err)
+	}
+	closure := &models.TemporaryClosure{BranchID: branchID, Reason: "Fire drill",
+		StartsAt: time.Date(2026, 9, 23, 10, 0, 0, 0, riyadh), EndsAt: time.Date(2026, 9, 23, 11, 0, 0, 0, riyadh)}
+	if err := store.CreateTemporaryClosure(ctx, closure); err != nil {
+		t.Fatalf("creating the closure: %v", err)
+	}
+
+	moments := []struct {
+		name string
+		at   time.Time
+		open bool
+	}{
+		{"in the weekly hours", time.Date(2026, 9, 23, 17, 0, 0, 0, riyadh), true},
+		{"during the lunch break", time.Date(2026, 9, 23, 13, 0, 0, 0, riyadh), false},
+		{"during a closure", time.Date(2026, 9, 23, 10, 30, 0, 0, riyadh), false},
+		{"after midnight in the special hours of the day before", time.Date(2026, 2, 20, 1, 30, 0, 0, riyadh), true},
+		{"in the weekly hours replaced by special hours", time.Date(2026, 2, 19, 10, 0, 0, 0, riyadh), false},
+		{"on a holiday", time.Date(2026, 3, 22, 10, 0, 0, 0, riyadh), false},
+	}
+
+	for _, policy := range []string{models.OutOfHoursVoteAccept, models.OutOfHoursVoteFlag, models.OutOfHoursVoteReject} {
+		if err := services.OperatingHours.ReplaceOpeningHours(ctx, branchID, weeklyHours, policy); err != nil {
+			t.Fatalf("setting the opening hours: %v", err)
+		}
+		branchOffice, err := services.BranchOffices.GetBranchOfficeByID(ctx, branchID)
+		if err != nil || branchOffice == nil {
+			t.Fatalf("GetBranchOfficeByID() = %v, %v", branchOffice, err)
+		}
+
+		for _, moment := range moments {
+			t.Run(policy+" "+moment.name, func(t *testing.T) {
+				flagged, err := services.OperatingHours.CheckVoteOperatingHours(ctx, branchOffice, moment.at)
+
+				wantFlagged, wantErr := false, error(nil)
+				if !moment.open && policy == models.OutOfHoursVoteFlag {
+					wantFlagged = true
+				}
+				if !moment.open && policy == models.OutOfHoursVoteReject {
+					wantErr = ErrVoteOutsideOperatingHours
+				}
+				if flagged != wantFlagged || !errors.Is(err, wantErr) {
+					t.Errorf("CheckVoteOperatingHours() = %v, %v, want %v, %v", flagged, err, wantFlagged, wantErr)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"api-server/models"
+	"api-server/repository"
+	"context"
+)
+
+// RegionService manages regions and their cities
+type RegionService struct {
+	regions repository.RegionRepository
+}
+
+// NewRegionService creates a RegionService on the given repository
+func NewRegionService(regions repository.RegionRepository) *RegionService {
+	return &RegionService{regions: regions}
+}
+
+// GetAllRegions retrieves all regions
+func (s *RegionService) GetAllRegions(ctx context.Context) ([]models.RegionResponse, error) {
+	return s.regions.GetAllRegions(ctx)
+}
+
+// GetRegionByID retrieves a region by ID
+func (s *RegionService) GetRegionByID(ctx context.Context, id uint) (*models.RegionResponse, error) {
+	return s.regions.GetRegionByID(ctx, id)
+}
+
+// CreateRegion creates a new region
+func (s *RegionService) CreateRegion(ctx context.Context, region *models.RegionCreateRequest) error {
+	return s.regions.CreateRegion(ctx, region)
+}
+
+// UpdateRegion updates an existing region by ID
+func (s *RegionService) UpdateRegion(ctx context.Context, id uint, region *models.RegionCreateRequest) error {
+	return s.regions.UpdateRegion(ctx, id, region)
+}
+
+// DeleteRegion deletes a region by ID
+func (s *RegionService) DeleteRegion(ctx context.Context, id uint) error {
+	return s.regions.DeleteRegion(ctx, id)
+}
+
+// GetAllCities retrieves all cities, optionally only those of one region
+func (s *RegionService) GetAllCities(ctx context.Context, regionID *uint) ([]models.CityResponse, error) {
+	return s.regions.GetAllCities(ctx, regionID)
+}
+
+// CreateCity creates a new city
+func (s *RegionService) CreateCity(ctx context.Context, city *models.CityCreateRequest) error {
+	return s.regions.CreateCity(ctx, city)
+}
+
+// UpdateCity updates an existing city by ID
+func (s *RegionService) UpdateCity(ctx context.Context, id uint, city *models.CityCreateRequest) error {
+	return s.regions.UpdateCity(ctx, id, city)
+}
+
+// DeleteCity deletes a city by ID
+func (s *RegionService) DeleteCity(ctx context.Context, id uint) error {
+	return s.regions.DeleteCity(ctx, id)
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"api-server/config"
+	"api-server/repository"
+	"api-server/storage"
+)
+
+// Services bundles the services the HTTP handlers depend on
+type Services struct {
+	Users              *UserService
+	BranchOffices      *BranchOfficeService
+	BranchCounters     *BranchCounterService
+	CounterAssignments *CounterAssignmentService
+	OperatingHours     *OperatingHoursService
+	Votes              *VoteService
+	Dashboard          *DashboardService
+	Analytics          *AnalyticsService
+	Archive            *ArchiveService
+	Regions            *RegionService
+	CompanyProfile     *CompanyProfileService
+	Import             *ImportService
+	Uploads            *UploadService
+}
+
+// NewServices creates every service on the given repositories, accepting uploads as configured and keeping
+// them in the stores
+func NewServices(repos *repository.Repositories, uploads config.Uploads, stores *storage.Stores) *Services {
+	operatingHours := NewOperatingHoursService(repos.OperatingHours)
+
+	return &Services{
+		Users:              NewUserService(repos.Users, repos.Memberships, repos.Uploads, stores.Images),
+		BranchOffices:      NewBranchOfficeService(repos.BranchOffices),
+		BranchCounters:     NewBranchCounterService(repos.BranchCounters, repos.CounterAssignments),
+		CounterAssignments: NewCounterAssignmentService(repos.CounterAssignments, repos.Users, stores.Images),
+		OperatingHours:     operatingHours,
+		Votes:              NewVoteService(repos.Votes, repos.BranchOffices, repos.BranchCounters, repos.CounterAssignments, operatingHours),
+		Dashboard:          NewDashboardService(repos.Dashboard, repos.BranchOffices, operatingHours),
+		Analytics:          NewAnalyticsService(repos.Analytics),
+		Archive:            NewArchiveService(repos.Archive),
+		Regions:            NewRegionService(repos.Regions),
+		CompanyProfile:     NewCompanyProfileService(repos.Company, stores.Assets),
+		Import:             NewImportService(repos.Users, repos.BranchOffices, repos.Regions, uploads, stores.Images),
+		Uploads:            NewUploadService(repos.Uploads, stores),
+	}
+}
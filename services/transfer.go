@@ -0,0 +1,29 @@
+package services
+
+import (
+	"api-server/apperror"
+	"api-server/models"
+	"context"
+	"time"
+)
+
+// ErrTransferInFuture is returned for transfers dated in the future; they are applied when they happen
+var ErrTransferInFuture = apperror.InvalidField("effective_at", "effective_at cannot be in the future")
+
+// TransferUser moves a user to another branch office, effective now unless an earlier date is given
+func (s *UserService) TransferUser(ctx context.Context, transfer *models.TransferRequest) (*models.TransferResult, error) {
+	now := time.Now()
+	if transfer.EffectiveAt.IsZero() {
+		transfer.EffectiveAt = now
+	}
+	if transfer.EffectiveAt.After(now) {
+		return nil, ErrTransferInFuture
+	}
+
+	return s.memberships.TransferUser(ctx, transfer)
+}
+
+// GetBranchMembershipsByUserID retrieves the branch offices a user belonged to over time
+func (s *UserService) GetBranchMembershipsByUserID(ctx context.Context, userID uint) ([]models.BranchMembership, error) {
+	return s.memberships.GetBranchMembershipsByUserID(ctx, userID)
+}
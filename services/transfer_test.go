@@ -0,0 +1,147 @@
+package services
+
+import (
+	"api-server/models"
+	"api-server/repository"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTransferUser(t *testing.T) {
+	ctx := context.Background()
+	services, store := newTestServices(t)
+	fromID := createBranchOffice(t, store, "Riyadh Main")
+	toID := createBranchOffice(t, store, "Jeddah Corniche")
+	officer := createOfficer(t, services, fromID, "officer@example.com")
+	colleague := createOfficer(t, services, fromID, "colleague@example.com")
+
+	counter := &models.BranchCounter{CounterLocation: "Entrance", BranchID: fromID, UserID: officer.ID}
+	if err := store.CreateBranchCounter(ctx, counter); err != nil {
+		t.Fatalf("creating the counter: %v", err)
+	}
+	otherCounter := &models.BranchCounter{CounterLocation: "Back office", BranchID: fromID, UserID: colleague.ID}
+	if err := store.CreateBranchCounter(ctx, otherCounter); err != nil {
+		t.Fatalf("creating the other counter: %v", err)
+	}
+
+	now := time.Now()
+	shifts := map[string]*models.CounterAssignment{
+		"past":      {StartsAt: now.Add(-3 * time.Hour), EndsAt: now.Add(-2 * time.Hour)},
+		"running":   {StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour)},
+		"upcoming":  {StartsAt: now.Add(2 * time.Hour), EndsAt: now.Add(3 * time.Hour)},
+		"colleague": {StartsAt: now.Add(2 * time.Hour), EndsAt: now.Add(3 * time.Hour)},
+	}
+	for name, shift := range shifts {
+		shift.CounterID, shift.UserID, shift.BranchID = counter.ID, officer.ID, fromID
+		if name == "colleague" {
+			shift.CounterID, shift.UserID = otherCounter.ID, colleague.ID
+		}
+		if conflicts, err := store.CreateCounterAssignment(ctx, shift); err != nil || len(conflicts) > 0 {
+			t.Fatalf("creating the %s shift: %v, conflicts %+v", name, err, conflicts)
+		}
+	}
+
+	result, err := services.Users.TransferUser(ctx, &models.TransferRequest{UserID: officer.ID, BranchID: toID, Reason: "Reorganization"})
+	if err != nil {
+		t.Fatalf("TransferUser() error = %v", err)
+	}
+
+	if result.ReleasedCounters != 1 || result.CancelledShifts != 1 || result.ShortenedShifts != 1 {
+		t.Errorf("TransferUser() released %d counters, cancelled %d and shortened %d shifts, want 1 of each",
+			result.ReleasedCounters, result.CancelledShifts, result.ShortenedShifts)
+	}
+	closed, opened := result.ClosedMembership, result.OpenedMembership
+	if closed.BranchID != fromID || closed.EndsAt == nil || !closed.EndsAt.Equal(opened.StartsAt) {
+		t.Errorf("TransferUser() closed membership = %+v, want the one of branch %d ending at %v", closed, fromID, opened.StartsAt)
+	}
+	if opened.BranchID != toID || opened.EndsAt != nil || opened.Reason == nil || *opened.Reason != "Reorganization" {
+		t.Errorf("TransferUser() opened membership = %+v, want an open one of branch %d", opened, toID)
+	}
+
+	// The history lists the new membership first
+	memberships, err := services.Users.GetBranchMembershipsByUserID(ctx, officer.ID)
+	if err != nil {
+		t.Fatalf("GetBranchMembershipsByUserID() error = %v", err)
+	}
+	if len(memberships) != 2 || memberships[0].ID != opened.ID || memberships[1].ID != closed.ID {
+		t.Errorf("GetBranchMembershipsByUserID() = %+v, want the opened then the closed membership", memberships)
+	}
+
+	user, err := services.Users.GetUserByID(ctx, officer.ID)
+	if err != nil || user == nil || user.BranchId != toID {
+		t.Errorf("GetUserByID() after the transfer = %+v, %v, want branch %d", user, err, toID)
+	}
+
+	released, err := store.GetBranchCounterByID(ctx, counter.ID)
+	if err != nil || released == nil || released.UserID != 0 {
+		t.Errorf("counter after the transfer = %+v, %v, want it released", released, err)
+	}
+	kept, err := store.GetBranchCounterByID(ctx, otherCounter.ID)
+	if err != nil || kept == nil || kept.UserID != colleague.ID {
+		t.Errorf("counter of the colleague after the transfer = %+v, %v, want it kept", kept, err)
+	}
+
+	// The past shift is kept, the running one ends at the transfer, the upcoming one is cancelled and the
+	// colleague's is left alone
+	remaining, err := store.GetCounterAssignmentsByBranchID(ctx, fromID, now.Add(-24*time.Hour), now.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("GetCounterAssignmentsByBranchID() error = %v", err)
+	}
+	ends := map[uint]time.Time{}
+	for _, shift := range remaining {
+		ends[shift.ID] = shift.EndsAt
+	}
+	if len(ends) != 3 {
+		t.Errorf("%d shifts left in the old branch office, want 3: %+v", len(ends), remaining)
+	}
+	if end, ok := ends[shifts["past"].ID]; !ok || !end.Equal(shifts["past"].EndsAt) {
+		t.Errorf("past shift ends at %v (kept %v), want it unchanged", end, ok)
+	}
+	if end, ok := ends[shifts["running"].ID]; !ok || !end.Equal(opened.StartsAt) {
+		t.Errorf("running shift ends at %v (kept %v), want %v", end, ok, opened.StartsAt)
+	}
+	if _, ok := ends[shifts["upcoming"].ID]; ok {
+		t.Errorf("upcoming shift kept after the transfer")
+	}
+	if _, ok := ends[shifts["colleague"].ID]; !ok {
+		t.Errorf("shift of the colleague cancelled by the transfer")
+	}
+}
+
+func TestTransferUserRejected(t *testing.T) {
+	ctx := context.Background()
+	services, store := newTestServices(t)
+	fromID := createBranchOffice(t, store, "Riyadh Main")
+	toID := createBranchOffice(t, store, "Jeddah Corniche")
+	officer := createOfficer(t, services, fromID, "officer@example.com")
+
+	tests := []struct {
+		name     string
+		transfer models.TransferRequest
+		want     error
+	}{
+		{"same branch office", models.TransferRequest{UserID: officer.ID, BranchID: fromID}, repository.ErrTransferSameBranch},
+		{"unknown branch office", models.TransferRequest{UserID: officer.ID, BranchID: toID + 100}, repository.ErrBranchOfficeNotFound},
+		{"in the future", models.TransferRequest{UserID: officer.ID, BranchID: toID, EffectiveAt: time.Now().Add(time.Hour)}, ErrTransferInFuture},
+		{"before the membership", models.TransferRequest{UserID: officer.ID, BranchID: toID, EffectiveAt: time.Now().Add(-time.Hour)}, repository.ErrTransferBeforeStart},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transfer := tt.transfer
+			if _, err := services.Users.TransferUser(ctx, &transfer); !errors.Is(err, tt.want) {
+				t.Errorf("TransferUser() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	// Nothing changed
+	memberships, err := services.Users.GetBranchMembershipsByUserID(ctx, officer.ID)
+	if err != nil {
+		t.Fatalf("GetBranchMembershipsByUserID() error = %v", err)
+	}
+	if len(memberships) != 1 || memberships[0].BranchID != fromID || memberships[0].EndsAt != nil {
+		t.Errorf("GetBranchMembershipsByUserID() after the rejected transfers = %+v, want the first membership only", memberships)
+	}
+}
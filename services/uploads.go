@@ -0,0 +1,156 @@
+package services
+
+import (
+	"api-server/imaging"
+	"api-server/models"
+	"api-server/repository"
+	"api-server/storage"
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// UploadService releases and collects the uploaded files no row references anymore
+type UploadService struct {
+	uploads repository.UploadRepository
+	stores  *storage.Stores
+}
+
+// NewUploadService creates an UploadService; the uploads repository tells which files of the stores are
+// referenced
+func NewUploadService(uploads repository.UploadRepository, stores *storage.Stores) *UploadService {
+	return &UploadService{uploads: uploads, stores: stores}
+}
+
+// ReleaseImages deletes the profile images, with their thumbnails, that no user references anymore, e.g.
+// after purging users. Failures are logged, the garbage collector deletes the files left.
+func (s *UploadService) ReleaseImages(ctx context.Context, images []string) {
+	for _, image := range images {
+		releaseImage(ctx, s.uploads, s.stores.Images, image)
+	}
+}
+
+// CollectGarbage deletes the files of the stores that no row references and that are older than minAge,
+// which spares the files of changes still in progress. A dry run only reports them.
+func (s *UploadService) CollectGarbage(ctx context.Context, dryRun bool, minAge time.Duration) (*models.UploadGCResult, error) {
+	result := &models.UploadGCResult{DryRun: dryRun, MinAge: minAge.String(), Stores: []models.UploadGCStoreResult{}}
+
+	images, err := s.uploads.GetReferencedImages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	logos, err := s.uploads.GetReferencedLogos(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// References are read before listing, so a file referenced meanwhile is recent and kept
+	before := time.Now().Add(-minAge)
+	for _, store := range []struct {
+		name       string
+		storage    storage.Storage
+		referenced []string
+	}{
+		{"images", s.stores.Images, images},
+		{"assets", s.stores.Assets, logos},
+	} {
+		storeResult, err := collectGarbage(ctx, store.storage, referencedFiles(store.referenced), before, dryRun)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", store.name, err)
+		}
+		storeResult.Store = store.name
+		result.Stores = append(result.Stores, *storeResult)
+	}
+
+	return result, nil
+}
+
+// collectGarbage deletes the files of a store that are not referenced and were stored before a time
+func collectGarbage(ctx context.Context, store storage.Storage, referenced map[string]bool, before time.Time, dryRun bool) (*models.UploadGCStoreResult, error) {
+	files, err := store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.UploadGCStoreResult{Scanned: len(files), Orphaned: []models.OrphanedFile{}}
+	for _, file := range files {
+		switch {
+		case referenced[file.Name]:
+			result.Referenced++
+		case !file.ModTime.Before(before):
+			result.Recent++
+		default:
+			result.Orphaned = append(result.Orphaned, models.OrphanedFile{Name: file.Name, Size: file.Size, ModTime: file.ModTime})
+		}
+	}
+	if dryRun {
+		return result, nil
+	}
+
+	for _, file := range result.Orphaned {
+		if err := store.Delete(ctx, file.Name); err != nil {
+			slog.ErrorContext(ctx, "Error deleting orphaned file", "name", file.Name, "error", err)
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", file.Name, err))
+			continue
+		}
+		result.Deleted++
+		result.FreedBytes += file.Size
+	}
+	return result, nil
+}
+
+// referencedFiles returns the set of the referenced files with their thumbnails
+func referencedFiles(names []string) map[string]bool {
+	referenced := map[string]bool{}
+	for _, name := range names {
+		referenced[name] = true
+		if thumbnail := imaging.ThumbnailName(name); thumbnail != "" {
+			referenced[thumbnail] = true
+		}
+	}
+	return referenced
+}
+
+// imageURLs returns the URLs of a stored image and of its thumbnail, "" for none. The images are private:
+// their raw names are refused by the file server, only these signed URLs can be handed out.
+func imageURLs(images storage.Storage, name string) (string, string) {
+	if name == "" {
+		return "", ""
+	}
+	thumbnail := ""
+	if thumbnailName := imaging.ThumbnailName(name); thumbnailName != "" {
+		thumbnail = images.URL(thumbnailName)
+	}
+	return images.URL(name), thumbnail
+}
+
+// releaseImage deletes a profile image no user references anymore; identical images share their name
+func releaseImage(ctx context.Context, uploads repository.UploadRepository, store imaging.Store, image string) {
+	referenced, err := uploads.IsImageReferenced(ctx, image)
+	if err != nil {
+		return // Kept for the garbage collector
+	}
+	if referenced {
+		return
+	}
+	if err := imaging.Remove(ctx, store, image); err != nil {
+		slog.ErrorContext(ctx, "Error deleting user image", "error", err)
+	}
+}
+
+// commitFiles completes the files of a committed database change; files it could not delete are left to
+// the garbage collector
+func commitFiles(ctx context.Context, tx *storage.Transaction) {
+	if err := tx.Commit(ctx); err != nil {
+		slog.ErrorContext(ctx, "Error deleting replaced files", "error", err)
+	}
+}
+
+// rollbackFiles deletes the files of a failed database change; files it could not delete are left to the
+// garbage collector
+func rollbackFiles(ctx context.Context, tx *storage.Transaction) {
+	if err := tx.Rollback(ctx); err != nil {
+		slog.ErrorContext(ctx, "Error deleting staged files", "error", err)
+	}
+}
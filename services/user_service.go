@@ -1,45 +1,121 @@
 package services
 
 import (
+	"api-server/apperror"
+	"api-server/helpers"
+	"api-server/imaging"
 	"api-server/models"
 	"api-server/repository"
+	"api-server/storage"
+	"context"
 	"errors"
+	"log/slog"
+	"time"
 )
 
+// UserService manages users, their authentication and their transfers between branch offices
+type UserService struct {
+	users       repository.UserRepository
+	memberships repository.MembershipRepository
+	uploads     repository.UploadRepository
+	images      storage.Storage
+}
+
+// NewUserService creates a UserService; the memberships record the branch history of users, the profile
+// images are kept in images
+func NewUserService(users repository.UserRepository, memberships repository.MembershipRepository, uploads repository.UploadRepository, images storage.Storage) *UserService {
+	return &UserService{users: users, memberships: memberships, uploads: uploads, images: images}
+}
+
 // GetAllUsers retrieves all users with pagination
-func GetAllUsers(limit, offset int, role string) ([]models.UserAllResponse, error) {
-	return repository.GetAllUsers(limit, offset, role)
+func (s *UserService) GetAllUsers(ctx context.Context, limit, offset int, role string) ([]models.UserAllResponse, error) {
+	return s.users.GetAllUsers(ctx, limit, offset, role)
 }
 
 // GetUserByID retrieves a user by ID
-func GetUserByID(id uint) (*models.User, error) {
-	return repository.GetUserByID(id)
+func (s *UserService) GetUserByID(ctx context.Context, id uint) (*models.User, error) {
+	return s.users.GetUserByID(ctx, id)
 }
 
-// CreateUser creates a new user
-func CreateUser(user *models.User) error {
-	// Perform validation before insertion
-	if err := repository.CreateUser(user); err != nil {
+// CreateUser creates a new user, hashing their password. The profile image, if any, is stored before the
+// user and deleted again when the user cannot be created.
+func (s *UserService) CreateUser(ctx context.Context, user *models.User, image *imaging.Image) error {
+	if err := hashPassword(ctx, user); err != nil {
 		return err
 	}
 
+	tx := storage.Begin(s.images)
+	if image != nil {
+		if err := imaging.Save(ctx, tx, image); err != nil {
+			rollbackFiles(ctx, tx)
+			return err
+		}
+		user.Image = image.Name
+	}
+
+	if err := s.users.CreateUser(ctx, user); err != nil {
+		rollbackFiles(ctx, tx)
+		return err
+	}
+
+	commitFiles(ctx, tx)
 	return nil
 }
 
-// UpdateUser updates an existing user
-func UpdateUser(id uint, user *models.User) error {
-	return repository.UpdateUser(id, user)
+// UpdateUser updates an existing user. The password is hashed only when passwordChanged is set,
+// otherwise user.Password holds the stored hash and is kept as is. user.Image holds the stored image,
+// replaced by image when one is given: the new image is stored before the update and deleted again when it
+// fails, the old one is deleted once no user references it.
+func (s *UserService) UpdateUser(ctx context.Context, id uint, user *models.User, passwordChanged bool, image *imaging.Image) error {
+	if passwordChanged {
+		if err := hashPassword(ctx, user); err != nil {
+			return err
+		}
+	}
+
+	oldImage := user.Image
+	tx := storage.Begin(s.images)
+	if image != nil {
+		if err := imaging.Save(ctx, tx, image); err != nil {
+			rollbackFiles(ctx, tx)
+			return err
+		}
+		user.Image = image.Name
+	}
+
+	if err := s.users.UpdateUser(ctx, id, user); err != nil {
+		rollbackFiles(ctx, tx)
+		return err
+	}
+
+	if oldImage != "" && oldImage != user.Image {
+		releaseImage(ctx, s.uploads, tx, oldImage)
+	}
+	commitFiles(ctx, tx)
+	return nil
 }
 
 // DeleteUser deletes a user by ID
-func DeleteUser(id uint) error {
-	return repository.DeleteUser(id)
+func (s *UserService) DeleteUser(ctx context.Context, id uint) error {
+	return s.users.DeleteUser(ctx, id)
+}
+
+// hashPassword replaces the plain password of a user with its hash before it is stored
+func hashPassword(ctx context.Context, user *models.User) error {
+	hashedPassword, err := helpers.HashingPasswordFunc(user.Password)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error hashing password", "error", err)
+		return errors.New("failed to hash password")
+	}
+	user.Password = hashedPassword
+
+	return nil
 }
 
 // GetUsersCount fetches the count of users based on the role from the repository.
-func GetUsersCount(role string) (int, error) {
+func (s *UserService) GetUsersCount(ctx context.Context, role string) (int, error) {
 	// Call the repository function and get the count and error
-	count, err := repository.GetUsersCount(role)
+	count, err := s.users.GetUsersCount(ctx, role)
 	if err != nil {
 		// If there is an error, return 0 and the error
 		return 0, err
@@ -49,36 +125,41 @@ func GetUsersCount(role string) (int, error) {
 	return count, nil
 }
 
-func GetUsersByBranchID(brancdId uint) ([]models.UserByBranchOfiiceResponse, error) {
-	return repository.GetAllUsersByBranchOfiice(brancdId)
+func (s *UserService) GetUsersByBranchID(ctx context.Context, brancdId uint) ([]models.UserByBranchOfiiceResponse, error) {
+	return s.users.GetAllUsersByBranchOfiice(ctx, brancdId)
+}
+
+// GetAvailableOfficersByBranchID lists the officers of a branch that are free during [from, to)
+func (s *UserService) GetAvailableOfficersByBranchID(ctx context.Context, branchId uint, from time.Time, to time.Time) ([]models.UserByBranchOfiiceResponse, error) {
+	return s.users.GetAvailableOfficersByBranchOffice(ctx, branchId, from, to)
 }
 
 // Authentication
-func AuthenticationLoginUser(email string, password string) (models.User, error) {
+func (s *UserService) AuthenticationLoginUser(ctx context.Context, email string, password string) (models.User, error) {
 	// Call the repository function to check authentication
-	user, err := repository.CheckUserAuthentication(email, password)
+	user, err := s.users.CheckUserAuthentication(ctx, email, password)
 	if err != nil {
 		return user, err
 	}
 
 	// If user is not an admin, return an authorization error
-	if user.Role != "administrator" && user.Role != "admin" && user.Role != "supervisor" {
-		return user, errors.New("authorization failed: user is not authorized")
+	if user.Role != "administrator" && user.Role != "admin" && user.Role != "supervisor" && user.Role != "regional_manager" {
+		return user, apperror.Forbidden("authorization failed: user is not authorized")
 	}
 
 	return user, nil
 }
 
-func AuthenticationLoginUserMobile(email string, password string, branch_id uint) (models.User, error) {
+func (s *UserService) AuthenticationLoginUserMobile(ctx context.Context, email string, password string, branch_id uint) (models.User, error) {
 	// Call the repository function to check authentication
-	user, err := repository.CheckUserAuthenticationMobile(email, password, branch_id)
+	user, err := s.users.CheckUserAuthenticationMobile(ctx, email, password, branch_id)
 	if err != nil {
 		return user, err
 	}
 
 	// If user is not an admin, return an authorization error
-	if user.Role != "admin" && user.Role != "supervisor" {
-		return user, errors.New("authorization failed: user is not authorized")
+	if user.Role != "admin" && user.Role != "supervisor" && user.Role != "regional_manager" {
+		return user, apperror.Forbidden("authorization failed: user is not authorized")
 	}
 
 	return user, nil
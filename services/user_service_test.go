@@ -0,0 +1,117 @@
+package services
+
+import (
+	"api-server/config"
+	"api-server/models"
+	"api-server/repository/memory"
+	"api-server/storage"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+// newTestServices creates the services on an empty in-memory store, keeping the uploads in temporary
+// directories
+func newTestServices(t *testing.T) (*Services, *memory.Store) {
+	t.Helper()
+	store := memory.NewStore()
+	stores := &storage.Stores{
+		Images: storage.NewLocal(t.TempDir(), "/images/"),
+		Assets: storage.NewLocal(t.TempDir(), "/assets/"),
+	}
+	return NewServices(store.Repositories(), config.Uploads{}, stores), store
+}
+
+// createBranchOffice creates a branch office and returns its ID
+func createBranchOffice(t *testing.T, store *memory.Store, name string) uint {
+	t.Helper()
+	ctx := context.Background()
+	request := &models.BranchOfficeCreateRequest{Name: name, Address: name + " Street", TotalCounter: 3, Timezone: "Asia/Riyadh"}
+	if err := store.CreateBranchOffice(ctx, request); err != nil {
+		t.Fatalf("creating branch office %q: %v", name, err)
+	}
+
+	options, err := store.GetAllBranchOfficesOption(ctx)
+	if err != nil {
+		t.Fatalf("listing the branch offices: %v", err)
+	}
+	for _, option := range options {
+		if option.Name == name {
+			return option.ID
+		}
+	}
+	t.Fatalf("branch office %q not found after creating it", name)
+	return 0
+}
+
+// createOfficer creates an officer of a branch office through the service and returns them
+func createOfficer(t *testing.T, services *Services, branchID uint, email string) *models.User {
+	t.Helper()
+	user := &models.User{FullName: "Test Officer", Email: email, Password: "secret123", Role: "officer", BranchId: branchID}
+	if err := services.Users.CreateUser(context.Background(), user, nil); err != nil {
+		t.Fatalf("creating officer %s: %v", email, err)
+	}
+	return user
+}
+
+// isUniqueViolation tells whether an error is reported like a PostgreSQL unique constraint violation
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+func TestUserServiceEmailReusedAfterDelete(t *testing.T) {
+	ctx := context.Background()
+	services, store := newTestServices(t)
+	branchID := createBranchOffice(t, store, "Riyadh Main")
+
+	first := createOfficer(t, services, branchID, "officer@example.com")
+
+	// Emails are unique among active users, whatever their case
+	duplicate := &models.User{FullName: "Other Officer", Email: "Officer@Example.com", Password: "secret123", Role: "officer", BranchId: branchID}
+	if err := services.Users.CreateUser(ctx, duplicate, nil); !isUniqueViolation(err) {
+		t.Fatalf("CreateUser() with the email of an active user = %v, want a unique violation", err)
+	}
+
+	if err := services.Users.DeleteUser(ctx, first.ID); err != nil {
+		t.Fatalf("DeleteUser() error = %v", err)
+	}
+	if deleted, err := services.Users.GetUserByID(ctx, first.ID); err != nil || deleted != nil {
+		t.Fatalf("GetUserByID() of a deleted user = %v, %v, want nil", deleted, err)
+	}
+
+	second := createOfficer(t, services, branchID, "officer@example.com")
+	if second.ID == first.ID {
+		t.Errorf("CreateUser() reused the ID %d of the deleted user", first.ID)
+	}
+
+	if stored, err := services.Users.GetUserByID(ctx, second.ID); err != nil || stored == nil || stored.Email != "officer@example.com" {
+		t.Errorf("GetUserByID() of the new user = %v, %v", stored, err)
+	}
+	count, err := services.Users.GetUsersCount(ctx, "officer")
+	if err != nil {
+		t.Fatalf("GetUsersCount() error = %v", err)
+	}
+	if count != 1 {
+		t.Errorf("GetUsersCount() = %d, want 1", count)
+	}
+}
+
+func TestUserServiceRestoreTakenEmail(t *testing.T) {
+	ctx := context.Background()
+	services, store := newTestServices(t)
+	branchID := createBranchOffice(t, store, "Riyadh Main")
+
+	first := createOfficer(t, services, branchID, "officer@example.com")
+	if err := services.Users.DeleteUser(ctx, first.ID); err != nil {
+		t.Fatalf("DeleteUser() error = %v", err)
+	}
+	createOfficer(t, services, branchID, "officer@example.com")
+
+	// The email went to another user meanwhile, the archived one cannot come back with it
+	if err := services.Archive.RestoreUser(ctx, first.ID); !isUniqueViolation(err) {
+		t.Errorf("RestoreUser() of a user whose email was reused = %v, want a unique violation", err)
+	}
+}
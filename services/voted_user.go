@@ -3,8 +3,65 @@ package services
 import (
 	"api-server/models"
 	"api-server/repository"
+	"context"
+	"time"
 )
 
-func VotedUser(voteType string, data *models.User) error {
-	return repository.VotedUserLike(voteType, data)
+// VoteService records the votes cast for officers
+type VoteService struct {
+	votes              repository.VoteRepository
+	branchOffices      repository.BranchOfficeRepository
+	branchCounters     repository.BranchCounterRepository
+	counterAssignments repository.CounterAssignmentRepository
+	operatingHours     *OperatingHoursService
+}
+
+// NewVoteService creates a VoteService. The branch offices, counters and shifts attribute a vote to the
+// counter where it was cast, and the operating hours apply the out-of-hours policy.
+func NewVoteService(votes repository.VoteRepository, branchOffices repository.BranchOfficeRepository, branchCounters repository.BranchCounterRepository,
+	counterAssignments repository.CounterAssignmentRepository, operatingHours *OperatingHoursService) *VoteService {
+	return &VoteService{
+		votes:              votes,
+		branchOffices:      branchOffices,
+		branchCounters:     branchCounters,
+		counterAssignments: counterAssignments,
+		operatingHours:     operatingHours,
+	}
+}
+
+// VotedUser records a vote for an officer. When no counter is given, the vote is attributed to the
+// counter of the officer's current shift, falling back to the counter they are statically linked to.
+// The out-of-hours policy of the officer's branch office decides whether a vote received while the
+// branch is closed is accepted, flagged (returned as true) or rejected with ErrVoteOutsideOperatingHours.
+func (s *VoteService) VotedUser(ctx context.Context, voteType string, data *models.User, counterID *uint) (bool, error) {
+	now := time.Now()
+
+	branchOffice, err := s.branchOffices.GetBranchOfficesById(ctx, data.BranchId)
+	if err != nil {
+		return false, err
+	}
+
+	outOfHours, err := s.operatingHours.CheckVoteOperatingHours(ctx, branchOffice, now)
+	if err != nil {
+		return false, err
+	}
+
+	if counterID == nil {
+		assignment, err := s.counterAssignments.GetActiveAssignmentByUserID(ctx, data.ID, now)
+		if err != nil {
+			return false, err
+		}
+
+		if assignment != nil {
+			counterID = &assignment.CounterID
+		} else {
+			currentCounterID, err := s.branchCounters.GetBranchCounterIDByUserID(ctx, data.ID)
+			if err != nil {
+				return false, err
+			}
+			counterID = currentCounterID
+		}
+	}
+
+	return outOfHours, s.votes.VotedUserLike(ctx, voteType, data, counterID, outOfHours)
 }
@@ -0,0 +1,101 @@
+// The gc command deletes the uploaded files no row references: the profile images not in users.image and
+// the logos not in company_profiles.logo, with their thumbnails. Run it regularly, e.g. daily from cron:
+//
+//	go run ./storage/gc -dry-run     report the orphaned files without deleting them
+//	go run ./storage/gc              delete them
+//
+// Files younger than -min-age are kept, they may belong to an upload in progress.
+package main
+
+import (
+	"api-server/config"
+	"api-server/models"
+	"api-server/repository"
+	"api-server/services"
+	"api-server/storage"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "report the orphaned files without deleting them")
+	minAge := flag.Duration("min-age", 24*time.Hour, "keep orphaned files younger than this")
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	flag.Parse()
+
+	// Load the database and storage settings from the environment, .env or CONFIG_FILE
+	cfg, err := config.LoadUploads()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *minAge < time.Minute {
+		log.Fatal("-min-age must be at least 1m, younger files may belong to an upload in progress")
+	}
+
+	db, err := sql.Open("postgres", cfg.Database.DSN())
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	stores, err := storage.NewStores(ctx, cfg.Storage, cfg.Uploads)
+	if err != nil {
+		log.Fatalf("Failed to open the storage: %v\n", err)
+	}
+
+	uploads := services.NewUploadService(repository.NewPostgresUploadRepository(db, cfg.Database.StatementTimeout), stores)
+	result, err := uploads.CollectGarbage(ctx, *dryRun, *minAge)
+	if err != nil {
+		log.Fatalf("Garbage collection failed: %v\n", err)
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(result); err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		printResult(result)
+	}
+
+	for _, store := range result.Stores {
+		if len(store.Errors) > 0 {
+			os.Exit(1)
+		}
+	}
+}
+
+// printResult prints the orphaned files of every store and what was done with them
+func printResult(result *models.UploadGCResult) {
+	for _, store := range result.Stores {
+		var orphanedBytes int64
+		for _, file := range store.Orphaned {
+			orphanedBytes += file.Size
+		}
+
+		fmt.Printf("%s: %d files, %d referenced, %d orphaned (%d bytes), %d recent kept\n",
+			store.Store, store.Scanned, store.Referenced, len(store.Orphaned), orphanedBytes, store.Recent)
+		for _, file := range store.Orphaned {
+			fmt.Printf("  %s\t%d bytes\t%s\n", file.Name, file.Size, file.ModTime.Format(time.RFC3339))
+		}
+
+		if result.DryRun {
+			fmt.Printf("  dry run, nothing deleted\n")
+			continue
+		}
+		fmt.Printf("  deleted %d files, freed %d bytes\n", store.Deleted, store.FreedBytes)
+		for _, message := range store.Errors {
+			fmt.Printf("  error: %s\n", message)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"api-server/config"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path/filepath"
+)
+
+// Local stores files in a directory. It only suits a single instance, or a directory shared by all.
+type Local struct {
+	dir     string
+	baseURL string
+}
+
+// NewLocal creates a Local storage in a directory, serving the files under baseURL
+func NewLocal(dir string, baseURL string) *Local {
+	return &Local{dir: dir, baseURL: baseURL}
+}
+
+// NewLocalStores stores the uploads in the configured directories
+func NewLocalStores(uploads config.Uploads) *Stores {
+	return &Stores{
+		Images: NewLocal(uploads.ImagesDir, uploads.ImageURL),
+		Assets: NewLocal(uploads.AssetsDir, uploads.AssetURL),
+	}
+}
+
+// Put writes the file under a temporary name and renames it, so a file is never served half written
+func (s *Local) Put(_ context.Context, name string, data io.Reader, _ int64, _ string) error {
+	if !validName(name) {
+		return ErrInvalidName
+	}
+
+	file, err := os.CreateTemp(s.dir, ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name()) // No-op once renamed
+
+	if _, err := io.Copy(file, data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(file.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), filepath.Join(s.dir, name))
+}
+
+func (s *Local) Get(_ context.Context, name string) (*Object, error) {
+	if !validName(name) {
+		return nil, ErrNotExist
+	}
+
+	file, err := os.Open(filepath.Join(s.dir, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if info.IsDir() {
+		file.Close()
+		return nil, ErrNotExist
+	}
+
+	return &Object{
+		ReadSeekCloser: file,
+		Size:           info.Size(),
+		ContentType:    mime.TypeByExtension(filepath.Ext(name)),
+		ModTime:        info.ModTime(),
+	}, nil
+}
+
+func (s *Local) Delete(_ context.Context, name string) error {
+	if !validName(name) {
+		return ErrInvalidName
+	}
+
+	if err := os.Remove(filepath.Join(s.dir, name)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *Local) URL(name string) string {
+	return s.baseURL + name
+}
+
+// List returns the files of the directory, without the temporary files and subdirectories
+func (s *Local) List(_ context.Context) ([]FileInfo, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []FileInfo{}
+	for _, entry := range entries {
+		if entry.IsDir() || !validName(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if errors.Is(err, fs.ErrNotExist) { // Deleted meanwhile
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, FileInfo{Name: entry.Name(), Size: info.Size(), ModTime: info.ModTime()})
+	}
+	return files, nil
+}
+
+// Check creates and removes a file in the directory
+func (s *Local) Check(_ context.Context) error {
+	file, err := os.CreateTemp(s.dir, ".readyz-*")
+	if err != nil {
+		return fmt.Errorf("%s is not writable: %w", s.dir, err)
+	}
+	file.Close()
+	os.Remove(file.Name())
+	return nil
+}
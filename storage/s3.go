@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"api-server/config"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3 stores files in a bucket of an S3-compatible service (AWS S3, MinIO), under a key prefix. The files
+// are still served by the API server, the bucket does not need to be public.
+type S3 struct {
+	client  *minio.Client
+	bucket  string
+	prefix  string
+	baseURL string
+}
+
+// NewS3 creates an S3 storage of the files under a key prefix of a bucket, serving them under baseURL
+func NewS3(client *minio.Client, bucket string, prefix string, baseURL string) *S3 {
+	return &S3{client: client, bucket: bucket, prefix: prefix, baseURL: baseURL}
+}
+
+// NewS3Stores stores the uploads in the configured bucket, which must exist: the profile images under
+// images/ and the company logos under assets/
+func NewS3Stores(ctx context.Context, settings config.S3, uploads config.Uploads) (*Stores, error) {
+	client, err := minio.New(settings.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(settings.AccessKey, settings.SecretKey, ""),
+		Secure: settings.UseSSL,
+		Region: settings.Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := client.BucketExists(ctx, settings.Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("bucket %s: %w", settings.Bucket, err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("bucket %s does not exist", settings.Bucket)
+	}
+
+	return &Stores{
+		Images: NewS3(client, settings.Bucket, "images/", uploads.ImageURL),
+		Assets: NewS3(client, settings.Bucket, "assets/", uploads.AssetURL),
+	}, nil
+}
+
+func (s *S3) Put(ctx context.Context, name string, data io.Reader, size int64, contentType string) error {
+	if !validName(name) {
+		return ErrInvalidName
+	}
+
+	_, err := s.client.PutObject(ctx, s.bucket, s.prefix+name, data, size, minio.PutObjectOptions{ContentType: contentType})
+	return err
+}
+
+func (s *S3) Get(ctx context.Context, name string) (*Object, error) {
+	if !validName(name) {
+		return nil, ErrNotExist
+	}
+
+	object, err := s.client.GetObject(ctx, s.bucket, s.prefix+name, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	// The object is fetched lazily, Stat tells whether it exists
+	info, err := object.Stat()
+	if err != nil {
+		object.Close()
+		if minio.ToErrorResponse(err).StatusCode == http.StatusNotFound {
+			return nil, ErrNotExist
+		}
+		return nil, err
+	}
+
+	return &Object{ReadSeekCloser: object, Size: info.Size, ContentType: info.ContentType, ModTime: info.LastModified}, nil
+}
+
+func (s *S3) Delete(ctx context.Context, name string) error {
+	if !validName(name) {
+		return ErrInvalidName
+	}
+
+	return s.client.RemoveObject(ctx, s.bucket, s.prefix+name, minio.RemoveObjectOptions{})
+}
+
+func (s *S3) URL(name string) string {
+	return s.baseURL + name
+}
+
+// List returns the objects under the key prefix
+func (s *S3) List(ctx context.Context) ([]FileInfo, error) {
+	files := []FileInfo{}
+	for object := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: s.prefix}) {
+		if object.Err != nil {
+			return nil, object.Err
+		}
+		name := strings.TrimPrefix(object.Key, s.prefix)
+		if !validName(name) {
+			continue
+		}
+		files = append(files, FileInfo{Name: name, Size: object.Size, ModTime: object.LastModified})
+	}
+	return files, nil
+}
+
+// Check checks the bucket is reachable
+func (s *S3) Check(ctx context.Context) error {
+	if _, err := s.client.BucketExists(ctx, s.bucket); err != nil {
+		return fmt.Errorf("bucket %s: %w", s.bucket, err)
+	}
+	return nil
+}
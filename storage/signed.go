@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrURLSignature is returned by VerifyURL for a URL that is not signed, or not by this server
+	ErrURLSignature = errors.New("invalid URL signature")
+	// ErrURLExpired is returned by VerifyURL for a signed URL past its expiry
+	ErrURLExpired = errors.New("URL expired")
+)
+
+// URLVerifier is implemented by storages handing out signed URLs: their files are only served to requests
+// carrying a valid signature
+type URLVerifier interface {
+	// VerifyURL checks the query of a request for a file, ErrURLSignature or ErrURLExpired when it is refused
+	VerifyURL(name string, query url.Values) error
+	// Expires returns when the URL of a verified request expires
+	Expires(query url.Values) time.Time
+}
+
+// Signed hands out URLs of the files of a storage signed with an HMAC and an expiry, so private files such
+// as the officer photos can be linked from responses without being public
+type Signed struct {
+	Storage
+	key    []byte
+	expiry time.Duration
+}
+
+var _ URLVerifier = (*Signed)(nil)
+
+// NewSigned signs the URLs of a storage with a key derived from secret, valid for expiry at least
+func NewSigned(store Storage, secret string, expiry time.Duration) *Signed {
+	// The secret may be shared with the tokens, a derived key keeps the signatures of each apart
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("signed file URLs"))
+	return &Signed{Storage: store, key: mac.Sum(nil), expiry: expiry}
+}
+
+// URL returns the signed URL of a file, "" for no file. The expiry is rounded up to half the validity, so
+// a file keeps the same URL for a while and browsers can cache it.
+func (s *Signed) URL(name string) string {
+	if name == "" {
+		return ""
+	}
+	step := s.expiry / 2
+	expires := time.Now().Add(s.expiry).Truncate(step).Add(step).Unix()
+
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", s.sign(name, expires))
+	return s.Storage.URL(name) + "?" + query.Encode()
+}
+
+func (s *Signed) VerifyURL(name string, query url.Values) error {
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil {
+		return ErrURLSignature
+	}
+	signature := query.Get("signature")
+	// Constant-time, so the signature cannot be guessed byte after byte from the response times
+	if !hmac.Equal([]byte(signature), []byte(s.sign(name, expires))) {
+		return ErrURLSignature
+	}
+	if time.Now().Unix() >= expires {
+		return ErrURLExpired
+	}
+	return nil
+}
+
+func (s *Signed) Expires(query url.Values) time.Time {
+	expires, _ := strconv.ParseInt(query.Get("expires"), 10, 64)
+	return time.Unix(expires, 0)
+}
+
+// sign returns the signature of a file name until an expiry, in Unix seconds
+func (s *Signed) sign(name string, expires int64) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(name + "\n" + strconv.FormatInt(expires, 10)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
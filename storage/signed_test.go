@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+const signedName = "0123456789abcdef0123456789abcdef.jpg"
+
+func newTestSigned() *Signed {
+	return NewSigned(NewLocal("", "/images/"), "secret", time.Hour)
+}
+
+// signedQuery returns the query of the URL handed out for a file
+func signedQuery(t *testing.T, s *Signed, name string) url.Values {
+	t.Helper()
+	parsed, err := url.Parse(s.URL(name))
+	if err != nil {
+		t.Fatalf("parsing the signed URL: %v", err)
+	}
+	if parsed.Path != "/images/"+name {
+		t.Fatalf("signed URL path = %q, want %q", parsed.Path, "/images/"+name)
+	}
+	return parsed.Query()
+}
+
+func TestSignedVerifyURL(t *testing.T) {
+	s := newTestSigned()
+	past := time.Now().Add(-time.Minute).Unix()
+
+	tests := []struct {
+		name  string
+		file  string
+		query func(query url.Values) url.Values
+		want  error
+	}{
+		{
+			name:  "valid",
+			file:  signedName,
+			query: func(query url.Values) url.Values { return query },
+		},
+		{
+			name:  "tampered name",
+			file:  "fedcba9876543210fedcba9876543210.jpg",
+			query: func(query url.Values) url.Values { return query },
+			want:  ErrURLSignature,
+		},
+		{
+			name: "tampered expires",
+			file: signedName,
+			query: func(query url.Values) url.Values {
+				expires, _ := strconv.ParseInt(query.Get("expires"), 10, 64)
+				query.Set("expires", strconv.FormatInt(expires+3600, 10))
+				return query
+			},
+			want: ErrURLSignature,
+		},
+		{
+			name: "non-numeric expires",
+			file: signedName,
+			query: func(query url.Values) url.Values {
+				query.Set("expires", "never")
+				return query
+			},
+			want: ErrURLSignature,
+		},
+		{
+			name: "tampered signature",
+			file: signedName,
+			query: func(query url.Values) url.Values {
+				signature := []byte(query.Get("signature"))
+				signature[0] ^= 1
+				query.Set("signature", string(signature))
+				return query
+			},
+			want: ErrURLSignature,
+		},
+		{
+			name: "missing signature",
+			file: signedName,
+			query: func(query url.Values) url.Values {
+				query.Del("signature")
+				return query
+			},
+			want: ErrURLSignature,
+		},
+		{
+			name:  "missing query",
+			file:  signedName,
+			query: func(url.Values) url.Values { return url.Values{} },
+			want:  ErrURLSignature,
+		},
+		{
+			name: "expired",
+			file: signedName,
+			query: func(url.Values) url.Values {
+				return url.Values{
+					"expires":   {strconv.FormatInt(past, 10)},
+					"signature": {s.sign(signedName, past)},
+				}
+			},
+			want: ErrURLExpired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := tt.query(signedQuery(t, s, signedName))
+			if err := s.VerifyURL(tt.file, query); !errors.Is(err, tt.want) {
+				t.Errorf("VerifyURL() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignedVerifyURLOtherSecret(t *testing.T) {
+	query := signedQuery(t, newTestSigned(), signedName)
+	other := NewSigned(NewLocal("", "/images/"), "other secret", time.Hour)
+	if err := other.VerifyURL(signedName, query); !errors.Is(err, ErrURLSignature) {
+		t.Errorf("VerifyURL() with another secret = %v, want %v", err, ErrURLSignature)
+	}
+}
+
+func TestSignedURL(t *testing.T) {
+	s := newTestSigned()
+	if got := s.URL(""); got != "" {
+		t.Errorf("URL(\"\") = %q, want \"\"", got)
+	}
+
+	query := signedQuery(t, s, signedName)
+	expires := s.Expires(query)
+	// Rounded up to half the validity
+	earliest, latest := time.Now().Add(time.Hour-time.Second), time.Now().Add(time.Hour+time.Hour/2)
+	if expires.Before(earliest) || expires.After(latest) {
+		t.Errorf("Expires() = %v, want between %v and %v", expires, earliest, latest)
+	}
+}
@@ -0,0 +1,79 @@
+// Package storage stores the uploaded files, on the local disk of a single instance or in an S3-compatible
+// bucket shared by every instance of the API server.
+package storage
+
+import (
+	"api-server/config"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotExist is returned by Get for a file that is not stored
+	ErrNotExist = errors.New("file does not exist")
+	// ErrInvalidName is returned for names that are not a plain file name
+	ErrInvalidName = errors.New("invalid file name")
+)
+
+// Storage stores files under plain names (no directories)
+type Storage interface {
+	// Put stores a file of the given size, replacing the file of the same name
+	Put(ctx context.Context, name string, data io.Reader, size int64, contentType string) error
+	// Get opens a stored file, ErrNotExist when there is none. The caller closes it.
+	Get(ctx context.Context, name string) (*Object, error)
+	// Delete removes a file; removing a file that is not stored succeeds
+	Delete(ctx context.Context, name string) error
+	// URL returns the URL the file is served at
+	URL(name string) string
+	// List returns every stored file, for collecting the files no row references
+	List(ctx context.Context) ([]FileInfo, error)
+	// Check reports whether files can be stored, for the readiness probe
+	Check(ctx context.Context) error
+}
+
+// FileInfo describes a stored file
+type FileInfo struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+}
+
+// Object is a stored file opened for reading
+type Object struct {
+	io.ReadSeekCloser
+	Size        int64
+	ContentType string // empty when unknown
+	ModTime     time.Time
+}
+
+// Stores holds the storage of each kind of upload
+type Stores struct {
+	Images Storage // The profile images, served under /images at signed URLs
+	Assets Storage // The company logos, served under /assets
+}
+
+// NewStores opens the storages of the configured backend. The profile images are served at signed URLs
+// when a signing secret is configured, which Load always provides.
+func NewStores(ctx context.Context, settings config.Storage, uploads config.Uploads) (*Stores, error) {
+	stores := NewLocalStores(uploads)
+	if settings.Backend == config.StorageS3 {
+		var err error
+		if stores, err = NewS3Stores(ctx, settings.S3, uploads); err != nil {
+			return nil, err
+		}
+	}
+
+	if uploads.ImageURLSecret != "" {
+		stores.Images = NewSigned(stores.Images, uploads.ImageURLSecret, uploads.ImageURLExpiry)
+	}
+	return stores, nil
+}
+
+// validName tells whether a name is a plain file name. Hidden names are refused too, they are the
+// temporary files of the local storage.
+func validName(name string) bool {
+	return name != "" && !strings.HasPrefix(name, ".") && !strings.ContainsAny(name, `/\`)
+}
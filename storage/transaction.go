@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// Transaction stages the files of a database change. New files are stored right away, so the committed
+// rows never point at missing files, and deleted again by Rollback when the change fails. Deleted files
+// are only deleted by Commit, once the change no longer references them. The files of a transaction that
+// never ends (e.g. a crash) are left to the garbage collector.
+type Transaction struct {
+	store   Storage
+	created []string
+	deleted []string
+}
+
+// Begin starts a transaction on a storage
+func Begin(store Storage) *Transaction {
+	return &Transaction{store: store}
+}
+
+// Put stores a file now. A file already stored under the name, as content-addressed names are shared, is
+// written again so its age restarts for the garbage collector, but kept by Rollback.
+func (t *Transaction) Put(ctx context.Context, name string, data io.Reader, size int64, contentType string) error {
+	existed, err := t.exists(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	if err := t.store.Put(ctx, name, data, size, contentType); err != nil {
+		return err
+	}
+	if !existed {
+		t.created = append(t.created, name)
+	}
+	return nil
+}
+
+// Delete deletes a file on Commit
+func (t *Transaction) Delete(_ context.Context, name string) error {
+	t.deleted = append(t.deleted, name)
+	return nil
+}
+
+// Commit deletes the files deleted by the transaction, once the database change is committed
+func (t *Transaction) Commit(ctx context.Context) error {
+	var errs []error
+	for _, name := range t.deleted {
+		if err := t.store.Delete(ctx, name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	t.created, t.deleted = nil, nil
+	return errors.Join(errs...)
+}
+
+// Rollback deletes the files created by the transaction, once the database change has failed
+func (t *Transaction) Rollback(ctx context.Context) error {
+	var errs []error
+	for _, name := range t.created {
+		if err := t.store.Delete(ctx, name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	t.created, t.deleted = nil, nil
+	return errors.Join(errs...)
+}
+
+// exists tells whether a file is stored
+func (t *Transaction) exists(ctx context.Context, name string) (bool, error) {
+	object, err := t.store.Get(ctx, name)
+	if errors.Is(err, ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	object.Close()
+	return true, nil
+}